@@ -1,3 +1,6 @@
+import { KeyRound } from 'lucide-react'
+import type { OIDCProviderInfo } from '#/lib/use-public-settings'
+
 // OAuth redirects must be absolute — the browser navigates there directly.
 // Prefer VITE_OAUTH_URL, fall back to VITE_API_URL, and never silently
 // default to an insecure localhost URL in production.
@@ -70,12 +73,20 @@ const ALL_PROVIDERS = [
 
 interface Props {
   availableProviders: string[]
+  oidcProviders?: OIDCProviderInfo[]
 }
 
-export function OAuthButtons({ availableProviders }: Props) {
+export function OAuthButtons({ availableProviders, oidcProviders = [] }: Props) {
   if (!OAUTH_BASE) return null
 
-  const filtered = ALL_PROVIDERS.filter((p) => availableProviders.includes(p.id))
+  const filtered = [
+    ...ALL_PROVIDERS.filter((p) => availableProviders.includes(p.id)),
+    ...oidcProviders.map((p) => ({
+      id: p.id,
+      label: `Continue with ${p.displayName}`,
+      icon: <KeyRound className="h-4 w-4" aria-hidden="true" />,
+    })),
+  ]
   if (filtered.length === 0) return null
 
   return (
@@ -1,6 +1,7 @@
 import { useRoomContext } from '@livekit/components-react'
 import { RoomEvent } from 'livekit-client'
 import { createContext, type ReactNode, useCallback, useContext, useEffect, useMemo, useRef, useState } from 'react'
+import { api } from '#/lib/api'
 import { useUserStore } from '#/lib/user.store'
 import { useChatPersistence } from './chat/useChatPersistence'
 
@@ -31,6 +32,43 @@ export interface ChatMessage {
   isLocal: boolean
 }
 
+// StoredChatMessage is a chat message as the server stores and returns it.
+// Attachments carry no kind: every stored attachment is an image.
+interface StoredChatMessage {
+  id: string
+  senderIdentity: string
+  senderName: string
+  message: string
+  attachments: Omit<ChatAttachment, 'kind'>[] | null
+  createdAt: string
+}
+
+function toImageAttachments(raw: unknown): ChatAttachment[] {
+  if (!Array.isArray(raw)) return []
+  return raw.map((a) => ({ ...(a as Omit<ChatAttachment, 'kind'>), kind: 'image' as const }))
+}
+
+function fromStored(m: StoredChatMessage, currentUserId: string): ChatMessage {
+  return {
+    id: m.id,
+    timestamp: Date.parse(m.createdAt) || Date.now(),
+    senderName: m.senderName,
+    senderIdentity: m.senderIdentity,
+    message: m.message,
+    attachments: toImageAttachments(m.attachments),
+    isLocal: m.senderIdentity === currentUserId,
+  }
+}
+
+// mergeChatMessages adds the messages not seen yet, keeping time order. The
+// server relays every message to its sender too, so duplicates are expected.
+function mergeChatMessages(prev: ChatMessage[], incoming: ChatMessage[]): ChatMessage[] {
+  const seen = new Set(prev.map((m) => m.id))
+  const fresh = incoming.filter((m) => !seen.has(m.id))
+  if (fresh.length === 0) return prev
+  return [...prev, ...fresh].sort((a, b) => a.timestamp - b.timestamp)
+}
+
 const KNOWN_SYSTEM_EVENTS = new Set(['kick', 'ban', 'ask_unmute', 'ask_camera', 'spotlight', 'deafen', 'undeafen'])
 
 // ── Room context (static / slow-changing metadata) ──────────────────────────
@@ -142,22 +180,21 @@ export function MeetingProvider({ roomId, roomName, adminId, children }: Meeting
           return
         }
 
-        if (topic === 'chat' && raw.type === 'chat') {
-          // Resolve sender identity from the participant object (RemoteParticipant)
-          const p = participant as { identity?: string; name?: string } | null
-          const senderIdentity = (raw.senderIdentity as string) || p?.identity || ''
-          const senderName = (raw.senderName as string) || p?.name || p?.identity || 'Unknown'
-
+        // Chat is only accepted from the server, which stores and moderates
+        // every message before relaying it. Packets published by participants
+        // themselves are ignored.
+        if (topic === 'chat' && raw.type === 'chat' && !participant && typeof raw.id === 'string') {
+          const senderIdentity = (raw.senderIdentity as string) || ''
           const msg: ChatMessage = {
-            id: (raw.id as string) || crypto.randomUUID(),
+            id: raw.id,
             timestamp: (raw.timestamp as number) || Date.now(),
-            senderName,
+            senderName: (raw.senderName as string) || senderIdentity || 'Unknown',
             senderIdentity,
             message: (raw.message as string) || '',
-            attachments: Array.isArray(raw.attachments) ? (raw.attachments as ChatAttachment[]) : [],
-            isLocal: false,
+            attachments: toImageAttachments(raw.attachments),
+            isLocal: senderIdentity === currentUserId,
           }
-          setChatMessages((prev) => [...prev, msg])
+          setChatMessages((prev) => mergeChatMessages(prev, [msg]))
         }
       } catch {
         // Silently discard malformed data messages — a malicious participant
@@ -170,6 +207,24 @@ export function MeetingProvider({ roomId, roomName, adminId, children }: Meeting
     }
   }, [room, currentUserId])
 
+  // Load the stored history, e.g. what was said before this user joined.
+  useEffect(() => {
+    let cancelled = false
+    api
+      .get<{ messages: StoredChatMessage[] }>(`/api/room/${roomId}/messages`)
+      .then((res) => {
+        if (cancelled) return
+        const history = res.messages.map((m) => fromStored(m, currentUserId))
+        setChatMessages((prev) => mergeChatMessages(prev, history))
+      })
+      .catch((err) => {
+        if (import.meta.env.DEV) console.error('[MeetingContext] failed to load chat history:', err)
+      })
+    return () => {
+      cancelled = true
+    }
+  }, [roomId, currentUserId])
+
   // Increment unread counter only for messages that arrive after the last markRead()
   useEffect(() => {
     const chatDelta = chatMessages.length - chatSeenRef.current
@@ -187,46 +242,18 @@ export function MeetingProvider({ roomId, roomName, adminId, children }: Meeting
     setUnreadCount(0)
   }, [chatMessages.length, systemMessages.length])
 
-  // sendChat publishes a reliable data packet on the "chat" topic.
-  // The message is also echoed locally immediately for zero-latency feedback.
+  // sendChat posts the message to the server, which stores it, enforces chat
+  // blocks and relays it to everyone in the room.
   const sendChat = useCallback(
     (text: string, attachments?: ChatAttachment[]) => {
-      const lp = room.localParticipant
-      const id = crypto.randomUUID()
-      const timestamp = Date.now()
-      const senderName = lp.name || lp.identity || 'You'
-      const senderIdentity = lp.identity || ''
-
-      const payload = {
-        type: 'chat',
-        id,
-        timestamp,
-        senderName,
-        senderIdentity,
-        message: text,
-        attachments: attachments ?? [],
-      }
-
-      const data = new TextEncoder().encode(JSON.stringify(payload))
-      lp.publishData(data, { reliable: true, topic: 'chat' }).catch((err) => {
-        if (import.meta.env.DEV) console.error('[MeetingContext] failed to publish chat message:', err)
-      })
-
-      // Local echo so the sender sees the message immediately
-      setChatMessages((prev) => [
-        ...prev,
-        {
-          id,
-          timestamp,
-          senderName,
-          senderIdentity,
-          message: text,
-          attachments: attachments ?? [],
-          isLocal: true,
-        },
-      ])
+      api
+        .post<StoredChatMessage>(`/api/room/${roomId}/messages`, { message: text, attachments: attachments ?? [] })
+        .then((m) => setChatMessages((prev) => mergeChatMessages(prev, [fromStored(m, currentUserId)])))
+        .catch((err) => {
+          if (import.meta.env.DEV) console.error('[MeetingContext] failed to send chat message:', err)
+        })
     },
-    [room],
+    [roomId, currentUserId],
   )
 
   const toggleSelfDeafen = useCallback(() => {
@@ -1,10 +1,16 @@
 import { api } from '#/lib/api'
 
+export interface OIDCProviderInfo {
+  id: string
+  displayName: string
+}
+
 export interface PublicSettings {
   registrationEnabled: boolean
   tokenRegistrationOnly: boolean
   passkeysEnabled: boolean
   oauthProviders: string[]
+  oidcProviders?: OIDCProviderInfo[]
 }
 
 let cached: PublicSettings | null = null
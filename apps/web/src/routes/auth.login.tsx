@@ -41,6 +41,7 @@ function LoginPage() {
 
   const showPasskey = settings?.passkeysEnabled !== false
   const oauthProviders = settings?.oauthProviders ?? []
+  const oidcProviders = settings?.oidcProviders ?? []
   const hasAltAuth = showPasskey || oauthProviders.length > 0
 
   function handleSuccess(res: AuthResponse) {
@@ -205,7 +206,7 @@ function LoginPage() {
       )}
 
       {/* OAuth */}
-      <OAuthButtons availableProviders={oauthProviders} />
+      <OAuthButtons availableProviders={oauthProviders} oidcProviders={oidcProviders} />
 
       {/* Footer links */}
       <p className="text-center text-sm text-muted-foreground">
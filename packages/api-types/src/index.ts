@@ -6,6 +6,10 @@ export interface RoomSettings {
   allowAudio: boolean;
   requireApproval: boolean;
   e2ee: boolean;
+  /** Lets guests into E2EE rooms; otherwise they are turned away. */
+  e2eeAllowGuests?: boolean;
+  /** Days to keep chat history; 0 keeps it for the lifetime of the room. */
+  chatRetentionDays: number;
 }
 
 export interface RoomParticipant {
@@ -33,6 +37,12 @@ export interface Room {
   settings: RoomSettings;
   relationship?: string;
   mode: string;
+  hasPasscode?: boolean;
+  parentRoomId?: string;
+  permanent?: boolean;
+  lastActivityAt?: string;
+  archivedAt?: string;
+  livekitNode?: string;
   participants?: RoomParticipant[];
 }
 
@@ -43,6 +53,8 @@ export interface User {
   avatarUrl?: string;
   provider?: string;
   isAdmin?: boolean;
+  emailVerified?: boolean;
+  mfaEnabled?: boolean;
 }
 
 export interface AdminUser {
@@ -63,6 +75,21 @@ export interface Message {
   isLocal: boolean;
 }
 
+/** A signed-in device, from GET /auth/sessions. */
+export interface Session {
+  id: string;
+  userId: string;
+  deviceName: string;
+  userAgent: string;
+  ip: string;
+  /** Whether the session signed in with a second factor or a passkey. */
+  mfa: boolean;
+  expiresAt: string;
+  createdAt: string;
+  lastUsedAt: string;
+  current: boolean;
+}
+
 export interface AuthTokens {
   accessToken: string;
   refreshToken: string;
@@ -86,6 +113,49 @@ export interface LoginResponse {
   };
 }
 
+/** Returned by login instead of tokens when the account has two-factor authentication enabled. */
+export interface MFAChallengeResponse {
+  mfaRequired: true;
+  mfaToken: string;
+}
+
+export interface MFAVerifyRequest {
+  mfaToken: string;
+  /** A TOTP code or an unused recovery code. */
+  code: string;
+}
+
+export interface MFAStatus {
+  enabled: boolean;
+  /** Whether the site requires two-factor authentication for this account. */
+  required: boolean;
+  recoveryCodesRemaining: number;
+}
+
+export interface MFAEnrollment {
+  secret: string;
+  /** otpauth:// provisioning URI, to be shown as a QR code. */
+  uri: string;
+}
+
+export interface MFARecoveryCodesResponse {
+  recoveryCodes: string[];
+}
+
+export interface VerifyEmailRequest {
+  token: string;
+}
+
+export interface ForgotPasswordRequest {
+  email: string;
+}
+
+/** Token comes from the link in the password reset email. */
+export interface ResetPasswordRequest {
+  token: string;
+  password: string;
+}
+
 export interface GuestLoginRequest {
   name: string;
 }
@@ -124,11 +194,31 @@ export interface CreateRoomRequest {
   maxParticipants?: number;
   isPublic?: boolean;
   mode?: string;
+  permanent?: boolean;
+  /** Preferred LiveKit region; defaults to GeoIP placement. */
+  region?: string;
   settings?: RoomSettings;
 }
 
 export interface JoinRoomRequest {
   roomName: string;
+  passcode?: string;
+  inviteToken?: string;
+}
+
+export interface GuestJoinRoomRequest {
+  roomName: string;
+  guestName: string;
+  passcode?: string;
+  inviteToken?: string;
+}
+
+export interface UpdateRoomSettingsRequest {
+  isPublic?: boolean;
+  maxParticipants?: number;
+  settings?: RoomSettings;
+  /** Sets or rotates the join passcode; an empty string clears it. */
+  passcode?: string;
 }
 
 export interface JoinRoomResponse {
@@ -144,6 +234,18 @@ export interface JoinRoomResponse {
   expiresAt: string;
   settings: RoomSettings;
   mode: string;
+  /** Present for rooms with settings.e2ee. */
+  e2ee?: E2EEKey;
+}
+
+/**
+ * A room's shared end-to-end encryption key. After a "rekey" system message,
+ * fetch the new version from E2EE_KEY (or GUEST_E2EE_KEY for guests).
+ */
+export interface E2EEKey {
+  /** Raw key, base64 encoded. */
+  key: string;
+  version: number;
 }
 
 export interface UserRoomResponse {
@@ -167,12 +269,369 @@ export interface UpdateUserStatusRequest {
 }
 
 export interface GenerateRoomTokenRequest {
-  userId: string;
-  duration?: number;
+  identity?: string;
+  name?: string;
+  metadata?: string;
+  ttlSeconds?: number;
+  hidden?: boolean;
+  recorder?: boolean;
+  subscribeOnly?: boolean;
+  canPublishSources?: ("camera" | "microphone" | "screen_share" | "screen_share_audio")[];
 }
 
 export interface GenerateRoomTokenResponse {
+  id: string;
   token: string;
+  identity: string;
+  room: string;
+  expiresAt: string;
+  livekitHost: string;
+}
+
+export interface IssuedRoomToken {
+  id: string;
+  roomId: string;
+  roomName: string;
+  identity: string;
+  name: string;
+  metadata: string;
+  hidden: boolean;
+  recorder: boolean;
+  subscribeOnly: boolean;
+  canPublishSources: string[];
+  issuedBy: string;
+  expiresAt: string;
+  createdAt: string;
+}
+
+export type RecordingType = "composite" | "track";
+
+export type RecordingStatus =
+  | "starting"
+  | "active"
+  | "ending"
+  | "complete"
+  | "failed"
+  | "aborted"
+  | "limit_reached";
+
+export interface StartRecordingRequest {
+  type?: RecordingType;
+  trackSid?: string;
+  layout?: string;
+  audioOnly?: boolean;
+}
+
+export interface Recording {
+  id: string;
+  roomId: string;
+  egressId: string;
+  type: RecordingType;
+  trackSid?: string;
+  status: RecordingStatus;
+  startedBy: string;
+  backend: string;
+  size: number;
+  durationSeconds: number;
+  error?: string;
+  startedAt: string;
+  endedAt?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export type IngressType = "rtmp" | "whip";
+
+export interface CreateIngressRequest {
+  type?: IngressType;
+  name?: string;
+}
+
+export interface RoomIngress {
+  id: string;
+  roomId: string;
+  ingressId: string;
+  type: IngressType;
+  name: string;
+  participantIdentity: string;
+  url: string;
+  streamKey: string;
+  createdBy: string;
+  createdAt: string;
+}
+
+export interface ChatAttachment {
+  url: string;
+  mime: string;
+  size: number;
+  w: number;
+  h: number;
+}
+
+export interface ChatMessage {
+  id: string;
+  roomId: string;
+  senderIdentity: string;
+  senderName: string;
+  message: string;
+  attachments: ChatAttachment[];
+  createdAt: string;
+}
+
+export interface PostChatMessageRequest {
+  message: string;
+  attachments?: ChatAttachment[];
+}
+
+export interface ChatMessagesResponse {
+  messages: ChatMessage[];
+  nextCursor?: string;
+}
+
+export interface CreateBreakoutsRequest {
+  count: number;
+  assign?: "manual" | "random";
+  /** Participant identity → breakout number (1..count). */
+  assignments?: Record<string, number>;
+}
+
+export interface CreateBreakoutsResponse {
+  rooms: Room[];
+  /** Participant identity → breakout room ID. */
+  assignments: Record<string, string>;
+}
+
+export interface BreakoutRoom extends Room {
+  participants: { identity: string; name: string }[];
+}
+
+export interface MoveParticipantRequest {
+  identity: string;
+  targetRoomId: string;
+}
+
+export interface CloseBreakoutsRequest {
+  countdownSeconds?: number;
+}
+
+/** System message sent on the "system" topic for breakout events. */
+export interface BreakoutSystemMessage {
+  type: "system";
+  event: "breakout_move" | "breakout_closing";
+  actor: string;
+  target?: string;
+  roomId?: string;
+  roomName?: string;
+  token?: string;
+  livekitHost?: string;
+  seconds?: number;
+}
+
+export type RoomInviteRole = "participant" | "moderator" | "stage";
+
+export interface CreateRoomInviteRequest {
+  role?: RoomInviteRole;
+  /** 0 means unlimited. */
+  maxUses?: number;
+  email?: string;
+  expiresInHours?: number;
+}
+
+export interface RoomInvite {
+  id: string;
+  roomId: string;
+  token: string;
+  email?: string;
+  role: RoomInviteRole;
+  maxUses: number;
+  uses: number;
+  expiresAt: string;
+  revokedAt: string | null;
+  createdBy: string;
+  createdAt: string;
+}
+
+export interface RoomInviteRedemption {
+  id: string;
+  inviteId: string;
+  roomId: string;
+  identity: string;
+  name: string;
+  ip: string;
+  redeemedAt: string;
+}
+
+export interface RoomInviteInfo {
+  roomName: string;
+  role: RoomInviteRole;
+  expiresAt: string;
+  requiresSignIn: boolean;
+}
+
+export type RoomACLType = "user" | "domain" | "group";
+
+export interface RoomACLEntry {
+  id: string;
+  roomId: string;
+  type: RoomACLType;
+  /** User ID or email, email domain, or group (user access label). */
+  value: string;
+  createdBy: string;
+  createdAt: string;
+}
+
+export interface AddACLEntryRequest {
+  type: RoomACLType;
+  value: string;
+}
+
+export interface RoomAccessRequest {
+  id: string;
+  roomId: string;
+  userId: string;
+  name: string;
+  email: string;
+  message: string;
+  status: "pending" | "approved" | "denied";
+  resolvedBy?: string;
+  resolvedAt: string | null;
+  createdAt: string;
+}
+
+export interface BanParticipantRequest {
+  reason?: string;
+  /** Omit or 0 for a permanent ban. */
+  durationMinutes?: number;
+}
+
+export interface RoomBan {
+  id: string;
+  roomId: string;
+  identity: string;
+  displayName: string;
+  reason: string;
+  bannedBy: string;
+  expiresAt: string | null;
+  createdAt: string;
+}
+
+export type BlocklistEntryType = "user" | "email" | "domain" | "ip";
+
+export interface BlocklistEntry {
+  id: string;
+  type: BlocklistEntryType;
+  /** User ID, lowercased email, email domain, or CIDR range. */
+  value: string;
+  reason: string;
+  createdBy: string;
+  expiresAt: string | null;
+  createdAt: string;
+}
+
+export interface AddBlocklistEntryRequest {
+  /** Guessed from the value when omitted. */
+  type?: BlocklistEntryType;
+  value: string;
+  reason?: string;
+  /** Omit or 0 to block until removed. */
+  expiresInHours?: number;
+}
+
+export interface ScheduleMeetingRequest {
+  title: string;
+  description?: string;
+  /** RFC 3339 timestamp, or a local date and time ("2026-05-04T09:30") in timeZone. */
+  startAt: string;
+  durationMinutes: number;
+  /** IANA time zone; defaults to UTC. */
+  timeZone?: string;
+  /** RFC 5545 recurrence rule, e.g. "FREQ=WEEKLY;BYDAY=MO". */
+  rrule?: string;
+  /** Refuse joins earlier than this many minutes before an occurrence. */
+  earlyJoinMinutes?: number | null;
+}
+
+export interface ScheduledMeeting {
+  id: string;
+  roomId: string;
+  title: string;
+  description: string;
+  startAt: string;
+  durationMinutes: number;
+  timeZone: string;
+  rrule: string;
+  earlyJoinMinutes: number | null;
+  createdBy: string;
+  createdAt: string;
+  updatedAt: string;
+  /** Current or next occurrence; null once the series is over. */
+  nextStartAt: string | null;
+}
+
+export interface CalendarFeedResponse {
+  /**
+   * Secret ICS subscription URL; rotate it to revoke. Only returned when the
+   * feed is created or rotated.
+   */
+  url?: string;
+  createdAt: string;
+}
+
+export interface RenewRoomRequest {
+  hours?: number;
+  permanent?: boolean;
+}
+
+export interface RoomSession {
+  id: string;
+  roomId: string;
+  startedAt: string;
+  /** Null while the meeting is still running. */
+  endedAt: string | null;
+  durationSeconds: number;
+  attendees: number;
+}
+
+export interface AttendanceInterval {
+  joinedAt: string;
+  leftAt: string | null;
+  durationSeconds: number;
+}
+
+export interface AttendanceRecord {
+  identity: string;
+  displayName: string;
+  email?: string;
+  guest: boolean;
+  firstJoinedAt: string;
+  lastLeftAt: string | null;
+  durationSeconds: number;
+  segments: AttendanceInterval[];
+}
+
+export interface SessionAttendanceResponse {
+  session: Omit<RoomSession, "durationSeconds" | "attendees">;
+  attendance: AttendanceRecord[];
+}
+
+export interface UsagePoint {
+  start: string;
+  samples: number;
+  participantsMax: number;
+  participantsAvg: number;
+  roomsMax: number;
+  roomsAvg: number;
+  publishersMax: number;
+  publishersAvg: number;
+  participantMinutes: number;
+  roomMinutes: number;
+}
+
+export interface StatsHistoryResponse {
+  bucket: "hour" | "day" | "week" | "month";
+  from: string;
+  to: string;
+  points: UsagePoint[];
 }
 
 // ─── Endpoint Constants ───
@@ -185,6 +644,18 @@ export const API_ENDPOINTS = {
     REFRESH: "/auth/refresh",
     LOGOUT: "/auth/logout",
     ME: "/auth/me",
+    SESSIONS: "/auth/sessions",
+    SESSION: (sessionId: string) => `/auth/sessions/${sessionId}`,
+    MFA: "/auth/mfa",
+    MFA_ENROLL: "/auth/mfa/enroll",
+    MFA_ENABLE: "/auth/mfa/enable",
+    MFA_DISABLE: "/auth/mfa/disable",
+    MFA_RECOVERY_CODES: "/auth/mfa/recovery-codes",
+    MFA_VERIFY: "/auth/mfa/verify",
+    VERIFY_EMAIL: "/auth/verify-email",
+    VERIFY_EMAIL_RESEND: "/auth/verify-email/resend",
+    PASSWORD_FORGOT: "/auth/password/forgot",
+    PASSWORD_RESET: "/auth/password/reset",
     PASSKEY_REGISTER_BEGIN: "/auth/passkey/register/begin",
     PASSKEY_REGISTER_FINISH: "/auth/passkey/register/finish",
     PASSKEY_LOGIN_BEGIN: "/auth/passkey/login/begin",
@@ -202,6 +673,10 @@ export const API_ENDPOINTS = {
       `/room/${roomId}/kick/${identity}`,
     MUTE: (roomId: string, identity: string) =>
       `/room/${roomId}/mute/${identity}`,
+    BAN: (roomId: string, identity: string) =>
+      `/room/${roomId}/ban/${identity}`,
+    BANS: (roomId: string) => `/room/${roomId}/bans`,
+    BAN_LIFT: (roomId: string, banId: string) => `/room/${roomId}/bans/${banId}`,
     VIDEO_OFF: (roomId: string, identity: string) =>
       `/room/${roomId}/video/${identity}/off`,
     STAGE_BRING: (roomId: string, identity: string) =>
@@ -209,12 +684,62 @@ export const API_ENDPOINTS = {
     STAGE_REMOVE: (roomId: string, identity: string) =>
       `/room/${roomId}/stage/${identity}/remove`,
     SETTINGS: (roomId: string) => `/room/${roomId}/settings`,
+    MESSAGES: (roomId: string) => `/room/${roomId}/messages`,
+    CHAT_BLOCK: (roomId: string, identity: string) =>
+      `/room/${roomId}/chat/${identity}/block`,
+    CHAT_UNBLOCK: (roomId: string, identity: string) =>
+      `/room/${roomId}/chat/${identity}/unblock`,
+    RECORDINGS: (roomId: string) => `/room/${roomId}/recordings`,
+    RECORDING_START: (roomId: string) => `/room/${roomId}/recordings/start`,
+    RECORDING_STOP: (roomId: string, recordingId: string) =>
+      `/room/${roomId}/recordings/${recordingId}/stop`,
+    RECORDING_DOWNLOAD: (roomId: string, recordingId: string) =>
+      `/room/${roomId}/recordings/${recordingId}/download`,
+    INGRESS: (roomId: string) => `/room/${roomId}/ingress`,
+    INGRESS_DELETE: (roomId: string, ingressId: string) =>
+      `/room/${roomId}/ingress/${ingressId}`,
+    BREAKOUTS: (roomId: string) => `/room/${roomId}/breakouts`,
+    BREAKOUTS_MOVE: (roomId: string) => `/room/${roomId}/breakouts/move`,
+    BREAKOUTS_CLOSE: (roomId: string) => `/room/${roomId}/breakouts/close`,
+    INVITES: (roomId: string) => `/room/${roomId}/invites`,
+    INVITE: (roomId: string, inviteId: string) =>
+      `/room/${roomId}/invites/${inviteId}`,
+    INVITE_REDEMPTIONS: (roomId: string, inviteId: string) =>
+      `/room/${roomId}/invites/${inviteId}/redemptions`,
+    INVITE_INFO: (token: string) => `/room/invite/${token}`,
+    ACL: (roomId: string) => `/room/${roomId}/acl`,
+    ACL_ENTRY: (roomId: string, entryId: string) =>
+      `/room/${roomId}/acl/${entryId}`,
+    ACCESS_REQUESTS: (roomId: string) => `/room/${roomId}/access-requests`,
+    ACCESS_REQUEST_APPROVE: (roomId: string, requestId: string) =>
+      `/room/${roomId}/access-requests/${requestId}/approve`,
+    ACCESS_REQUEST_DENY: (roomId: string, requestId: string) =>
+      `/room/${roomId}/access-requests/${requestId}/deny`,
+    RENEW: (roomId: string) => `/room/${roomId}/renew`,
+    SESSIONS: (roomId: string) => `/room/${roomId}/sessions`,
+    E2EE_KEY: (roomId: string) => `/room/${roomId}/e2ee-key`,
+    GUEST_E2EE_KEY: (roomId: string, identity: string) =>
+      `/room/${roomId}/e2ee-key/guest/${identity}`,
+    SESSION_ATTENDANCE: (roomId: string, sessionId: string) =>
+      `/room/${roomId}/sessions/${sessionId}/attendance`,
+    MEETINGS: (roomId: string) => `/room/${roomId}/meetings`,
+    MEETING: (roomId: string, meetingId: string) =>
+      `/room/${roomId}/meetings/${meetingId}`,
+    MEETING_ICS: (roomId: string, meetingId: string) =>
+      `/room/${roomId}/meetings/${meetingId}/ics`,
+    CALENDAR_FEED: "/calendar/feed",
+    CALENDAR_FEED_ROTATE: "/calendar/feed/rotate",
   },
   ADMIN: {
     USERS: "/admin/users",
     USER_STATUS: (userId: string) => `/admin/users/${userId}/status`,
+    USER_SESSIONS: (userId: string) => `/admin/users/${userId}/sessions`,
     ROOMS: "/admin/rooms",
     ROOM: (roomId: string) => `/admin/rooms/${roomId}`,
     ROOM_TOKEN: (roomId: string) => `/admin/rooms/${roomId}/token`,
+    ROOM_TOKENS: (roomId: string) => `/admin/rooms/${roomId}/tokens`,
+    STATS_HISTORY: "/admin/stats/history",
+    BLOCKLIST: "/admin/blocklist",
+    BLOCKLIST_ENTRY: (id: string) => `/admin/blocklist/${id}`,
   },
 } as const;
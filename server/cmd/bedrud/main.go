@@ -102,6 +102,9 @@ func main() {
 		lkDomainFlag := installCmd.String("livekit-domain", "", "Separate domain for the local LiveKit server (e.g. lk.example.com, bypasses CDN)")
 		lkIPFlag := installCmd.String("lk-ip", "", "Separate IP for LiveKit NodeIP (when server behind CDN, LiveKit needs direct-reachable IP)")
 		lkUDPPortRangeFlag := installCmd.String("lk-udp-range", "", "UDP port range for WebRTC media, e.g. 50000-60000 (default 50000-60000)")
+		ingressFlag := installCmd.Bool("ingress", false, "Configure LiveKit Ingress for RTMP/WHIP streaming into rooms")
+		ingressRTMPPortFlag := installCmd.String("ingress-rtmp-port", "", "Override ingress RTMP port (default 1935)")
+		ingressWHIPPortFlag := installCmd.String("ingress-whip-port", "", "Override ingress WHIP port (default 8080)")
 		_ = installCmd.Parse(os.Args[2:])
 
 		lkUDPPortRangeStart := ""
@@ -137,6 +140,9 @@ func main() {
 			ExternalLKURL:       *externalLKFlag,
 			LKDomain:            *lkDomainFlag,
 			LKIP:                *lkIPFlag,
+			Ingress:             *ingressFlag,
+			IngressRTMPPort:     *ingressRTMPPortFlag,
+			IngressWHIPPort:     *ingressWHIPPortFlag,
 		}
 
 		if err := install.LinuxInstall(&cfg); err != nil {
@@ -204,6 +210,43 @@ func main() {
 			os.Exit(1)
 		}
 
+	case "ban":
+		banCmd := flag.NewFlagSet("ban", flag.ExitOnError)
+		configPath := banCmd.String("config", "/etc/bedrud/config.yaml", "Path to Bedrud config file")
+		_ = banCmd.Parse(os.Args[2:])
+
+		if len(banCmd.Args()) == 0 {
+			fmt.Println("Usage: bedrud ban <subcommand> [flags]")
+			fmt.Println("  add    --value <value> [--type user|email|domain|ip] [--reason <text>] [--for <duration>]")
+			fmt.Println("  remove --value <value> [--type user|email|domain|ip]")
+			fmt.Println("  list")
+			os.Exit(1)
+		}
+		sub := banCmd.Args()[0]
+		subCmd := flag.NewFlagSet(sub, flag.ExitOnError)
+		typeFlag := subCmd.String("type", "", "Entry type: user, email, domain or ip (guessed from the value if omitted)")
+		valueFlag := subCmd.String("value", "", "User ID, email, email domain, IP address or CIDR range")
+		reasonFlag := subCmd.String("reason", "", "Reason for the ban")
+		forFlag := subCmd.Duration("for", 0, "How long the ban lasts, e.g. 72h (default: until removed)")
+		_ = subCmd.Parse(banCmd.Args()[1:])
+
+		var err error
+		switch sub {
+		case "add":
+			err = usercli.BanAdd(*configPath, *typeFlag, *valueFlag, *reasonFlag, *forFlag)
+		case "remove":
+			err = usercli.BanRemove(*configPath, *typeFlag, *valueFlag)
+		case "list":
+			err = usercli.BanList(*configPath)
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown ban subcommand: %s\n", sub)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 	case "version":
 		fmt.Println("bedrud " + version)
 
@@ -236,12 +279,18 @@ func printUsage() {
 	fmt.Println("                   --fresh, --behind-proxy,")
 	fmt.Println("                   --livekit-domain <domain>  (local LK on its own domain)")
 	fmt.Println("                   --external-livekit <url>   (fully separate LK machine)")
+	fmt.Println("                   --ingress [--ingress-rtmp-port, --ingress-whip-port]")
+	fmt.Println("                                              (RTMP/WHIP streaming into rooms)")
 	fmt.Println("  uninstall Uninstall Bedrud from the system")
 	fmt.Println("  user      Manage users")
 	fmt.Println("            create  --email <email> --password <password> --name <name>")
 	fmt.Println("            delete  --email <email>")
 	fmt.Println("            promote --email <email>  Grant superadmin access")
 	fmt.Println("            demote  --email <email>  Remove superadmin access")
+	fmt.Println("  ban       Manage the site-wide blocklist")
+	fmt.Println("            add    --value <v> [--type user|email|domain|ip] [--reason <text>] [--for <duration>]")
+	fmt.Println("            remove --value <v> [--type user|email|domain|ip]")
+	fmt.Println("            list")
 	fmt.Println("  version   Print version")
 	fmt.Println("  help      Show this help message")
 }
@@ -18,6 +18,8 @@ type Config struct {
 	Logger   LoggerConfig   `yaml:"logger"`
 	Cors     CorsConfig     `yaml:"cors"`
 	Chat     ChatConfig     `yaml:"chat"`
+	Rooms    RoomsConfig    `yaml:"rooms"`
+	Mail     MailConfig     `yaml:"mail"`
 }
 
 type ServerConfig struct {
@@ -63,6 +65,83 @@ type LiveKitConfig struct {
 	// External skips the embedded LiveKit server and /livekit proxy.
 	// Set to true when using a separate LiveKit deployment (e.g. lk.bedrud.org).
 	External bool `yaml:"external"`
+	// Recording configures meeting recordings made through LiveKit Egress.
+	Recording RecordingConfig `yaml:"recording"`
+	// Ingress configures RTMP/WHIP streaming into rooms through LiveKit Ingress.
+	Ingress IngressConfig `yaml:"ingress"`
+	// Nodes lists the LiveKit servers rooms can be placed on. When empty, the
+	// host and credentials above describe the only node.
+	Nodes []LiveKitNodeConfig `yaml:"nodes"`
+	// Regions maps region names to the visitors placed there by GeoIP.
+	Regions map[string]LiveKitRegionConfig `yaml:"regions"`
+	// GeoIPDatabase is the path to a MaxMind country or city database
+	// (.mmdb). Without it rooms are only placed by the client's region hint.
+	GeoIPDatabase string `yaml:"geoipDatabase"`
+}
+
+// LiveKitNodeConfig describes one LiveKit server.
+type LiveKitNodeConfig struct {
+	// ID is stored on every room placed on the node, so keep it stable.
+	ID            string `yaml:"id"`
+	Region        string `yaml:"region"`
+	Host          string `yaml:"host"`
+	InternalHost  string `yaml:"internalHost"`
+	APIKey        string `yaml:"apiKey"`
+	APISecret     string `yaml:"apiSecret"`
+	SkipTLSVerify bool   `yaml:"skipTLSVerify"`
+	// Weight sets the node's share of new rooms within its region (default 1).
+	Weight int `yaml:"weight"`
+}
+
+// LiveKitRegionConfig lists the ISO country codes and continent codes
+// (AF, AN, AS, EU, NA, OC, SA) whose visitors get rooms in a region.
+// Countries are matched before continents, and a code listed in several
+// regions goes to the first of them by name.
+type LiveKitRegionConfig struct {
+	Countries  []string `yaml:"countries"`
+	Continents []string `yaml:"continents"`
+}
+
+// DefaultNodeID is the ID of the node described by the top-level LiveKit
+// settings when no nodes are listed.
+const DefaultNodeID = "default"
+
+// NodeConfigs returns the configured LiveKit nodes, or the single node
+// described by the top-level settings.
+func (c *LiveKitConfig) NodeConfigs() []LiveKitNodeConfig {
+	if len(c.Nodes) > 0 {
+		return c.Nodes
+	}
+	return []LiveKitNodeConfig{{
+		ID:            DefaultNodeID,
+		Host:          c.Host,
+		InternalHost:  c.InternalHost,
+		APIKey:        c.APIKey,
+		APISecret:     c.APISecret,
+		SkipTLSVerify: c.SkipTLSVerify,
+	}}
+}
+
+// IngressConfig controls the RTMP/WHIP ingress endpoints. The stream URLs
+// handed out come from the LiveKit server's own ingress settings.
+type IngressConfig struct {
+	// Enabled turns on the ingress endpoints. Requires a running ingress service.
+	Enabled bool `yaml:"enabled"`
+}
+
+// RecordingConfig controls where LiveKit Egress writes meeting recordings.
+// Backend choices: "disk" (default) writes under DiskDir on the egress host,
+// which must be shared with this server for downloads to work;
+// "s3" uploads to an S3-compatible bucket (requires S3 fields).
+type RecordingConfig struct {
+	// Enabled turns on the recording endpoints. Requires a running egress service.
+	Enabled bool `yaml:"enabled"`
+	// Backend is "disk" (default) or "s3".
+	Backend string `yaml:"backend"`
+	// DiskDir is the directory for disk-backend recordings. Default: ./data/recordings
+	DiskDir string `yaml:"diskDir"`
+	// S3 holds connection info for the S3-compatible storage backend.
+	S3 ChatUploadS3Config `yaml:"s3"`
 }
 
 type AuthConfig struct {
@@ -73,6 +152,9 @@ type AuthConfig struct {
 	Twitter       OAuth2Config `yaml:"twitter"`
 	FrontendURL   string       `yaml:"frontendURL"`
 	SessionSecret string       `yaml:"sessionSecret"`
+	// OIDC lists generic OpenID Connect providers such as Keycloak or
+	// Authentik. Providers saved in the admin settings replace this list.
+	OIDC []OIDCProviderConfig `yaml:"oidc"`
 }
 
 type OAuth2Config struct {
@@ -81,6 +163,26 @@ type OAuth2Config struct {
 	RedirectURL  string `yaml:"redirectUrl"`
 }
 
+// OIDCProviderConfig configures a generic OpenID Connect provider. Endpoints
+// are found through discovery on IssuerURL.
+type OIDCProviderConfig struct {
+	// ID names the provider in URLs (/api/auth/{id}/login) and on users.
+	ID           string   `yaml:"id"`
+	DisplayName  string   `yaml:"displayName"`
+	IssuerURL    string   `yaml:"issuerUrl"`
+	ClientID     string   `yaml:"clientId"`
+	ClientSecret string   `yaml:"clientSecret"`
+	RedirectURL  string   `yaml:"redirectUrl"`
+	Scopes       []string `yaml:"scopes"`
+	// EmailClaim, NameClaim and GroupsClaim name the ID token claims to read.
+	// Default: email, name and groups.
+	EmailClaim  string `yaml:"emailClaim"`
+	NameClaim   string `yaml:"nameClaim"`
+	GroupsClaim string `yaml:"groupsClaim"`
+	// GroupAccess maps groups to the access level their members get.
+	GroupAccess map[string]string `yaml:"groupAccess"`
+}
+
 type LoggerConfig struct {
 	Level      string `yaml:"level"`
 	OutputPath string `yaml:"outputPath"`
@@ -127,6 +229,43 @@ type ChatUploadS3Config struct {
 	PublicBaseURL string `yaml:"publicBaseUrl"`
 }
 
+// RoomsConfig is the room lifecycle policy. Admins can override it in the
+// system settings.
+type RoomsConfig struct {
+	// DefaultTTLHours is how long a new room lasts before it expires
+	// (default 24). Owners can renew it.
+	DefaultTTLHours int `yaml:"defaultTTLHours"`
+	// AllowPermanent lets room owners create rooms that never expire.
+	// Superadmins can always do so.
+	AllowPermanent bool `yaml:"allowPermanent"`
+	// ArchiveAfterIdleDays archives rooms nobody has joined for this many
+	// days. 0 disables idle archiving.
+	ArchiveAfterIdleDays int `yaml:"archiveAfterIdleDays"`
+	// DeleteAfterArchiveDays deletes archived rooms for good after this many
+	// days. 0 keeps them.
+	DeleteAfterArchiveDays int `yaml:"deleteAfterArchiveDays"`
+}
+
+// MailConfig controls outbound email. Admins can override it in the system
+// settings.
+// Driver choices: "log" (default) writes messages to the server log instead
+// of sending them, for development, with link tokens redacted unless the log
+// level is debug; "smtp" sends through Host:Port, which can be a local sink
+// such as MailHog (localhost:1025).
+type MailConfig struct {
+	// Driver is "log" (default) or "smtp".
+	Driver   string `yaml:"driver"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// From is the sender address, e.g. "Bedrud <no-reply@example.com>".
+	From string `yaml:"from"`
+	// TLS is "starttls" (default, used when the server offers it), "tls" for
+	// implicit TLS (usually port 465) or "none".
+	TLS string `yaml:"tls"`
+}
+
 var (
 	config *Config
 	once   sync.Once
@@ -233,6 +372,27 @@ func Load(configPath string) (*Config, error) {
 			config.Auth.FrontendURL = frontendURL
 		}
 
+		if mailDriver := os.Getenv("MAIL_DRIVER"); mailDriver != "" {
+			config.Mail.Driver = mailDriver
+		}
+		if mailHost := os.Getenv("MAIL_HOST"); mailHost != "" {
+			config.Mail.Host = mailHost
+		}
+		if mailPort := os.Getenv("MAIL_PORT"); mailPort != "" {
+			if i, err := strconv.Atoi(mailPort); err == nil {
+				config.Mail.Port = i
+			}
+		}
+		if mailUsername := os.Getenv("MAIL_USERNAME"); mailUsername != "" {
+			config.Mail.Username = mailUsername
+		}
+		if mailPassword := os.Getenv("MAIL_PASSWORD"); mailPassword != "" {
+			config.Mail.Password = mailPassword
+		}
+		if mailFrom := os.Getenv("MAIL_FROM"); mailFrom != "" {
+			config.Mail.From = mailFrom
+		}
+
 		// CORS environment variable overrides
 		if corsAllowedOrigins := os.Getenv("CORS_ALLOWED_ORIGINS"); corsAllowedOrigins != "" {
 			config.Cors.AllowedOrigins = corsAllowedOrigins
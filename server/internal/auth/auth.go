@@ -129,7 +129,7 @@ func (s *AuthService) Register(email, password, name string) (*models.User, erro
 // @Success 200 {object} TokenResponse
 // @Failure 401 {object} ErrorResponse
 // @Router /auth/login [post]
-func (s *AuthService) Login(email, password string) (*LoginResponse, error) {
+func (s *AuthService) Login(email, password string, info SessionInfo) (*LoginResponse, error) {
 	user, err := s.userRepo.GetUserByEmail(email)
 	if err != nil {
 		return nil, err
@@ -156,24 +156,13 @@ func (s *AuthService) Login(email, password string) (*LoginResponse, error) {
 		return nil, errors.New("account is deactivated")
 	}
 
-	// Generate tokens
-	accessToken, refreshToken, err := GenerateTokenPair(user.ID, user.Email, user.Name, user.Accesses, config.Get())
-	if err != nil {
-		return nil, errors.New("failed to generate tokens")
-	}
-
-	// Update refresh token in database
-	if err := s.userRepo.UpdateRefreshToken(user.ID, refreshToken); err != nil {
-		return nil, errors.New("failed to save refresh token")
+	// With MFA enabled the password is only the first step; VerifyMFA
+	// finishes the sign-in.
+	if user.MFAEnabled {
+		return nil, mfaChallenge(user)
 	}
 
-	return &LoginResponse{
-		User: user,
-		Token: TokenPair{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-		},
-	}, nil
+	return s.StartSession(user, info)
 }
 
 // GuestLoginRequest represents guest login request data
@@ -182,7 +171,7 @@ type GuestLoginRequest struct {
 }
 
 // GuestLogin creates a temporary guest user and returns tokens
-func (s *AuthService) GuestLogin(name string) (*LoginResponse, error) {
+func (s *AuthService) GuestLogin(name string, info SessionInfo) (*LoginResponse, error) {
 	// Create a guest user
 	// Note: In a production app, you might want to cleanup these users eventually
 	user := &models.User{
@@ -201,15 +190,52 @@ func (s *AuthService) GuestLogin(name string) (*LoginResponse, error) {
 		return nil, err
 	}
 
-	// Generate tokens
-	accessToken, refreshToken, err := GenerateTokenPair(user.ID, user.Email, user.Name, user.Accesses, config.Get())
+	return s.StartSession(user, info)
+}
+
+// SessionInfo describes the device a session is signed in from.
+type SessionInfo struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
+
+// ErrRefreshTokenReused is returned when a refresh token that has already been
+// rotated is presented again. Either the client or someone holding a stolen
+// copy is replaying it, so the whole session is revoked.
+var ErrRefreshTokenReused = errors.New("refresh token has already been used")
+
+// ErrSessionNotFound is returned when a refresh token belongs to a session
+// that has been signed out or has expired.
+var ErrSessionNotFound = errors.New("session not found")
+
+// StartSession signs the user in on a new device and returns the session's
+// first token pair.
+func (s *AuthService) StartSession(user *models.User, info SessionInfo) (*LoginResponse, error) {
+	return s.startSession(user, info, false)
+}
+
+// startSession is StartSession for sign-ins that may have used a second
+// factor.
+func (s *AuthService) startSession(user *models.User, info SessionInfo, mfa bool) (*LoginResponse, error) {
+	now := time.Now()
+	session := &models.Session{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		DeviceName: info.DeviceName,
+		UserAgent:  info.UserAgent,
+		IP:         info.IP,
+		MFA:        mfa,
+		ExpiresAt:  now.Add(RefreshTokenDuration),
+		LastUsedAt: now,
+	}
+	accessToken, refreshToken, err := GenerateTokenPair(session, user, config.Get())
 	if err != nil {
 		return nil, errors.New("failed to generate tokens")
 	}
-
-	// Update refresh token in database
-	if err := s.userRepo.UpdateRefreshToken(user.ID, refreshToken); err != nil {
-		return nil, errors.New("failed to save refresh token")
+	session.RefreshTokenHash = tokenHash(refreshToken)
+	if err := s.userRepo.CreateSession(session); err != nil {
+		return nil, errors.New("failed to save session")
 	}
 
 	return &LoginResponse{
@@ -230,8 +256,73 @@ func (s *AuthService) GuestLogin(name string) (*LoginResponse, error) {
 // @Success 200 {object} TokenResponse
 // @Failure 401 {object} ErrorResponse
 // @Router /auth/refresh [post]
-func (s *AuthService) UpdateRefreshToken(userID, refreshToken string) error {
-	return s.userRepo.UpdateRefreshToken(userID, refreshToken)
+func (s *AuthService) RefreshSession(refreshToken string, info SessionInfo) (*TokenPair, error) {
+	claims, session, err := s.validateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return nil, errors.New("user not found or deactivated")
+	}
+
+	accessToken, newRefreshToken, err := GenerateTokenPair(session, user, config.Get())
+	if err != nil {
+		return nil, errors.New("failed to generate tokens")
+	}
+	now := time.Now()
+	rotated, err := s.userRepo.RotateSession(session.ID, session.RefreshTokenHash, tokenHash(newRefreshToken), info.IP, info.UserAgent, now.Add(RefreshTokenDuration), now)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request rotated the token between our read and write.
+		s.revokeReusedSession(session)
+		return nil, ErrRefreshTokenReused
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
+}
+
+// ListSessions returns the user's signed-in sessions, most recently used first.
+func (s *AuthService) ListSessions(userID string) ([]models.Session, error) {
+	return s.userRepo.GetActiveSessions(userID, time.Now())
+}
+
+// SignOutSession ends one of the user's sessions and invalidates its tokens.
+// It reports whether the session existed.
+func (s *AuthService) SignOutSession(userID, sessionID string) (bool, error) {
+	found, err := s.userRepo.DeleteSession(userID, sessionID)
+	if err != nil || !found {
+		return false, err
+	}
+	RevokeSession(sessionID, userID, config.Get())
+	return true, nil
+}
+
+// SignOutEverywhere ends every session of the user except exceptSessionID,
+// which may be empty, and returns how many were ended.
+func (s *AuthService) SignOutEverywhere(userID, exceptSessionID string) (int, error) {
+	ids, err := s.userRepo.DeleteUserSessions(userID, exceptSessionID)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		RevokeSession(id, userID, config.Get())
+	}
+	return len(ids), nil
+}
+
+// revokeReusedSession ends a session whose refresh token family was replayed.
+func (s *AuthService) revokeReusedSession(session *models.Session) {
+	log.Warn().Str("userId", session.UserID).Str("sessionId", session.ID).
+		Msg("Refresh token reused, revoking session")
+	if _, err := s.SignOutSession(session.UserID, session.ID); err != nil {
+		log.Error().Err(err).Str("sessionId", session.ID).Msg("Failed to revoke session after refresh token reuse")
+	}
 }
 
 // @Summary Get user profile
@@ -303,6 +394,11 @@ func (s *AuthService) Logout(userID string, refreshToken string, accessToken str
 	if err := s.BlockRefreshToken(userID, refreshToken); err != nil {
 		return err
 	}
+	if claims, err := parseTokenUnchecked(refreshToken, config.Get()); err == nil && claims.SessionID != "" {
+		if _, err := s.SignOutSession(userID, claims.SessionID); err != nil {
+			return err
+		}
+	}
 	RevokeAccessToken(accessToken, config.Get())
 	return nil
 }
@@ -332,37 +428,42 @@ func (s *AuthService) BlockRefreshToken(userID, refreshToken string) error {
 	return s.userRepo.BlockRefreshToken(userID, refreshToken, time.Unix(claims.ExpiresAt.Unix(), 0))
 }
 
-// ErrRefreshTokenMismatch is returned when the presented refresh token does not
-// match the token currently stored for the user (e.g. it was rotated on another device).
-var ErrRefreshTokenMismatch = errors.New("refresh token does not match stored token for user")
-
-// Updated refresh token validation
+// ValidateRefreshToken checks a refresh token against its session. Presenting
+// a token that has since been rotated revokes the session.
 func (s *AuthService) ValidateRefreshToken(refreshToken string) (*Claims, error) {
+	claims, _, err := s.validateRefreshToken(refreshToken)
+	return claims, err
+}
+
+func (s *AuthService) validateRefreshToken(refreshToken string) (*Claims, *models.Session, error) {
 	// Check if token is blocked
 	if s.userRepo.IsRefreshTokenBlocked(refreshToken) {
-		return nil, errors.New("refresh token has been revoked")
+		return nil, nil, errors.New("refresh token has been revoked")
 	}
 
 	// Validate the token signature and claims
 	claims, err := ValidateToken(refreshToken, config.Get())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	// Only refresh tokens carry a jti; an access token must not be usable here.
+	if claims.ID == "" || claims.SessionID == "" {
+		return nil, nil, errors.New("not a refresh token")
 	}
 
-	// Verify the token matches what is currently stored for this user.
-	// This prevents replay of a token that was rotated on another device.
-	user, err := s.userRepo.GetUserByID(claims.UserID)
+	session, err := s.userRepo.GetSession(claims.SessionID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if user == nil {
-		return nil, errors.New("user not found")
+	if session == nil || session.UserID != claims.UserID || !time.Now().Before(session.ExpiresAt) {
+		return nil, nil, ErrSessionNotFound
 	}
-	if !s.userRepo.MatchRefreshToken(user.ID, refreshToken) {
-		return nil, ErrRefreshTokenMismatch
+	if session.RefreshTokenHash != tokenHash(refreshToken) {
+		s.revokeReusedSession(session)
+		return nil, nil, ErrRefreshTokenReused
 	}
 
-	return claims, nil
+	return claims, session, nil
 }
 
 // New method to update user accesses
@@ -424,7 +525,7 @@ func (s *AuthService) FinishRegisterPasskey(userID, challengeStr string, clientD
 	return s.passkeyRepo.CreatePasskey(passkey)
 }
 
-func (s *AuthService) FinishSignupPasskey(userID, email, name, challengeStr string, clientDataJSON, attestationObject []byte, rpID, origin string) (*LoginResponse, error) {
+func (s *AuthService) FinishSignupPasskey(userID, email, name, challengeStr string, clientDataJSON, attestationObject []byte, rpID, origin string, info SessionInfo) (*LoginResponse, error) {
 	challenge, err := base64.RawURLEncoding.DecodeString(challengeStr)
 	if err != nil {
 		return nil, err
@@ -473,24 +574,8 @@ func (s *AuthService) FinishSignupPasskey(userID, email, name, challengeStr stri
 		return nil, err
 	}
 
-	// Generate tokens
-	cfg := config.Get()
-	accessToken, refreshToken, err := GenerateTokenPair(user.ID, user.Email, user.Name, user.Accesses, cfg)
-	if err != nil {
-		return nil, errors.New("failed to generate tokens")
-	}
-
-	if err := s.userRepo.UpdateRefreshToken(user.ID, refreshToken); err != nil {
-		return nil, errors.New("failed to save refresh token")
-	}
-
-	return &LoginResponse{
-		User: user,
-		Token: TokenPair{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-		},
-	}, nil
+	// A passkey is itself a second factor.
+	return s.startSession(user, info, true)
 }
 
 func (s *AuthService) BeginLoginPasskey() (string, error) {
@@ -501,7 +586,7 @@ func (s *AuthService) BeginLoginPasskey() (string, error) {
 	return base64.RawURLEncoding.EncodeToString(challenge), nil
 }
 
-func (s *AuthService) FinishLoginPasskey(challengeStr string, credentialID, clientDataJSON, authenticatorData, signature []byte, rpID, origin string) (*LoginResponse, error) {
+func (s *AuthService) FinishLoginPasskey(challengeStr string, credentialID, clientDataJSON, authenticatorData, signature []byte, rpID, origin string, info SessionInfo) (*LoginResponse, error) {
 	challenge, err := base64.RawURLEncoding.DecodeString(challengeStr)
 	if err != nil {
 		return nil, err
@@ -547,41 +632,34 @@ func (s *AuthService) FinishLoginPasskey(challengeStr string, credentialID, clie
 		return nil, errors.New("account is deactivated")
 	}
 
-	// Generate tokens
-	cfg := config.Get()
-	accessToken, refreshToken, err := GenerateTokenPair(user.ID, user.Email, user.Name, user.Accesses, cfg)
-	if err != nil {
-		return nil, errors.New("failed to generate tokens")
-	}
-
-	// Update refresh token in database
-	if err := s.userRepo.UpdateRefreshToken(user.ID, refreshToken); err != nil {
-		return nil, errors.New("failed to save refresh token")
-	}
-
-	return &LoginResponse{
-		User: user,
-		Token: TokenPair{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-		},
-	}, nil
+	// A passkey is itself a second factor.
+	return s.startSession(user, info, true)
 }
 
 // activeProviders tracks which provider names were successfully initialized.
 var activeProviders []string
 
+// activeOIDCProviders describes the OIDC providers among activeProviders.
+var activeOIDCProviders []OIDCProviderInfo
+
+// OIDCProviderInfo is what a login page needs to offer an OIDC provider.
+type OIDCProviderInfo struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
 func Init(cfg *config.Config) {
 	initProvidersFromConfig(cfg)
 }
 
 func initProvidersFromConfig(cfg *config.Config) {
-	providers, names := buildProviders(
+	providers, names, oidc := buildProviders(
 		cfg.Auth.Google.ClientID, cfg.Auth.Google.ClientSecret, cfg.Auth.Google.RedirectURL,
 		cfg.Auth.Github.ClientID, cfg.Auth.Github.ClientSecret, cfg.Auth.Github.RedirectURL,
 		cfg.Auth.Twitter.ClientID, cfg.Auth.Twitter.ClientSecret, cfg.Auth.Twitter.RedirectURL,
+		models.OIDCProvidersFromConfig(cfg.Auth.OIDC),
 	)
-	activeProviders = names
+	activeProviders, activeOIDCProviders = names, oidc
 	log.Debug().Strs("providers", names).Msg("Using providers")
 	goth.UseProviders(providers...)
 }
@@ -589,16 +667,18 @@ func initProvidersFromConfig(cfg *config.Config) {
 // ReloadProviders reinitializes goth OAuth providers from effective settings.
 // Called after admin saves auth settings.
 func ReloadProviders(s *models.SystemSettings) {
-	providers, names := buildProviders(
+	providers, names, oidc := buildProviders(
 		s.GoogleClientID, s.GoogleClientSecret, s.GoogleRedirectURL,
 		s.GithubClientID, s.GithubClientSecret, s.GithubRedirectURL,
 		s.TwitterClientID, s.TwitterClientSecret, s.TwitterRedirectURL,
+		s.OIDCProviders,
 	)
-	activeProviders = names
+	activeProviders, activeOIDCProviders = names, oidc
 	log.Info().
 		Strs("providers", names).
 		Int("count", len(providers)).
 		Msg("Reloaded OAuth providers from settings")
+	goth.ClearProviders()
 	goth.UseProviders(providers...)
 }
 
@@ -613,9 +693,11 @@ func buildProviders(
 	googleID, googleSecret, googleRedirect,
 	githubID, githubSecret, githubRedirect,
 	twitterID, twitterSecret, twitterRedirect string,
-) ([]goth.Provider, []string) {
+	oidcProviders []models.OIDCProvider,
+) ([]goth.Provider, []string, []OIDCProviderInfo) {
 	var providers []goth.Provider
 	var names []string
+	var oidc []OIDCProviderInfo
 
 	if googleID != "" && googleSecret != "" && !looksLikePlaceholder(googleID) && !looksLikePlaceholder(googleSecret) {
 		p := google.New(googleID, googleSecret, googleRedirect, "email", "profile", "openid")
@@ -631,8 +713,24 @@ func buildProviders(
 		providers = append(providers, twitter.New(twitterID, twitterSecret, twitterRedirect))
 		names = append(names, "twitter")
 	}
+	for _, cfg := range oidcProviders {
+		if err := cfg.Validate(); err != nil {
+			log.Warn().Err(err).Msg("Skipping OIDC provider")
+			continue
+		}
+		if looksLikePlaceholder(cfg.ClientID) || containsString(names, cfg.ID) {
+			continue
+		}
+		providers = append(providers, newOIDCProvider(cfg))
+		names = append(names, cfg.ID)
+		label := cfg.DisplayName
+		if label == "" {
+			label = cfg.ID
+		}
+		oidc = append(oidc, OIDCProviderInfo{ID: cfg.ID, DisplayName: label})
+	}
 
-	return providers, names
+	return providers, names, oidc
 }
 
 // ConfiguredProviders returns the provider names that were successfully
@@ -644,3 +742,44 @@ func ConfiguredProviders() []string {
 	}
 	return activeProviders
 }
+
+// ConfiguredOIDCProviders returns the initialized OIDC providers with the
+// labels their login buttons show.
+func ConfiguredOIDCProviders() []OIDCProviderInfo {
+	if activeOIDCProviders == nil {
+		return []OIDCProviderInfo{}
+	}
+	return activeOIDCProviders
+}
+
+// OAuthAccesses returns the accesses of a user who signed in through an
+// OAuth provider. OIDC providers with a group mapping grant the levels
+// mapped to the user's groups; everyone else gets the default user access.
+func OAuthAccesses(user goth.User) []string {
+	if provider, err := goth.GetProvider(user.Provider); err == nil {
+		if p, ok := provider.(*oidcProvider); ok && len(p.cfg.GroupAccess) > 0 {
+			return p.accesses(user.RawData)
+		}
+	}
+	return []string{string(models.AccessUser)}
+}
+
+// IsOIDCProvider reports whether name is a configured OIDC provider.
+func IsOIDCProvider(name string) bool {
+	provider, err := goth.GetProvider(name)
+	if err != nil {
+		return false
+	}
+	_, ok := provider.(*oidcProvider)
+	return ok
+}
+
+// OAuthEmailVerified reports whether the user's provider vouched for their
+// email address. Only OIDC providers do, through the email_verified claim.
+func OAuthEmailVerified(user goth.User) bool {
+	if !IsOIDCProvider(user.Provider) {
+		return false
+	}
+	verified, _ := user.RawData["email_verified"].(bool)
+	return verified
+}
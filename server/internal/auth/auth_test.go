@@ -119,26 +119,6 @@ func TestAuthService_GetUserByEmail(t *testing.T) {
 	}
 }
 
-func TestAuthService_UpdateRefreshToken(t *testing.T) {
-	db := testutil.SetupTestDB(t)
-	userRepo := repository.NewUserRepository(db)
-	passkeyRepo := repository.NewPasskeyRepository(db)
-	svc := NewAuthService(userRepo, passkeyRepo)
-
-	user, _ := svc.Register("refresh@example.com", "pass", "Refresh User")
-
-	err := svc.UpdateRefreshToken(user.ID, "new-refresh-token")
-	if err != nil {
-		t.Fatalf("failed to update refresh token: %v", err)
-	}
-
-	foundUser, _ := svc.GetUserByID(user.ID)
-	// Token is stored as SHA-256 hash, not plaintext
-	if foundUser.RefreshToken == "new-refresh-token" {
-		t.Fatal("refresh token should be hashed, not stored in plaintext")
-	}
-}
-
 func TestAuthService_UpdateUserAccesses(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	userRepo := repository.NewUserRepository(db)
@@ -277,7 +257,7 @@ func TestAuthService_Login_Success(t *testing.T) {
 
 	_, _ = svc.Register("loginok@example.com", "correctpass", "Login OK")
 
-	resp, err := svc.Login("loginok@example.com", "correctpass")
+	resp, err := svc.Login("loginok@example.com", "correctpass", SessionInfo{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -301,7 +281,7 @@ func TestAuthService_Login_WrongPassword(t *testing.T) {
 
 	_, _ = svc.Register("wrongpass@example.com", "realpass", "User")
 
-	_, err := svc.Login("wrongpass@example.com", "wrongpass")
+	_, err := svc.Login("wrongpass@example.com", "wrongpass", SessionInfo{})
 	if err == nil {
 		t.Fatal("expected error for wrong password")
 	}
@@ -314,7 +294,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 	svc, cfg := setupAuthService(t)
 	config.SetForTest(cfg)
 
-	_, err := svc.Login("nobody@example.com", "anypass")
+	_, err := svc.Login("nobody@example.com", "anypass", SessionInfo{})
 	if err == nil {
 		t.Fatal("expected error for missing user")
 	}
@@ -329,7 +309,7 @@ func TestAuthService_GuestLogin_Success(t *testing.T) {
 	svc, cfg := setupAuthService(t)
 	config.SetForTest(cfg)
 
-	resp, err := svc.GuestLogin("Guest Player")
+	resp, err := svc.GuestLogin("Guest Player", SessionInfo{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -386,7 +366,7 @@ func TestAuthService_ChangePassword_Success(t *testing.T) {
 	}
 
 	// Verify new password works
-	_, loginErr := svc.Login("chpass@example.com", "newpass456")
+	_, loginErr := svc.Login("chpass@example.com", "newpass456", SessionInfo{})
 	if loginErr != nil {
 		t.Fatalf("login with new password failed: %v", loginErr)
 	}
@@ -441,7 +421,7 @@ func TestAuthService_Logout_Success(t *testing.T) {
 	config.SetForTest(cfg)
 
 	user, _ := svc.Register("logout@example.com", "pass", "Logout User")
-	loginResp, _ := svc.Login("logout@example.com", "pass")
+	loginResp, _ := svc.Login("logout@example.com", "pass", SessionInfo{})
 
 	err := svc.Logout(user.ID, loginResp.Token.RefreshToken, loginResp.Token.AccessToken)
 	if err != nil {
@@ -466,7 +446,7 @@ func TestAuthService_ValidateRefreshToken_Success(t *testing.T) {
 	config.SetForTest(cfg)
 
 	user, _ := svc.Register("valrt@example.com", "pass", "Val RT")
-	loginResp, _ := svc.Login("valrt@example.com", "pass")
+	loginResp, _ := svc.Login("valrt@example.com", "pass", SessionInfo{})
 
 	claims, err := svc.ValidateRefreshToken(loginResp.Token.RefreshToken)
 	if err != nil {
@@ -482,7 +462,7 @@ func TestAuthService_ValidateRefreshToken_Blocked(t *testing.T) {
 	config.SetForTest(cfg)
 
 	_, _ = svc.Register("blockedrt@example.com", "pass", "Blocked RT")
-	loginResp, _ := svc.Login("blockedrt@example.com", "pass")
+	loginResp, _ := svc.Login("blockedrt@example.com", "pass", SessionInfo{})
 
 	// Block the token via logout
 	_ = svc.BlockRefreshToken("blockedrt@example.com", loginResp.Token.RefreshToken)
@@ -503,29 +483,77 @@ func TestAuthService_ValidateRefreshToken_Invalid(t *testing.T) {
 	}
 }
 
-func TestRefreshTokenBoundToUser(t *testing.T) {
+func TestRefreshSession_ReuseRevokesFamily(t *testing.T) {
 	svc, cfg := setupAuthService(t)
 	config.SetForTest(cfg)
 
-	// Register and login to get a valid refresh token
 	_, _ = svc.Register("bound@example.com", "pass", "Bound User")
-	loginResp, err := svc.Login("bound@example.com", "pass")
+	laptop, err := svc.Login("bound@example.com", "pass", SessionInfo{DeviceName: "Laptop"})
 	if err != nil {
 		t.Fatalf("unexpected login error: %v", err)
 	}
-	oldRefreshToken := loginResp.Token.RefreshToken
+	phone, _ := svc.Login("bound@example.com", "pass", SessionInfo{DeviceName: "Phone"})
 
-	// Simulate token rotation on another device: update stored token to a new value
-	if err := svc.UpdateRefreshToken(loginResp.User.ID, "rotated-token-from-other-device"); err != nil {
-		t.Fatalf("failed to rotate token: %v", err)
+	rotated, err := svc.RefreshSession(laptop.Token.RefreshToken, SessionInfo{})
+	if err != nil {
+		t.Fatalf("unexpected refresh error: %v", err)
+	}
+	if rotated.RefreshToken == laptop.Token.RefreshToken {
+		t.Fatal("expected the refresh token to rotate")
 	}
 
-	// The old refresh token should now be rejected with ErrRefreshTokenMismatch
-	_, err = svc.ValidateRefreshToken(oldRefreshToken)
-	if err == nil {
-		t.Fatal("expected error: old refresh token should be rejected after rotation")
+	// Replaying the first token revokes the whole laptop session.
+	if _, err := svc.RefreshSession(laptop.Token.RefreshToken, SessionInfo{}); err != ErrRefreshTokenReused {
+		t.Fatalf("expected ErrRefreshTokenReused, got: %v", err)
+	}
+	if _, err := svc.RefreshSession(rotated.RefreshToken, SessionInfo{}); err == nil {
+		t.Fatal("expected the rotated token to be revoked with its family")
+	}
+	if _, err := ValidateToken(rotated.AccessToken, cfg); err != ErrTokenRevoked {
+		t.Fatalf("expected the session's access token to be revoked, got: %v", err)
+	}
+
+	// Signing in on the phone did not sign the laptop out, and reuse on the
+	// laptop does not affect the phone.
+	if _, err := svc.RefreshSession(phone.Token.RefreshToken, SessionInfo{}); err != nil {
+		t.Fatalf("expected the phone session to survive, got: %v", err)
+	}
+}
+
+func TestRefreshSession_RejectsAccessToken(t *testing.T) {
+	svc, cfg := setupAuthService(t)
+	config.SetForTest(cfg)
+
+	_, _ = svc.Register("access@example.com", "pass", "Access User")
+	login, _ := svc.Login("access@example.com", "pass", SessionInfo{})
+
+	if _, err := svc.RefreshSession(login.Token.AccessToken, SessionInfo{}); err == nil {
+		t.Fatal("expected an access token to be rejected as a refresh token")
+	}
+	if _, err := svc.RefreshSession(login.Token.RefreshToken, SessionInfo{}); err != nil {
+		t.Fatalf("expected the session to be unaffected, got: %v", err)
+	}
+}
+
+func TestAuthService_SignOutEverywhere(t *testing.T) {
+	svc, cfg := setupAuthService(t)
+	config.SetForTest(cfg)
+
+	user, _ := svc.Register("everywhere@example.com", "pass", "Everywhere")
+	first, _ := svc.Login("everywhere@example.com", "pass", SessionInfo{DeviceName: "First"})
+	_, _ = svc.Login("everywhere@example.com", "pass", SessionInfo{DeviceName: "Second"})
+	third, _ := svc.Login("everywhere@example.com", "pass", SessionInfo{DeviceName: "Third"})
+
+	current, _ := ValidateToken(third.Token.AccessToken, cfg)
+	n, err := svc.SignOutEverywhere(user.ID, current.SessionID)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 sessions signed out, got %d, %v", n, err)
+	}
+	sessions, _ := svc.ListSessions(user.ID)
+	if len(sessions) != 1 || sessions[0].DeviceName != "Third" {
+		t.Fatalf("expected only the current session to remain, got %+v", sessions)
 	}
-	if err != ErrRefreshTokenMismatch {
-		t.Fatalf("expected ErrRefreshTokenMismatch, got: %v", err)
+	if _, err := ValidateToken(first.Token.AccessToken, cfg); err != ErrTokenRevoked {
+		t.Fatalf("expected signed-out access tokens to be revoked, got: %v", err)
 	}
 }
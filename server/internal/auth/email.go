@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"bedrud/internal/mailer"
+	"bedrud/internal/models"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// EmailVerificationTokenDuration is how long a verification link works.
+	EmailVerificationTokenDuration = 48 * time.Hour
+	// PasswordResetTokenDuration is how long a password reset link works.
+	PasswordResetTokenDuration = time.Hour
+)
+
+var (
+	// ErrInvalidEmailToken is returned for an unknown, used or expired
+	// verification or reset token.
+	ErrInvalidEmailToken = errors.New("invalid or expired link")
+	// ErrEmailAlreadyVerified is returned when asking to verify a verified
+	// address.
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+)
+
+// SendVerificationEmail mails the user a link that verifies their address.
+// baseURL is the frontend the link points to.
+func (s *AuthService) SendVerificationEmail(user *models.User, baseURL string) error {
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	if user.Provider == models.ProviderGuest {
+		return errors.New("guests have no email address")
+	}
+	token, err := s.newEmailToken(user, models.EmailTokenVerify, EmailVerificationTokenDuration)
+	if err != nil {
+		return err
+	}
+	msg, err := mailer.Render("verify_email", user.Email, "Verify your email address", map[string]interface{}{
+		"Name":      user.Name,
+		"Email":     user.Email,
+		"Link":      emailLink(baseURL, "/auth/verify-email", token),
+		"ExpiresIn": "48 hours",
+	})
+	if err != nil {
+		return err
+	}
+	return mailer.Send(msg)
+}
+
+// VerifyEmail marks the address a verification link was sent to verified.
+func (s *AuthService) VerifyEmail(token string) error {
+	t, err := s.userRepo.ConsumeEmailToken(tokenHash(token), models.EmailTokenVerify, time.Now())
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return ErrInvalidEmailToken
+	}
+	ok, err := s.userRepo.MarkEmailVerified(t.UserID, t.Email)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// The user was deleted or changed address since.
+		return ErrInvalidEmailToken
+	}
+	return nil
+}
+
+// RequestPasswordReset mails a reset link to the local account registered
+// with email. Unknown addresses are ignored so the response does not reveal
+// which addresses have accounts.
+func (s *AuthService) RequestPasswordReset(email, baseURL string) error {
+	user, err := s.userRepo.GetUserByEmailAndProvider(email, models.ProviderLocal)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.IsActive {
+		return nil
+	}
+	token, err := s.newEmailToken(user, models.EmailTokenReset, PasswordResetTokenDuration)
+	if err != nil {
+		return err
+	}
+	msg, err := mailer.Render("reset_password", user.Email, "Reset your Bedrud password", map[string]interface{}{
+		"Name":      user.Name,
+		"Link":      emailLink(baseURL, "/auth/reset-password", token),
+		"ExpiresIn": "1 hour",
+	})
+	if err != nil {
+		return err
+	}
+	return mailer.Send(msg)
+}
+
+// ResetPassword sets a new password with a token from a reset link and signs
+// the user out everywhere. Opening the link also proves the address.
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	t, err := s.userRepo.ConsumeEmailToken(tokenHash(token), models.EmailTokenReset, time.Now())
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return ErrInvalidEmailToken
+	}
+	user, err := s.userRepo.GetUserByID(t.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.IsActive || user.Email != t.Email {
+		return ErrInvalidEmailToken
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.Password = string(hashed)
+	user.EmailVerified = true
+	if err := s.userRepo.UpdateUser(user); err != nil {
+		return err
+	}
+	_, err = s.SignOutEverywhere(user.ID, "")
+	return err
+}
+
+// newEmailToken stores a new token for user and returns it.
+func (s *AuthService) newEmailToken(user *models.User, purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	err := s.userRepo.CreateEmailToken(&models.EmailToken{
+		TokenHash: tokenHash(token),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func emailLink(baseURL, path, token string) string {
+	return strings.TrimRight(baseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
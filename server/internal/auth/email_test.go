@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"bedrud/config"
+	"bedrud/internal/mailer"
+	"bedrud/internal/models"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mailbox captures mail sent through the mailer package.
+type mailbox struct {
+	mu   sync.Mutex
+	sent []*mailer.Message
+}
+
+func (m *mailbox) Send(from string, msg *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func useMailbox(t *testing.T) *mailbox {
+	t.Helper()
+	box := &mailbox{}
+	mailer.Start()
+	mailer.SetSenderForTest(box)
+	t.Cleanup(func() { mailer.Configure(&models.SystemSettings{}) })
+	return box
+}
+
+var linkToken = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// lastToken returns the token in the last link mailed to to.
+func (m *mailbox) lastToken(t *testing.T, to string) string {
+	t.Helper()
+	if !mailer.Flush(5 * time.Second) {
+		t.Fatal("mail queue did not drain")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To == to {
+			if match := linkToken.FindStringSubmatch(m.sent[i].Text); match != nil {
+				return match[1]
+			}
+		}
+	}
+	t.Fatalf("no link mailed to %s", to)
+	return ""
+}
+
+func TestEmailVerification(t *testing.T) {
+	svc, cfg := setupAuthService(t)
+	config.SetForTest(cfg)
+	box := useMailbox(t)
+
+	user, _ := svc.Register("verify@example.com", "pass", "Verify")
+	if err := svc.SendVerificationEmail(user, "https://bedrud.example/"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := box.lastToken(t, "verify@example.com")
+	_ = svc.SendVerificationEmail(user, "https://bedrud.example")
+	second := box.lastToken(t, "verify@example.com")
+
+	if err := svc.VerifyEmail(first); err != ErrInvalidEmailToken {
+		t.Fatalf("expected a superseded link to stop working, got: %v", err)
+	}
+	if err := svc.VerifyEmail(second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u, _ := svc.GetUserByID(user.ID); !u.EmailVerified {
+		t.Fatal("expected the address to be verified")
+	}
+	if err := svc.VerifyEmail(second); err != ErrInvalidEmailToken {
+		t.Fatalf("expected a used link to be rejected, got: %v", err)
+	}
+	verified, _ := svc.GetUserByID(user.ID)
+	if err := svc.SendVerificationEmail(verified, "https://bedrud.example"); err != ErrEmailAlreadyVerified {
+		t.Fatalf("expected ErrEmailAlreadyVerified, got: %v", err)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	svc, cfg := setupAuthService(t)
+	config.SetForTest(cfg)
+	box := useMailbox(t)
+
+	_, _ = svc.Register("reset@example.com", "old-password", "Reset")
+	before, _ := svc.Login("reset@example.com", "old-password", SessionInfo{})
+
+	if err := svc.RequestPasswordReset("nobody@example.com", "https://bedrud.example"); err != nil {
+		t.Fatalf("expected unknown addresses to be ignored, got: %v", err)
+	}
+	if err := svc.RequestPasswordReset("reset@example.com", "https://bedrud.example"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token := box.lastToken(t, "reset@example.com")
+	if len(box.sent) != 1 {
+		t.Fatalf("expected one email, got %d", len(box.sent))
+	}
+
+	if err := svc.ResetPassword(token, "new-password"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Login("reset@example.com", "old-password", SessionInfo{}); err == nil {
+		t.Fatal("expected the old password to stop working")
+	}
+	if _, err := svc.Login("reset@example.com", "new-password", SessionInfo{}); err != nil {
+		t.Fatalf("expected the new password to work, got: %v", err)
+	}
+	if _, err := ValidateToken(before.Token.AccessToken, cfg); err != ErrTokenRevoked {
+		t.Fatalf("expected existing sessions to be signed out, got: %v", err)
+	}
+	if err := svc.ResetPassword(token, "another-password"); err != ErrInvalidEmailToken {
+		t.Fatalf("expected a used link to be rejected, got: %v", err)
+	}
+}
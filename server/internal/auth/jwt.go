@@ -2,6 +2,8 @@ package auth
 
 import (
 	"bedrud/config"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -11,32 +13,58 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
+// tokenAudience is the audience of access and refresh tokens. Tokens for
+// other purposes, such as pending MFA logins, use a different audience so
+// they cannot be used to authenticate.
+const tokenAudience = "bedrud"
+
+// RefreshTokenDuration is how long a refresh token, and so an idle session,
+// stays valid.
+const RefreshTokenDuration = 7 * 24 * time.Hour
+
 // ErrTokenRevoked is returned when a token has been explicitly revoked (e.g. on logout).
 var ErrTokenRevoked = errors.New("token has been revoked")
 
 // tokenHash returns the hex-encoded SHA-256 hash of a token string.
-// Storing hashes instead of full JWTs reduces memory usage in the revocation set.
+// Only hashes of revoked tokens are stored, never the tokens themselves.
 func tokenHash(token string) string {
 	h := sha256.Sum256([]byte(token))
 	return hex.EncodeToString(h[:])
 }
 
-// revokedSet holds revoked access tokens keyed by their SHA-256 hash, with their expiry time.
-//
-// TRADE-OFF: The revocation list is kept in-memory and is lost on server restart.
-// With a default access token TTL of 24 hours, a revoked token could remain valid
-// after a restart for up to its remaining lifetime. This is an acceptable trade-off
-// for now: the impact is limited to tokens that were explicitly revoked (e.g. user
-// logout) and does not affect token validation itself. If stricter revocation is
-// needed in the future, persist this set to the database.
-type revokedSet struct {
-	mu sync.RWMutex
-	m  map[string]time.Time
+// revocationCacheTTL is how long a "not revoked" answer from the database is
+// trusted. A token revoked on another instance is rejected here at most this
+// long afterwards. Revocations themselves are cached until the token expires.
+const revocationCacheTTL = 30 * time.Second
+
+type revocationEntry struct {
+	revoked bool
+	until   time.Time
 }
 
-var revokedTokens = &revokedSet{m: make(map[string]time.Time)}
+// revocationStore keeps revoked access tokens in the database, keyed by their
+// SHA-256 hash, so revocations survive restarts and are shared by every
+// instance. Lookups are cached in process to keep ValidateToken cheap.
+type revocationStore struct {
+	mu    sync.RWMutex
+	repo  *repository.UserRepository
+	cache map[string]revocationEntry
+}
+
+var revokedTokens = &revocationStore{cache: make(map[string]revocationEntry)}
+
+// InitRevocations sets the repository revoked access tokens are stored in and
+// clears the cache. Until it is called, or after InitRevocations(nil),
+// revocations are only kept in memory.
+func InitRevocations(r *repository.UserRepository) {
+	revokedTokens.mu.Lock()
+	defer revokedTokens.mu.Unlock()
+	revokedTokens.repo = r
+	revokedTokens.cache = make(map[string]revocationEntry)
+}
 
 // RevokeAccessToken marks a JWT as invalid until its natural expiry.
 func RevokeAccessToken(tokenStr string, cfg *config.Config) {
@@ -44,31 +72,107 @@ func RevokeAccessToken(tokenStr string, cfg *config.Config) {
 	if err != nil {
 		return
 	}
-	exp := time.Unix(claims.ExpiresAt.Unix(), 0)
-	h := tokenHash(tokenStr)
+	revoke(tokenHash(tokenStr), claims.UserID, time.Unix(claims.ExpiresAt.Unix(), 0))
+}
+
+// RevokeSession marks every token issued for a session as invalid. Access
+// tokens are not tracked individually, so the revocation is kept for as long
+// as a newly issued access token could still be valid.
+func RevokeSession(sessionID, userID string, cfg *config.Config) {
+	revoke(sessionKey(sessionID), userID, time.Now().Add(time.Duration(cfg.Auth.TokenDuration)*time.Hour))
+}
+
+// sessionKey is the revocation key for a session. It cannot collide with a
+// token hash, which is always 64 hex digits.
+func sessionKey(sessionID string) string {
+	return "sid:" + sessionID
+}
+
+func revoke(key, userID string, exp time.Time) {
 	revokedTokens.mu.Lock()
-	revokedTokens.m[h] = exp
+	revokedTokens.cache[key] = revocationEntry{revoked: true, until: exp}
+	r := revokedTokens.repo
 	revokedTokens.mu.Unlock()
+
+	if r != nil {
+		if err := r.RevokeAccessToken(key, userID, exp); err != nil {
+			log.Error().Err(err).Str("userId", userID).Msg("Failed to store revoked access token")
+		}
+	}
 }
 
-// PruneRevokedTokens removes expired entries from the revocation set. Call periodically (e.g. hourly).
-func PruneRevokedTokens() {
+// PruneRevokedTokens deletes revocations of expired tokens, sessions whose
+// refresh token has expired and expired email tokens, and drops stale cache
+// entries. The scheduler calls it hourly.
+func PruneRevokedTokens() error {
 	now := time.Now()
 	revokedTokens.mu.Lock()
-	defer revokedTokens.mu.Unlock()
-	for h, exp := range revokedTokens.m {
-		if now.After(exp) {
-			delete(revokedTokens.m, h)
+	for h, e := range revokedTokens.cache {
+		if !now.Before(e.until) {
+			delete(revokedTokens.cache, h)
 		}
 	}
+	r := revokedTokens.repo
+	revokedTokens.mu.Unlock()
+
+	if r == nil {
+		return nil
+	}
+	n, err := r.PurgeRevokedAccessTokens(now)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Info().Int64("deleted", n).Msg("Purged expired access token revocations")
+	}
+	n, err = r.PurgeExpiredSessions(now)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Info().Int64("deleted", n).Msg("Purged expired sessions")
+	}
+	n, err = r.PurgeExpiredEmailTokens(now)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Info().Int64("deleted", n).Msg("Purged expired email tokens")
+	}
+	return nil
 }
 
-func isRevoked(tokenStr string) bool {
-	h := tokenHash(tokenStr)
+// isRevoked reports whether the token hash or session key has been revoked.
+// If the database cannot be reached the token is accepted: its signature and
+// expiry have already been checked, and failing closed would log every user
+// out during an outage.
+func isRevoked(h string) bool {
+	now := time.Now()
+
 	revokedTokens.mu.RLock()
-	defer revokedTokens.mu.RUnlock()
-	exp, exists := revokedTokens.m[h]
-	return exists && time.Now().Before(exp)
+	e, cached := revokedTokens.cache[h]
+	r := revokedTokens.repo
+	revokedTokens.mu.RUnlock()
+	if cached && now.Before(e.until) {
+		return e.revoked
+	}
+	if r == nil {
+		return false
+	}
+
+	exp, err := r.GetAccessTokenRevocation(h, now)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check access token revocation")
+		return false
+	}
+	e = revocationEntry{until: now.Add(revocationCacheTTL)}
+	if exp != nil {
+		e = revocationEntry{revoked: true, until: *exp}
+	}
+	revokedTokens.mu.Lock()
+	revokedTokens.cache[h] = e
+	revokedTokens.mu.Unlock()
+	return e.revoked
 }
 
 type Claims struct {
@@ -77,6 +181,12 @@ type Claims struct {
 	Name     string   `json:"name"`
 	Provider string   `json:"provider"`
 	Accesses []string `json:"accesses"`
+	// SessionID is the sign-in session the token was issued for. Tokens
+	// issued outside a session, e.g. by GenerateToken, leave it empty.
+	SessionID string `json:"sid,omitempty"`
+	// MFA is set when the session was signed in with a second factor or a
+	// passkey.
+	MFA bool `json:"mfa,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -92,7 +202,7 @@ func GenerateToken(userID, email, name, provider string, accesses []string, cfg
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "bedrud",
 			Subject:   userID,
-			Audience:  []string{"bedrud"},
+			Audience:  []string{tokenAudience},
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -116,7 +226,7 @@ func parseTokenUnchecked(tokenString string, cfg *config.Config) (*Claims, error
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(cfg.Auth.JWTSecret), nil
-	})
+	}, jwt.WithAudience(tokenAudience))
 	if err != nil {
 		return nil, err
 	}
@@ -134,39 +244,46 @@ func ValidateToken(tokenString string, cfg *config.Config) (*Claims, error) {
 		return nil, err
 	}
 
-	if isRevoked(tokenString) {
+	if isRevoked(tokenHash(tokenString)) || (claims.SessionID != "" && isRevoked(sessionKey(claims.SessionID))) {
 		return nil, ErrTokenRevoked
 	}
 
 	return claims, nil
 }
 
-func GenerateTokenPair(userID, email, name string, accesses []string, cfg *config.Config) (accessToken, refreshToken string, err error) {
-	// Generate access token
-	accessToken, err = GenerateToken(userID, email, name, "local", accesses, cfg)
-	if err != nil {
-		return "", "", err
+// GenerateTokenPair issues an access token and a refresh token for the
+// session. The refresh token carries a unique jti so each rotation produces
+// a different token.
+func GenerateTokenPair(session *models.Session, user *models.User, cfg *config.Config) (accessToken, refreshToken string, err error) {
+	now := time.Now()
+	newClaims := func(exp time.Time) *Claims {
+		return &Claims{
+			UserID:    user.ID,
+			Email:     user.Email,
+			Name:      user.Name,
+			Provider:  "local",
+			Accesses:  user.Accesses,
+			SessionID: session.ID,
+			MFA:       session.MFA,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "bedrud",
+				Subject:   user.ID,
+				Audience:  []string{tokenAudience},
+				ExpiresAt: jwt.NewNumericDate(exp),
+				IssuedAt:  jwt.NewNumericDate(now),
+			},
+		}
 	}
 
-	// Generate refresh token
-	refreshClaims := &Claims{
-		UserID:   userID,
-		Email:    email,
-		Name:     name,
-		Provider: "local",
-		Accesses: accesses,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "bedrud",
-			Subject:   userID,
-			Audience:  []string{"bedrud"},
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * 7)), // 7 days
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ID:        uuid.New().String(),
-		},
+	accessClaims := newClaims(now.Add(time.Duration(cfg.Auth.TokenDuration) * time.Hour))
+	accessToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims).SignedString([]byte(cfg.Auth.JWTSecret))
+	if err != nil {
+		return "", "", err
 	}
 
-	rt := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshToken, err = rt.SignedString([]byte(cfg.Auth.JWTSecret))
+	refreshClaims := newClaims(now.Add(RefreshTokenDuration))
+	refreshClaims.ID = uuid.New().String()
+	refreshToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(cfg.Auth.JWTSecret))
 	if err != nil {
 		return "", "", err
 	}
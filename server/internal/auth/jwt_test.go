@@ -2,6 +2,9 @@ package auth
 
 import (
 	"bedrud/config"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"bedrud/internal/testutil"
 	"testing"
 	"time"
 
@@ -166,7 +169,7 @@ func TestValidateToken_WrongSigningMethod(t *testing.T) {
 
 func TestGenerateTokenPair_Success(t *testing.T) {
 	cfg := testConfig()
-	accessToken, refreshToken, err := GenerateTokenPair("user-123", "test@example.com", "Test", []string{"user"}, cfg)
+	accessToken, refreshToken, err := GenerateTokenPair(&models.Session{ID: "session-1"}, &models.User{ID: "user-123", Email: "test@example.com", Name: "Test", Accesses: []string{"user"}}, cfg)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -183,7 +186,7 @@ func TestGenerateTokenPair_Success(t *testing.T) {
 
 func TestGenerateTokenPair_RefreshTokenHasJTI(t *testing.T) {
 	cfg := testConfig()
-	_, refreshToken, _ := GenerateTokenPair("user-123", "test@example.com", "Test", []string{"user"}, cfg)
+	_, refreshToken, _ := GenerateTokenPair(&models.Session{ID: "session-1"}, &models.User{ID: "user-123", Email: "test@example.com", Name: "Test", Accesses: []string{"user"}}, cfg)
 
 	// Validate the refresh token and check it has a JTI
 	claims, err := ValidateToken(refreshToken, cfg)
@@ -197,7 +200,7 @@ func TestGenerateTokenPair_RefreshTokenHasJTI(t *testing.T) {
 
 func TestGenerateTokenPair_RefreshTokenLongerExpiration(t *testing.T) {
 	cfg := testConfig()
-	accessToken, refreshToken, _ := GenerateTokenPair("user-123", "test@example.com", "Test", []string{"user"}, cfg)
+	accessToken, refreshToken, _ := GenerateTokenPair(&models.Session{ID: "session-1"}, &models.User{ID: "user-123", Email: "test@example.com", Name: "Test", Accesses: []string{"user"}}, cfg)
 
 	accessClaims, _ := ValidateToken(accessToken, cfg)
 	refreshClaims, _ := ValidateToken(refreshToken, cfg)
@@ -210,7 +213,7 @@ func TestGenerateTokenPair_RefreshTokenLongerExpiration(t *testing.T) {
 
 func TestGenerateTokenPair_AccessTokenContainsAccesses(t *testing.T) {
 	cfg := testConfig()
-	accessToken, _, _ := GenerateTokenPair("user-123", "test@example.com", "Test", []string{"user", "admin"}, cfg)
+	accessToken, _, _ := GenerateTokenPair(&models.Session{ID: "session-1"}, &models.User{ID: "user-123", Email: "test@example.com", Name: "Test", Accesses: []string{"user", "admin"}}, cfg)
 
 	claims, err := ValidateToken(accessToken, cfg)
 	if err != nil {
@@ -253,6 +256,52 @@ func TestAccessTokenRevocation(t *testing.T) {
 	}
 }
 
+func TestAccessTokenRevocation_PersistsAcrossRestart(t *testing.T) {
+	cfg := testConfig()
+	db := testutil.SetupTestDB(t)
+	InitRevocations(repository.NewUserRepository(db))
+	t.Cleanup(func() { InitRevocations(nil) })
+
+	token, err := GenerateToken("user-123", "test@example.com", "Test User", "local", []string{"user"}, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error generating token: %v", err)
+	}
+	other, _ := GenerateToken("user-456", "other@example.com", "Other", "local", []string{"user"}, cfg)
+	if _, err := ValidateToken(other, cfg); err != nil {
+		t.Fatalf("expected the other token to be valid, got: %v", err)
+	}
+
+	RevokeAccessToken(token, cfg)
+
+	// A restart, or another instance sharing the database, starts with an empty cache.
+	InitRevocations(repository.NewUserRepository(db))
+	if _, err := ValidateToken(token, cfg); err != ErrTokenRevoked {
+		t.Fatalf("expected ErrTokenRevoked after a restart, got: %v", err)
+	}
+	if _, err := ValidateToken(other, cfg); err != nil {
+		t.Fatalf("expected the other token to stay valid, got: %v", err)
+	}
+}
+
+func TestPruneRevokedTokens(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := repository.NewUserRepository(db)
+	InitRevocations(repo)
+	t.Cleanup(func() { InitRevocations(nil) })
+
+	_ = repo.RevokeAccessToken(tokenHash("expired"), "user-1", time.Now().Add(-time.Minute))
+	_ = repo.RevokeAccessToken(tokenHash("live"), "user-1", time.Now().Add(time.Hour))
+
+	if err := PruneRevokedTokens(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var count int64
+	db.Model(&models.RevokedAccessToken{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected 1 remaining revocation, got %d", count)
+	}
+}
+
 // --- Claims Tests ---
 
 func TestClaims_Structure(t *testing.T) {
@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"bedrud/config"
+	"bedrud/internal/models"
+	"bedrud/internal/totp"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// mfaIssuer names the account in authenticator apps.
+	mfaIssuer = "Bedrud"
+	// mfaAudience is the audience of pending MFA tokens.
+	mfaAudience = "bedrud-mfa"
+	// mfaTokenDuration is how long the user has to enter a code after their
+	// password was accepted.
+	mfaTokenDuration = 5 * time.Minute
+	// mfaMaxFailures wrong codes at sign-in lock code entry for
+	// mfaLockDuration. The lock outlasts every MFA token issued before it.
+	mfaMaxFailures  = 5
+	mfaLockDuration = 15 * time.Minute
+)
+
+var (
+	// ErrMFAInvalidCode is returned when a TOTP or recovery code is wrong or
+	// has already been used.
+	ErrMFAInvalidCode = errors.New("invalid two-factor code")
+	// ErrMFAAlreadyEnabled is returned when enrolling a user who already has MFA.
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFANotEnabled is returned when MFA is not enabled or not enrolled.
+	ErrMFANotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrMFAUnavailable is returned for accounts without a password, which
+	// sign in with a passkey or an OAuth provider instead. Such accounts
+	// meet the admin MFA policy by adding a passkey and signing in with it.
+	ErrMFAUnavailable = errors.New("two-factor authentication is only available for password accounts; sign in with a passkey instead")
+	// ErrMFALocked is returned by VerifyMFA after too many wrong codes.
+	ErrMFALocked = errors.New("too many wrong two-factor codes, try again later")
+	// ErrMFARequiredByPolicy is returned when disabling MFA the site requires.
+	ErrMFARequiredByPolicy = errors.New("two-factor authentication is required for this account")
+)
+
+// MFARequiredError is returned by Login when the password was right but the
+// account has MFA enabled. Token is passed to VerifyMFA with a code.
+type MFARequiredError struct {
+	Token string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+// MFAEnrollment is a new TOTP secret waiting to be confirmed. URI is the
+// otpauth:// provisioning URI, which clients show as a QR code.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFAStatus describes a user's MFA setup.
+type MFAStatus struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining"`
+}
+
+var requireAdminMFA atomic.Bool
+
+// ApplyMFAPolicy loads the MFA policy from effective settings. Call it at
+// startup and whenever settings change.
+func ApplyMFAPolicy(s *models.SystemSettings) {
+	requireAdminMFA.Store(s.RequireAdminMFA)
+}
+
+// MFARequired reports whether holders of any of accesses must sign in with a
+// second factor.
+func MFARequired(accesses []string) bool {
+	if !requireAdminMFA.Load() {
+		return false
+	}
+	for _, a := range accesses {
+		if a == string(models.AccessAdmin) || a == string(models.AccessSuperAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+// mfaChallenge returns the MFARequiredError that asks user for a code.
+func mfaChallenge(user *models.User) error {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Issuer:    "bedrud",
+		Subject:   user.ID,
+		Audience:  []string{mfaAudience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenDuration)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.Get().Auth.JWTSecret))
+	if err != nil {
+		return errors.New("failed to generate tokens")
+	}
+	return &MFARequiredError{Token: token}
+}
+
+// VerifyMFA finishes a sign-in that Login answered with an MFARequiredError.
+// code is a TOTP code or an unused recovery code. Wrong codes count against
+// the user, not the token, so signing in again doesn't reset them.
+func (s *AuthService) VerifyMFA(mfaToken, code string, info SessionInfo) (*LoginResponse, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(mfaToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(config.Get().Auth.JWTSecret), nil
+	}, jwt.WithAudience(mfaAudience))
+	if err != nil {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+
+	user, err := s.userRepo.GetUserByID(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive || !user.MFAEnabled {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+	now := time.Now()
+	if user.MFALockedUntil != nil && now.Before(*user.MFALockedUntil) {
+		return nil, ErrMFALocked
+	}
+	if err := s.checkMFACode(user, code); err != nil {
+		if errors.Is(err, ErrMFAInvalidCode) {
+			if ferr := s.userRepo.RecordMFAFailure(user.ID, mfaMaxFailures, now.Add(mfaLockDuration)); ferr != nil {
+				return nil, ferr
+			}
+		}
+		return nil, err
+	}
+	if user.MFAFailures > 0 || user.MFALockedUntil != nil {
+		if err := s.userRepo.ResetMFAFailures(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return s.startSession(user, info, true)
+}
+
+// MFAStatus returns the user's MFA setup.
+func (s *AuthService) MFAStatus(userID string) (*MFAStatus, error) {
+	user, err := s.mfaUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &MFAStatus{Enabled: user.MFAEnabled, Required: MFARequired(user.Accesses)}
+	if user.MFAEnabled {
+		if status.RecoveryCodesRemaining, err = s.userRepo.CountUnusedRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginMFAEnrollment creates a TOTP secret for the user. MFA is enabled once
+// the user proves they added it to an authenticator with EnableMFA.
+func (s *AuthService) BeginMFAEnrollment(userID string) (*MFAEnrollment, error) {
+	user, err := s.mfaUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Password == "" {
+		return nil, ErrMFAUnavailable
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetPendingMFASecret(userID, secret); err != nil {
+		return nil, err
+	}
+	return &MFAEnrollment{Secret: secret, URI: totp.URI(mfaIssuer, user.Email, secret)}, nil
+}
+
+// EnableMFA confirms the pending secret with a code from the authenticator
+// and returns the user's recovery codes. They are shown this once.
+func (s *AuthService) EnableMFA(userID, code string) ([]string, error) {
+	user, err := s.mfaUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.MFASecret == "" {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.checkTOTP(user, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.EnableMFA(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableMFA turns MFA off after checking a current code.
+func (s *AuthService) DisableMFA(userID, code string) error {
+	user, err := s.mfaUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if MFARequired(user.Accesses) {
+		return ErrMFARequiredByPolicy
+	}
+	if err := s.checkMFACode(user, code); err != nil {
+		return err
+	}
+	return s.userRepo.DisableMFA(userID)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a
+// current code.
+func (s *AuthService) RegenerateRecoveryCodes(userID, code string) ([]string, error) {
+	user, err := s.mfaUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.checkMFACode(user, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *AuthService) mfaUser(userID string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// checkMFACode accepts a TOTP code or, failing that, an unused recovery code,
+// which is then spent.
+func (s *AuthService) checkMFACode(user *models.User, code string) error {
+	if err := s.checkTOTP(user, code); err == nil {
+		return nil
+	}
+	used, err := s.userRepo.UseRecoveryCode(user.ID, tokenHash(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrMFAInvalidCode
+	}
+	return nil
+}
+
+// checkTOTP accepts a TOTP code for the user's secret, once.
+func (s *AuthService) checkTOTP(user *models.User, code string) error {
+	step, ok := totp.Validate(user.MFASecret, code, time.Now())
+	if !ok {
+		return ErrMFAInvalidCode
+	}
+	fresh, err := s.userRepo.UseMFAStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrMFAInvalidCode
+	}
+	return nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns fresh recovery codes, formatted as xxxxx-xxxxx,
+// and their hashes.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < models.MFARecoveryCodeCount; i++ {
+		b := make([]byte, 7) // 12 base32 characters, of which 10 are used
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		c := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		code := c[:5] + "-" + c[5:10]
+		codes = append(codes, code)
+		hashes = append(hashes, tokenHash(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode lets users type recovery codes in any case, with or
+// without the dash.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"bedrud/config"
+	"bedrud/internal/models"
+	"bedrud/internal/totp"
+	"errors"
+	"testing"
+	"time"
+)
+
+// enrollMFA registers a user and enables MFA, returning the secret and
+// recovery codes.
+func enrollMFA(t *testing.T, svc *AuthService, email string) (*models.User, string, []string) {
+	t.Helper()
+	user, err := svc.Register(email, "pass", "MFA User")
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	enrollment, err := svc.BeginMFAEnrollment(user.ID)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	code, _ := totp.Code(enrollment.Secret, totp.Step(time.Now())-1)
+	codes, err := svc.EnableMFA(user.ID, code)
+	if err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	return user, enrollment.Secret, codes
+}
+
+func TestMFA_LoginNeedsSecondStep(t *testing.T) {
+	svc, cfg := setupAuthService(t)
+	config.SetForTest(cfg)
+	_, secret, _ := enrollMFA(t, svc, "mfa@example.com")
+
+	_, err := svc.Login("mfa@example.com", "pass", SessionInfo{})
+	var challenge *MFARequiredError
+	if !errors.As(err, &challenge) || challenge.Token == "" {
+		t.Fatalf("expected an MFA challenge, got: %v", err)
+	}
+	if _, err := ValidateToken(challenge.Token, cfg); err == nil {
+		t.Fatal("expected the MFA token to be unusable as an access token")
+	}
+
+	// The code used to enable MFA was for the previous step; the next one is
+	// still inside the skew window.
+	code, _ := totp.Code(secret, totp.Step(time.Now())+1)
+	login, err := svc.VerifyMFA(challenge.Token, code, SessionInfo{})
+	if err != nil {
+		t.Fatalf("unexpected verify error: %v", err)
+	}
+	claims, err := ValidateToken(login.Token.AccessToken, cfg)
+	if err != nil || !claims.MFA {
+		t.Fatalf("expected an MFA access token, got %+v, %v", claims, err)
+	}
+
+	if _, err := svc.VerifyMFA(challenge.Token, code, SessionInfo{}); err != ErrMFAInvalidCode {
+		t.Fatalf("expected a replayed code to be rejected, got: %v", err)
+	}
+	if _, err := svc.VerifyMFA("not-a-token", code, SessionInfo{}); err == nil {
+		t.Fatal("expected an invalid MFA token to be rejected")
+	}
+}
+
+func TestMFA_RecoveryCodesAreSingleUse(t *testing.T) {
+	svc, cfg := setupAuthService(t)
+	config.SetForTest(cfg)
+	user, _, codes := enrollMFA(t, svc, "recovery@example.com")
+	if len(codes) != models.MFARecoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", models.MFARecoveryCodeCount, len(codes))
+	}
+
+	_, err := svc.Login("recovery@example.com", "pass", SessionInfo{})
+	challenge := err.(*MFARequiredError)
+	if _, err := svc.VerifyMFA(challenge.Token, "  "+codes[0][:5]+codes[0][6:]+" ", SessionInfo{}); err != nil {
+		t.Fatalf("expected the recovery code to work without its dash, got: %v", err)
+	}
+	if _, err := svc.VerifyMFA(challenge.Token, codes[0], SessionInfo{}); err != ErrMFAInvalidCode {
+		t.Fatalf("expected a used recovery code to be rejected, got: %v", err)
+	}
+	status, _ := svc.MFAStatus(user.ID)
+	if !status.Enabled || status.RecoveryCodesRemaining != int64(len(codes)-1) {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestMFA_AdminPolicy(t *testing.T) {
+	svc, cfg := setupAuthService(t)
+	config.SetForTest(cfg)
+	ApplyMFAPolicy(&models.SystemSettings{RequireAdminMFA: true})
+	t.Cleanup(func() { ApplyMFAPolicy(&models.SystemSettings{}) })
+
+	if MFARequired([]string{"user"}) || !MFARequired([]string{"user", "admin"}) {
+		t.Fatal("expected the policy to cover admin access only")
+	}
+
+	user, _, codes := enrollMFA(t, svc, "admin-mfa@example.com")
+	_ = svc.UpdateUserAccesses(user.ID, []string{"user", "admin"})
+	if err := svc.DisableMFA(user.ID, codes[0]); err != ErrMFARequiredByPolicy {
+		t.Fatalf("expected the policy to keep MFA on, got: %v", err)
+	}
+}
+
+func TestMFA_WrongCodesLockSignIn(t *testing.T) {
+	svc, cfg := setupAuthService(t)
+	config.SetForTest(cfg)
+	_, secret, _ := enrollMFA(t, svc, "locked@example.com")
+
+	for i := 0; i < mfaMaxFailures; i++ {
+		_, err := svc.Login("locked@example.com", "pass", SessionInfo{})
+		challenge := err.(*MFARequiredError)
+		if _, err := svc.VerifyMFA(challenge.Token, "wrong", SessionInfo{}); err != ErrMFAInvalidCode {
+			t.Fatalf("attempt %d: expected an invalid code, got: %v", i+1, err)
+		}
+	}
+
+	_, err := svc.Login("locked@example.com", "pass", SessionInfo{})
+	challenge := err.(*MFARequiredError)
+	code, _ := totp.Code(secret, totp.Step(time.Now())+1)
+	if _, err := svc.VerifyMFA(challenge.Token, code, SessionInfo{}); err != ErrMFALocked {
+		t.Fatalf("expected a fresh token to stay locked out, got: %v", err)
+	}
+}
@@ -0,0 +1,465 @@
+package auth
+
+import (
+	"bedrud/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/markbates/goth"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// oidcHTTPTimeout bounds every request to an OIDC provider.
+	oidcHTTPTimeout = 10 * time.Second
+	// oidcKeysMinRefresh limits how often an issuer's keys are refetched
+	// when a token is signed with a key we do not know.
+	oidcKeysMinRefresh = time.Minute
+	// oidcClockSkew is the leeway allowed on ID token timestamps.
+	oidcClockSkew = time.Minute
+)
+
+// oidcSigningMethods are the ID token algorithms we accept. Symmetric
+// algorithms are left out on purpose: the keys come from the issuer's JWKS.
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+var defaultOIDCScopes = []string{"openid", "email", "profile"}
+
+// oidcDiscovery is the part of an OpenID provider's discovery document we use.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider is a goth.Provider for a generic OpenID Connect provider. It
+// signs users in with the authorization code flow and PKCE, and validates
+// the ID token against the keys the issuer publishes.
+type oidcProvider struct {
+	cfg    models.OIDCProvider
+	name   string
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      jose.JSONWebKeySet
+	keysAt    time.Time
+}
+
+func newOIDCProvider(cfg models.OIDCProvider) *oidcProvider {
+	return &oidcProvider{
+		cfg:    cfg,
+		name:   cfg.ID,
+		client: &http.Client{Timeout: oidcHTTPTimeout},
+	}
+}
+
+func (p *oidcProvider) Name() string        { return p.name }
+func (p *oidcProvider) SetName(name string) { p.name = name }
+func (p *oidcProvider) Debug(bool)          {}
+
+// RefreshTokenAvailable is false: Bedrud issues its own sessions and never
+// calls the provider again after sign-in.
+func (p *oidcProvider) RefreshTokenAvailable() bool { return false }
+
+func (p *oidcProvider) RefreshToken(string) (*oauth2.Token, error) {
+	return nil, errors.New("refresh tokens are not supported for OIDC sign-in")
+}
+
+// BeginAuth starts the authorization code flow with a PKCE challenge and a
+// nonce, which are kept in the session for the callback.
+func (p *oidcProvider) BeginAuth(state string) (goth.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcHTTPTimeout)
+	defer cancel()
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+	authURL := p.oauth2Config(d).AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	return &oidcSession{AuthURL: authURL, CodeVerifier: verifier, Nonce: nonce}, nil
+}
+
+func (p *oidcProvider) UnmarshalSession(data string) (goth.Session, error) {
+	s := &oidcSession{}
+	if err := json.Unmarshal([]byte(data), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// FetchUser maps the claims of an authorized session to a user. UserID is
+// derived from the issuer and subject, because subjects can be longer than
+// the user IDs we store.
+func (p *oidcProvider) FetchUser(session goth.Session) (goth.User, error) {
+	s, ok := session.(*oidcSession)
+	if !ok || s.Claims == nil {
+		return goth.User{}, errors.New("OIDC session is not authorized")
+	}
+	email := claimString(s.Claims, p.claimName(p.cfg.EmailClaim, "email"))
+	if email == "" {
+		return goth.User{}, fmt.Errorf("%s did not return an email address", p.name)
+	}
+	if v, ok := s.Claims["email_verified"]; ok && v != true {
+		return goth.User{}, fmt.Errorf("%s has not verified the email address %s", p.name, email)
+	}
+	name := claimString(s.Claims, p.claimName(p.cfg.NameClaim, "name"))
+	if name == "" {
+		name = claimString(s.Claims, "preferred_username")
+	}
+	if name == "" {
+		name = email
+	}
+	issuer, _ := s.Claims["iss"].(string)
+	subject, _ := s.Claims["sub"].(string)
+	return goth.User{
+		Provider:    p.name,
+		UserID:      uuid.NewSHA1(uuid.NameSpaceURL, []byte(issuer+"#"+subject)).String(),
+		Email:       email,
+		Name:        name,
+		NickName:    claimString(s.Claims, "preferred_username"),
+		AvatarURL:   claimString(s.Claims, "picture"),
+		AccessToken: s.AccessToken,
+		IDToken:     s.IDToken,
+		RawData:     s.Claims,
+	}, nil
+}
+
+// accesses returns the access levels granted to members of groups, on top
+// of the default user access.
+func (p *oidcProvider) accesses(claims map[string]interface{}) []string {
+	accesses := []string{string(models.AccessUser)}
+	for _, group := range claimStrings(claims, p.claimName(p.cfg.GroupsClaim, "groups")) {
+		level, ok := p.cfg.GroupAccess[group]
+		if !ok || containsString(accesses, string(level)) {
+			continue
+		}
+		accesses = append(accesses, string(level))
+	}
+	return accesses
+}
+
+func (p *oidcProvider) claimName(configured, fallback string) string {
+	if configured != "" {
+		return configured
+	}
+	return fallback
+}
+
+func (p *oidcProvider) oauth2Config(d *oidcDiscovery) *oauth2.Config {
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultOIDCScopes
+	} else if !containsString(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  d.AuthorizationEndpoint,
+			TokenURL: d.TokenEndpoint,
+		},
+		Scopes: scopes,
+	}
+}
+
+// discover fetches and caches the issuer's discovery document. Failures are
+// not cached, so an issuer that was down is retried on the next sign-in.
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	d := p.discovery
+	p.mu.Unlock()
+	if d != nil {
+		return d, nil
+	}
+
+	issuer := strings.TrimRight(p.cfg.IssuerURL, "/")
+	d = &oidcDiscovery{}
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", "", d); err != nil {
+		return nil, fmt.Errorf("OIDC discovery for %s failed: %w", p.name, err)
+	}
+	if strings.TrimRight(d.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery for %s returned issuer %q", p.name, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery for %s is missing endpoints", p.name)
+	}
+
+	p.mu.Lock()
+	p.discovery = d
+	p.mu.Unlock()
+	return d, nil
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience, expiry
+// and nonce, and returns its claims.
+func (p *oidcProvider) verifyIDToken(ctx context.Context, d *oidcDiscovery, raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, d, kid)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.cfg.ClientID {
+			return nil, errors.New("invalid ID token: authorized party mismatch")
+		}
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("invalid ID token: no subject")
+	}
+	return claims, nil
+}
+
+// signingKey returns the issuer's public key with the given ID. The key set
+// is refetched when the key is unknown, as issuers rotate keys.
+func (p *oidcProvider) signingKey(ctx context.Context, d *oidcDiscovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	key := findSigningKey(&p.keys, kid)
+	stale := time.Since(p.keysAt) >= oidcKeysMinRefresh
+	p.mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+	if !stale {
+		return nil, errors.New("unknown signing key")
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := p.getJSON(ctx, d.JWKSURI, "", &keys); err != nil {
+		return nil, fmt.Errorf("fetching signing keys: %w", err)
+	}
+	p.mu.Lock()
+	p.keys, p.keysAt = keys, time.Now()
+	p.mu.Unlock()
+
+	if key := findSigningKey(&keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// findSigningKey returns the public signing key with the given ID, or the
+// only signing key when the token names none.
+func findSigningKey(keys *jose.JSONWebKeySet, kid string) interface{} {
+	var found []jose.JSONWebKey
+	for _, k := range keys.Keys {
+		if (k.Use == "" || k.Use == "sig") && k.IsPublic() && (kid == "" || k.KeyID == kid) {
+			found = append(found, k)
+		}
+	}
+	if len(found) != 1 {
+		return nil
+	}
+	return found[0].Key
+}
+
+// userinfo fetches claims from the userinfo endpoint, for providers that
+// leave some of them out of the ID token.
+func (p *oidcProvider) userinfo(ctx context.Context, d *oidcDiscovery, accessToken string) (map[string]interface{}, error) {
+	claims := map[string]interface{}{}
+	if err := p.getJSON(ctx, d.UserinfoEndpoint, accessToken, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, url, bearer string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// oidcSession is the goth session of an OIDC sign-in. Only what the callback
+// needs is marshalled into the session cookie; the claims live in memory
+// for the rest of the request.
+type oidcSession struct {
+	AuthURL      string `json:"authUrl"`
+	CodeVerifier string `json:"codeVerifier"`
+	Nonce        string `json:"nonce"`
+
+	AccessToken string                 `json:"-"`
+	IDToken     string                 `json:"-"`
+	Claims      map[string]interface{} `json:"-"`
+}
+
+func (s *oidcSession) GetAuthURL() (string, error) {
+	if s.AuthURL == "" {
+		return "", errors.New(goth.NoAuthUrlErrorMessage)
+	}
+	return s.AuthURL, nil
+}
+
+func (s *oidcSession) Marshal() string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// Authorize exchanges the authorization code, proving possession of the PKCE
+// verifier, and validates the returned ID token.
+func (s *oidcSession) Authorize(provider goth.Provider, params goth.Params) (string, error) {
+	p, ok := provider.(*oidcProvider)
+	if !ok {
+		return "", errors.New("not an OIDC provider")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*oidcHTTPTimeout)
+	defer cancel()
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := p.oauth2Config(d).Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.client),
+		params.Get("code"), oauth2.VerifierOption(s.CodeVerifier))
+	if err != nil {
+		return "", err
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return "", errors.New("token response has no ID token")
+	}
+	claims, err := p.verifyIDToken(ctx, d, rawIDToken, s.Nonce)
+	if err != nil {
+		return "", err
+	}
+
+	// Fill in claims the ID token left out from userinfo, which must describe
+	// the same subject.
+	if d.UserinfoEndpoint != "" && token.AccessToken != "" && p.missingClaims(claims) {
+		info, err := p.userinfo(ctx, d, token.AccessToken)
+		if err == nil && info["sub"] == claims["sub"] {
+			for k, v := range info {
+				if _, ok := claims[k]; !ok {
+					claims[k] = v
+				}
+			}
+		}
+	}
+
+	s.AccessToken = token.AccessToken
+	s.IDToken = rawIDToken
+	s.Claims = claims
+	return token.AccessToken, nil
+}
+
+// missingClaims reports whether the ID token lacks a mapped claim or
+// email_verified.
+func (p *oidcProvider) missingClaims(claims map[string]interface{}) bool {
+	if claimString(claims, p.claimName(p.cfg.EmailClaim, "email")) == "" {
+		return true
+	}
+	if _, ok := claims["email_verified"]; !ok {
+		return true
+	}
+	if claimString(claims, p.claimName(p.cfg.NameClaim, "name")) == "" {
+		return true
+	}
+	return len(p.cfg.GroupAccess) > 0 && claimValue(claims, p.claimName(p.cfg.GroupsClaim, "groups")) == nil
+}
+
+// claimValue looks up a claim by name. Names with dots also match nested
+// claims, such as Keycloak's realm_access.roles.
+func claimValue(claims map[string]interface{}, name string) interface{} {
+	if v, ok := claims[name]; ok {
+		return v
+	}
+	var cur interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if cur, ok = m[part]; !ok {
+			return nil
+		}
+	}
+	return cur
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	s, _ := claimValue(claims, name).(string)
+	return s
+}
+
+// claimStrings reads a claim holding a list of strings or a single string.
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch v := claimValue(claims, name).(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,350 @@
+package auth
+
+import (
+	"bedrud/internal/models"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/markbates/goth"
+)
+
+const (
+	testOIDCClientID = "bedrud-test"
+	testOIDCSubject  = "f81d4fae-7dec-11d0-a765-00a0c91e6bf6:very-long-subject-from-the-identity-provider"
+)
+
+// fakeIdP is a minimal OpenID provider: discovery, JWKS, token and userinfo.
+type fakeIdP struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+	issuer    string
+	// tamper edits the ID token claims before signing.
+	tamper func(jwt.MapClaims)
+	// signWith signs the ID token with another key than the published one.
+	signWith *rsa.PrivateKey
+	userinfo map[string]interface{}
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	f := &fakeIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		issuer := f.issuer
+		f.mu.Unlock()
+		if issuer == "" {
+			issuer = f.srv.URL
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": f.srv.URL + "/authorize",
+			"token_endpoint":         f.srv.URL + "/token",
+			"userinfo_endpoint":      f.srv.URL + "/userinfo",
+			"jwks_uri":               f.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "key-1", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", f.token)
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(f.userinfo)
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if r.Form.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != f.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":          f.srv.URL,
+		"aud":          testOIDCClientID,
+		"sub":          testOIDCSubject,
+		"exp":          now.Add(5 * time.Minute).Unix(),
+		"iat":          now.Unix(),
+		"nonce":        f.nonce,
+		"email":        "ada@example.com",
+		"name":         "Ada Lovelace",
+		"realm_access": map[string]interface{}{"roles": []string{"bedrud-admins", "offline_access"}},
+	}
+	if f.tamper != nil {
+		f.tamper(claims)
+	}
+	signer := f.key
+	if f.signWith != nil {
+		signer = f.signWith
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "key-1"
+	signed, err := idToken.SignedString(signer)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (f *fakeIdP) provider() *oidcProvider {
+	return newOIDCProvider(models.OIDCProvider{
+		ID:           "keycloak",
+		IssuerURL:    f.srv.URL + "/",
+		ClientID:     testOIDCClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8090/api/auth/keycloak/callback",
+		GroupsClaim:  "realm_access.roles",
+		GroupAccess:  map[string]models.AccessLevel{"bedrud-admins": models.AccessAdmin},
+	})
+}
+
+// signIn runs the browser's part of the flow: it follows the auth URL, which
+// the fake IdP approves, and completes the callback with a session that went
+// through the session cookie.
+func (f *fakeIdP) signIn(t *testing.T, p *oidcProvider, code string) (*oidcSession, error) {
+	t.Helper()
+	sess, err := p.BeginAuth("state-1")
+	if err != nil {
+		t.Fatalf("BeginAuth: %v", err)
+	}
+	authURL, _ := sess.GetAuthURL()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid auth URL: %v", err)
+	}
+	q := u.Query()
+	if !strings.HasPrefix(authURL, f.srv.URL+"/authorize?") || q.Get("state") != "state-1" ||
+		q.Get("code_challenge_method") != "S256" || q.Get("nonce") == "" || !strings.Contains(q.Get("scope"), "openid") {
+		t.Fatalf("unexpected auth URL: %s", authURL)
+	}
+	f.mu.Lock()
+	f.challenge, f.nonce = q.Get("code_challenge"), q.Get("nonce")
+	f.mu.Unlock()
+
+	restored, err := p.UnmarshalSession(sess.Marshal())
+	if err != nil {
+		t.Fatalf("UnmarshalSession: %v", err)
+	}
+	_, err = restored.Authorize(p, url.Values{"code": {code}, "state": {"state-1"}})
+	return restored.(*oidcSession), err
+}
+
+func TestOIDCProvider_SignIn(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := idp.provider()
+
+	sess, err := idp.signIn(t, p, "good-code")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(sess.Marshal(), "ada@example.com") {
+		t.Error("expected claims to stay out of the session cookie")
+	}
+
+	user, err := p.FetchUser(sess)
+	if err != nil {
+		t.Fatalf("FetchUser: %v", err)
+	}
+	if user.Provider != "keycloak" || user.Email != "ada@example.com" || user.Name != "Ada Lovelace" {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+	if len(user.UserID) != 36 {
+		t.Fatalf("expected a UUID user ID for a long subject, got %q", user.UserID)
+	}
+	again, _ := p.FetchUser(sess)
+	if again.UserID != user.UserID {
+		t.Fatal("expected the user ID to be stable")
+	}
+
+	accesses := p.accesses(user.RawData)
+	if len(accesses) != 2 || accesses[0] != "user" || accesses[1] != "admin" {
+		t.Fatalf("expected group mapping to grant admin, got %v", accesses)
+	}
+}
+
+func TestOIDCProvider_RejectsInvalidTokens(t *testing.T) {
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	tests := []struct {
+		name     string
+		code     string
+		tamper   func(jwt.MapClaims)
+		signWith *rsa.PrivateKey
+	}{
+		{name: "wrong code", code: "bad-code"},
+		{name: "nonce mismatch", tamper: func(c jwt.MapClaims) { c["nonce"] = "replayed" }},
+		{name: "wrong audience", tamper: func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{name: "wrong issuer", tamper: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{name: "expired", tamper: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "no expiry", tamper: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "bad signature", signWith: otherKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newFakeIdP(t)
+			idp.tamper, idp.signWith = tt.tamper, tt.signWith
+			code := tt.code
+			if code == "" {
+				code = "good-code"
+			}
+			p := idp.provider()
+			sess, err := idp.signIn(t, p, code)
+			if err == nil {
+				t.Fatal("expected sign-in to fail")
+			}
+			if _, err := p.FetchUser(sess); err == nil {
+				t.Fatal("expected no user from a failed sign-in")
+			}
+		})
+	}
+}
+
+func TestOIDCProvider_EmailVerified(t *testing.T) {
+	tests := []struct {
+		name     string
+		verified interface{}
+		wantErr  bool
+	}{
+		{name: "verified", verified: true},
+		{name: "not verified", verified: false, wantErr: true},
+		{name: "not a bool", verified: "true", wantErr: true},
+		{name: "not reported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newFakeIdP(t)
+			idp.tamper = func(c jwt.MapClaims) {
+				if tt.verified != nil {
+					c["email_verified"] = tt.verified
+				}
+			}
+			p := idp.provider()
+			sess, err := idp.signIn(t, p, "good-code")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			user, err := p.FetchUser(sess)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchUser error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && user.RawData["email_verified"] != tt.verified {
+				t.Fatalf("expected email_verified %v in the raw claims, got %v", tt.verified, user.RawData["email_verified"])
+			}
+		})
+	}
+}
+
+func TestOIDCProvider_UserinfoFillsMissingClaims(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.tamper = func(c jwt.MapClaims) { delete(c, "email") }
+	idp.userinfo = map[string]interface{}{"sub": testOIDCSubject, "email": "ada@example.com"}
+	p := idp.provider()
+
+	sess, err := idp.signIn(t, p, "good-code")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user, err := p.FetchUser(sess)
+	if err != nil || user.Email != "ada@example.com" {
+		t.Fatalf("expected the email from userinfo, got %q (%v)", user.Email, err)
+	}
+
+	// Userinfo for another subject is ignored.
+	idp.userinfo = map[string]interface{}{"sub": "someone-else", "email": "eve@example.com"}
+	sess, err = idp.signIn(t, idp.provider(), "good-code")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.FetchUser(sess); err == nil {
+		t.Fatal("expected no user without an email address")
+	}
+}
+
+func TestOIDCProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.issuer = "https://evil.example"
+	if _, err := idp.provider().BeginAuth("state-1"); err == nil {
+		t.Fatal("expected discovery to reject a different issuer")
+	}
+}
+
+func TestReloadProviders_OIDC(t *testing.T) {
+	t.Cleanup(func() { ReloadProviders(&models.SystemSettings{}) })
+
+	ReloadProviders(&models.SystemSettings{OIDCProviders: []models.OIDCProvider{
+		{
+			ID:          "authentik",
+			DisplayName: "Company SSO",
+			IssuerURL:   "https://sso.example.com/application/o/bedrud/",
+			ClientID:    "bedrud",
+			GroupAccess: map[string]models.AccessLevel{"mods": models.AccessMod},
+		},
+		{ID: "google", IssuerURL: "https://accounts.google.com", ClientID: "x"},
+		{ID: "broken", IssuerURL: "not a url", ClientID: "x"},
+	}})
+
+	oidc := ConfiguredOIDCProviders()
+	if len(oidc) != 1 || oidc[0].ID != "authentik" || oidc[0].DisplayName != "Company SSO" {
+		t.Fatalf("expected only the valid provider, got %+v", oidc)
+	}
+	if _, err := goth.GetProvider("authentik"); err != nil {
+		t.Fatalf("expected the provider to be registered: %v", err)
+	}
+	accesses := OAuthAccesses(goth.User{Provider: "authentik", RawData: map[string]interface{}{"groups": []interface{}{"mods"}}})
+	if len(accesses) != 2 || accesses[1] != "moderator" {
+		t.Fatalf("expected moderator access from groups, got %v", accesses)
+	}
+
+	ReloadProviders(&models.SystemSettings{})
+	if _, err := goth.GetProvider("authentik"); err == nil {
+		t.Fatal("expected the provider to be removed without a restart")
+	}
+	if got := OAuthAccesses(goth.User{Provider: "authentik"}); len(got) != 1 || got[0] != "user" {
+		t.Fatalf("expected default access, got %v", got)
+	}
+}
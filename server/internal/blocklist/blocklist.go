@@ -0,0 +1,180 @@
+// Package blocklist keeps the site-wide blocklist in memory so it can be
+// checked on every request without a database round trip.
+package blocklist
+
+import (
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type entry struct {
+	models.BlocklistEntry
+	ipNet *net.IPNet
+}
+
+var (
+	mu      sync.RWMutex
+	repo    *repository.BlocklistRepository
+	entries []entry
+)
+
+// Init loads the blocklist from repo. Until Init is called, or after
+// Init(nil), nothing is blocked.
+func Init(r *repository.BlocklistRepository) error {
+	mu.Lock()
+	repo = r
+	entries = nil
+	mu.Unlock()
+	return Reload()
+}
+
+// Reload re-reads the blocklist from the database. Call it after changing
+// entries.
+func Reload() error {
+	mu.RLock()
+	r := repo
+	mu.RUnlock()
+	if r == nil {
+		return nil
+	}
+	rows, err := r.GetActiveEntries()
+	if err != nil {
+		return err
+	}
+	loaded := make([]entry, 0, len(rows))
+	for _, row := range rows {
+		e := entry{BlocklistEntry: row}
+		if row.Type == models.BlockTypeIP {
+			if _, e.ipNet, err = net.ParseCIDR(row.Value); err != nil {
+				log.Warn().Str("value", row.Value).Msg("Skipping invalid blocklist IP range")
+				continue
+			}
+		}
+		loaded = append(loaded, e)
+	}
+	mu.Lock()
+	entries = loaded
+	mu.Unlock()
+	return nil
+}
+
+// PurgeExpired deletes expired entries and reloads the list, which also picks
+// up changes made by other instances or the CLI.
+func PurgeExpired() error {
+	mu.RLock()
+	r := repo
+	mu.RUnlock()
+	if r == nil {
+		return nil
+	}
+	n, err := r.PurgeExpiredEntries(time.Now())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Info().Int64("deleted", n).Msg("Purged expired blocklist entries")
+	}
+	return Reload()
+}
+
+// Match returns the first entry in force that blocks the user ID, email or IP
+// address, or nil. Empty arguments are not checked.
+func Match(userID, email, ip string) *models.BlocklistEntry {
+	email = strings.ToLower(email)
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain = email[at+1:]
+	}
+	addr := net.ParseIP(ip)
+	now := time.Now()
+
+	mu.RLock()
+	defer mu.RUnlock()
+	for i := range entries {
+		e := &entries[i]
+		if !e.Active(now) {
+			continue
+		}
+		var hit bool
+		switch e.Type {
+		case models.BlockTypeUser:
+			hit = userID != "" && e.Value == userID
+		case models.BlockTypeEmail:
+			hit = email != "" && e.Value == email
+		case models.BlockTypeDomain:
+			hit = domain != "" && e.Value == domain
+		case models.BlockTypeIP:
+			hit = addr != nil && e.ipNet.Contains(addr)
+		}
+		if hit {
+			found := e.BlocklistEntry
+			return &found
+		}
+	}
+	return nil
+}
+
+// Normalize validates a blocklist entry and returns its stored form. An
+// empty type is guessed from the value.
+func Normalize(typ, value string) (string, string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", "", errors.New("value is required")
+	}
+	if typ == "" {
+		typ = guessType(value)
+	}
+	switch typ {
+	case models.BlockTypeUser:
+	case models.BlockTypeEmail:
+		value = strings.ToLower(value)
+		if at := strings.Index(value, "@"); at <= 0 || at == len(value)-1 {
+			return "", "", errors.New("value must be an email address")
+		}
+	case models.BlockTypeDomain:
+		value = strings.ToLower(strings.TrimPrefix(value, "@"))
+		if strings.Contains(value, "@") || !strings.Contains(value, ".") {
+			return "", "", errors.New("value must be an email domain such as example.com")
+		}
+	case models.BlockTypeIP:
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return "", "", errors.New("value must be an IP address or CIDR range")
+			}
+			if ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return "", "", errors.New("value must be an IP address or CIDR range")
+		}
+		value = ipNet.String()
+	default:
+		return "", "", errors.New("type must be user, email, domain or ip")
+	}
+	return typ, value, nil
+}
+
+func guessType(value string) string {
+	switch {
+	case net.ParseIP(value) != nil || strings.Contains(value, "/"):
+		return models.BlockTypeIP
+	case strings.HasPrefix(value, "@"):
+		return models.BlockTypeDomain
+	case strings.Contains(value, "@"):
+		return models.BlockTypeEmail
+	default:
+		return models.BlockTypeUser
+	}
+}
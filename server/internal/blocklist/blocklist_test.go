@@ -0,0 +1,84 @@
+package blocklist
+
+import (
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"bedrud/internal/testutil"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		typ, value       string
+		wantTyp, wantVal string
+		wantErr          bool
+	}{
+		{"", "203.0.113.7", models.BlockTypeIP, "203.0.113.7/32", false},
+		{"ip", "2001:db8::1", models.BlockTypeIP, "2001:db8::1/128", false},
+		{"", "10.1.2.3/8", models.BlockTypeIP, "10.0.0.0/8", false},
+		{"", "@Spam.Example", models.BlockTypeDomain, "spam.example", false},
+		{"", "Troll@Example.com", models.BlockTypeEmail, "troll@example.com", false},
+		{"", "user-123", models.BlockTypeUser, "user-123", false},
+		{"ip", "not-an-ip", "", "", true},
+		{"domain", "localhost", "", "", true},
+		{"email", "@example.com", "", "", true},
+		{"other", "x", "", "", true},
+	}
+	for _, tt := range tests {
+		typ, val, err := Normalize(tt.typ, tt.value)
+		if (err != nil) != tt.wantErr || typ != tt.wantTyp || val != tt.wantVal {
+			t.Errorf("Normalize(%q, %q) = %q, %q, %v", tt.typ, tt.value, typ, val, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	repo := repository.NewBlocklistRepository(testutil.SetupTestDB(t))
+	expired := time.Now().Add(-time.Hour)
+	for _, e := range []models.BlocklistEntry{
+		{Type: models.BlockTypeUser, Value: "user-1"},
+		{Type: models.BlockTypeEmail, Value: "troll@example.com"},
+		{Type: models.BlockTypeDomain, Value: "spam.example"},
+		{Type: models.BlockTypeIP, Value: "203.0.113.0/24"},
+		{Type: models.BlockTypeIP, Value: "198.51.100.1/32", ExpiresAt: &expired},
+	} {
+		e := e
+		if err := repo.CreateEntry(&e); err != nil {
+			t.Fatalf("create entry: %v", err)
+		}
+	}
+	if err := Init(repo); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	t.Cleanup(func() { _ = Init(nil) })
+
+	blocked := [][3]string{
+		{"user-1", "", ""},
+		{"", "Troll@Example.com", ""},
+		{"", "someone@spam.example", ""},
+		{"", "", "203.0.113.99"},
+	}
+	for _, b := range blocked {
+		if Match(b[0], b[1], b[2]) == nil {
+			t.Errorf("expected %v to be blocked", b)
+		}
+	}
+	allowed := [][3]string{
+		{"user-2", "friend@example.com", "192.0.2.1"},
+		{"", "", "198.51.100.1"},
+		{"", "a@notspam.example", ""},
+	}
+	for _, a := range allowed {
+		if e := Match(a[0], a[1], a[2]); e != nil {
+			t.Errorf("expected %v to be allowed, matched %+v", a, e)
+		}
+	}
+
+	if err := PurgeExpired(); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if entries, _ := repo.GetActiveEntries(); len(entries) != 4 {
+		t.Fatalf("expected 4 active entries, got %d", len(entries))
+	}
+}
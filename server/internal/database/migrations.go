@@ -1,12 +1,17 @@
 package database
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"time"
 
 	"bedrud/internal/models"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
 )
 
 // RunMigrations performs all database migrations
@@ -27,9 +32,29 @@ func RunMigrations() error {
 	if err := db.AutoMigrate(&models.BlockedRefreshToken{}); err != nil {
 		return err
 	}
+	if err := db.AutoMigrate(&models.RevokedAccessToken{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Session{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.MFARecoveryCode{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.EmailToken{}); err != nil {
+		return err
+	}
+	// Rooms created before the lifecycle policy existed never expired, even
+	// though they carry an expiry. Detect them before the columns are added.
+	legacyRooms := db.Migrator().HasTable(&models.Room{}) && !db.Migrator().HasColumn(&models.Room{}, "archived_at")
 	if err := db.AutoMigrate(&models.Room{}); err != nil {
 		return err
 	}
+	if legacyRooms {
+		if err := backfillRoomLifecycle(db); err != nil {
+			return err
+		}
+	}
 	if err := db.AutoMigrate(&models.RoomParticipant{}); err != nil {
 		return err
 	}
@@ -48,6 +73,52 @@ func RunMigrations() error {
 	if err := db.AutoMigrate(&models.UserPreferences{}); err != nil {
 		return err
 	}
+	if err := db.AutoMigrate(&models.IssuedRoomToken{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.Recording{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.RoomIngress{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.ChatMessage{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.RoomInvite{}, &models.RoomInviteRedemption{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.RoomACLEntry{}, &models.RoomAccessRequest{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.RoomBan{}); err != nil {
+		return err
+	}
+	if err := backfillRoomBans(db); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.BlocklistEntry{}); err != nil {
+		return err
+	}
+	// Calendar feeds used to store their token in plain text. Hash it in
+	// place so existing subscription URLs keep working.
+	if db.Migrator().HasColumn(&models.CalendarFeed{}, "token") {
+		if err := hashCalendarFeedTokens(db); err != nil {
+			return err
+		}
+	}
+	if err := db.AutoMigrate(&models.ScheduledMeeting{}, &models.CalendarFeed{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.RoomSession{}, &models.AttendanceSegment{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.UsageStat{}, &models.UsageSampleClaim{}); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(&models.RoomE2EEKey{}); err != nil {
+		return err
+	}
 
 	// Add foreign key constraints manually (idempotent, Postgres only)
 	// SQLite does not support ALTER TABLE ADD CONSTRAINT for composite FKs.
@@ -68,3 +139,73 @@ func RunMigrations() error {
 	log.Info().Msg("Database migrations completed successfully")
 	return nil
 }
+
+// backfillRoomBans gives every participant banned before room bans existed a
+// permanent ban record, so it shows up in the ban list and can be lifted.
+func backfillRoomBans(db *gorm.DB) error {
+	var banned []models.RoomParticipant
+	err := db.Where("is_banned = ? AND NOT EXISTS (SELECT 1 FROM room_bans b WHERE b.room_id = room_participants.room_id AND b.identity = room_participants.user_id)", true).
+		Find(&banned).Error
+	if err != nil {
+		return err
+	}
+	for _, p := range banned {
+		ban := &models.RoomBan{
+			ID: uuid.New().String(), RoomID: p.RoomID, Identity: p.UserID,
+			GuestKey: p.GuestKey, DisplayName: p.DisplayName,
+		}
+		if err := db.Create(ban).Error; err != nil {
+			return err
+		}
+	}
+	if len(banned) > 0 {
+		log.Info().Int("count", len(banned)).Msg("Created room bans for previously banned participants")
+	}
+	return nil
+}
+
+// hashCalendarFeedTokens moves plain-text calendar feed tokens to the
+// token_hash column, replacing each with its SHA-256 hash.
+func hashCalendarFeedTokens(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Migrator().HasIndex(&models.CalendarFeed{}, "idx_calendar_feeds_token") {
+			if err := tx.Migrator().DropIndex(&models.CalendarFeed{}, "idx_calendar_feeds_token"); err != nil {
+				return err
+			}
+		}
+		if err := tx.Migrator().RenameColumn(&models.CalendarFeed{}, "token", "token_hash"); err != nil {
+			return err
+		}
+		var feeds []models.CalendarFeed
+		if err := tx.Find(&feeds).Error; err != nil {
+			return err
+		}
+		for _, f := range feeds {
+			sum := sha256.Sum256([]byte(f.TokenHash))
+			if err := tx.Model(&models.CalendarFeed{}).Where("user_id = ?", f.UserID).
+				Update("token_hash", hex.EncodeToString(sum[:])).Error; err != nil {
+				return err
+			}
+		}
+		if len(feeds) > 0 {
+			log.Info().Int("count", len(feeds)).Msg("Hashed calendar feed tokens")
+		}
+		return nil
+	})
+}
+
+// backfillRoomLifecycle makes rooms that predate the lifecycle policy
+// permanent, so upgrading does not archive every room past its old 24 hour
+// expiry. Renewing a room without "permanent" makes it expire again.
+func backfillRoomLifecycle(db *gorm.DB) error {
+	res := db.Model(&models.Room{}).
+		Where("permanent = ?", false).
+		Updates(map[string]interface{}{"permanent": true, "last_activity_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		log.Info().Int64("count", res.RowsAffected).Msg("Made rooms created before the lifecycle policy permanent")
+	}
+	return nil
+}
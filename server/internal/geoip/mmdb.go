@@ -0,0 +1,361 @@
+// Package geoip looks up the country and continent of IP addresses in a
+// MaxMind DB file (GeoLite2/GeoIP2 Country or City).
+//
+// Only the parts of the MaxMind DB format needed for those lookups are
+// implemented: the search tree with 24, 28 and 32 bit records and the data
+// section decoder.
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// ErrInvalidDatabase is returned for files that aren't MaxMind DBs or are corrupt.
+var ErrInvalidDatabase = errors.New("invalid MaxMind database")
+
+// Location is what the database knows about an address.
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 code, e.g. "DE".
+	Country string
+	// Continent is the two letter continent code, e.g. "EU".
+	Continent string
+}
+
+// Reader looks up addresses in a MaxMind DB loaded into memory.
+type Reader struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+}
+
+// Open reads the database at path into memory.
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytes(buf)
+}
+
+// FromBytes parses a MaxMind DB held in memory.
+func FromBytes(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("%w: metadata not found", ErrInvalidDatabase)
+	}
+	meta := buf[start+len(metadataMarker):]
+	v, _, err := (&decoder{buf: meta}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+	r := &Reader{
+		buf:        buf,
+		nodeCount:  uint(asUint(m["node_count"])),
+		recordSize: uint(asUint(m["record_size"])),
+		ipVersion:  uint(asUint(m["ip_version"])),
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, r.recordSize)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > uint(start) {
+		return nil, fmt.Errorf("%w: search tree overruns the file", ErrInvalidDatabase)
+	}
+	r.data = buf[treeSize+16 : start]
+
+	// IPv4 addresses live under ::/96 in IPv6 databases.
+	if r.ipVersion == 6 {
+		for i := 0; i < 96 && r.ipv4Start < r.nodeCount; i++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+// Lookup returns the location of ip. ok is false when the database has no
+// entry for it.
+func (r *Reader) Lookup(ip net.IP) (loc Location, ok bool, err error) {
+	node, bits := r.ipv4Start, 32
+	addr := ip.To4()
+	if addr == nil {
+		if r.ipVersion == 4 {
+			return Location{}, false, nil
+		}
+		addr, node, bits = ip.To16(), 0, 128
+		if addr == nil {
+			return Location{}, false, nil
+		}
+	}
+	for i := 0; i < bits && node < r.nodeCount; i++ {
+		bit := uint(addr[i>>3]>>(7-uint(i&7))) & 1
+		node = r.record(node, bit)
+	}
+	if node <= r.nodeCount {
+		return Location{}, false, nil
+	}
+	offset := node - r.nodeCount - 16
+	if offset >= uint(len(r.data)) {
+		return Location{}, false, fmt.Errorf("%w: data pointer out of range", ErrInvalidDatabase)
+	}
+	v, _, err := (&decoder{buf: r.data}).decode(offset, 0)
+	if err != nil {
+		return Location{}, false, fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+	}
+	m, _ := v.(map[string]interface{})
+	loc.Country = field(m, "country", "iso_code")
+	if loc.Country == "" {
+		loc.Country = field(m, "registered_country", "iso_code")
+	}
+	loc.Continent = field(m, "continent", "code")
+	return loc, true, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a tree node.
+func (r *Reader) record(node, bit uint) uint {
+	b := r.buf[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+func field(m map[string]interface{}, outer, inner string) string {
+	sub, _ := m[outer].(map[string]interface{})
+	s, _ := sub[inner].(string)
+	return s
+}
+
+func asUint(v interface{}) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case int64:
+		if n >= 0 {
+			return uint64(n)
+		}
+	}
+	return 0
+}
+
+// Data section types.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// maxDepth bounds nesting so a corrupt file can't recurse forever.
+const maxDepth = 32
+
+// maxValues bounds the values decoded for one record, so pointers in a
+// corrupt file can't expand a few bytes into an enormous record.
+const maxValues = 1 << 16
+
+type decoder struct {
+	buf    []byte
+	values int
+}
+
+// decode decodes the value at offset and returns it with the offset just
+// past it.
+func (d *decoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, errors.New("data nested too deeply")
+	}
+	if d.values++; d.values > maxValues {
+		return nil, 0, errors.New("record too large")
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(target, depth+1)
+		return v, next, err
+	}
+	// Every map entry takes at least two bytes and every array element one,
+	// so a larger count than that is corrupt and must not size an allocation.
+	remaining := uint(len(d.buf)) - offset
+	if typ == typeMap {
+		if size > remaining/2 {
+			return nil, 0, errors.New("map size overruns the data section")
+		}
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			v, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key], offset = v, next
+		}
+		return m, offset, nil
+	}
+	if typ == typeArray {
+		if size > remaining {
+			return nil, 0, errors.New("array size overruns the data section")
+		}
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			v, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a, offset = append(a, v), next
+		}
+		return a, offset, nil
+	}
+	if typ == typeBool {
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buf)) {
+		return nil, 0, errors.New("value overruns the data section")
+	}
+	b := d.buf[offset:end]
+	switch typ {
+	case typeString:
+		return string(b), end, nil
+	case typeBytes:
+		return append([]byte(nil), b...), end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("bad double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("bad float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), end, nil
+	case typeUint16, typeUint32, typeUint64, typeUint128:
+		if size > 8 {
+			// Only the low 64 bits of a uint128 are kept; nothing we read needs more.
+			b = b[size-8:]
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, end, nil
+	case typeInt32:
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int64(int32(n)), end, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+}
+
+// control decodes a field's control byte(s) into its type and size.
+func (d *decoder) control(offset uint) (typ, size, next uint, err error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errors.New("offset outside the data section")
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ = uint(ctrl >> 5)
+	if typ == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errors.New("truncated extended type")
+		}
+		typ = 7 + uint(d.buf[offset])
+		offset++
+	}
+	size = uint(ctrl & 0x1F)
+	if typ == typePointer {
+		return typ, size, offset, nil
+	}
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return 0, 0, 0, errors.New("truncated size")
+		}
+		var extra uint
+		for _, c := range d.buf[offset : offset+n] {
+			extra = extra<<8 | uint(c)
+		}
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + extra
+		case 2:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+	return typ, size, offset, nil
+}
+
+// pointer resolves a pointer whose control byte carried the low five bits in
+// size. It returns the target offset and the offset after the pointer.
+func (d *decoder) pointer(size, offset uint) (target, next uint, err error) {
+	n := (size>>3)&0x3 + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errors.New("truncated pointer")
+	}
+	var p uint
+	if n < 4 {
+		p = size & 0x7
+	}
+	for _, c := range d.buf[offset : offset+n] {
+		p = p<<8 | uint(c)
+	}
+	switch n {
+	case 2:
+		p += 2048
+	case 3:
+		p += 526336
+	}
+	return p, offset + n, nil
+}
@@ -0,0 +1,216 @@
+package geoip
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sort"
+	"testing"
+)
+
+// The helpers below write just enough of the MaxMind DB format to build
+// small test databases.
+
+func encodeString(s string) []byte {
+	return append([]byte{byte(typeString<<5 | len(s))}, s...)
+}
+
+func encodeUint(typ int, n uint64) []byte {
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	if typ >= 8 {
+		return append([]byte{byte(len(b)), byte(typ - 7)}, b...)
+	}
+	return append([]byte{byte(typ<<5 | len(b))}, b...)
+}
+
+func encodeMap(m map[string][]byte) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := []byte{byte(typeMap<<5 | len(m))}
+	for _, k := range keys {
+		out = append(out, encodeString(k)...)
+		out = append(out, m[k]...)
+	}
+	return out
+}
+
+func location(country, continent string) []byte {
+	return encodeMap(map[string][]byte{
+		"country":   encodeMap(map[string][]byte{"iso_code": encodeString(country)}),
+		"continent": encodeMap(map[string][]byte{"code": encodeString(continent)}),
+	})
+}
+
+type trieNode struct {
+	child [2]*trieNode
+	data  int // index into the data records + 1, 0 when none
+}
+
+// buildDB writes an IPv6 database with 24 bit records mapping each network
+// to the data record at the same index.
+func buildDB(t testing.TB, networks []string, records [][]byte) []byte {
+	t.Helper()
+	root := &trieNode{}
+	for i, cidr := range networks {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ones, _ := n.Mask.Size()
+		ip := n.IP.To16()
+		if v4 := n.IP.To4(); v4 != nil {
+			ip, ones = append(make(net.IP, 12), v4...), ones+96
+		}
+		node := root
+		for b := 0; b < ones; b++ {
+			bit := ip[b>>3] >> (7 - uint(b&7)) & 1
+			if node.child[bit] == nil {
+				node.child[bit] = &trieNode{}
+			}
+			node = node.child[bit]
+		}
+		node.data = i + 1
+	}
+
+	var order []*trieNode
+	index := map[*trieNode]int{}
+	var walk func(n *trieNode)
+	walk = func(n *trieNode) {
+		if n == nil || n.data != 0 {
+			return
+		}
+		index[n] = len(order)
+		order = append(order, n)
+		walk(n.child[0])
+		walk(n.child[1])
+	}
+	walk(root)
+
+	var data []byte
+	offsets := make([]int, len(records))
+	for i, r := range records {
+		offsets[i] = len(data)
+		data = append(data, r...)
+	}
+	count := len(order)
+	value := func(n *trieNode) int {
+		switch {
+		case n == nil:
+			return count
+		case n.data != 0:
+			return count + 16 + offsets[n.data-1]
+		default:
+			return index[n]
+		}
+	}
+	var out bytes.Buffer
+	for _, n := range order {
+		for _, c := range n.child {
+			v := value(c)
+			out.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data)
+	out.Write(metadataMarker)
+	out.Write(encodeMap(map[string][]byte{
+		"node_count":    encodeUint(typeUint32, uint64(count)),
+		"record_size":   encodeUint(typeUint16, 24),
+		"ip_version":    encodeUint(typeUint16, 6),
+		"database_type": encodeString("Test-Country"),
+	}))
+	return out.Bytes()
+}
+
+func TestLookup(t *testing.T) {
+	db := buildDB(t,
+		[]string{"81.2.69.0/24", "2001:db8::/32", "10.0.0.0/8"},
+		[][]byte{location("GB", "EU"), location("US", "NA"), location("DE", "EU")},
+	)
+	r, err := FromBytes(db)
+	if err != nil {
+		t.Fatalf("FromBytes: %v", err)
+	}
+	for ip, want := range map[string]Location{
+		"81.2.69.160":     {Country: "GB", Continent: "EU"},
+		"2001:db8::1":     {Country: "US", Continent: "NA"},
+		"10.20.30.40":     {Country: "DE", Continent: "EU"},
+		"::ffff:10.1.2.3": {Country: "DE", Continent: "EU"},
+	} {
+		got, ok, err := r.Lookup(net.ParseIP(ip))
+		if err != nil || !ok || got != want {
+			t.Errorf("Lookup(%s) = %+v, %v, %v; want %+v", ip, got, ok, err, want)
+		}
+	}
+	if _, ok, err := r.Lookup(net.ParseIP("8.8.8.8")); ok || err != nil {
+		t.Errorf("expected no entry for 8.8.8.8, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestFromBytes_Rejects(t *testing.T) {
+	if _, err := FromBytes([]byte("not a database")); !errors.Is(err, ErrInvalidDatabase) {
+		t.Errorf("expected ErrInvalidDatabase, got %v", err)
+	}
+	db := buildDB(t, []string{"10.0.0.0/8"}, [][]byte{location("DE", "EU")})
+	truncated := append(db[:10:10], db[bytes.LastIndex(db, metadataMarker):]...)
+	if _, err := FromBytes(truncated); !errors.Is(err, ErrInvalidDatabase) {
+		t.Errorf("expected ErrInvalidDatabase for a truncated tree, got %v", err)
+	}
+}
+
+func TestDecode_Pointers(t *testing.T) {
+	// A map whose value is a pointer back to the string at offset 0.
+	buf := append(encodeString("EU"), byte(typeMap<<5|1))
+	buf = append(buf, encodeString("code")...)
+	buf = append(buf, byte(typePointer<<5), 0)
+	v, _, err := (&decoder{buf: buf}).decode(3, 0)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if m := v.(map[string]interface{}); m["code"] != "EU" {
+		t.Errorf("expected the pointer to resolve to EU, got %v", m)
+	}
+}
+
+func TestDecode_RejectsOversizedContainers(t *testing.T) {
+	// Sizes of 65821 + 0xFFFFFF in a buffer holding a handful of bytes.
+	for name, buf := range map[string][]byte{
+		"map":   {typeMap<<5 | 31, 0xFF, 0xFF, 0xFF, 0x41},
+		"array": {typeExtended<<5 | 31, typeArray - 7, 0xFF, 0xFF, 0xFF, 0x41},
+	} {
+		if _, _, err := (&decoder{buf: buf}).decode(0, 0); err == nil {
+			t.Errorf("%s: expected an error for a size larger than the data", name)
+		}
+	}
+}
+
+func TestDecode_RejectsPointerBlowup(t *testing.T) {
+	// A map of 16 entries whose values all point back at the map.
+	buf := []byte{typeMap<<5 | 16}
+	for i := 0; i < 16; i++ {
+		buf = append(buf, encodeString(string(rune('a'+i)))...)
+		buf = append(buf, typePointer<<5, 0)
+	}
+	if _, _, err := (&decoder{buf: buf}).decode(0, 0); err == nil {
+		t.Error("expected an error for a record that expands without bound")
+	}
+}
+
+func FuzzFromBytes(f *testing.F) {
+	f.Add(buildDB(f, []string{"10.0.0.0/8", "2001:db8::/32"}, [][]byte{location("DE", "EU"), location("US", "NA")}))
+	f.Fuzz(func(t *testing.T, db []byte) {
+		r, err := FromBytes(db)
+		if err != nil {
+			return
+		}
+		for _, ip := range []string{"10.1.2.3", "2001:db8::1", "8.8.8.8"} {
+			_, _, _ = r.Lookup(net.ParseIP(ip))
+		}
+	})
+}
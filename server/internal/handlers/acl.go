@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/mailer"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const accessRequestMaxMessage = 500
+
+// AddACLEntryRequest is the body for POST /room/:roomId/acl.
+type AddACLEntryRequest struct {
+	// Type is "user" (user ID or email), "domain" or "group".
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// RequestAccessRequest is the body for POST /room/:roomId/access-requests.
+type RequestAccessRequest struct {
+	Message string `json:"message"`
+}
+
+// ListACL returns the room's access list.
+func (h *RoomHandler) ListACL(c *fiber.Ctx) error {
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	entries, err := h.roomRepo.GetRoomACLEntries(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list room ACL")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list access list"})
+	}
+	if entries == nil {
+		entries = []models.RoomACLEntry{}
+	}
+	return c.JSON(entries)
+}
+
+// AddACLEntry allows a user, email domain or group into the room.
+func (h *RoomHandler) AddACLEntry(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	var req AddACLEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	value, err := normalizeACLValue(req.Type, req.Value)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	entry := &models.RoomACLEntry{RoomID: room.ID, Type: req.Type, Value: value, CreatedBy: claims.UserID}
+	if err := h.roomRepo.AddRoomACLEntry(entry); err != nil {
+		if errors.Is(err, repository.ErrACLEntryExists) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to add room ACL entry")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to add access list entry"})
+	}
+	return c.Status(201).JSON(entry)
+}
+
+// DeleteACLEntry removes an entry from the room's access list.
+func (h *RoomHandler) DeleteACLEntry(c *fiber.Ctx) error {
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	found, err := h.roomRepo.DeleteRoomACLEntry(room.ID, c.Params("entryId"))
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to delete room ACL entry")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete access list entry"})
+	}
+	if !found {
+		return c.Status(404).JSON(fiber.Map{"error": "Access list entry not found"})
+	}
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// RequestAccess asks the room admin to let the caller into a private room.
+// The request is stored for the admin to list. The admin and moderators are
+// notified if they are in the room, and the admin by email.
+func (h *RoomHandler) RequestAccess(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	allowed, err := h.canEnterPrivateRoom(room, adminId, claims)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to check room access")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to check room access"})
+	}
+	if room.IsPublic || allowed {
+		return c.Status(409).JSON(fiber.Map{"error": "You already have access to this room"})
+	}
+	pending, err := h.roomRepo.HasPendingRoomAccessRequest(room.ID, claims.UserID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to look up access requests")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up access requests"})
+	}
+	if pending {
+		return c.Status(409).JSON(fiber.Map{"error": "Your request is already pending"})
+	}
+
+	var req RequestAccessRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if len(req.Message) > accessRequestMaxMessage {
+		return c.Status(400).JSON(fiber.Map{"error": "Message is too long"})
+	}
+
+	ar := &models.RoomAccessRequest{
+		RoomID: room.ID, UserID: claims.UserID, Name: claims.Name, Email: claims.Email, Message: req.Message,
+	}
+	if err := h.roomRepo.CreateRoomAccessRequest(ar); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to store access request")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to request access"})
+	}
+	h.notifyModerators(c.Context(), room, adminId, "access_requested", claims.UserID)
+	h.mailAccessRequest(room, adminId, ar)
+	return c.Status(201).JSON(ar)
+}
+
+// mailAccessRequest emails the room admin about a new access request, so it
+// isn't missed while nobody is in the room. Only verified addresses are
+// mailed.
+func (h *RoomHandler) mailAccessRequest(room *models.Room, adminId string, ar *models.RoomAccessRequest) {
+	admin, err := h.roomRepo.GetUserByID(adminId)
+	if err != nil || !admin.EmailVerified {
+		return
+	}
+	link := ""
+	if h.settings != nil {
+		if s, err := h.settings.GetEffectiveSettings(); err == nil && s.FrontendURL != "" {
+			link = strings.TrimRight(s.FrontendURL, "/") + "/m/" + room.Name
+		}
+	}
+	msg, err := mailer.Render("access_request", admin.Email, "Access request for "+room.Name, map[string]interface{}{
+		"Name":           admin.Name,
+		"Requester":      ar.Name,
+		"RequesterEmail": ar.Email,
+		"Room":           room.Name,
+		"Message":        ar.Message,
+		"Link":           link,
+	})
+	if err == nil {
+		err = mailer.Send(msg)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("roomID", room.ID).Msg("Failed to email access request")
+	}
+}
+
+// ListAccessRequests returns the room's pending access requests.
+func (h *RoomHandler) ListAccessRequests(c *fiber.Ctx) error {
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	reqs, err := h.roomRepo.GetPendingRoomAccessRequests(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list access requests")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list access requests"})
+	}
+	if reqs == nil {
+		reqs = []models.RoomAccessRequest{}
+	}
+	return c.JSON(reqs)
+}
+
+// ApproveAccessRequest adds the requester to the room's access list.
+func (h *RoomHandler) ApproveAccessRequest(c *fiber.Ctx) error {
+	return h.resolveAccessRequest(c, models.AccessRequestApproved)
+}
+
+// DenyAccessRequest rejects an access request.
+func (h *RoomHandler) DenyAccessRequest(c *fiber.Ctx) error {
+	return h.resolveAccessRequest(c, models.AccessRequestDenied)
+}
+
+func (h *RoomHandler) resolveAccessRequest(c *fiber.Ctx, status string) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	ar, err := h.roomRepo.GetRoomAccessRequest(c.Params("requestId"))
+	if err != nil {
+		log.Error().Err(err).Str("requestID", c.Params("requestId")).Msg("Failed to look up access request")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up access request"})
+	}
+	if ar == nil || ar.RoomID != room.ID {
+		return c.Status(404).JSON(fiber.Map{"error": "Access request not found"})
+	}
+	if ar.Status != models.AccessRequestPending {
+		return c.Status(409).JSON(fiber.Map{"error": "Access request was already " + ar.Status})
+	}
+
+	if status == models.AccessRequestApproved {
+		entry := &models.RoomACLEntry{RoomID: room.ID, Type: models.RoomACLTypeUser, Value: ar.UserID, CreatedBy: claims.UserID}
+		if err := h.roomRepo.AddRoomACLEntry(entry); err != nil && !errors.Is(err, repository.ErrACLEntryExists) {
+			log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to add room ACL entry")
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to approve access request"})
+		}
+	}
+	if err := h.roomRepo.ResolveRoomAccessRequest(ar.ID, status, claims.UserID); err != nil {
+		log.Error().Err(err).Str("requestID", ar.ID).Msg("Failed to resolve access request")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to resolve access request"})
+	}
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// canEnterPrivateRoom reports whether claims may join the room while it is
+// private: room moderators and users on the access list may. Breakout rooms
+// use their main room's list.
+func (h *RoomHandler) canEnterPrivateRoom(room *models.Room, adminId string, claims *auth.Claims) (bool, error) {
+	if isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
+		return true, nil
+	}
+	aclRoomID := room.ID
+	if room.IsBreakout() {
+		aclRoomID = room.ParentRoomID
+	}
+	return h.roomRepo.IsAllowedByACL(aclRoomID, claims.UserID, claims.Accesses)
+}
+
+// resolveRoomAdmin loads the room from the route and checks the caller is its
+// admin or a superadmin. Writes the error response itself.
+func (h *RoomHandler) resolveRoomAdmin(c *fiber.Ctx) (*models.Room, bool) {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil, false
+	}
+	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
+		_ = c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
+		return nil, false
+	}
+	return room, true
+}
+
+// normalizeACLValue validates an ACL entry and returns its stored form.
+func normalizeACLValue(typ, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("value is required")
+	}
+	switch typ {
+	case models.RoomACLTypeUser:
+		if strings.Contains(value, "@") {
+			value = strings.ToLower(value)
+		}
+	case models.RoomACLTypeDomain:
+		value = strings.ToLower(strings.TrimPrefix(value, "@"))
+		if strings.Contains(value, "@") || !strings.Contains(value, ".") {
+			return "", errors.New("value must be an email domain such as example.com")
+		}
+	case models.RoomACLTypeGroup:
+	default:
+		return "", errors.New("type must be user, domain or group")
+	}
+	return value, nil
+}
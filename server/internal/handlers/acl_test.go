@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/mailer"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupACLTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name:    "board-meeting",
+		private: true,
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/list", h.ListRooms)
+			app.Get("/room/:roomId/acl", h.ListACL)
+			app.Post("/room/:roomId/acl", h.AddACLEntry)
+			app.Delete("/room/:roomId/acl/:entryId", h.DeleteACLEntry)
+			app.Get("/room/:roomId/access-requests", h.ListAccessRequests)
+			app.Post("/room/:roomId/access-requests", h.RequestAccess)
+			app.Post("/room/:roomId/access-requests/:requestId/approve", h.ApproveAccessRequest)
+			app.Post("/room/:roomId/access-requests/:requestId/deny", h.DenyAccessRequest)
+		},
+	})
+	f.handler.nodes.Default().Rooms = &fakeRoomService{}
+	f.db.Create(&models.User{ID: "member-user", Email: "member@partner.com", Name: "Member", Provider: "local", IsActive: true, EmailVerified: true})
+	f.addUser("unverified-user", "eve@partner.com", "Eve")
+	return f.app, f.repo, f.room, &f.current
+}
+
+func listRoomNames(t *testing.T, app *fiber.App) []string {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/room/list", nil), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var rooms []models.Room
+	_ = json.NewDecoder(resp.Body).Decode(&rooms)
+	names := make([]string, len(rooms))
+	for i := range rooms {
+		names[i] = rooms[i].Name
+	}
+	return names
+}
+
+func TestACL_PrivateRoomRequiresEntry(t *testing.T) {
+	app, _, room, current := setupACLTestApp(t)
+
+	*current = &auth.Claims{UserID: "member-user", Email: "member@partner.com", Name: "Member", Accesses: []string{"user", "finance"}}
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusForbidden || body["canRequestAccess"] != true || body["roomId"] != room.ID {
+		t.Fatalf("expected 403 with request-access hint, got %d (%v)", status, body)
+	}
+	if names := listRoomNames(t, app); len(names) != 0 {
+		t.Fatalf("expected no shared rooms yet, got %v", names)
+	}
+
+	for _, entry := range []map[string]string{
+		{"type": "user", "value": "Member@Partner.com"},
+		{"type": "domain", "value": "@partner.com"},
+		{"type": "group", "value": "finance"},
+	} {
+		*current = &auth.Claims{UserID: "owner-user", Email: "owner@ex.com", Name: "Owner", Accesses: []string{"user"}}
+		status, added := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/acl", entry)
+		if status != http.StatusCreated {
+			t.Fatalf("expected 201 adding %v, got %d (%v)", entry, status, added)
+		}
+
+		*current = &auth.Claims{UserID: "member-user", Email: "member@partner.com", Name: "Member", Accesses: []string{"user", "finance"}}
+		status, _ = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+		if status != http.StatusOK {
+			t.Fatalf("expected %s entry to admit member, got %d", entry["type"], status)
+		}
+		if names := listRoomNames(t, app); len(names) != 1 || names[0] != room.Name {
+			t.Fatalf("expected shared room in list, got %v", names)
+		}
+
+		*current = &auth.Claims{UserID: "owner-user", Email: "owner@ex.com", Name: "Owner", Accesses: []string{"user"}}
+		status, _ = doJSONRequest(t, app, http.MethodDelete, "/room/"+room.ID+"/acl/"+added["id"].(string), nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected 200 removing entry, got %d", status)
+		}
+	}
+
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/acl", map[string]string{"type": "domain", "value": "nodot"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid domain, got %d", status)
+	}
+}
+
+func TestACL_EmailEntriesNeedVerifiedAddress(t *testing.T) {
+	app, _, room, current := setupACLTestApp(t)
+
+	for _, entry := range []map[string]string{
+		{"type": "user", "value": "eve@partner.com"},
+		{"type": "domain", "value": "partner.com"},
+	} {
+		status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/acl", entry)
+		if status != http.StatusCreated {
+			t.Fatalf("expected 201 adding %v, got %d", entry, status)
+		}
+	}
+
+	*current = &auth.Claims{UserID: "unverified-user", Email: "eve@partner.com", Name: "Eve", Accesses: []string{"user"}}
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected an unverified address to be refused, got %d", status)
+	}
+	if names := listRoomNames(t, app); len(names) != 0 {
+		t.Fatalf("expected no shared rooms for an unverified address, got %v", names)
+	}
+}
+
+func TestACL_RequestAccessFlow(t *testing.T) {
+	app, roomRepo, room, current := setupACLTestApp(t)
+	box := &testMailbox{}
+	mailer.Start()
+	mailer.SetSenderForTest(box)
+	t.Cleanup(func() { mailer.Configure(&models.SystemSettings{}) })
+
+	*current = &auth.Claims{UserID: "member-user", Email: "member@ex.com", Name: "Member", Accesses: []string{"user"}}
+	status, ar := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/access-requests", map[string]string{"message": "I'm presenting"})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%v)", status, ar)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/access-requests", nil)
+	if status != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate request, got %d", status)
+	}
+	if !mailer.Flush(5 * time.Second) {
+		t.Fatal("mail queue did not drain")
+	}
+	if len(box.sent) != 1 || box.sent[0].To != "owner@ex.com" || !strings.Contains(box.sent[0].Text, "I'm presenting") {
+		t.Fatalf("expected the owner to be emailed the request once, got %+v", box.sent)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/access-requests/"+ar["id"].(string)+"/approve", nil)
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 approving own request, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "owner-user", Email: "owner@ex.com", Name: "Owner", Accesses: []string{"user"}}
+	if pending, _ := roomRepo.GetPendingRoomAccessRequests(room.ID); len(pending) != 1 {
+		t.Fatalf("expected 1 pending request, got %d", len(pending))
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/access-requests/"+ar["id"].(string)+"/approve", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 approving, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/access-requests/"+ar["id"].(string)+"/deny", nil)
+	if status != http.StatusConflict {
+		t.Fatalf("expected 409 resolving twice, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "member-user", Email: "member@ex.com", Name: "Member", Accesses: []string{"user"}}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusOK {
+		t.Fatalf("expected approved member to join, got %d", status)
+	}
+}
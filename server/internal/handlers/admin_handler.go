@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bedrud/internal/auth"
+	"bedrud/internal/mailer"
 	"bedrud/internal/models"
 	"bedrud/internal/repository"
 	"crypto/rand"
@@ -44,6 +45,7 @@ func (h *AdminHandler) GetPublicSettings(c *fiber.Ctx) error {
 		"tokenRegistrationOnly": s.TokenRegistrationOnly,
 		"passkeysEnabled":       s.PasskeysEnabled,
 		"oauthProviders":        auth.ConfiguredProviders(),
+		"oidcProviders":         auth.ConfiguredOIDCProviders(),
 	})
 }
 
@@ -61,6 +63,12 @@ func (h *AdminHandler) UpdateSettings(c *fiber.Ctx) error {
 
 	// Unmask: if the client sent masked placeholders, keep the existing value
 	unmaskSecrets(&input, existing)
+	if current, err := h.settingsRepo.GetEffectiveSettings(); err == nil {
+		unmaskOIDCSecrets(input.OIDCProviders, current.OIDCProviders)
+	}
+	if err := models.ValidateOIDCProviders(input.OIDCProviders); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	input.ID = 1
 	if err := h.settingsRepo.SaveSettings(&input); err != nil {
@@ -73,6 +81,8 @@ func (h *AdminHandler) UpdateSettings(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "Settings saved but failed to reload"})
 	}
 	auth.ReloadProviders(effective)
+	auth.ApplyMFAPolicy(effective)
+	mailer.Configure(effective)
 
 	log.Info().Msg("Admin settings updated and providers reloaded")
 	return c.JSON(maskSettings(effective))
@@ -93,6 +103,7 @@ func unmaskSecrets(input, existing *models.SystemSettings) {
 		{&input.SessionSecret, existing.SessionSecret},
 		{&input.LiveKitAPISecret, existing.LiveKitAPISecret},
 		{&input.ChatUploadS3SecretKey, existing.ChatUploadS3SecretKey},
+		{&input.MailPassword, existing.MailPassword},
 	}
 	for _, s := range secrets {
 		if strings.TrimSpace(*s.incoming) == maskedSecret || strings.TrimSpace(*s.incoming) == "" {
@@ -125,9 +136,36 @@ func maskSettings(s *models.SystemSettings) *models.SystemSettings {
 	if cp.ChatUploadS3SecretKey != "" {
 		cp.ChatUploadS3SecretKey = maskedSecret
 	}
+	if cp.MailPassword != "" {
+		cp.MailPassword = maskedSecret
+	}
+	cp.OIDCProviders = append([]models.OIDCProvider(nil), s.OIDCProviders...)
+	for i := range cp.OIDCProviders {
+		if cp.OIDCProviders[i].ClientSecret != "" {
+			cp.OIDCProviders[i].ClientSecret = maskedSecret
+		}
+	}
 	return &cp
 }
 
+// unmaskOIDCSecrets does what unmaskSecrets does for OIDC client secrets,
+// matching providers by ID. current are the effective providers, which may
+// come from config.yaml.
+func unmaskOIDCSecrets(input, current []models.OIDCProvider) {
+	for i := range input {
+		p := &input[i]
+		if strings.TrimSpace(p.ClientSecret) != maskedSecret && strings.TrimSpace(p.ClientSecret) != "" {
+			continue
+		}
+		p.ClientSecret = ""
+		for _, cur := range current {
+			if cur.ID == p.ID {
+				p.ClientSecret = cur.ClientSecret
+			}
+		}
+	}
+}
+
 func (h *AdminHandler) ListInviteTokens(c *fiber.Ctx) error {
 	tokens, err := h.inviteTokenRepo.List()
 	if err != nil {
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bedrud/internal/auth"
+	"bedrud/internal/models"
 	"bedrud/internal/repository"
 	"bedrud/internal/testutil"
 	"bytes"
@@ -117,6 +118,75 @@ func TestAdminHandler_UpdateSettings_InvalidBody(t *testing.T) {
 	}
 }
 
+func TestAdminHandler_UpdateSettings_OIDCProviders(t *testing.T) {
+	app, settingsRepo, _ := setupAdminTestApp(t)
+	t.Cleanup(func() { auth.ReloadProviders(&models.SystemSettings{}) })
+
+	put := func(providers []map[string]interface{}) (int, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]interface{}{"oidcProviders": providers})
+		req := httptest.NewRequest(http.MethodPut, "/admin/settings", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req, -1)
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+	keycloak := map[string]interface{}{
+		"id":           "keycloak",
+		"displayName":  "Keycloak",
+		"issuerUrl":    "https://sso.example.com/realms/bedrud",
+		"clientId":     "bedrud",
+		"clientSecret": "s3cret",
+		"groupAccess":  map[string]string{"bedrud-admins": "admin"},
+	}
+
+	status, result := put([]map[string]interface{}{keycloak})
+	if status != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %v", http.StatusOK, status, result)
+	}
+	providers, _ := result["oidcProviders"].([]interface{})
+	if len(providers) != 1 || providers[0].(map[string]interface{})["clientSecret"] != maskedSecret {
+		t.Fatalf("expected a masked client secret, got %v", result["oidcProviders"])
+	}
+
+	// Sending the masked secret back keeps the stored one.
+	keycloak["clientSecret"] = maskedSecret
+	keycloak["displayName"] = "Company SSO"
+	if status, result := put([]map[string]interface{}{keycloak}); status != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %v", http.StatusOK, status, result)
+	}
+	saved, _ := settingsRepo.GetSettings()
+	if len(saved.OIDCProviders) != 1 || saved.OIDCProviders[0].ClientSecret != "s3cret" || saved.OIDCProviders[0].DisplayName != "Company SSO" {
+		t.Fatalf("unexpected stored providers: %+v", saved.OIDCProviders)
+	}
+
+	// The provider is live without a restart.
+	req := httptest.NewRequest(http.MethodGet, "/public/settings", http.NoBody)
+	resp, _ := app.Test(req, -1)
+	defer resp.Body.Close()
+	var public map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&public)
+	oidc, _ := public["oidcProviders"].([]interface{})
+	if len(oidc) != 1 || oidc[0].(map[string]interface{})["displayName"] != "Company SSO" {
+		t.Fatalf("expected the provider in public settings, got %v", public["oidcProviders"])
+	}
+
+	for name, bad := range map[string]map[string]interface{}{
+		"reserved id":  {"id": "github", "issuerUrl": "https://sso.example.com", "clientId": "x"},
+		"bad issuer":   {"id": "sso", "issuerUrl": "sso.example.com", "clientId": "x"},
+		"no client id": {"id": "sso", "issuerUrl": "https://sso.example.com"},
+		"bad access":   {"id": "sso", "issuerUrl": "https://sso.example.com", "clientId": "x", "groupAccess": map[string]string{"g": "root"}},
+	} {
+		if status, _ := put([]map[string]interface{}{bad}); status != http.StatusBadRequest {
+			t.Errorf("%s: expected %d, got %d", name, http.StatusBadRequest, status)
+		}
+	}
+	if status, _ := put([]map[string]interface{}{keycloak, keycloak}); status != http.StatusBadRequest {
+		t.Errorf("duplicate ids: expected %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
 func TestAdminHandler_ListInviteTokens_Empty(t *testing.T) {
 	app, _, _ := setupAdminTestApp(t)
 
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bedrud/internal/models"
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// RoomSessionResponse is a session in GET /room/:roomId/sessions.
+type RoomSessionResponse struct {
+	models.RoomSession
+	DurationSeconds int64 `json:"durationSeconds"`
+	Attendees       int   `json:"attendees"`
+}
+
+// AttendanceRecord sums up one participant's attendance in a session.
+type AttendanceRecord struct {
+	Identity        string               `json:"identity"`
+	DisplayName     string               `json:"displayName"`
+	Email           string               `json:"email,omitempty"`
+	Guest           bool                 `json:"guest"`
+	FirstJoinedAt   time.Time            `json:"firstJoinedAt"`
+	LastLeftAt      *time.Time           `json:"lastLeftAt"`
+	DurationSeconds int64                `json:"durationSeconds"`
+	Segments        []AttendanceInterval `json:"segments"`
+}
+
+// AttendanceInterval is one join-to-leave stretch of an AttendanceRecord.
+type AttendanceInterval struct {
+	JoinedAt        time.Time  `json:"joinedAt"`
+	LeftAt          *time.Time `json:"leftAt"`
+	DurationSeconds int64      `json:"durationSeconds"`
+}
+
+// ListSessions returns the room's meetings, newest first. Only the room
+// owner can see them.
+func (h *RoomHandler) ListSessions(c *fiber.Ctx) error {
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	sessions, err := h.roomRepo.GetRoomSessions(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list room sessions")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list sessions"})
+	}
+	ids := make([]string, len(sessions))
+	for i := range sessions {
+		ids[i] = sessions[i].ID
+	}
+	counts, err := h.roomRepo.CountSessionAttendees(ids)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to count session attendees")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list sessions"})
+	}
+
+	now := time.Now()
+	out := make([]RoomSessionResponse, 0, len(sessions))
+	for i := range sessions {
+		out = append(out, RoomSessionResponse{
+			RoomSession:     sessions[i],
+			DurationSeconds: int64(sessions[i].Duration(now).Seconds()),
+			Attendees:       counts[sessions[i].ID],
+		})
+	}
+	return c.JSON(out)
+}
+
+// SessionAttendance returns who attended a session and for how long, as JSON
+// or, with ?format=csv, as a CSV download with one row per join.
+func (h *RoomHandler) SessionAttendance(c *fiber.Ctx) error {
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	format := strings.ToLower(c.Query("format", "json"))
+	if format != "json" && format != "csv" {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be json or csv"})
+	}
+	session, err := h.roomRepo.GetRoomSession(c.Params("sessionId"))
+	if err != nil {
+		log.Error().Err(err).Str("sessionID", c.Params("sessionId")).Msg("Failed to look up room session")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up session"})
+	}
+	if session == nil || session.RoomID != room.ID {
+		return c.Status(404).JSON(fiber.Map{"error": "Session not found"})
+	}
+	segments, err := h.roomRepo.GetAttendanceSegments(session.ID)
+	if err != nil {
+		log.Error().Err(err).Str("sessionID", session.ID).Msg("Failed to load attendance")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load attendance"})
+	}
+
+	records, err := h.attendanceRecords(segments, time.Now())
+	if err != nil {
+		log.Error().Err(err).Str("sessionID", session.ID).Msg("Failed to look up attendees")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load attendance"})
+	}
+	if format == "csv" {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="attendance-`+room.Name+`-`+session.StartedAt.UTC().Format("20060102-1504")+`.csv"`)
+		return c.Send(attendanceCSV(records))
+	}
+	return c.JSON(fiber.Map{"session": session, "attendance": records})
+}
+
+// attendanceRecords groups segments by participant in order of first join.
+// Registered users are looked up for their email; identities that don't
+// belong to a user are reported as guests.
+func (h *RoomHandler) attendanceRecords(segments []models.AttendanceSegment, now time.Time) ([]AttendanceRecord, error) {
+	ids := make([]string, 0, len(segments))
+	seen := make(map[string]bool)
+	for i := range segments {
+		if id := segments[i].Identity; !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	found, err := h.roomRepo.GetUsersByIDs(ids...)
+	if err != nil {
+		return nil, err
+	}
+	users := make(map[string]*models.User, len(found))
+	for i := range found {
+		users[found[i].ID] = &found[i]
+	}
+
+	records := []AttendanceRecord{}
+	index := make(map[string]int)
+	for i := range segments {
+		seg := &segments[i]
+		n, seen := index[seg.Identity]
+		if !seen {
+			rec := AttendanceRecord{Identity: seg.Identity, DisplayName: seg.DisplayName, Guest: true, FirstJoinedAt: seg.JoinedAt}
+			if user := users[seg.Identity]; user != nil {
+				rec.Guest, rec.Email = false, user.Email
+				if rec.DisplayName == "" {
+					rec.DisplayName = user.Name
+				}
+			}
+			n = len(records)
+			index[seg.Identity] = n
+			records = append(records, rec)
+		}
+		rec := &records[n]
+		d := int64(seg.Duration(now).Seconds())
+		rec.Segments = append(rec.Segments, AttendanceInterval{JoinedAt: seg.JoinedAt, LeftAt: seg.LeftAt, DurationSeconds: d})
+		rec.DurationSeconds += d
+		// Segments come in join order, so the latest one decides; it is
+		// still open while the participant is connected.
+		rec.LastLeftAt = seg.LeftAt
+	}
+	return records, nil
+}
+
+func attendanceCSV(records []AttendanceRecord) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"identity", "display_name", "email", "guest", "joined_at", "left_at", "duration_seconds"})
+	for _, rec := range records {
+		for _, seg := range rec.Segments {
+			left := ""
+			if seg.LeftAt != nil {
+				left = seg.LeftAt.UTC().Format(time.RFC3339)
+			}
+			_ = w.Write([]string{
+				csvSafe(rec.Identity),
+				csvSafe(rec.DisplayName),
+				csvSafe(rec.Email),
+				strconv.FormatBool(rec.Guest),
+				seg.JoinedAt.UTC().Format(time.RFC3339),
+				left,
+				strconv.FormatInt(seg.DurationSeconds, 10),
+			})
+		}
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// csvSafe keeps spreadsheet apps from evaluating user-chosen values, such as
+// display names and email addresses, as formulas.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
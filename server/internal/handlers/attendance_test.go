@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bedrud/config"
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"bedrud/internal/testutil"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAttendance_SessionsAndExport(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+	lkCfg := config.LiveKitConfig{Host: "http://localhost:9999", APIKey: "test-key", APISecret: "test-secret"}
+	handler := NewRoomHandler(&lkCfg, &config.ChatConfig{}, roomRepo)
+
+	current := &auth.Claims{UserID: "owner-user", Name: "Owner", Accesses: []string{"user"}}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", current)
+		return c.Next()
+	})
+	app.Get("/room/:roomId/sessions", handler.ListSessions)
+	app.Get("/room/:roomId/sessions/:sessionId/attendance", handler.SessionAttendance)
+
+	db.Create(&models.User{ID: "owner-user", Email: "owner@ex.com", Name: "Owner", Provider: "local", IsActive: true})
+	room, _ := roomRepo.CreateRoom("owner-user", "standup", true, models.RoomModeStandard, &models.RoomSettings{})
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	_ = roomRepo.RecordAttendanceJoin(room.ID, "owner-user", "", start)
+	_ = roomRepo.RecordAttendanceJoin(room.ID, "guest-1", "=HYPERLINK(\"x\")", start.Add(2*time.Minute))
+	_ = roomRepo.RecordAttendanceLeave(room.ID, "guest-1", start.Add(7*time.Minute))
+	_ = roomRepo.RecordAttendanceJoin(room.ID, "guest-1", "=HYPERLINK(\"x\")", start.Add(10*time.Minute))
+	_ = roomRepo.EndRoomSession(room.ID, start.Add(30*time.Minute))
+	// The next meeting in the same room is a separate session.
+	_ = roomRepo.RecordAttendanceJoin(room.ID, "owner-user", "", start.Add(24*time.Hour))
+
+	status, raw := getBody(t, app, "/room/"+room.ID+"/sessions")
+	if status != 200 {
+		t.Fatalf("expected 200 listing sessions, got %d: %s", status, raw)
+	}
+	var sessions []RoomSessionResponse
+	if err := json.Unmarshal([]byte(raw), &sessions); err != nil {
+		t.Fatalf("decode sessions: %v", err)
+	}
+	if len(sessions) != 2 || sessions[1].Attendees != 2 || sessions[1].DurationSeconds != 1800 || sessions[0].EndedAt != nil {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	first := sessions[1].ID
+
+	status, body := doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/sessions/"+first+"/attendance", nil)
+	if status != 200 {
+		t.Fatalf("expected 200 for attendance, got %d: %v", status, body)
+	}
+	records := body["attendance"].([]interface{})
+	owner := records[0].(map[string]interface{})
+	guest := records[1].(map[string]interface{})
+	if owner["email"] != "owner@ex.com" || owner["guest"] != false || owner["durationSeconds"] != float64(1800) {
+		t.Errorf("unexpected owner record: %v", owner)
+	}
+	if guest["guest"] != true || guest["durationSeconds"] != float64(300+1200) || len(guest["segments"].([]interface{})) != 2 {
+		t.Errorf("unexpected guest record: %v", guest)
+	}
+
+	status, csv := getBody(t, app, "/room/"+room.ID+"/sessions/"+first+"/attendance?format=csv")
+	if status != 200 {
+		t.Fatalf("expected 200 for csv, got %d", status)
+	}
+	lines := strings.Split(strings.TrimSpace(csv), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "identity,display_name,email") {
+		t.Fatalf("unexpected csv:\n%s", csv)
+	}
+	if !strings.Contains(csv, `guest-1,"'=HYPERLINK(""x"")",,true,2026-03-02T09:02:00Z,2026-03-02T09:07:00Z,300`) {
+		t.Errorf("expected an escaped guest row, got:\n%s", csv)
+	}
+
+	current = &auth.Claims{UserID: "member-user", Accesses: []string{"user"}}
+	if status, _ = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/sessions", nil); status != 403 {
+		t.Errorf("expected 403 for a non-owner, got %d", status)
+	}
+}
+
+func TestAttendanceCSV_EscapesUserValues(t *testing.T) {
+	joined := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	out := string(attendanceCSV([]AttendanceRecord{{
+		Identity:    "-id",
+		DisplayName: "+name",
+		Email:       "=cmd@ex.com",
+		Segments:    []AttendanceInterval{{JoinedAt: joined}},
+	}}))
+	if !strings.Contains(out, "'-id,'+name,'=cmd@ex.com,false,") {
+		t.Errorf("expected every user value escaped, got:\n%s", out)
+	}
+}
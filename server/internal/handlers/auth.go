@@ -212,13 +212,23 @@ func (h *AuthHandler) CallbackHandler(c *fiber.Ctx) error {
 
 	log.Debug().Str("provider", provider).Msg("Auth completed successfully")
 
+	// OIDC users are matched by their subject, never by email address, so
+	// an account at the identity provider can't take over another one.
+	userRepo := repository.NewUserRepository(database.GetDB())
+	isOIDC := auth.IsOIDCProvider(gothUser.Provider)
+
 	// Check registration settings — block new account creation if disabled,
 	// but allow existing users to log in via OAuth.
 	if h.settingsRepo != nil {
 		settings, _ := h.settingsRepo.GetSettings()
 		if settings != nil && !settings.RegistrationEnabled {
 			// Check if user already exists — if so, allow login
-			existing, _ := h.authService.GetUserByEmail(gothUser.Email)
+			var existing *models.User
+			if isOIDC {
+				existing, _ = userRepo.GetUserByID(gothUser.UserID)
+			} else {
+				existing, _ = h.authService.GetUserByEmail(gothUser.Email)
+			}
 			if existing == nil {
 				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 					"error": "Registration is currently disabled",
@@ -228,48 +238,38 @@ func (h *AuthHandler) CallbackHandler(c *fiber.Ctx) error {
 	}
 
 	// Create or update user in database
-	userRepo := repository.NewUserRepository(database.GetDB())
 	dbUser := &models.User{
-		ID:        gothUser.UserID,
-		Email:     gothUser.Email,
-		Name:      gothUser.Name,
-		Provider:  gothUser.Provider,
-		AvatarURL: gothUser.AvatarURL,
-		Accesses:  []string{string(models.AccessUser)}, // Add default access
+		ID:            gothUser.UserID,
+		Email:         gothUser.Email,
+		Name:          gothUser.Name,
+		Provider:      gothUser.Provider,
+		AvatarURL:     gothUser.AvatarURL,
+		Accesses:      auth.OAuthAccesses(gothUser), // Default access, or the user's mapped OIDC groups
+		EmailVerified: auth.OAuthEmailVerified(gothUser),
 	}
 
-	if err := userRepo.CreateOrUpdateUser(dbUser); err != nil {
+	saveUser := userRepo.CreateOrUpdateUser
+	if isOIDC {
+		saveUser = userRepo.CreateOrUpdateUserByID
+	}
+	if err := saveUser(dbUser); err != nil {
 		log.Error().Err(err).Msg("Failed to create/update user")
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
 			Error: "Failed to process user data",
 		})
 	}
 
-	// Generate token pair (access + refresh)
-	accessToken, refreshToken, err := auth.GenerateTokenPair(
-		dbUser.ID,
-		dbUser.Email,
-		dbUser.Name,
-		dbUser.Accesses,
-		h.config,
-	)
+	// Start a session for this device
+	login, err := h.authService.StartSession(dbUser, sessionInfo(c))
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to generate JWT token pair")
+		log.Error().Err(err).Msg("Failed to start session for OAuth user")
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
 			Error: "Failed to generate authentication token",
 		})
 	}
 
-	// Store refresh token in database
-	if err := h.authService.UpdateRefreshToken(dbUser.ID, refreshToken); err != nil {
-		log.Error().Err(err).Msg("Failed to save refresh token for OAuth user")
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
-			Error: "Failed to save refresh token",
-		})
-	}
-
 	// Set both access and refresh token cookies
-	setAuthCookies(c, h.config, accessToken, refreshToken)
+	setAuthCookies(c, h.config, login.Token.AccessToken, login.Token.RefreshToken)
 
 	// frontend url debug print
 	log.Debug().Str("frontend url", h.config.Auth.FrontendURL).Msg("frontend url")
@@ -298,6 +298,6 @@ func (h *AuthHandler) CallbackHandler(c *fiber.Ctx) error {
 			Provider:  dbUser.Provider,
 			AvatarURL: dbUser.AvatarURL,
 		},
-		Token: accessToken,
+		Token: login.Token.AccessToken,
 	})
 }
@@ -5,6 +5,7 @@ import (
 	"bedrud/internal/auth"
 	"bedrud/internal/repository"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/mail"
@@ -60,7 +61,7 @@ func setAuthCookies(c *fiber.Ctx, cfg *config.Config, accessToken, refreshToken
 	c.Cookie(&fiber.Cookie{
 		Name:     "refresh_token",
 		Value:    refreshToken,
-		MaxAge:   7 * 24 * 3600, // 7 days, matches GenerateTokenPair
+		MaxAge:   int(auth.RefreshTokenDuration.Seconds()),
 		HTTPOnly: true,
 		Secure:   secure,
 		SameSite: sameSite,
@@ -69,6 +70,23 @@ func setAuthCookies(c *fiber.Ctx, cfg *config.Config, accessToken, refreshToken
 	})
 }
 
+// maxDeviceNameLength matches the size of Session.DeviceName.
+const maxDeviceNameLength = 100
+
+// sessionInfo describes the device making the request. Apps can name the
+// device with the X-Device-Name header; browsers are told apart by user agent.
+func sessionInfo(c *fiber.Ctx) auth.SessionInfo {
+	name := []rune(strings.TrimSpace(c.Get("X-Device-Name")))
+	if len(name) > maxDeviceNameLength {
+		name = name[:maxDeviceNameLength]
+	}
+	return auth.SessionInfo{
+		DeviceName: string(name),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+		IP:         c.IP(),
+	}
+}
+
 // clearAuthCookies removes both auth cookies (used on logout).
 func clearAuthCookies(c *fiber.Ctx, cfg *config.Config) {
 	secure := cfg.Server.EnableTLS || cfg.Server.BehindProxy
@@ -127,6 +145,10 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
+	if rejectBlocked(c, "", input.Email) {
+		return nil
+	}
+
 	// Check registration settings
 	if h.settingsRepo != nil {
 		settings, _ := h.settingsRepo.GetSettings()
@@ -172,23 +194,14 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
-	accessToken, refreshToken, err := auth.GenerateTokenPair(
-		user.ID,
-		user.Email,
-		user.Name,
-		user.Accesses, // Add accesses
-		h.config,
-	)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate tokens",
-		})
+	if err := h.authService.SendVerificationEmail(user, h.emailBaseURL(c)); err != nil {
+		log.Error().Err(err).Str("userId", user.ID).Msg("Failed to send verification email")
 	}
 
-	err = h.authService.UpdateRefreshToken(user.ID, refreshToken)
+	login, err := h.authService.StartSession(user, sessionInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to save refresh token",
+			"error": "Failed to start session",
 		})
 	}
 
@@ -202,14 +215,8 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		}
 	}
 
-	setAuthCookies(c, h.config, accessToken, refreshToken)
-	return c.JSON(auth.LoginResponse{
-		User: user,
-		Token: auth.TokenPair{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-		},
-	})
+	setAuthCookies(c, h.config, login.Token.AccessToken, login.Token.RefreshToken)
+	return c.JSON(login)
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -236,7 +243,12 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	loginResponse, err := h.authService.Login(input.Email, input.Password)
+	loginResponse, err := h.authService.Login(input.Email, input.Password, sessionInfo(c))
+	var mfaErr *auth.MFARequiredError
+	if errors.As(err, &mfaErr) {
+		// No cookies yet: the client finishes with POST /auth/mfa/verify.
+		return c.JSON(fiber.Map{"mfaRequired": true, "mfaToken": mfaErr.Token})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid credentials",
@@ -264,7 +276,11 @@ func (h *AuthHandler) GuestLogin(c *fiber.Ctx) error {
 		})
 	}
 
-	loginResponse, err := h.authService.GuestLogin(input.Name)
+	if rejectBlocked(c, "", "") {
+		return nil
+	}
+
+	loginResponse, err := h.authService.GuestLogin(input.Name, sessionInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create guest user",
@@ -306,39 +322,14 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validate the refresh token
-	claims, err := h.authService.ValidateRefreshToken(input.RefreshToken)
+	// Rotate the refresh token. Replaying an old one revokes the session.
+	tokens, err := h.authService.RefreshSession(input.RefreshToken, sessionInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid or expired refresh token",
 		})
 	}
-
-	// Block the old refresh token to prevent replay
-	if err := h.authService.BlockRefreshToken(claims.UserID, input.RefreshToken); err != nil {
-		log.Error().Err(err).Msg("Failed to block old refresh token during rotation")
-	}
-
-	// Generate new token pair
-	accessToken, refreshToken, err := auth.GenerateTokenPair(
-		claims.UserID,
-		claims.Email,
-		claims.Name,
-		claims.Accesses,
-		h.config,
-	)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate tokens",
-		})
-	}
-
-	// Update refresh token in database
-	if err := h.authService.UpdateRefreshToken(claims.UserID, refreshToken); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update refresh token",
-		})
-	}
+	accessToken, refreshToken := tokens.AccessToken, tokens.RefreshToken
 
 	setAuthCookies(c, h.config, accessToken, refreshToken)
 	return c.JSON(fiber.Map{
@@ -432,7 +423,12 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 			log.Error().Err(err).Msg("Failed to invalidate tokens on logout")
 		}
 	} else if accessToken != "" {
-		// No refresh token provided — at least revoke the access token
+		// No refresh token provided — end the session the access token belongs to
+		if claims.SessionID != "" {
+			if _, err := h.authService.SignOutSession(claims.UserID, claims.SessionID); err != nil {
+				log.Error().Err(err).Msg("Failed to end session on logout")
+			}
+		}
 		auth.RevokeAccessToken(accessToken, h.config)
 	}
 
@@ -625,7 +621,7 @@ func (h *AuthHandler) PasskeyLoginFinish(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid signature encoding"})
 	}
 
-	loginResponse, err := h.authService.FinishLoginPasskey(challenge, credID, clientData, authData, sig, h.getRPID(c), h.getOrigin(c))
+	loginResponse, err := h.authService.FinishLoginPasskey(challenge, credID, clientData, authData, sig, h.getRPID(c), h.getOrigin(c), sessionInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -746,7 +742,7 @@ func (h *AuthHandler) PasskeySignupFinish(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid attestationObject encoding"})
 	}
 
-	loginResponse, err := h.authService.FinishSignupPasskey(userID, email, name, challenge, clientData, attestation, h.getRPID(c), h.getOrigin(c))
+	loginResponse, err := h.authService.FinishSignupPasskey(userID, email, name, challenge, clientData, attestation, h.getRPID(c), h.getOrigin(c), sessionInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
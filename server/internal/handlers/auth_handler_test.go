@@ -363,7 +363,7 @@ func TestAuthHandler_RefreshToken_Success(t *testing.T) {
 	app, authService, cfg := setupAuthTestApp(t)
 
 	_, _ = authService.Register("refresh@example.com", "pass", "Refresh User")
-	loginResp, _ := authService.Login("refresh@example.com", "pass")
+	loginResp, _ := authService.Login("refresh@example.com", "pass", auth.SessionInfo{})
 
 	authHandler := NewAuthHandler(authService, cfg, nil, nil)
 	app.Post("/api/auth/refresh", authHandler.RefreshToken)
@@ -503,7 +503,7 @@ func TestAuthHandler_Logout_Success(t *testing.T) {
 	app, authService, cfg := setupAuthTestAppFull(t)
 
 	user, _ := authService.Register("logoutha@example.com", "pass", "Logout User")
-	loginResp, _ := authService.Login("logoutha@example.com", "pass")
+	loginResp, _ := authService.Login("logoutha@example.com", "pass", auth.SessionInfo{})
 	token, _ := auth.GenerateToken(user.ID, user.Email, user.Name, "local", user.Accesses, cfg)
 
 	body, _ := json.Marshal(map[string]string{"refresh_token": loginResp.Token.RefreshToken})
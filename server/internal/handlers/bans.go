@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	banMaxReason       = 500
+	banMaxMinutes      = 365 * 24 * 60
+	guestKeyCookie     = "bedrud_guest"
+	guestKeyCookieDays = 365
+)
+
+// BanParticipantRequest is the optional body for POST
+// /room/:roomId/ban/:identity. Without a duration the ban is permanent.
+type BanParticipantRequest struct {
+	Reason          string `json:"reason"`
+	DurationMinutes int    `json:"durationMinutes"`
+}
+
+// BanParticipant removes a participant from the room and keeps them out until
+// the ban is lifted or expires. Guests are also banned by their guest key, so
+// rejoining under another name doesn't get around it.
+func (h *RoomHandler) BanParticipant(c *fiber.Ctx) error {
+	identity := c.Params("identity")
+	claims := c.Locals("user").(*auth.Claims)
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+
+	var req BanParticipantRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) > banMaxReason {
+		return c.Status(400).JSON(fiber.Map{"error": "Reason is too long"})
+	}
+	if req.DurationMinutes < 0 || req.DurationMinutes > banMaxMinutes {
+		return c.Status(400).JSON(fiber.Map{"error": "durationMinutes must be between 0 and one year"})
+	}
+
+	// Store the ban before removing the participant, so they can't rejoin in
+	// between.
+	ban := &models.RoomBan{RoomID: room.ID, Identity: identity, Reason: req.Reason, BannedBy: claims.UserID}
+	if p, err := h.roomRepo.GetParticipant(room.ID, identity); err == nil && p != nil {
+		ban.GuestKey = p.GuestKey
+		ban.DisplayName = p.DisplayName
+	}
+	if req.DurationMinutes > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		ban.ExpiresAt = &expiresAt
+	}
+	if err := h.roomRepo.BanParticipant(ban); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Str("identity", identity).Msg("Failed to store room ban")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to ban participant"})
+	}
+
+	// Participants who already left can be banned too.
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	if _, err := h.lk(ctx).Rooms.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity}); err != nil && !isLiveKitNotFound(err) {
+		log.Error().Err(err).Str("roomID", room.ID).Str("identity", identity).Msg("Failed to remove banned participant")
+		return c.Status(500).JSON(fiber.Map{"error": "Participant was banned but could not be removed from the call"})
+	}
+	h.sendSystemMessage(ctx, room.Name, "ban", claims.UserID, identity)
+	h.revokeE2EEKey(ctx, room, identity, claims.UserID)
+	return c.JSON(fiber.Map{"status": "success", "ban": ban})
+}
+
+// ListBans returns the bans in force for the room.
+func (h *RoomHandler) ListBans(c *fiber.Ctx) error {
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	bans, err := h.roomRepo.GetActiveRoomBans(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list room bans")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list bans"})
+	}
+	if bans == nil {
+		bans = []models.RoomBan{}
+	}
+	return c.JSON(bans)
+}
+
+// LiftBan unbans a participant before their ban expires.
+func (h *RoomHandler) LiftBan(c *fiber.Ctx) error {
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	ban, err := h.roomRepo.GetRoomBan(c.Params("banId"))
+	if err != nil {
+		log.Error().Err(err).Str("banID", c.Params("banId")).Msg("Failed to look up room ban")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up ban"})
+	}
+	if ban == nil || ban.RoomID != room.ID {
+		return c.Status(404).JSON(fiber.Map{"error": "Ban not found"})
+	}
+	if err := h.roomRepo.LiftRoomBan(ban); err != nil {
+		log.Error().Err(err).Str("banID", ban.ID).Msg("Failed to lift room ban")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to lift ban"})
+	}
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// guestKey returns the caller's stable guest key from its signed cookie,
+// issuing a new cookie when it is missing or doesn't verify.
+func (h *RoomHandler) guestKey(c *fiber.Ctx) string {
+	if key, ok := h.verifiedGuestKey(c); ok {
+		return key
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	key := hex.EncodeToString(b)
+	c.Cookie(&fiber.Cookie{
+		Name:     guestKeyCookie,
+		Value:    key + "." + h.signGuestKey(key),
+		MaxAge:   guestKeyCookieDays * 24 * 3600,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: "Lax",
+		Path:     "/",
+	})
+	return key
+}
+
+// verifiedGuestKey returns the guest key from the caller's cookie if its
+// signature checks out.
+func (h *RoomHandler) verifiedGuestKey(c *fiber.Ctx) (string, bool) {
+	key, sig, ok := strings.Cut(c.Cookies(guestKeyCookie), ".")
+	if !ok || key == "" || !hmac.Equal([]byte(sig), []byte(h.signGuestKey(key))) {
+		return "", false
+	}
+	return key, true
+}
+
+// SetGuestKeySecret sets the server secret guest key cookies are signed
+// with. It is kept apart from the LiveKit API secrets, which external
+// LiveKit deployments also hold.
+func (h *RoomHandler) SetGuestKeySecret(secret string) {
+	if secret != "" {
+		h.guestKeySecret = []byte(secret)
+	}
+}
+
+func (h *RoomHandler) signGuestKey(key string) string {
+	mac := hmac.New(sha256.New, h.guestKeySecret)
+	mac.Write([]byte("guest-key:" + key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/twitchtv/twirp"
+)
+
+func setupBanTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *fakeRoomService, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "town-hall",
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Post("/room/:roomId/ban/:identity", h.BanParticipant)
+			app.Get("/room/:roomId/bans", h.ListBans)
+			app.Delete("/room/:roomId/bans/:banId", h.LiftBan)
+		},
+	})
+	fake := &fakeRoomService{}
+	f.handler.nodes.Default().Rooms = fake
+	return f.app, f.repo, fake, f.room, &f.current
+}
+
+// guestJoin joins as a guest presenting cookie, and returns the status, the
+// guest identity and the guest cookie the server set, if any.
+func guestJoin(t *testing.T, app *fiber.App, roomName, guestName string, cookie *http.Cookie) (int, string, *http.Cookie) {
+	t.Helper()
+	b, _ := json.Marshal(map[string]string{"roomName": roomName, "guestName": guestName})
+	req := httptest.NewRequest(http.MethodPost, "/room/guest-join", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	for _, c := range resp.Cookies() {
+		if c.Name == guestKeyCookie {
+			cookie = c
+		}
+	}
+	var body map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	token, _ := body["token"].(string)
+	if token == "" {
+		return resp.StatusCode, "", cookie
+	}
+	v, err := lkauth.ParseAPIToken(token)
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	return resp.StatusCode, v.Identity(), cookie
+}
+
+func TestBans_GuestBannedByCookieUntilLifted(t *testing.T) {
+	app, roomRepo, _, room, _ := setupBanTestApp(t)
+
+	status, identity, cookie := guestJoin(t, app, room.Name, "Heckler", nil)
+	if status != http.StatusOK || cookie == nil {
+		t.Fatalf("expected 200 with guest cookie, got %d (cookie %v)", status, cookie)
+	}
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ban/"+identity, map[string]string{"reason": "spam"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 banning, got %d (%v)", status, body)
+	}
+
+	// A new name doesn't get around the ban; a forged cookie just gets a new key.
+	if status, _, _ = guestJoin(t, app, room.Name, "Totally New", cookie); status != http.StatusForbidden {
+		t.Fatalf("expected 403 for banned guest, got %d", status)
+	}
+	forged := &http.Cookie{Name: guestKeyCookie, Value: "abc.def"}
+	if status, _, _ = guestJoin(t, app, room.Name, "Someone", forged); status != http.StatusOK {
+		t.Fatalf("expected 200 for unrelated guest, got %d", status)
+	}
+
+	bans, _ := roomRepo.GetActiveRoomBans(room.ID)
+	if len(bans) != 1 || bans[0].Reason != "spam" || bans[0].DisplayName != "Heckler" || bans[0].ExpiresAt != nil {
+		t.Fatalf("expected one permanent ban, got %+v", bans)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodDelete, "/room/"+room.ID+"/bans/"+bans[0].ID, nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 lifting ban, got %d", status)
+	}
+	if status, _, _ = guestJoin(t, app, room.Name, "Heckler", cookie); status != http.StatusOK {
+		t.Fatalf("expected 200 after unban, got %d", status)
+	}
+}
+
+func TestBans_GuestKeyNotSignedWithLiveKitSecret(t *testing.T) {
+	f := newRoomFixture(t, roomFixtureOptions{name: "town-hall"})
+	f.handler.SetGuestKeySecret("server-secret")
+
+	lk := hmac.New(sha256.New, []byte(f.handler.nodes.Default().APISecret))
+	lk.Write([]byte("guest-key:abc"))
+	if hmac.Equal([]byte(f.handler.signGuestKey("abc")), []byte(hex.EncodeToString(lk.Sum(nil)))) {
+		t.Fatal("expected guest keys not to be signed with the LiveKit API secret")
+	}
+
+	other := newRoomFixture(t, roomFixtureOptions{name: "town-hall"})
+	other.handler.SetGuestKeySecret("server-secret")
+	if f.handler.signGuestKey("abc") != other.handler.signGuestKey("abc") {
+		t.Fatal("expected guest keys signed with the same server secret to match")
+	}
+}
+
+func TestBans_TimedUserBanExpires(t *testing.T) {
+	app, roomRepo, _, room, current := setupBanTestApp(t)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ban/member-user", map[string]interface{}{"durationMinutes": 10})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 banning, got %d (%v)", status, body)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/bans", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 listing bans, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	if status, _ = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name}); status != http.StatusForbidden {
+		t.Fatalf("expected 403 while banned, got %d", status)
+	}
+	if status, _ = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/bans", nil); status != http.StatusForbidden {
+		t.Fatalf("expected 403 listing bans as non-owner, got %d", status)
+	}
+
+	if n, err := roomRepo.LiftExpiredRoomBans(time.Now().Add(11 * time.Minute)); err != nil || n != 1 {
+		t.Fatalf("expected 1 expired ban lifted, got %d (%v)", n, err)
+	}
+	if status, _ = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name}); status != http.StatusOK {
+		t.Fatalf("expected 200 once the ban expired, got %d", status)
+	}
+}
+
+func TestBans_ParticipantAlreadyGone(t *testing.T) {
+	app, roomRepo, fake, room, current := setupBanTestApp(t)
+	fake.removeErr = twirp.NotFoundError("participant not found")
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ban/member-user", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 banning a disconnected participant, got %d (%v)", status, body)
+	}
+	if bans, _ := roomRepo.GetActiveRoomBans(room.ID); len(bans) != 1 {
+		t.Fatalf("expected the ban stored, got %d", len(bans))
+	}
+
+	// Other LiveKit failures are reported, but the ban still holds.
+	fake.removeErr = twirp.InternalError("unavailable")
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ban/other-user", nil)
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected 500 when removal fails, got %d", status)
+	}
+	*current = &auth.Claims{UserID: "other-user", Name: "Other", Accesses: []string{"user"}}
+	if status, _ = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name}); status != http.StatusForbidden {
+		t.Fatalf("expected 403 for the banned user, got %d", status)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/blocklist"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const blocklistMaxHours = 10 * 365 * 24
+
+// BlocklistHandler manages the site-wide blocklist.
+type BlocklistHandler struct {
+	repo *repository.BlocklistRepository
+}
+
+func NewBlocklistHandler(repo *repository.BlocklistRepository) *BlocklistHandler {
+	return &BlocklistHandler{repo: repo}
+}
+
+// AddBlocklistEntryRequest is the body for POST /admin/blocklist.
+type AddBlocklistEntryRequest struct {
+	// Type is "user", "email", "domain" or "ip"; guessed from Value when empty.
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+	// ExpiresInHours of 0 blocks until the entry is removed.
+	ExpiresInHours int `json:"expiresInHours"`
+}
+
+// ListEntries returns the blocklist entries in force.
+func (h *BlocklistHandler) ListEntries(c *fiber.Ctx) error {
+	entries, err := h.repo.GetActiveEntries()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list blocklist")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list blocklist"})
+	}
+	if entries == nil {
+		entries = []models.BlocklistEntry{}
+	}
+	return c.JSON(entries)
+}
+
+// AddEntry blocks a user, email, email domain or IP range site-wide.
+func (h *BlocklistHandler) AddEntry(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	var req AddBlocklistEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	typ, value, err := blocklist.Normalize(req.Type, req.Value)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > blocklistMaxHours {
+		return c.Status(400).JSON(fiber.Map{"error": "expiresInHours is out of range"})
+	}
+
+	entry := &models.BlocklistEntry{Type: typ, Value: value, Reason: strings.TrimSpace(req.Reason), CreatedBy: claims.UserID}
+	if req.ExpiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		entry.ExpiresAt = &expiresAt
+	}
+	if err := h.repo.CreateEntry(entry); err != nil {
+		if errors.Is(err, repository.ErrBlocklistEntryExists) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error().Err(err).Msg("Failed to add blocklist entry")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to add blocklist entry"})
+	}
+	if err := blocklist.Reload(); err != nil {
+		log.Error().Err(err).Msg("Failed to reload blocklist")
+	}
+	log.Info().Str("type", typ).Str("value", value).Str("by", claims.UserID).Msg("Blocklist entry added")
+	return c.Status(201).JSON(entry)
+}
+
+// DeleteEntry removes an entry from the blocklist.
+func (h *BlocklistHandler) DeleteEntry(c *fiber.Ctx) error {
+	found, err := h.repo.DeleteEntry(c.Params("id"))
+	if err != nil {
+		log.Error().Err(err).Str("id", c.Params("id")).Msg("Failed to delete blocklist entry")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete blocklist entry"})
+	}
+	if !found {
+		return c.Status(404).JSON(fiber.Map{"error": "Blocklist entry not found"})
+	}
+	if err := blocklist.Reload(); err != nil {
+		log.Error().Err(err).Msg("Failed to reload blocklist")
+	}
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// rejectBlocked answers 403 when the blocklist matches the user, email or the
+// request's client IP. Reports whether the request was rejected.
+func rejectBlocked(c *fiber.Ctx, userID, email string) bool {
+	if blocklist.Match(userID, email, c.IP()) == nil {
+		return false
+	}
+	_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Access denied"})
+	return true
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/blocklist"
+	"bedrud/internal/repository"
+	"bedrud/internal/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupBlocklistTestApp(t *testing.T) (*fiber.App, *repository.BlocklistRepository) {
+	t.Helper()
+	repo := repository.NewBlocklistRepository(testutil.SetupTestDB(t))
+	if err := blocklist.Init(repo); err != nil {
+		t.Fatalf("init blocklist: %v", err)
+	}
+	t.Cleanup(func() { _ = blocklist.Init(nil) })
+	handler := NewBlocklistHandler(repo)
+
+	app, _, _ := setupAuthTestApp(t)
+	admin := app.Group("/admin", func(c *fiber.Ctx) error {
+		c.Locals("user", &auth.Claims{UserID: "admin-user-id", Accesses: []string{"superadmin"}})
+		return c.Next()
+	})
+	admin.Get("/blocklist", handler.ListEntries)
+	admin.Post("/blocklist", handler.AddEntry)
+	admin.Delete("/blocklist/:id", handler.DeleteEntry)
+	return app, repo
+}
+
+func TestBlocklist_RegisterAndGuestLoginBlocked(t *testing.T) {
+	app, repo := setupBlocklistTestApp(t)
+
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/admin/blocklist", map[string]interface{}{"value": "@spam.example", "reason": "bot signups"})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201 adding domain, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/admin/blocklist", map[string]interface{}{"type": "domain", "value": "spam.example"})
+	if status != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate entry, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/admin/blocklist", map[string]interface{}{"type": "ip", "value": "999.1.1.1"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid IP, got %d", status)
+	}
+
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/api/auth/register", map[string]string{"email": "bot@spam.example", "password": "password123", "name": "Bot"})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 registering from blocked domain, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/api/auth/guest-login", map[string]string{"name": "Guest"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 for guest login, got %d", status)
+	}
+
+	// app.Test requests come from 0.0.0.0.
+	status, ipEntry := doJSONRequest(t, app, http.MethodPost, "/admin/blocklist", map[string]interface{}{"value": "0.0.0.0/8", "expiresInHours": 24})
+	if status != http.StatusCreated || ipEntry["expiresAt"] == nil {
+		t.Fatalf("expected 201 with expiry adding IP range, got %d (%v)", status, ipEntry)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/api/auth/guest-login", map[string]string{"name": "Guest"})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for guest login from blocked IP, got %d", status)
+	}
+
+	status, _ = doJSONRequest(t, app, http.MethodDelete, "/admin/blocklist/"+ipEntry["id"].(string), nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 removing entry, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/api/auth/guest-login", map[string]string{"name": "Guest"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 once unblocked, got %d", status)
+	}
+	if entries, _ := repo.GetActiveEntries(); len(entries) != 1 {
+		t.Fatalf("expected 1 remaining entry, got %d", len(entries))
+	}
+}
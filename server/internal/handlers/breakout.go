@@ -0,0 +1,477 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	breakoutMaxRooms         = 50
+	breakoutDefaultCountdown = 30
+	breakoutMaxCountdown     = 300
+)
+
+// breakoutReturnGrace is how long closed breakout rooms stay up in LiveKit
+// after everyone was sent back, so the move messages reach their clients.
+var breakoutReturnGrace = 5 * time.Second
+
+// CreateBreakoutsRequest is the body for POST /room/:roomId/breakouts.
+type CreateBreakoutsRequest struct {
+	Count int `json:"count"`
+	// Assign is "manual" (default) or "random".
+	Assign string `json:"assign"`
+	// Assignments maps participant identities to a breakout number (1..count)
+	// for manual assignment.
+	Assignments map[string]int `json:"assignments"`
+}
+
+// MoveParticipantRequest is the body for POST /room/:roomId/breakouts/move.
+// TargetRoomID is the main room or one of its breakout rooms.
+type MoveParticipantRequest struct {
+	Identity     string `json:"identity"`
+	TargetRoomID string `json:"targetRoomId"`
+}
+
+// CloseBreakoutsRequest is the body for POST /room/:roomId/breakouts/close.
+type CloseBreakoutsRequest struct {
+	CountdownSeconds *int `json:"countdownSeconds"`
+}
+
+// BreakoutParticipant is a participant currently connected to a breakout room.
+type BreakoutParticipant struct {
+	Identity string `json:"identity"`
+	Name     string `json:"name"`
+}
+
+// BreakoutRoom is a breakout room together with who is in it right now.
+type BreakoutRoom struct {
+	models.Room
+	Participants []BreakoutParticipant `json:"participants"`
+}
+
+// liveParticipant is a participant and the room of the family it is connected to.
+type liveParticipant struct {
+	room *models.Room
+	info *livekit.ParticipantInfo
+}
+
+// CreateBreakouts opens count breakout rooms under the main room and
+// optionally moves participants into them.
+func (h *RoomHandler) CreateBreakouts(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	parent, adminId, ok := h.resolveMainRoom(c)
+	if !ok {
+		return nil
+	}
+
+	var req CreateBreakoutsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Count < 1 || req.Count > breakoutMaxRooms {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("count must be between 1 and %d", breakoutMaxRooms)})
+	}
+	req.Assign = strings.ToLower(req.Assign)
+	switch req.Assign {
+	case "", "manual":
+		for identity, n := range req.Assignments {
+			if n < 1 || n > req.Count {
+				return c.Status(400).JSON(fiber.Map{"error": "Invalid breakout number for " + identity})
+			}
+		}
+	case "random":
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "assign must be manual or random"})
+	}
+
+	existing, err := h.roomRepo.GetBreakoutRooms(parent.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", parent.ID).Msg("Failed to list breakout rooms")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list breakout rooms"})
+	}
+	if len(existing) > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "Breakout rooms are already open"})
+	}
+
+	names := make([]string, req.Count)
+	for i := range names {
+		names[i] = breakoutRoomName(parent.Name, i+1)
+		if taken, err := h.roomRepo.GetRoomByName(names[i]); err != nil || taken != nil {
+			return c.Status(409).JSON(fiber.Map{"error": "Room name " + names[i] + " is already taken"})
+		}
+	}
+
+	// Breakout rooms live on the same node as their main room.
+	ctx := h.withAuth(c.Context(), parent, &lkauth.VideoGrant{RoomCreate: true})
+	for _, name := range names {
+		if _, err := h.lk(ctx).Rooms.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: name, MaxParticipants: uint32(parent.MaxParticipants)}); err != nil {
+			log.Error().Err(err).Str("room", name).Msg("LiveKit CreateRoom failed")
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to create media room"})
+		}
+	}
+	rooms, err := h.roomRepo.CreateBreakoutRooms(parent, names)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", parent.ID).Msg("Failed to create breakout rooms")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to create breakout rooms"})
+	}
+
+	live := h.liveParticipants(c.Context(), []*models.Room{parent})
+	plan := map[string]*models.Room{}
+	if req.Assign == "random" {
+		var identities []string
+		for identity := range live {
+			if identity == claims.UserID || strings.HasPrefix(identity, models.IngressIdentityPrefix) ||
+				h.isPublishExempt(parent, adminId, identity, nil) {
+				continue
+			}
+			identities = append(identities, identity)
+		}
+		sort.Strings(identities)
+		rand.Shuffle(len(identities), func(i, j int) { identities[i], identities[j] = identities[j], identities[i] })
+		for i, identity := range identities {
+			plan[identity] = &rooms[i%len(rooms)]
+		}
+	} else {
+		for identity, n := range req.Assignments {
+			plan[identity] = &rooms[n-1]
+		}
+	}
+
+	assigned := map[string]string{}
+	for identity, target := range plan {
+		lp, ok := live[identity]
+		if !ok {
+			log.Warn().Str("room", parent.Name).Str("identity", identity).Msg("Breakout assignee is not in the room")
+			continue
+		}
+		if err := h.moveParticipant(c.Context(), lp, target, claims.UserID); err != nil {
+			log.Error().Err(err).Str("identity", identity).Str("target", target.Name).Msg("Failed to move participant")
+			continue
+		}
+		assigned[identity] = target.ID
+	}
+
+	log.Info().Str("room", parent.Name).Int("count", len(rooms)).Str("by", claims.UserID).Msg("Breakout rooms opened")
+	return c.Status(201).JSON(fiber.Map{"rooms": rooms, "assignments": assigned})
+}
+
+// ListBreakouts returns the main room's breakout rooms and who is in each.
+func (h *RoomHandler) ListBreakouts(c *fiber.Ctx) error {
+	parent, _, ok := h.resolveMainRoom(c)
+	if !ok {
+		return nil
+	}
+	rooms, err := h.roomRepo.GetBreakoutRooms(parent.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", parent.ID).Msg("Failed to list breakout rooms")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list breakout rooms"})
+	}
+
+	out := make([]BreakoutRoom, 0, len(rooms))
+	for i := range rooms {
+		br := BreakoutRoom{Room: rooms[i], Participants: []BreakoutParticipant{}}
+		for _, lp := range h.liveParticipants(c.Context(), []*models.Room{&rooms[i]}) {
+			br.Participants = append(br.Participants, BreakoutParticipant{Identity: lp.info.Identity, Name: lp.info.Name})
+		}
+		sort.Slice(br.Participants, func(a, b int) bool { return br.Participants[a].Identity < br.Participants[b].Identity })
+		out = append(out, br)
+	}
+	return c.JSON(out)
+}
+
+// MoveBreakoutParticipant moves a participant between the main room and its
+// breakout rooms by sending them a join token for the target room.
+func (h *RoomHandler) MoveBreakoutParticipant(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	parent, _, ok := h.resolveMainRoom(c)
+	if !ok {
+		return nil
+	}
+
+	var req MoveParticipantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Identity == "" || req.TargetRoomID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "identity and targetRoomId are required"})
+	}
+
+	family, err := h.roomFamily(parent)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", parent.ID).Msg("Failed to list breakout rooms")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list breakout rooms"})
+	}
+	var target *models.Room
+	for _, r := range family {
+		if r.ID == req.TargetRoomID {
+			target = r
+		}
+	}
+	if target == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Target room not found"})
+	}
+
+	lp, ok := h.liveParticipants(c.Context(), family)[req.Identity]
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "Participant is not in the meeting"})
+	}
+	if err := h.moveParticipant(c.Context(), lp, target, claims.UserID); err != nil {
+		log.Error().Err(err).Str("identity", req.Identity).Str("target", target.Name).Msg("Failed to move participant")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to move participant"})
+	}
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// CloseBreakouts announces a countdown in every breakout room, then sends
+// everyone back to the main room and deletes the breakouts.
+func (h *RoomHandler) CloseBreakouts(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	parent, _, ok := h.resolveMainRoom(c)
+	if !ok {
+		return nil
+	}
+
+	var req CloseBreakoutsRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	countdown := breakoutDefaultCountdown
+	if req.CountdownSeconds != nil {
+		countdown = *req.CountdownSeconds
+	}
+	if countdown < 0 || countdown > breakoutMaxCountdown {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("countdownSeconds must be between 0 and %d", breakoutMaxCountdown)})
+	}
+
+	rooms, err := h.roomRepo.GetBreakoutRooms(parent.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", parent.ID).Msg("Failed to list breakout rooms")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list breakout rooms"})
+	}
+	if len(rooms) == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "No breakout rooms are open"})
+	}
+
+	for i := range rooms {
+		ctx := h.withAuth(c.Context(), &rooms[i], &lkauth.VideoGrant{RoomAdmin: true, Room: rooms[i].Name})
+		h.sendBreakoutMessage(ctx, rooms[i].Name, breakoutMessage{Event: "breakout_closing", Actor: claims.UserID, Seconds: countdown}, "")
+	}
+
+	closeAll := func() { h.returnFromBreakouts(context.Background(), parent, rooms, claims.UserID) }
+	if countdown == 0 {
+		closeAll()
+	} else {
+		time.AfterFunc(time.Duration(countdown)*time.Second, closeAll)
+	}
+
+	log.Info().Str("room", parent.Name).Int("countdown", countdown).Str("by", claims.UserID).Msg("Closing breakout rooms")
+	return c.JSON(fiber.Map{"status": "success", "countdownSeconds": countdown})
+}
+
+// returnFromBreakouts moves everyone in the breakout rooms back to the main
+// room and deletes the breakouts.
+func (h *RoomHandler) returnFromBreakouts(ctx context.Context, parent *models.Room, rooms []models.Room, actor string) {
+	ptrs := make([]*models.Room, len(rooms))
+	for i := range rooms {
+		ptrs[i] = &rooms[i]
+	}
+	for identity, lp := range h.liveParticipants(ctx, ptrs) {
+		if strings.HasPrefix(identity, models.IngressIdentityPrefix) {
+			continue
+		}
+		if err := h.moveParticipant(ctx, lp, parent, actor); err != nil {
+			log.Warn().Err(err).Str("identity", identity).Str("room", parent.Name).Msg("Failed to return participant to main room")
+		}
+	}
+
+	for i := range rooms {
+		if err := h.roomRepo.AdminDeleteRoom(rooms[i].ID); err != nil {
+			log.Error().Err(err).Str("roomID", rooms[i].ID).Msg("Failed to delete breakout room")
+		}
+	}
+	teardown := func() {
+		for i := range rooms {
+			h.deleteLiveKitRoom(ctx, &rooms[i])
+		}
+	}
+	if breakoutReturnGrace == 0 {
+		teardown()
+	} else {
+		time.AfterFunc(breakoutReturnGrace, teardown)
+	}
+}
+
+// deleteLiveKitRoom removes a room and its ingresses from LiveKit.
+func (h *RoomHandler) deleteLiveKitRoom(ctx context.Context, room *models.Room) {
+	lkCtx := h.withAuth(ctx, room, &lkauth.VideoGrant{RoomCreate: true})
+	_, _ = h.lk(lkCtx).Rooms.DeleteRoom(lkCtx, &livekit.DeleteRoomRequest{Room: room.Name})
+	if h.ingressOn {
+		h.deleteRoomIngresses(ctx, room)
+	}
+}
+
+// moveParticipant issues a join token for target and sends it to the
+// participant in the room they are connected to.
+func (h *RoomHandler) moveParticipant(ctx context.Context, lp liveParticipant, target *models.Room, actor string) error {
+	identity := lp.info.Identity
+	if lp.room.ID == target.ID {
+		return nil
+	}
+	adminId := target.AdminID
+	if adminId == "" {
+		adminId = target.CreatedBy
+	}
+
+	var token string
+	var err error
+	if strings.HasPrefix(identity, "guest-") {
+		token, err = h.guestJoinToken(target, identity, lp.info.Name, h.publishGrantFor(target, adminId, identity, nil))
+	} else {
+		user, lookupErr := h.roomRepo.GetUserByID(identity)
+		if lookupErr != nil {
+			return lookupErr
+		}
+		pub := h.publishGrantFor(target, adminId, identity, user.Accesses)
+		token, err = h.userJoinToken(target, identity, lp.info.Name, user.Accesses, pub)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Guests keep their guest key, so they can fetch the target room's E2EE
+	// key and stay covered by bans.
+	if p, err := h.roomRepo.GetParticipant(lp.room.ID, identity); err == nil && p != nil && p.GuestKey != "" {
+		if err := h.roomRepo.RecordGuestKey(target.ID, identity, lp.info.Name, p.GuestKey); err != nil {
+			return err
+		}
+	}
+	// Moved participants skip the target room's lobby.
+	if err := h.roomRepo.AddParticipant(target.ID, identity); err != nil {
+		return err
+	}
+	if err := h.roomRepo.UpdateParticipantStatus(target.ID, identity, map[string]interface{}{"is_approved": true}); err != nil {
+		return err
+	}
+
+	lkCtx := h.withAuth(ctx, lp.room, &lkauth.VideoGrant{RoomAdmin: true, Room: lp.room.Name})
+	h.sendBreakoutMessage(lkCtx, lp.room.Name, breakoutMessage{
+		Event:       "breakout_move",
+		Actor:       actor,
+		Target:      identity,
+		RoomID:      target.ID,
+		RoomName:    target.Name,
+		Token:       token,
+		LivekitHost: h.node(target).Host,
+	}, identity)
+	return nil
+}
+
+// breakoutMessage is a system data message about breakout rooms.
+type breakoutMessage struct {
+	Type        string `json:"type"`
+	Event       string `json:"event"`
+	Actor       string `json:"actor"`
+	Target      string `json:"target,omitempty"`
+	RoomID      string `json:"roomId,omitempty"`
+	RoomName    string `json:"roomName,omitempty"`
+	Token       string `json:"token,omitempty"`
+	LivekitHost string `json:"livekitHost,omitempty"`
+	Seconds     int    `json:"seconds,omitempty"`
+}
+
+// sendBreakoutMessage sends msg on the system topic, to destination only when
+// it is set.
+func (h *RoomHandler) sendBreakoutMessage(ctx context.Context, roomName string, msg breakoutMessage, destination string) {
+	msg.Type = "system"
+	b, _ := json.Marshal(msg)
+	topic := "system"
+	req := &livekit.SendDataRequest{
+		Room:  roomName,
+		Data:  b,
+		Kind:  livekit.DataPacket_RELIABLE,
+		Topic: &topic,
+	}
+	if destination != "" {
+		req.DestinationIdentities = []string{destination}
+	}
+	if _, err := h.lk(ctx).Rooms.SendData(ctx, req); err != nil {
+		log.Warn().Err(err).Str("room", roomName).Str("event", msg.Event).Msg("Failed to send breakout message")
+	}
+}
+
+// liveParticipants returns who is connected to each of rooms, keyed by
+// identity. A participant is attributed to the first room it is found in.
+func (h *RoomHandler) liveParticipants(ctx context.Context, rooms []*models.Room) map[string]liveParticipant {
+	live := map[string]liveParticipant{}
+	for _, r := range rooms {
+		lkCtx := h.withAuth(ctx, r, &lkauth.VideoGrant{RoomAdmin: true, Room: r.Name})
+		res, err := h.lk(lkCtx).Rooms.ListParticipants(lkCtx, &livekit.ListParticipantsRequest{Room: r.Name})
+		if err != nil {
+			log.Warn().Err(err).Str("room", r.Name).Msg("Failed to list participants")
+			continue
+		}
+		for _, p := range res.Participants {
+			if _, seen := live[p.Identity]; !seen {
+				live[p.Identity] = liveParticipant{room: r, info: p}
+			}
+		}
+	}
+	return live
+}
+
+// roomFamily returns the main room followed by its breakout rooms.
+func (h *RoomHandler) roomFamily(parent *models.Room) ([]*models.Room, error) {
+	rooms, err := h.roomRepo.GetBreakoutRooms(parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	family := []*models.Room{parent}
+	for i := range rooms {
+		family = append(family, &rooms[i])
+	}
+	return family, nil
+}
+
+// resolveMainRoom loads the room from the route, checks the caller hosts it
+// and that it is not itself a breakout. Writes the error response itself.
+func (h *RoomHandler) resolveMainRoom(c *fiber.Ctx) (*models.Room, string, bool) {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil, "", false
+	}
+	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
+		_ = c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
+		return nil, "", false
+	}
+	if room.IsBreakout() {
+		_ = c.Status(400).JSON(fiber.Map{"error": "Breakout rooms cannot have breakout rooms"})
+		return nil, "", false
+	}
+	return room, adminId, true
+}
+
+// breakoutRoomName derives the name of the n-th breakout room, shortening the
+// parent name so the result stays within the room name limit.
+func breakoutRoomName(parentName string, n int) string {
+	suffix := fmt.Sprintf("-breakout-%d", n)
+	base := parentName
+	if len(base)+len(suffix) > models.RoomNameMaxLength {
+		base = strings.TrimRight(base[:models.RoomNameMaxLength-len(suffix)], "-")
+	}
+	return base + suffix
+}
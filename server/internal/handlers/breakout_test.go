@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+)
+
+// fakeBreakoutRoomService keeps participants per room and records room
+// creation and deletion.
+type fakeBreakoutRoomService struct {
+	fakeRoomService
+	rooms   map[string][]*livekit.ParticipantInfo
+	created []string
+	deleted []string
+}
+
+func (f *fakeBreakoutRoomService) ListParticipants(_ context.Context, req *livekit.ListParticipantsRequest) (*livekit.ListParticipantsResponse, error) {
+	return &livekit.ListParticipantsResponse{Participants: f.rooms[req.Room]}, nil
+}
+
+func (f *fakeBreakoutRoomService) CreateRoom(_ context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
+	f.created = append(f.created, req.Name)
+	return &livekit.Room{Name: req.Name}, nil
+}
+
+func (f *fakeBreakoutRoomService) DeleteRoom(_ context.Context, req *livekit.DeleteRoomRequest) (*livekit.DeleteRoomResponse, error) {
+	f.deleted = append(f.deleted, req.Room)
+	return &livekit.DeleteRoomResponse{}, nil
+}
+
+// breakoutPacket decodes a system message sent through the fake.
+func breakoutPacket(t *testing.T, req *livekit.SendDataRequest) breakoutMessage {
+	t.Helper()
+	var msg breakoutMessage
+	if err := json.Unmarshal(req.Data, &msg); err != nil {
+		t.Fatalf("decode system message: %v", err)
+	}
+	return msg
+}
+
+func setupBreakoutTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *fakeBreakoutRoomService, *models.Room, **auth.Claims) {
+	t.Helper()
+	grace := breakoutReturnGrace
+	breakoutReturnGrace = 0
+	t.Cleanup(func() { breakoutReturnGrace = grace })
+
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "workshop",
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/breakouts", h.ListBreakouts)
+			app.Post("/room/:roomId/breakouts", h.CreateBreakouts)
+			app.Post("/room/:roomId/breakouts/move", h.MoveBreakoutParticipant)
+			app.Post("/room/:roomId/breakouts/close", h.CloseBreakouts)
+			app.Delete("/room/:roomId", h.DeleteRoom)
+		},
+	})
+	fake := &fakeBreakoutRoomService{rooms: map[string][]*livekit.ParticipantInfo{}}
+	f.handler.nodes.Default().Rooms = fake
+	f.addUser("member-user", "member@ex.com", "Member")
+	f.setSettings(t, models.RoomSettings{AllowAudio: true, AllowVideo: false, AllowChat: true})
+	fake.rooms[f.room.Name] = []*livekit.ParticipantInfo{
+		{Identity: "owner-user", Name: "Owner"},
+		{Identity: "member-user", Name: "Member"},
+		{Identity: "guest-abc12345", Name: "Visitor"},
+	}
+	return f.app, f.repo, fake, f.room, &f.current
+}
+
+func TestBreakouts_CreateInheritsSettingsAndAssigns(t *testing.T) {
+	app, roomRepo, fake, room, _ := setupBreakoutTestApp(t)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts", map[string]interface{}{
+		"count":       2,
+		"assignments": map[string]int{"member-user": 2},
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%v)", status, body)
+	}
+	rooms, _ := roomRepo.GetBreakoutRooms(room.ID)
+	if len(rooms) != 2 || len(fake.created) != 2 {
+		t.Fatalf("expected 2 breakout rooms, got %d (livekit %d)", len(rooms), len(fake.created))
+	}
+	if rooms[0].Name != "workshop-breakout-1" || rooms[1].Name != "workshop-breakout-2" {
+		t.Fatalf("unexpected names %q, %q", rooms[0].Name, rooms[1].Name)
+	}
+	if rooms[0].Settings != room.Settings || rooms[0].AdminID != room.AdminID {
+		t.Fatalf("expected breakout to inherit settings and host, got %+v", rooms[0])
+	}
+	if assigned := body["assignments"].(map[string]interface{}); assigned["member-user"] != rooms[1].ID {
+		t.Fatalf("expected member-user in second breakout, got %v", assigned)
+	}
+
+	if len(fake.sent) != 1 {
+		t.Fatalf("expected 1 move message, got %d", len(fake.sent))
+	}
+	req := fake.sent[0]
+	if req.Room != room.Name || len(req.DestinationIdentities) != 1 || req.DestinationIdentities[0] != "member-user" {
+		t.Fatalf("expected targeted message in main room, got %+v", req)
+	}
+	msg := breakoutPacket(t, req)
+	if msg.Event != "breakout_move" || msg.RoomName != rooms[1].Name {
+		t.Fatalf("unexpected move message %+v", msg)
+	}
+	v, err := lkauth.ParseAPIToken(msg.Token)
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	_, claims, err := v.Verify("test-secret")
+	if err != nil {
+		t.Fatalf("verify token: %v", err)
+	}
+	if claims.Video.Room != rooms[1].Name || claims.Identity != "member-user" {
+		t.Fatalf("token issued for %q/%q", claims.Video.Room, claims.Identity)
+	}
+	if len(claims.Video.CanPublishSources) != 1 || claims.Video.CanPublishSources[0] != "microphone" {
+		t.Fatalf("expected inherited audio-only grant, got %v", claims.Video.CanPublishSources)
+	}
+
+	// Only one set of breakouts at a time.
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts", map[string]interface{}{"count": 1})
+	if status != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", status)
+	}
+}
+
+func TestBreakouts_RandomSkipsHost(t *testing.T) {
+	app, _, fake, room, _ := setupBreakoutTestApp(t)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts", map[string]interface{}{
+		"count": 2, "assign": "random",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%v)", status, body)
+	}
+	assigned := body["assignments"].(map[string]interface{})
+	if len(assigned) != 2 || assigned["owner-user"] != nil {
+		t.Fatalf("expected member and guest to be assigned, got %v", assigned)
+	}
+	if assigned["member-user"] == assigned["guest-abc12345"] {
+		t.Fatal("expected participants spread over both breakouts")
+	}
+	if len(fake.sent) != 2 {
+		t.Fatalf("expected 2 move messages, got %d", len(fake.sent))
+	}
+}
+
+func TestBreakouts_MoveAndClose(t *testing.T) {
+	app, roomRepo, fake, room, current := setupBreakoutTestApp(t)
+
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts", map[string]interface{}{"count": 2})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", status)
+	}
+	rooms, _ := roomRepo.GetBreakoutRooms(room.ID)
+	fake.rooms[room.Name] = fake.rooms[room.Name][:1]
+	fake.rooms[rooms[0].Name] = []*livekit.ParticipantInfo{{Identity: "member-user", Name: "Member"}}
+	fake.rooms[rooms[1].Name] = []*livekit.ParticipantInfo{{Identity: "guest-abc12345", Name: "Visitor"}}
+
+	// Move the member from the first breakout to the second.
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts/move", map[string]string{
+		"identity": "member-user", "targetRoomId": rooms[1].ID,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v)", status, body)
+	}
+	if req := fake.sent[len(fake.sent)-1]; req.Room != rooms[0].Name || breakoutPacket(t, req).RoomID != rooms[1].ID {
+		t.Fatalf("expected move sent in first breakout, got %+v", req)
+	}
+
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts/move", map[string]string{
+		"identity": "nobody", "targetRoomId": rooms[1].ID,
+	})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404 for absent participant, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts/close", map[string]int{"countdownSeconds": 0})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-host, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "owner-user", Name: "Owner", Accesses: []string{"user"}}
+	fake.sent = nil
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts/close", map[string]int{"countdownSeconds": 0})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	closing, returned := 0, map[string]bool{}
+	for _, req := range fake.sent {
+		msg := breakoutPacket(t, req)
+		switch msg.Event {
+		case "breakout_closing":
+			closing++
+		case "breakout_move":
+			if msg.RoomName != room.Name {
+				t.Errorf("expected return to main room, got %q", msg.RoomName)
+			}
+			returned[msg.Target] = true
+		}
+	}
+	if closing != 2 || !returned["member-user"] || !returned["guest-abc12345"] {
+		t.Fatalf("expected 2 countdowns and both participants returned, got %d / %v", closing, returned)
+	}
+	if left, _ := roomRepo.GetBreakoutRooms(room.ID); len(left) != 0 {
+		t.Fatalf("expected breakouts deleted, got %d", len(left))
+	}
+	if len(fake.deleted) != 2 {
+		t.Fatalf("expected 2 LiveKit rooms deleted, got %v", fake.deleted)
+	}
+}
+
+func TestBreakouts_DeletedWithParent(t *testing.T) {
+	app, roomRepo, fake, room, _ := setupBreakoutTestApp(t)
+
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts", map[string]interface{}{"count": 3})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodDelete, "/room/"+room.ID, nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if left, _ := roomRepo.GetBreakoutRooms(room.ID); len(left) != 0 {
+		t.Fatalf("expected breakouts deleted with parent, got %d", len(left))
+	}
+	if len(fake.deleted) != 4 {
+		t.Fatalf("expected 4 LiveKit rooms deleted, got %v", fake.deleted)
+	}
+}
+
+func TestBreakouts_InheritPasscode(t *testing.T) {
+	app, roomRepo, _, room, _ := setupBreakoutTestApp(t)
+	hashed, err := hashPasscode("letmein")
+	if err != nil {
+		t.Fatalf("hash passcode: %v", err)
+	}
+	room.PasscodeHash, room.HasPasscode = hashed, true
+	if err := roomRepo.UpdateRoom(room); err != nil {
+		t.Fatalf("failed to set passcode: %v", err)
+	}
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/breakouts", map[string]interface{}{"count": 1})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%v)", status, body)
+	}
+	rooms, _ := roomRepo.GetBreakoutRooms(room.ID)
+	if len(rooms) != 1 || !rooms[0].HasPasscode || rooms[0].PasscodeHash != hashed {
+		t.Fatalf("expected breakout to inherit the passcode, got %+v", rooms)
+	}
+
+	// Clearing the parent's passcode clears it on the breakouts too.
+	if err := roomRepo.SetBreakoutPasscode(room.ID, "", false); err != nil {
+		t.Fatalf("SetBreakoutPasscode: %v", err)
+	}
+	rooms, _ = roomRepo.GetBreakoutRooms(room.ID)
+	if rooms[0].HasPasscode || rooms[0].PasscodeHash != "" {
+		t.Fatalf("expected breakout passcode cleared, got %+v", rooms[0])
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"context"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	chatMaxMessageLength = 4000
+	chatMaxAttachments   = 10
+	chatDefaultPageSize  = 50
+	chatMaxPageSize      = 200
+)
+
+// PostChatMessageRequest is the body for POST /room/:roomId/messages.
+type PostChatMessageRequest struct {
+	Message     string                  `json:"message"`
+	Attachments []models.ChatAttachment `json:"attachments"`
+}
+
+// ChatMessagesResponse is one page of chat history, oldest message first.
+// NextCursor is passed back as ?before= to load older messages; it is empty
+// when there are none left.
+type ChatMessagesResponse struct {
+	Messages   []models.ChatMessage `json:"messages"`
+	NextCursor string               `json:"nextCursor,omitempty"`
+}
+
+// PostChatMessage stores a chat message and relays it to the room on the
+// "chat" data topic, so the server copy is the source of truth.
+func (h *RoomHandler) PostChatMessage(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+
+	participant, err := h.roomRepo.GetParticipant(room.ID, claims.UserID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to look up participant")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up participant"})
+	}
+	exempt := h.isPublishExempt(room, adminId, claims.UserID, claims.Accesses)
+	if !exempt {
+		if !mayChat(participant) {
+			return c.Status(403).JSON(fiber.Map{"error": "not a participant of this room"})
+		}
+		if !room.Settings.AllowChat {
+			return c.Status(403).JSON(fiber.Map{"error": "Chat is disabled in this room"})
+		}
+		if participant.IsChatBlocked {
+			return c.Status(403).JSON(fiber.Map{"error": "You are blocked from chatting in this room"})
+		}
+	}
+
+	var req PostChatMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" && len(req.Attachments) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "Message cannot be empty"})
+	}
+	if utf8.RuneCountInString(req.Message) > chatMaxMessageLength {
+		return c.Status(400).JSON(fiber.Map{"error": "Message is too long"})
+	}
+	if len(req.Attachments) > chatMaxAttachments {
+		return c.Status(400).JSON(fiber.Map{"error": "Too many attachments"})
+	}
+	if req.Attachments == nil {
+		req.Attachments = []models.ChatAttachment{}
+	}
+
+	senderName := claims.Name
+	if participant != nil && participant.DisplayName != "" {
+		senderName = participant.DisplayName
+	}
+	msg := &models.ChatMessage{
+		RoomID:         room.ID,
+		SenderIdentity: claims.UserID,
+		SenderName:     senderName,
+		Message:        req.Message,
+		Attachments:    req.Attachments,
+	}
+	if err := h.roomRepo.CreateChatMessage(msg); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to store chat message")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to store chat message"})
+	}
+
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	h.relayChatMessage(ctx, room.Name, msg)
+	return c.Status(201).JSON(msg)
+}
+
+// ListChatMessages returns chat history for the room, newest page first.
+// Anyone who has been in the room may read it.
+func (h *RoomHandler) ListChatMessages(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if !h.isPublishExempt(room, adminId, claims.UserID, claims.Accesses) {
+		participant, err := h.roomRepo.GetParticipant(room.ID, claims.UserID)
+		if err != nil {
+			log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to look up participant")
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to look up participant"})
+		}
+		if !mayChat(participant) {
+			return c.Status(403).JSON(fiber.Map{"error": "not a participant of this room"})
+		}
+	}
+
+	limit := c.QueryInt("limit", chatDefaultPageSize)
+	if limit <= 0 || limit > chatMaxPageSize {
+		limit = chatDefaultPageSize
+	}
+	msgs, err := h.roomRepo.GetChatMessages(room.ID, c.Query("before"), limit)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list chat messages")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list chat messages"})
+	}
+
+	resp := ChatMessagesResponse{Messages: make([]models.ChatMessage, 0, len(msgs))}
+	if len(msgs) == limit {
+		resp.NextCursor = msgs[len(msgs)-1].ID
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		resp.Messages = append(resp.Messages, msgs[i])
+	}
+	return c.JSON(resp)
+}
+
+// mayChat reports whether a participant record lets its holder read and post
+// chat: they must have been let in through the lobby and not banned since.
+func mayChat(p *models.RoomParticipant) bool {
+	return p != nil && !p.IsBanned && p.Admitted()
+}
+
+// relayChatMessage publishes a stored message on the "chat" topic in the same
+// shape clients use for their own chat packets.
+func (h *RoomHandler) relayChatMessage(ctx context.Context, roomName string, msg *models.ChatMessage) {
+	type chatPacket struct {
+		Type           string                  `json:"type"`
+		ID             string                  `json:"id"`
+		Timestamp      int64                   `json:"timestamp"`
+		SenderName     string                  `json:"senderName"`
+		SenderIdentity string                  `json:"senderIdentity"`
+		Message        string                  `json:"message"`
+		Attachments    []models.ChatAttachment `json:"attachments"`
+	}
+	b, _ := json.Marshal(chatPacket{
+		Type:           "chat",
+		ID:             msg.ID,
+		Timestamp:      msg.CreatedAt.UnixMilli(),
+		SenderName:     msg.SenderName,
+		SenderIdentity: msg.SenderIdentity,
+		Message:        msg.Message,
+		Attachments:    msg.Attachments,
+	})
+	topic := "chat"
+	if _, err := h.lk(ctx).Rooms.SendData(ctx, &livekit.SendDataRequest{
+		Room:  roomName,
+		Data:  b,
+		Kind:  livekit.DataPacket_RELIABLE,
+		Topic: &topic,
+	}); err != nil {
+		log.Warn().Err(err).Str("room", roomName).Str("messageID", msg.ID).Msg("Failed to relay chat message")
+	}
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/livekit/protocol/livekit"
+)
+
+func setupChatTestApp(t *testing.T, settings models.RoomSettings) (*fiber.App, *repository.RoomRepository, *fakeRoomService, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "chat-room",
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/messages", h.ListChatMessages)
+			app.Post("/room/:roomId/messages", h.PostChatMessage)
+			app.Post("/room/:roomId/chat/:identity/block", h.BlockChat)
+		},
+	})
+	fake := &fakeRoomService{}
+	f.handler.nodes.Default().Rooms = fake
+	f.setSettings(t, settings)
+	_ = f.repo.AddParticipant(f.room.ID, "member-user")
+	return f.app, f.repo, fake, f.room, &f.current
+}
+
+func TestChat_PostStoresAndRelays(t *testing.T) {
+	app, _, fake, room, current := setupChatTestApp(t, models.RoomSettings{AllowChat: true})
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/messages", map[string]string{"message": "  hello  "})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%v)", status, body)
+	}
+	if body["message"] != "hello" || body["senderIdentity"] != "member-user" {
+		t.Fatalf("unexpected message %v", body)
+	}
+	if len(fake.sent) != 1 || fake.sent[0].GetTopic() != "chat" {
+		t.Fatalf("expected one relayed chat packet, got %+v", fake.sent)
+	}
+	var packet map[string]interface{}
+	_ = json.Unmarshal(fake.sent[0].Data, &packet)
+	if packet["type"] != "chat" || packet["id"] != body["id"] || packet["message"] != "hello" {
+		t.Fatalf("unexpected packet %v", packet)
+	}
+
+	status, page := doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/messages", nil)
+	if status != http.StatusOK || len(page["messages"].([]interface{})) != 1 {
+		t.Fatalf("expected history with 1 message, got %d (%v)", status, page)
+	}
+}
+
+func TestChat_EnforcesRoomRules(t *testing.T) {
+	app, _, fake, room, current := setupChatTestApp(t, models.RoomSettings{AllowChat: false})
+	msg := map[string]string{"message": "hi"}
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	if status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/messages", msg); status != http.StatusForbidden {
+		t.Fatalf("expected 403 with chat disabled, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "stranger", Name: "Stranger", Accesses: []string{"user"}}
+	if status, _ := doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/messages", nil); status != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-participant, got %d", status)
+	}
+
+	// The owner is exempt from AllowChat.
+	*current = &auth.Claims{UserID: "owner-user", Name: "Owner", Accesses: []string{"user"}}
+	if status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/messages", msg); status != http.StatusCreated {
+		t.Fatalf("expected owner to post, got %d", status)
+	}
+	if len(fake.sent) != 1 {
+		t.Fatalf("expected only the owner's message to be relayed, got %d", len(fake.sent))
+	}
+}
+
+func TestChat_BlockedParticipant(t *testing.T) {
+	app, roomRepo, fake, room, current := setupChatTestApp(t, models.RoomSettings{AllowChat: true})
+	fake.participants = []*livekit.ParticipantInfo{{Identity: "member-user"}}
+
+	if status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/chat/member-user/block", nil); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if p, _ := roomRepo.GetParticipant(room.ID, "member-user"); p == nil || !p.IsChatBlocked {
+		t.Fatal("expected participant to be chat blocked")
+	}
+	if len(fake.updates) != 1 || fake.updates[0].Metadata != `{"chatBlocked":true}` {
+		t.Fatalf("expected chatBlocked metadata update, got %+v", fake.updates)
+	}
+	if perm := fake.updates[0].Permission; perm == nil || perm.CanPublishData {
+		t.Fatalf("expected data publishing to be revoked, got %+v", perm)
+	}
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/messages", map[string]string{"message": "hi"})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for blocked participant, got %d", status)
+	}
+	// Blocked participants can still read history.
+	if status, _ := doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/messages", nil); status != http.StatusOK {
+		t.Fatalf("expected 200 reading history, got %d", status)
+	}
+}
+
+func TestChat_LobbyParticipantsLockedOut(t *testing.T) {
+	app, roomRepo, _, room, current := setupChatTestApp(t, models.RoomSettings{AllowChat: true})
+	if _, err := roomRepo.RequestLobbyEntry(room.ID, "waiting-user", "Waiting"); err != nil {
+		t.Fatalf("failed to enter lobby: %v", err)
+	}
+
+	*current = &auth.Claims{UserID: "waiting-user", Name: "Waiting", Accesses: []string{"user"}}
+	for _, deny := range []bool{false, true} {
+		if deny {
+			_ = roomRepo.DenyLobbyEntry(room.ID, "waiting-user")
+		}
+		if status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/messages", map[string]string{"message": "hi"}); status != http.StatusForbidden {
+			t.Fatalf("expected 403 posting from the lobby (denied=%v), got %d", deny, status)
+		}
+		if status, _ := doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/messages", nil); status != http.StatusForbidden {
+			t.Fatalf("expected 403 reading from the lobby (denied=%v), got %d", deny, status)
+		}
+	}
+}
+
+func TestChat_CursorPagination(t *testing.T) {
+	app, _, _, room, _ := setupChatTestApp(t, models.RoomSettings{AllowChat: true})
+	for i := 0; i < 5; i++ {
+		_, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/messages", map[string]string{"message": fmt.Sprintf("m%d", i)})
+	}
+
+	var seen []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		url := "/room/" + room.ID + "/messages?limit=2"
+		if cursor != "" {
+			url += "&before=" + cursor
+		}
+		status, body := doJSONRequest(t, app, http.MethodGet, url, nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+		var batch []string
+		for _, m := range body["messages"].([]interface{}) {
+			batch = append(batch, m.(map[string]interface{})["message"].(string))
+		}
+		seen = append(batch, seen...)
+		next, _ := body["nextCursor"].(string)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if fmt.Sprint(seen) != "[m0 m1 m2 m3 m4]" {
+		t.Fatalf("expected every message exactly once in order, got %v", seen)
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// E2EEKeyResponse carries a room's shared end-to-end encryption key. It is
+// part of the join response for E2EE rooms and is served on its own after a
+// "rekey" system message.
+type E2EEKeyResponse struct {
+	// Key is the raw key, base64 encoded.
+	Key     string `json:"key"`
+	Version int    `json:"version"`
+}
+
+func newE2EEKeyResponse(k *models.RoomE2EEKey) *E2EEKeyResponse {
+	return &E2EEKeyResponse{Key: base64.StdEncoding.EncodeToString(k.Key), Version: k.Version}
+}
+
+// addE2EEKey puts the room's key into a join response when the room is
+// end-to-end encrypted. It writes the error response and reports false when
+// the key can't be handed out.
+func (h *RoomHandler) addE2EEKey(c *fiber.Ctx, room *models.Room, guest bool, resp fiber.Map) bool {
+	if !room.Settings.E2EE {
+		return true
+	}
+	if guest && !room.Settings.E2EEAllowGuests {
+		_ = c.Status(403).JSON(fiber.Map{"error": "Guests can't join this end-to-end encrypted room"})
+		return false
+	}
+	key, err := h.roomRepo.GetE2EEKey(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to load E2EE key")
+		_ = c.Status(500).JSON(fiber.Map{"error": "Failed to load encryption key"})
+		return false
+	}
+	resp["e2ee"] = newE2EEKeyResponse(key)
+	return true
+}
+
+// E2EEKey returns the current key of an E2EE room to a signed-in participant.
+// Clients call it after a "rekey" system message or a breakout move.
+func (h *RoomHandler) E2EEKey(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, _, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	return h.serveE2EEKey(c, room, claims.UserID, "")
+}
+
+// GuestE2EEKey is E2EEKey for guests, who prove their identity with the
+// signed guest cookie they joined with.
+func (h *RoomHandler) GuestE2EEKey(c *fiber.Ctx) error {
+	identity := c.Params("identity")
+	guestKey, ok := h.verifiedGuestKey(c)
+	if !ok || !strings.HasPrefix(identity, "guest-") {
+		return c.Status(403).JSON(fiber.Map{"error": "Not a participant of this room"})
+	}
+	room, _, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if room.Settings.E2EE && !room.Settings.E2EEAllowGuests {
+		return c.Status(403).JSON(fiber.Map{"error": "Guests can't join this end-to-end encrypted room"})
+	}
+	return h.serveE2EEKey(c, room, identity, guestKey)
+}
+
+func (h *RoomHandler) serveE2EEKey(c *fiber.Ctx, room *models.Room, identity, guestKey string) error {
+	if !room.Settings.E2EE {
+		return c.Status(404).JSON(fiber.Map{"error": "Room is not end-to-end encrypted"})
+	}
+	p, err := h.roomRepo.GetParticipant(room.ID, identity)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Str("identity", identity).Msg("Failed to look up participant")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load encryption key"})
+	}
+	if !mayHoldE2EEKey(p, guestKey) {
+		return c.Status(403).JSON(fiber.Map{"error": "Not a participant of this room"})
+	}
+	key, err := h.roomRepo.GetE2EEKey(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to load E2EE key")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load encryption key"})
+	}
+	return c.JSON(newE2EEKeyResponse(key))
+}
+
+// mayHoldE2EEKey reports whether a participant record belongs to someone who
+// was let into the room and hasn't left, been removed or been banned since.
+// Guests must also present the guest key they joined with.
+func mayHoldE2EEKey(p *models.RoomParticipant, guestKey string) bool {
+	if p == nil || p.IsBanned || p.LeftAt != nil {
+		return false
+	}
+	if p.LobbyStatus == models.LobbyStatusPending || p.LobbyStatus == models.LobbyStatusDenied {
+		return false
+	}
+	return guestKey == "" || p.GuestKey == guestKey
+}
+
+// revokeE2EEKey locks a removed participant out of an E2EE room's key: it
+// records them as gone, replaces the key and tells everyone still connected
+// to fetch the new version. The key itself never travels over LiveKit. ctx
+// must be authorized for the room.
+func (h *RoomHandler) revokeE2EEKey(ctx context.Context, room *models.Room, identity, actor string) {
+	if !room.Settings.E2EE {
+		return
+	}
+	if err := h.roomRepo.UpdateParticipantStatus(room.ID, identity, map[string]interface{}{"is_active": false, "left_at": time.Now()}); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Str("identity", identity).Msg("Failed to mark participant as gone")
+	}
+	key, err := h.roomRepo.RotateE2EEKey(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to rotate E2EE key")
+		return
+	}
+	log.Info().Str("roomID", room.ID).Int("version", key.Version).Str("removed", identity).Msg("Rotated E2EE key")
+	h.sendSystemMessage(ctx, room.Name, "rekey", actor, strconv.Itoa(key.Version))
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupE2EETestApp(t *testing.T, settings models.RoomSettings) (*fiber.App, *repository.RoomRepository, *fakeRoomService, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name:     "secret-sync",
+		settings: settings,
+		public: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/e2ee-key/guest/:identity", h.GuestE2EEKey)
+			app.Post("/room/guest-join", h.GuestJoinRoom)
+		},
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/e2ee-key", h.E2EEKey)
+			app.Post("/room/:roomId/kick/:identity", h.KickParticipant)
+			app.Post("/room/:roomId/ban/:identity", h.BanParticipant)
+		},
+	})
+	fake := &fakeRoomService{}
+	f.handler.nodes.Default().Rooms = fake
+	f.addUser("member-user", "member@ex.com", "Member")
+	return f.app, f.repo, fake, f.room, &f.current
+}
+
+func e2eeKeyOf(t *testing.T, body map[string]interface{}) (string, int) {
+	t.Helper()
+	e2ee, ok := body["e2ee"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected an e2ee key in %v", body)
+	}
+	return e2ee["key"].(string), int(e2ee["version"].(float64))
+}
+
+// rekeys returns the key versions announced by "rekey" system messages.
+func rekeys(fake *fakeRoomService) []string {
+	var versions []string
+	for _, req := range fake.sent {
+		var msg struct{ Event, Target string }
+		if json.Unmarshal(req.Data, &msg) == nil && msg.Event == "rekey" {
+			versions = append(versions, msg.Target)
+		}
+	}
+	return versions
+}
+
+// getGuestKey fetches the E2EE key as a guest presenting cookie.
+func getGuestKey(t *testing.T, app *fiber.App, roomID, identity string, cookie *http.Cookie) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/room/"+roomID+"/e2ee-key/guest/"+identity, http.NoBody)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+func TestE2EE_KeyRotatesWhenParticipantKicked(t *testing.T) {
+	app, _, fake, room, current := setupE2EETestApp(t, models.RoomSettings{E2EE: true})
+	owner := *current
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != 200 {
+		t.Fatalf("expected 200 joining, got %d: %v", status, body)
+	}
+	key, version := e2eeKeyOf(t, body)
+	if version != 1 || len(key) < 40 {
+		t.Fatalf("expected a 32 byte first key, got version %d key %q", version, key)
+	}
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	_, body = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if memberKey, _ := e2eeKeyOf(t, body); memberKey != key {
+		t.Fatal("expected everyone in the meeting to share the key")
+	}
+	if status, body = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/e2ee-key", nil); status != 200 || body["key"] != key {
+		t.Fatalf("expected the member to fetch the key, got %d: %v", status, body)
+	}
+
+	*current = owner
+	if status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/kick/member-user", nil); status != 200 {
+		t.Fatalf("expected 200 kicking, got %d", status)
+	}
+	if versions := rekeys(fake); len(versions) != 1 || versions[0] != "2" {
+		t.Fatalf("expected one rekey message for version 2, got %v", versions)
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/e2ee-key", nil)
+	if status != 200 || body["key"] == key || body["version"] != float64(2) {
+		t.Fatalf("expected the owner to get a new key, got %d: %v", status, body)
+	}
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	if status, _ = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/e2ee-key", nil); status != 403 {
+		t.Fatalf("expected 403 for the kicked member, got %d", status)
+	}
+	*current = &auth.Claims{UserID: "stranger-user", Name: "Stranger", Accesses: []string{"user"}}
+	if status, _ = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/e2ee-key", nil); status != 403 {
+		t.Fatalf("expected 403 for a user who never joined, got %d", status)
+	}
+}
+
+func TestE2EE_GuestPolicy(t *testing.T) {
+	app, roomRepo, _, room, _ := setupE2EETestApp(t, models.RoomSettings{E2EE: true})
+
+	if status, _, _ := guestJoin(t, app, room.Name, "Gus", nil); status != 403 {
+		t.Fatalf("expected guests to be turned away by default, got %d", status)
+	}
+
+	room.Settings.E2EEAllowGuests = true
+	if err := roomRepo.UpdateRoom(room); err != nil {
+		t.Fatalf("update room: %v", err)
+	}
+	status, identity, cookie := guestJoin(t, app, room.Name, "Gus", nil)
+	if status != 200 || cookie == nil {
+		t.Fatalf("expected the guest to join, got %d", status)
+	}
+	if status, body := getGuestKey(t, app, room.ID, identity, cookie); status != 200 || body["version"] != float64(1) {
+		t.Fatalf("expected the guest to fetch the key, got %d: %v", status, body)
+	}
+	if status, _ := getGuestKey(t, app, room.ID, identity, nil); status != 403 {
+		t.Fatalf("expected 403 without the guest cookie, got %d", status)
+	}
+	_, _, otherCookie := guestJoin(t, app, room.Name, "Other", nil)
+	if status, _ := getGuestKey(t, app, room.ID, identity, otherCookie); status != 403 {
+		t.Fatalf("expected 403 with another guest's cookie, got %d", status)
+	}
+
+	if status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ban/"+identity, nil); status != 200 {
+		t.Fatalf("expected 200 banning, got %d", status)
+	}
+	if status, _ := getGuestKey(t, app, room.ID, identity, cookie); status != 403 {
+		t.Fatalf("expected 403 for the banned guest, got %d", status)
+	}
+	if key, _ := roomRepo.GetE2EEKey(room.ID); key == nil || key.Version != 2 {
+		t.Fatalf("expected the ban to rotate the key, got %+v", key)
+	}
+}
+
+func TestE2EE_PlainRoomHasNoKey(t *testing.T) {
+	app, _, fake, room, _ := setupE2EETestApp(t, models.RoomSettings{})
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != 200 {
+		t.Fatalf("expected 200 joining, got %d", status)
+	}
+	if _, ok := body["e2ee"]; ok {
+		t.Fatal("expected no key for a room without E2EE")
+	}
+	if status, _ = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/e2ee-key", nil); status != 404 {
+		t.Fatalf("expected 404, got %d", status)
+	}
+	doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/kick/member-user", nil)
+	if versions := rekeys(fake); len(versions) != 0 {
+		t.Fatalf("expected no rekey for a room without E2EE, got %v", versions)
+	}
+}
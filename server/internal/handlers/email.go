@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// emailBaseURL returns the frontend URL that links in emails point to. The
+// request's own host is only used when nothing is configured, as in local
+// development, since clients control the Host header.
+func (h *AuthHandler) emailBaseURL(c *fiber.Ctx) string {
+	if h.settingsRepo != nil {
+		if s, err := h.settingsRepo.GetEffectiveSettings(); err == nil && s.FrontendURL != "" {
+			return s.FrontendURL
+		}
+	}
+	if h.config.Auth.FrontendURL != "" {
+		return h.config.Auth.FrontendURL
+	}
+	if h.config.Server.Domain != "" {
+		return "https://" + h.config.Server.Domain
+	}
+	return c.BaseURL()
+}
+
+// VerifyEmail marks the caller's address verified with a token from a
+// verification email.
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var input struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token is required"})
+	}
+	if err := h.authService.VerifyEmail(input.Token); err != nil {
+		if errors.Is(err, auth.ErrInvalidEmailToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired link"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify email"})
+	}
+	return c.JSON(fiber.Map{"message": "Email verified"})
+}
+
+// ResendVerificationEmail mails the caller a new verification link.
+func (h *AuthHandler) ResendVerificationEmail(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	user, err := h.authService.GetUserByID(claims.UserID)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	if err := h.authService.SendVerificationEmail(user, h.emailBaseURL(c)); err != nil {
+		if errors.Is(err, auth.ErrEmailAlreadyVerified) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email is already verified"})
+		}
+		log.Error().Err(err).Str("userId", user.ID).Msg("Failed to send verification email")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send verification email"})
+	}
+	return c.JSON(fiber.Map{"message": "Verification email sent"})
+}
+
+// ForgotPassword mails a password reset link. It answers the same way
+// whether or not the address has an account.
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is required"})
+	}
+	if err := h.authService.RequestPasswordReset(input.Email, h.emailBaseURL(c)); err != nil {
+		log.Error().Err(err).Msg("Failed to send password reset email")
+	}
+	return c.JSON(fiber.Map{"message": "If an account exists for that address, a reset link is on its way"})
+}
+
+// ResetPassword sets a new password with a token from a reset email.
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token is required"})
+	}
+	if len(input.Password) < minPasswordLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Password must be at least %d characters", minPasswordLength),
+		})
+	}
+	if len(input.Password) > maxPasswordLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Password must be at most %d characters", maxPasswordLength),
+		})
+	}
+	if err := h.authService.ResetPassword(input.Token, input.Password); err != nil {
+		if errors.Is(err, auth.ErrInvalidEmailToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired link"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+	return c.JSON(fiber.Map{"message": "Password reset, please sign in again"})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bedrud/internal/mailer"
+	"bedrud/internal/models"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMailbox struct {
+	mu   sync.Mutex
+	sent []*mailer.Message
+}
+
+func (m *testMailbox) Send(from string, msg *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var mailedToken = regexp.MustCompile(`https://localhost/auth/[a-z-]+\?token=([A-Za-z0-9_-]+)`)
+
+// lastToken waits for the queue to drain and returns the token in the last
+// link mailed.
+func (m *testMailbox) lastToken(t *testing.T) string {
+	t.Helper()
+	if !mailer.Flush(5 * time.Second) {
+		t.Fatal("mail queue did not drain")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.sent) == 0 {
+		t.Fatal("no mail sent")
+	}
+	match := mailedToken.FindStringSubmatch(m.sent[len(m.sent)-1].Text)
+	if match == nil {
+		t.Fatalf("no link in %s", m.sent[len(m.sent)-1].Text)
+	}
+	return match[1]
+}
+
+func TestEmail_VerifyAndResetPassword(t *testing.T) {
+	app, authService, cfg := setupAuthTestApp(t)
+	authHandler := NewAuthHandler(authService, cfg, nil, nil)
+	app.Post("/api/auth/verify-email", authHandler.VerifyEmail)
+	app.Post("/api/auth/password/forgot", authHandler.ForgotPassword)
+	app.Post("/api/auth/password/reset", authHandler.ResetPassword)
+
+	box := &testMailbox{}
+	mailer.Start()
+	mailer.SetSenderForTest(box)
+	t.Cleanup(func() { mailer.Configure(&models.SystemSettings{}) })
+
+	status, raw := sessionRequest(t, app, http.MethodPost, "/api/auth/register", "", "",
+		map[string]string{"email": "mail@example.com", "password": "a-long-password", "name": "Mail"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 registering, got %d: %s", status, raw)
+	}
+	token := box.lastToken(t)
+	if status, _ = sessionRequest(t, app, http.MethodPost, "/api/auth/verify-email", "", "", map[string]string{"token": token}); status != http.StatusOK {
+		t.Fatalf("expected 200 verifying, got %d", status)
+	}
+	if user, _ := authService.GetUserByEmail("mail@example.com"); !user.EmailVerified {
+		t.Fatal("expected the address to be verified")
+	}
+
+	status, raw = sessionRequest(t, app, http.MethodPost, "/api/auth/password/forgot", "", "", map[string]string{"email": "nobody@example.com"})
+	unknownReply := string(raw)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 for an unknown address, got %d", status)
+	}
+	status, raw = sessionRequest(t, app, http.MethodPost, "/api/auth/password/forgot", "", "", map[string]string{"email": "mail@example.com"})
+	if status != http.StatusOK || string(raw) != unknownReply {
+		t.Fatalf("expected the same reply for known and unknown addresses, got %d: %s", status, raw)
+	}
+	token = box.lastToken(t)
+
+	if status, _ = sessionRequest(t, app, http.MethodPost, "/api/auth/password/reset", "", "", map[string]string{"token": token, "password": "short"}); status != http.StatusBadRequest {
+		t.Fatalf("expected a short password to be rejected, got %d", status)
+	}
+	if status, _ = sessionRequest(t, app, http.MethodPost, "/api/auth/password/reset", "", "", map[string]string{"token": token, "password": "a-new-long-password"}); status != http.StatusOK {
+		t.Fatalf("expected 200 resetting, got %d", status)
+	}
+	status, raw = sessionRequest(t, app, http.MethodPost, "/api/auth/login", "", "",
+		map[string]string{"email": "mail@example.com", "password": "a-new-long-password"})
+	if status != http.StatusOK || !strings.Contains(string(raw), "accessToken") {
+		t.Fatalf("expected to sign in with the new password, got %d: %s", status, raw)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bedrud/config"
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"bedrud/internal/testutil"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// roomFixture is a RoomHandler on a fresh test database with one room owned
+// by "owner-user". Requests run as current; tests replace it to act as
+// someone else.
+type roomFixture struct {
+	app     *fiber.App
+	db      *gorm.DB
+	repo    *repository.RoomRepository
+	handler *RoomHandler
+	room    *models.Room
+	current *auth.Claims
+}
+
+// roomFixtureOptions describes the room and the routes of a roomFixture.
+// Join and guest join are always routed.
+type roomFixtureOptions struct {
+	name    string
+	mode    string
+	private bool
+	// settings are passed to CreateRoom, so false flags get the column
+	// defaults. Use setSettings to store them exactly.
+	settings models.RoomSettings
+	liveKit  func(cfg *config.LiveKitConfig)
+	// public registers routes that run without a signed-in user.
+	public func(app *fiber.App, h *RoomHandler)
+	routes func(app *fiber.App, h *RoomHandler)
+}
+
+func newRoomFixture(t *testing.T, opts roomFixtureOptions) *roomFixture {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+	lkCfg := config.LiveKitConfig{Host: "http://localhost:9999", APIKey: "test-key", APISecret: "test-secret"}
+	if opts.liveKit != nil {
+		opts.liveKit(&lkCfg)
+	}
+	f := &roomFixture{
+		db:      db,
+		repo:    roomRepo,
+		handler: NewRoomHandler(&lkCfg, &config.ChatConfig{}, roomRepo),
+		current: &auth.Claims{UserID: "owner-user", Name: "Owner", Email: "owner@ex.com", Accesses: []string{"user"}},
+	}
+
+	f.app = fiber.New()
+	if opts.public != nil {
+		opts.public(f.app, f.handler)
+	}
+	f.app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", f.current)
+		return c.Next()
+	})
+	f.app.Post("/room/join", f.handler.JoinRoom)
+	f.app.Post("/room/guest-join", f.handler.GuestJoinRoom)
+	if opts.routes != nil {
+		opts.routes(f.app, f.handler)
+	}
+
+	db.Create(&models.User{ID: "owner-user", Email: "owner@ex.com", Name: "Owner", Provider: "local", IsActive: true, EmailVerified: true, Accesses: models.StringArray{"user"}})
+	mode := opts.mode
+	if mode == "" {
+		mode = models.RoomModeStandard
+	}
+	room, err := roomRepo.CreateRoom("owner-user", opts.name, !opts.private, mode, &opts.settings)
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+	f.room = room
+	return f
+}
+
+// addUser creates an active local user with user access.
+func (f *roomFixture) addUser(id, email, name string) {
+	f.db.Create(&models.User{ID: id, Email: email, Name: name, Provider: "local", IsActive: true, Accesses: models.StringArray{"user"}})
+}
+
+// setSettings stores the room's settings as given, false flags included.
+func (f *roomFixture) setSettings(t *testing.T, settings models.RoomSettings) {
+	t.Helper()
+	f.room.Settings = settings
+	if err := f.repo.UpdateRoom(f.room); err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+}
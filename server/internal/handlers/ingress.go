@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/rs/zerolog/log"
+	"github.com/twitchtv/twirp"
+)
+
+// CreateIngressRequest is the body for POST /room/:roomId/ingress.
+type CreateIngressRequest struct {
+	// Type is "rtmp" (default) or "whip".
+	Type string `json:"type"`
+	// Name is shown as the stream participant's display name.
+	Name string `json:"name"`
+}
+
+// CreateIngress creates a LiveKit Ingress that publishes into the room and
+// returns the URL and stream key to configure in the encoder.
+func (h *RoomHandler) CreateIngress(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
+		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+	if !h.ingressOn {
+		return c.Status(503).JSON(fiber.Map{"error": "Ingress is not enabled on this server"})
+	}
+
+	var req CreateIngressRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	req.Type = strings.ToLower(req.Type)
+	var inputType livekit.IngressInput
+	switch req.Type {
+	case "", models.IngressTypeRTMP:
+		req.Type, inputType = models.IngressTypeRTMP, livekit.IngressInput_RTMP_INPUT
+	case models.IngressTypeWHIP:
+		inputType = livekit.IngressInput_WHIP_INPUT
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "type must be rtmp or whip"})
+	}
+	if req.Name == "" {
+		req.Name = "Stream (" + strings.ToUpper(req.Type) + ")"
+	}
+	identity := models.IngressIdentityPrefix + strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{IngressAdmin: true})
+	info, err := h.lk(ctx).Ingress.CreateIngress(ctx, &livekit.CreateIngressRequest{
+		InputType:           inputType,
+		Name:                req.Name,
+		RoomName:            room.Name,
+		ParticipantIdentity: identity,
+		ParticipantName:     req.Name,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("room", room.Name).Str("type", req.Type).Msg("Failed to create ingress")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to create ingress"})
+	}
+
+	ing := &models.RoomIngress{
+		RoomID:              room.ID,
+		IngressID:           info.IngressId,
+		Type:                req.Type,
+		Name:                req.Name,
+		ParticipantIdentity: identity,
+		URL:                 info.Url,
+		StreamKey:           info.StreamKey,
+		CreatedBy:           claims.UserID,
+	}
+	if err := h.roomRepo.CreateRoomIngress(ing); err != nil {
+		log.Error().Err(err).Str("ingressID", info.IngressId).Msg("Failed to store ingress")
+		_, _ = h.lk(ctx).Ingress.DeleteIngress(ctx, &livekit.DeleteIngressRequest{IngressId: info.IngressId})
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to store ingress"})
+	}
+
+	log.Info().Str("room", room.Name).Str("ingressID", ing.IngressID).Str("type", ing.Type).Str("by", claims.UserID).Msg("Ingress created")
+	return c.Status(201).JSON(ing)
+}
+
+// ListIngresses returns the room's ingress endpoints.
+func (h *RoomHandler) ListIngresses(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
+		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+	ings, err := h.roomRepo.GetRoomIngresses(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list ingresses")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list ingresses"})
+	}
+	if ings == nil {
+		ings = []models.RoomIngress{}
+	}
+	return c.JSON(ings)
+}
+
+// DeleteIngress removes an ingress endpoint from LiveKit and the room.
+func (h *RoomHandler) DeleteIngress(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
+		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+	ing, err := h.roomRepo.GetRoomIngress(c.Params("ingressId"))
+	if err != nil {
+		log.Error().Err(err).Str("ingressID", c.Params("ingressId")).Msg("Failed to look up ingress")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up ingress"})
+	}
+	if ing == nil || ing.RoomID != room.ID {
+		return c.Status(404).JSON(fiber.Map{"error": "Ingress not found"})
+	}
+
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{IngressAdmin: true})
+	if err := h.deleteLiveKitIngress(ctx, ing.IngressID); err != nil {
+		log.Error().Err(err).Str("ingressID", ing.IngressID).Msg("Failed to delete ingress")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete ingress"})
+	}
+	if err := h.roomRepo.DeleteRoomIngress(ing.ID); err != nil {
+		log.Error().Err(err).Str("ingressID", ing.IngressID).Msg("Failed to delete ingress record")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete ingress"})
+	}
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// deleteRoomIngresses tears down every ingress bound to a room, e.g. when the
+// room itself is deleted. Failures are logged, not returned.
+func (h *RoomHandler) deleteRoomIngresses(ctx context.Context, room *models.Room) {
+	ings, err := h.roomRepo.GetRoomIngresses(room.ID)
+	if err != nil || len(ings) == 0 {
+		return
+	}
+	ctx = h.withAuth(ctx, room, &lkauth.VideoGrant{IngressAdmin: true})
+	for _, ing := range ings {
+		if err := h.deleteLiveKitIngress(ctx, ing.IngressID); err != nil {
+			log.Warn().Err(err).Str("ingressID", ing.IngressID).Msg("Failed to delete ingress")
+		}
+		_ = h.roomRepo.DeleteRoomIngress(ing.ID)
+	}
+}
+
+// deleteLiveKitIngress deletes an ingress, treating one LiveKit no longer
+// knows about as already gone.
+func (h *RoomHandler) deleteLiveKitIngress(ctx context.Context, ingressID string) error {
+	_, err := h.lk(ctx).Ingress.DeleteIngress(ctx, &livekit.DeleteIngressRequest{IngressId: ingressID})
+	if isLiveKitNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// isLiveKitNotFound reports whether a LiveKit API call failed because the
+// room, participant or ingress doesn't exist.
+func isLiveKitNotFound(err error) bool {
+	var terr twirp.Error
+	return errors.As(err, &terr) && terr.Code() == twirp.NotFound
+}
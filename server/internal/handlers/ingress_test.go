@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bedrud/config"
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/livekit/protocol/livekit"
+)
+
+// fakeIngress records ingress requests instead of calling LiveKit.
+type fakeIngress struct {
+	livekit.Ingress
+	created []*livekit.CreateIngressRequest
+	deleted []string
+}
+
+func (f *fakeIngress) CreateIngress(_ context.Context, req *livekit.CreateIngressRequest) (*livekit.IngressInfo, error) {
+	f.created = append(f.created, req)
+	url := "rtmp://media.example.com:1935/x"
+	if req.InputType == livekit.IngressInput_WHIP_INPUT {
+		url = "https://media.example.com:8080/w"
+	}
+	return &livekit.IngressInfo{
+		IngressId: "IN_" + req.ParticipantIdentity, Url: url, StreamKey: "sk_secret",
+		InputType: req.InputType, RoomName: req.RoomName, ParticipantIdentity: req.ParticipantIdentity,
+	}, nil
+}
+
+func (f *fakeIngress) DeleteIngress(_ context.Context, req *livekit.DeleteIngressRequest) (*livekit.IngressInfo, error) {
+	f.deleted = append(f.deleted, req.IngressId)
+	return &livekit.IngressInfo{IngressId: req.IngressId}, nil
+}
+
+func setupIngressTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *fakeIngress, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "ingress-room",
+		liveKit: func(cfg *config.LiveKitConfig) {
+			cfg.Ingress = config.IngressConfig{Enabled: true}
+		},
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/ingress", h.ListIngresses)
+			app.Post("/room/:roomId/ingress", h.CreateIngress)
+			app.Delete("/room/:roomId/ingress/:ingressId", h.DeleteIngress)
+		},
+	})
+	ingress := &fakeIngress{}
+	f.handler.nodes.Default().Ingress = ingress
+	return f.app, f.repo, ingress, f.room, &f.current
+}
+
+func TestIngress_CreateListDelete(t *testing.T) {
+	app, roomRepo, ingress, room, _ := setupIngressTestApp(t)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ingress", map[string]string{"type": "rtmp", "name": "OBS"})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%v)", status, body)
+	}
+	if body["url"] != "rtmp://media.example.com:1935/x" || body["streamKey"] != "sk_secret" {
+		t.Fatalf("expected stream url and key, got %v", body)
+	}
+	if len(ingress.created) != 1 {
+		t.Fatalf("expected one ingress, got %d", len(ingress.created))
+	}
+	req := ingress.created[0]
+	if req.RoomName != room.Name || req.ParticipantName != "OBS" || !strings.HasPrefix(req.ParticipantIdentity, models.IngressIdentityPrefix) {
+		t.Fatalf("unexpected ingress request %+v", req)
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ingress", map[string]string{"type": "whip"})
+	if status != http.StatusCreated || body["name"] != "Stream (WHIP)" {
+		t.Fatalf("expected whip ingress with default name, got %d (%v)", status, body)
+	}
+	whipID := body["id"].(string)
+
+	ings, _ := roomRepo.GetRoomIngresses(room.ID)
+	if len(ings) != 2 {
+		t.Fatalf("expected 2 stored ingresses, got %d", len(ings))
+	}
+
+	status, _ = doJSONRequest(t, app, http.MethodDelete, "/room/"+room.ID+"/ingress/"+whipID, nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(ingress.deleted) != 1 {
+		t.Fatalf("expected LiveKit ingress deletion, got %v", ingress.deleted)
+	}
+	if ing, _ := roomRepo.GetRoomIngress(whipID); ing != nil {
+		t.Fatal("expected ingress record to be removed")
+	}
+}
+
+func TestIngress_InvalidType(t *testing.T) {
+	app, _, _, room, _ := setupIngressTestApp(t)
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ingress", map[string]string{"type": "srt"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", status)
+	}
+}
+
+func TestIngress_OnlyAdminOrSuperadmin(t *testing.T) {
+	app, roomRepo, ingress, room, current := setupIngressTestApp(t)
+	_ = roomRepo.AddParticipant(room.ID, "mod-user")
+	_ = roomRepo.SetRoomModerator(room.ID, "mod-user", true)
+
+	*current = &auth.Claims{UserID: "mod-user", Name: "Mod", Accesses: []string{"user"}}
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ingress", nil)
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for moderator, got %d", status)
+	}
+	if len(ingress.created) != 0 {
+		t.Fatal("ingress should not have been created")
+	}
+
+	*current = &auth.Claims{UserID: "admin-user", Name: "Admin", Accesses: []string{"user", "superadmin"}}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/ingress", nil)
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201 for superadmin, got %d", status)
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	roomInviteDefaultHours = 7 * 24
+	roomInviteMaxHours     = 365 * 24
+)
+
+// CreateRoomInviteRequest is the body for POST /room/:roomId/invites.
+type CreateRoomInviteRequest struct {
+	// Role is "participant" (default), "moderator" or "stage".
+	Role           string `json:"role"`
+	MaxUses        int    `json:"maxUses"`
+	Email          string `json:"email"`
+	ExpiresInHours int    `json:"expiresInHours"`
+}
+
+// CreateRoomInvite creates an invite link for the room.
+func (h *RoomHandler) CreateRoomInvite(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
+		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+
+	var req CreateRoomInviteRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	switch req.Role {
+	case "":
+		req.Role = models.RoomInviteRoleParticipant
+	case models.RoomInviteRoleParticipant, models.RoomInviteRoleModerator, models.RoomInviteRoleStage:
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "role must be participant, moderator or stage"})
+	}
+	if req.MaxUses < 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "maxUses cannot be negative"})
+	}
+	if req.ExpiresInHours <= 0 {
+		req.ExpiresInHours = roomInviteDefaultHours
+	}
+	if req.ExpiresInHours > roomInviteMaxHours {
+		return c.Status(400).JSON(fiber.Map{"error": "expiresInHours is too large"})
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate secure token"})
+	}
+	inv := &models.RoomInvite{
+		RoomID:    room.ID,
+		Token:     hex.EncodeToString(b),
+		Email:     strings.ToLower(strings.TrimSpace(req.Email)),
+		Role:      req.Role,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour),
+		CreatedBy: claims.UserID,
+	}
+	if err := h.roomRepo.CreateRoomInvite(inv); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to create room invite")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to create invite"})
+	}
+	return c.Status(201).JSON(inv)
+}
+
+// ListRoomInvites returns the room's invites, including revoked and expired ones.
+func (h *RoomHandler) ListRoomInvites(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
+		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
+	}
+	invites, err := h.roomRepo.GetRoomInvites(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list room invites")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list invites"})
+	}
+	if invites == nil {
+		invites = []models.RoomInvite{}
+	}
+	return c.JSON(invites)
+}
+
+// RevokeRoomInvite stops an invite from being redeemed. Past redemptions are kept.
+func (h *RoomHandler) RevokeRoomInvite(c *fiber.Ctx) error {
+	inv, ok := h.resolveRoomInvite(c)
+	if !ok {
+		return nil
+	}
+	if err := h.roomRepo.RevokeRoomInvite(inv.ID); err != nil {
+		log.Error().Err(err).Str("inviteID", inv.ID).Msg("Failed to revoke room invite")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to revoke invite"})
+	}
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// ListRoomInviteRedemptions returns who redeemed an invite and when.
+func (h *RoomHandler) ListRoomInviteRedemptions(c *fiber.Ctx) error {
+	inv, ok := h.resolveRoomInvite(c)
+	if !ok {
+		return nil
+	}
+	reds, err := h.roomRepo.GetRoomInviteRedemptions(inv.ID)
+	if err != nil {
+		log.Error().Err(err).Str("inviteID", inv.ID).Msg("Failed to list invite redemptions")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list redemptions"})
+	}
+	if reds == nil {
+		reds = []models.RoomInviteRedemption{}
+	}
+	return c.JSON(reds)
+}
+
+// GetRoomInviteInfo lets an invite link be resolved to its room before
+// joining. Unusable invites are reported as not found.
+func (h *RoomHandler) GetRoomInviteInfo(c *fiber.Ctx) error {
+	inv, err := h.roomRepo.GetRoomInviteByToken(c.Params("token"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to look up room invite")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up invite"})
+	}
+	if inv == nil || !inv.Usable(time.Now()) {
+		return c.Status(404).JSON(fiber.Map{"error": "Invite not found or expired"})
+	}
+	room, err := h.roomRepo.GetRoom(inv.RoomID)
+	if err != nil || room == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Invite not found or expired"})
+	}
+	return c.JSON(fiber.Map{
+		"roomName": room.Name, "role": inv.Role, "expiresAt": inv.ExpiresAt,
+		"requiresSignIn": inv.Email != "",
+	})
+}
+
+// resolveRoomInvite loads the invite from the route and checks the caller
+// administers its room. Writes the error response itself.
+func (h *RoomHandler) resolveRoomInvite(c *fiber.Ctx) (*models.RoomInvite, bool) {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil, false
+	}
+	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
+		_ = c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
+		return nil, false
+	}
+	inv, err := h.roomRepo.GetRoomInvite(c.Params("inviteId"))
+	if err != nil {
+		log.Error().Err(err).Str("inviteID", c.Params("inviteId")).Msg("Failed to look up room invite")
+		_ = c.Status(500).JSON(fiber.Map{"error": "Failed to look up invite"})
+		return nil, false
+	}
+	if inv == nil || inv.RoomID != room.ID {
+		_ = c.Status(404).JSON(fiber.Map{"error": "Invite not found"})
+		return nil, false
+	}
+	return inv, true
+}
+
+// checkInvite validates an invite token presented on join. userID is the
+// signed-in user, empty for guests. An invite restricted to an email address
+// only admits a user who has verified that address. Writes the error response
+// itself.
+func (h *RoomHandler) checkInvite(c *fiber.Ctx, room *models.Room, token, userID string) (*models.RoomInvite, bool) {
+	inv, err := h.roomRepo.GetRoomInviteByToken(token)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to look up room invite")
+		_ = c.Status(500).JSON(fiber.Map{"error": "Failed to look up invite"})
+		return nil, false
+	}
+	if inv == nil || inv.RoomID != room.ID || !inv.Usable(time.Now()) {
+		_ = c.Status(403).JSON(fiber.Map{"error": "invalid or expired invite"})
+		return nil, false
+	}
+	if inv.Email == "" {
+		return inv, true
+	}
+	var user *models.User
+	if userID != "" {
+		user, err = h.roomRepo.GetUserByID(userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Error().Err(err).Str("userID", userID).Msg("Failed to look up invitee")
+			_ = c.Status(500).JSON(fiber.Map{"error": "Failed to look up invite"})
+			return nil, false
+		}
+	}
+	if user == nil || !strings.EqualFold(inv.Email, user.Email) {
+		_ = c.Status(403).JSON(fiber.Map{"error": "this invite is restricted to a different account"})
+		return nil, false
+	}
+	if !user.EmailVerified {
+		_ = c.Status(403).JSON(fiber.Map{"error": "verify your email address to use this invite"})
+		return nil, false
+	}
+	return inv, true
+}
+
+// redeemInvite records the use of an invite and applies it to identity: the
+// participant is pre-approved and granted the invite's role. Writes the error
+// response itself.
+func (h *RoomHandler) redeemInvite(c *fiber.Ctx, room *models.Room, inv *models.RoomInvite, identity, name string) bool {
+	err := h.roomRepo.RedeemRoomInvite(inv.ID, &models.RoomInviteRedemption{
+		RoomID: room.ID, Identity: identity, Name: name, IP: c.IP(),
+	})
+	if errors.Is(err, repository.ErrInviteUnavailable) {
+		_ = c.Status(403).JSON(fiber.Map{"error": "invalid or expired invite"})
+		return false
+	}
+	if err == nil {
+		err = h.roomRepo.AddParticipant(room.ID, identity)
+	}
+	if err == nil {
+		err = h.roomRepo.UpdateParticipantStatus(room.ID, identity, map[string]interface{}{"is_approved": true})
+	}
+	if err == nil {
+		switch inv.Role {
+		case models.RoomInviteRoleModerator:
+			err = h.roomRepo.SetRoomModerator(room.ID, identity, true)
+		case models.RoomInviteRoleStage:
+			err = h.roomRepo.BringToStage(room.ID, identity)
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Str("inviteID", inv.ID).Msg("Failed to redeem room invite")
+		_ = c.Status(500).JSON(fiber.Map{"error": "Failed to redeem invite"})
+		return false
+	}
+	log.Info().Str("room", room.Name).Str("inviteID", inv.ID).Str("identity", identity).Str("role", inv.Role).Msg("Room invite redeemed")
+	return true
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupInviteTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name:     "private-room",
+		private:  true,
+		settings: models.RoomSettings{RequireApproval: true},
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/invite/:token", h.GetRoomInviteInfo)
+			app.Get("/room/:roomId/invites", h.ListRoomInvites)
+			app.Post("/room/:roomId/invites", h.CreateRoomInvite)
+			app.Delete("/room/:roomId/invites/:inviteId", h.RevokeRoomInvite)
+			app.Get("/room/:roomId/invites/:inviteId/redemptions", h.ListRoomInviteRedemptions)
+		},
+	})
+	f.db.Create(&models.User{ID: "cohost-user", Email: "cohost@ex.com", Name: "Cohost", Provider: "google", IsActive: true, EmailVerified: true})
+	// Anyone can register a local account with someone else's address.
+	f.addUser("impostor-user", "cohost@ex.com", "Cohost")
+	return f.app, f.repo, f.room, &f.current
+}
+
+func createInvite(t *testing.T, app *fiber.App, roomID string, body map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	status, inv := doJSONRequest(t, app, http.MethodPost, "/room/"+roomID+"/invites", body)
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%v)", status, inv)
+	}
+	return inv
+}
+
+func TestRoomInvite_GuestJoinsPrivateRoom(t *testing.T) {
+	app, roomRepo, room, _ := setupInviteTestApp(t)
+	inv := createInvite(t, app, room.ID, map[string]interface{}{"maxUses": 1})
+	token := inv["token"].(string)
+
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client"})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 without invite, got %d", status)
+	}
+
+	status, body := doJSONRequest(t, app, http.MethodGet, "/room/invite/"+token, nil)
+	if status != http.StatusOK || body["roomName"] != room.Name {
+		t.Fatalf("expected invite info, got %d (%v)", status, body)
+	}
+
+	// The invite also skips the lobby.
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client", "inviteToken": token})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("expected 200 with token, got %d (%v)", status, body)
+	}
+
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Other", "inviteToken": token})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 once uses are exhausted, got %d", status)
+	}
+
+	reds, _ := roomRepo.GetRoomInviteRedemptions(inv["id"].(string))
+	if len(reds) != 1 || reds[0].Name != "Client" {
+		t.Fatalf("expected one recorded redemption, got %+v", reds)
+	}
+}
+
+func TestRoomInvite_ModeratorRoleAndEmailRestriction(t *testing.T) {
+	app, roomRepo, room, current := setupInviteTestApp(t)
+	inv := createInvite(t, app, room.ID, map[string]interface{}{"role": "moderator", "email": "Cohost@Ex.com"})
+	token := inv["token"].(string)
+
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client", "inviteToken": token})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for guest on email-restricted invite, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "other-user", Email: "other@ex.com", Name: "Other", Accesses: []string{"user"}}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name, "inviteToken": token})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for wrong account, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "impostor-user", Email: "cohost@ex.com", Name: "Cohost", Accesses: []string{"user"}}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name, "inviteToken": token})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for an unverified address, got %d", status)
+	}
+	if isMod, _ := roomRepo.IsRoomModerator(room.ID, "impostor-user"); isMod {
+		t.Fatal("expected no moderator rights for an unverified address")
+	}
+
+	*current = &auth.Claims{UserID: "cohost-user", Email: "cohost@ex.com", Name: "Cohost", Accesses: []string{"user"}}
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name, "inviteToken": token})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v)", status, body)
+	}
+	if isMod, _ := roomRepo.IsRoomModerator(room.ID, "cohost-user"); !isMod {
+		t.Fatal("expected invitee to be promoted to moderator")
+	}
+}
+
+func TestRoomInvite_RevokeAndPermissions(t *testing.T) {
+	app, roomRepo, room, current := setupInviteTestApp(t)
+	inv := createInvite(t, app, room.ID, nil)
+
+	status, _ := doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/invites", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 listing invites, got %d", status)
+	}
+
+	status, _ = doJSONRequest(t, app, http.MethodDelete, "/room/"+room.ID+"/invites/"+inv["id"].(string), nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 revoking, got %d", status)
+	}
+	if invites, _ := roomRepo.GetRoomInvites(room.ID); len(invites) != 1 || invites[0].RevokedAt == nil {
+		t.Fatalf("expected the invite to be kept as revoked, got %+v", invites)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodGet, "/room/invite/"+inv["token"].(string), nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("expected revoked invite to be gone, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client", "inviteToken": inv["token"].(string)})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for revoked invite, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/invites", map[string]interface{}{})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-owner, got %d", status)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const roomRenewMaxHours = 365 * 24
+
+// RenewRoomRequest is the optional body for POST /room/:roomId/renew.
+type RenewRoomRequest struct {
+	// Hours until the room expires; 0 uses the default room TTL.
+	Hours int `json:"hours"`
+	// Permanent makes the room never expire, when the room policy allows it.
+	Permanent bool `json:"permanent"`
+}
+
+// SetSettingsRepository gives the handler access to the system settings that
+// hold the room lifecycle policy. Without it the defaults apply.
+func (h *RoomHandler) SetSettingsRepository(sr *repository.SettingsRepository) {
+	h.settings = sr
+}
+
+// RenewRoom pushes out the room's expiry, or makes it permanent, and brings
+// it back if it was archived.
+func (h *RoomHandler) RenewRoom(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	if room.IsBreakout() {
+		return c.Status(400).JSON(fiber.Map{"error": "Breakout rooms follow their main room; renew that instead"})
+	}
+	var req RenewRoomRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	if req.Hours < 0 || req.Hours > roomRenewMaxHours {
+		return c.Status(400).JSON(fiber.Map{"error": "hours must be between 0 and one year"})
+	}
+	policy := h.roomLifecycle()
+	if req.Permanent && !policy.AllowPermanent && !containsAccess(claims.Accesses, "superadmin") {
+		return c.Status(403).JSON(fiber.Map{"error": "Permanent rooms are not allowed"})
+	}
+
+	ttl := policy.DefaultTTL
+	if req.Hours > 0 {
+		ttl = time.Duration(req.Hours) * time.Hour
+	}
+	expiresAt := time.Now().Add(ttl)
+	if err := h.roomRepo.RenewRoom(room.ID, expiresAt, req.Permanent); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to renew room")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to renew room"})
+	}
+	log.Info().Str("room", room.Name).Str("by", claims.UserID).Time("expiresAt", expiresAt).Bool("permanent", req.Permanent).Msg("Room renewed")
+	return c.JSON(fiber.Map{"status": "success", "expiresAt": expiresAt, "permanent": req.Permanent})
+}
+
+// checkRoomLifecycle turns joins to expired or archived rooms away with 410.
+// Writes the error response itself and reports whether the join may go ahead.
+func (h *RoomHandler) checkRoomLifecycle(c *fiber.Ctx, room *models.Room) bool {
+	if room.Expired(time.Now()) {
+		_ = c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "room has expired", "expiresAt": room.ExpiresAt})
+		return false
+	}
+	if room.ArchivedAt != nil {
+		_ = c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "room has been archived", "archivedAt": room.ArchivedAt})
+		return false
+	}
+	return true
+}
+
+// roomLifecycle returns the room lifecycle policy in force.
+func (h *RoomHandler) roomLifecycle() models.RoomLifecycle {
+	if h.settings != nil {
+		settings, err := h.settings.GetEffectiveSettings()
+		if err == nil {
+			return settings.RoomLifecycle()
+		}
+		log.Error().Err(err).Msg("Failed to load room lifecycle policy; using defaults")
+	}
+	return (&models.SystemSettings{}).RoomLifecycle()
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bedrud/config"
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func setupLifecycleTestApp(t *testing.T) (*fiber.App, *gorm.DB, *repository.RoomRepository, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "quarterly-review",
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Post("/room/create", h.CreateRoom)
+			app.Post("/room/:roomId/renew", h.RenewRoom)
+		},
+	})
+	settingsRepo := repository.NewSettingsRepository(f.db)
+	settingsRepo.SetConfig(&config.Config{Rooms: config.RoomsConfig{DefaultTTLHours: 48}})
+	f.handler.nodes.Default().Rooms = &fakeBreakoutRoomService{}
+	f.handler.SetSettingsRepository(settingsRepo)
+	return f.app, f.db, f.repo, f.room, &f.current
+}
+
+func TestLifecycle_ExpiredRoomGoneUntilRenewed(t *testing.T) {
+	app, db, roomRepo, room, current := setupLifecycleTestApp(t)
+	owner := *current
+	db.Model(&models.Room{}).Where("id = ?", room.ID).Update("expires_at", time.Now().Add(-time.Minute))
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != 410 || body["error"] != "room has expired" {
+		t.Fatalf("expected 410 joining an expired room, got %d: %v", status, body)
+	}
+	if status, _ = doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Gus"}); status != 410 {
+		t.Fatalf("expected 410 for a guest joining an expired room, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	if status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/renew", nil); status != 403 {
+		t.Fatalf("expected 403 for a member renewing, got %d", status)
+	}
+	*current = owner
+	if status, body = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/renew", map[string]int{"hours": 72}); status != 200 {
+		t.Fatalf("expected 200 renewing, got %d: %v", status, body)
+	}
+	renewed, _ := roomRepo.GetRoom(room.ID)
+	if renewed.ExpiresAt.Before(time.Now().Add(71 * time.Hour)) {
+		t.Errorf("expected expiry about 72h out, got %v", renewed.ExpiresAt)
+	}
+	if status, body = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name}); status != 200 {
+		t.Fatalf("expected 200 joining the renewed room, got %d: %v", status, body)
+	}
+	if renewed, _ = roomRepo.GetRoom(room.ID); renewed.LastActivityAt == nil {
+		t.Error("expected the join to record room activity")
+	}
+}
+
+func TestLifecycle_ArchivedRoomRenewAndPermanent(t *testing.T) {
+	app, _, roomRepo, room, _ := setupLifecycleTestApp(t)
+	if err := roomRepo.ArchiveRoom(room.ID); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != 410 || body["error"] != "room has been archived" {
+		t.Fatalf("expected 410 joining an archived room, got %d: %v", status, body)
+	}
+
+	if status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/renew", map[string]bool{"permanent": true}); status != 403 {
+		t.Fatalf("expected 403 making a room permanent without the policy, got %d", status)
+	}
+	if status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/renew", nil); status != 200 {
+		t.Fatalf("expected 200 renewing, got %d", status)
+	}
+	renewed, _ := roomRepo.GetRoom(room.ID)
+	if renewed.ArchivedAt != nil {
+		t.Error("expected renewal to unarchive the room")
+	}
+	if renewed.ExpiresAt.Before(time.Now().Add(47 * time.Hour)) {
+		t.Errorf("expected the configured 48h TTL, got %v", renewed.ExpiresAt)
+	}
+}
+
+func TestLifecycle_CreateRoomUsesPolicy(t *testing.T) {
+	app, _, _, _, current := setupLifecycleTestApp(t)
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/create", map[string]interface{}{"name": "policy-room"})
+	if status != 200 {
+		t.Fatalf("expected 200 creating room, got %d: %v", status, body)
+	}
+	expiresAt, _ := time.Parse(time.RFC3339Nano, body["expiresAt"].(string))
+	if expiresAt.Before(time.Now().Add(47 * time.Hour)) {
+		t.Errorf("expected the configured 48h TTL, got %v", expiresAt)
+	}
+
+	if status, _ = doJSONRequest(t, app, http.MethodPost, "/room/create", map[string]interface{}{"name": "forever-room", "permanent": true}); status != 403 {
+		t.Fatalf("expected 403 creating a permanent room without the policy, got %d", status)
+	}
+	(*current).Accesses = []string{"superadmin"}
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/create", map[string]interface{}{"name": "forever-room", "permanent": true})
+	if status != 200 || body["permanent"] != true {
+		t.Fatalf("expected a superadmin to create a permanent room, got %d: %v", status, body)
+	}
+}
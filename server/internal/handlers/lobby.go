@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Long-poll bounds for LobbyStatus.
+const (
+	lobbyMaxWait      = 30 * time.Second
+	lobbyPollInterval = time.Second
+)
+
+// LobbyEntry is the moderator-facing view of a participant waiting for approval.
+type LobbyEntry struct {
+	Identity    string    `json:"identity"`
+	Name        string    `json:"name"`
+	IsGuest     bool      `json:"isGuest"`
+	RequestedAt time.Time `json:"requestedAt"`
+}
+
+// enterLobby records a pending lobby entry, notifies the room's moderators and
+// responds with the waiting ticket the client polls with.
+func (h *RoomHandler) enterLobby(c *fiber.Ctx, room *models.Room, adminId, identity, name string) error {
+	entry, err := h.roomRepo.RequestLobbyEntry(room.ID, identity, name)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Str("identity", identity).Msg("Failed to create lobby entry")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to join lobby"})
+	}
+	h.notifyModerators(c.Context(), room, adminId, "lobby_request", identity)
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status": "waiting", "ticket": entry.LobbyTicket, "identity": identity,
+		"id": room.ID, "name": room.Name,
+	})
+}
+
+// notifyModerators sends a targeted system message to the room admin and every
+// active room moderator.
+func (h *RoomHandler) notifyModerators(ctx context.Context, room *models.Room, adminId, event, actor string) {
+	recipients := []string{adminId}
+	modIDs, err := h.roomRepo.GetRoomModeratorIDs(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list room moderators")
+	}
+	for _, id := range modIDs {
+		if id != adminId {
+			recipients = append(recipients, id)
+		}
+	}
+	ctx = h.withAuth(ctx, room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	for _, id := range recipients {
+		h.sendTargetedSystemMessage(ctx, room.Name, event, actor, id)
+	}
+}
+
+// ListLobby returns the participants waiting for approval in a room.
+func (h *RoomHandler) ListLobby(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
+		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
+	}
+	pending, err := h.roomRepo.GetLobbyEntries(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list lobby")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list lobby"})
+	}
+	entries := make([]LobbyEntry, 0, len(pending))
+	for _, p := range pending {
+		entries = append(entries, LobbyEntry{
+			Identity:    p.UserID,
+			Name:        p.DisplayName,
+			IsGuest:     strings.HasPrefix(p.UserID, "guest-"),
+			RequestedAt: p.JoinedAt,
+		})
+	}
+	return c.JSON(fiber.Map{"entries": entries})
+}
+
+// AdmitLobbyEntry lets a waiting participant into the room.
+func (h *RoomHandler) AdmitLobbyEntry(c *fiber.Ctx) error {
+	return h.resolveLobby(c, "lobby_admitted", h.roomRepo.AdmitLobbyEntry)
+}
+
+// DenyLobbyEntry turns a waiting participant away.
+func (h *RoomHandler) DenyLobbyEntry(c *fiber.Ctx) error {
+	return h.resolveLobby(c, "lobby_denied", h.roomRepo.DenyLobbyEntry)
+}
+
+func (h *RoomHandler) resolveLobby(c *fiber.Ctx, event string, apply func(roomID, userID string) error) error {
+	identity := c.Params("identity")
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
+		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
+	}
+	if err := apply(room.ID, identity); err != nil {
+		if errors.Is(err, models.ErrLobbyEntryNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error().Err(err).Str("roomID", room.ID).Str("identity", identity).Msg("Failed to update lobby entry")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update lobby entry"})
+	}
+	// Let the other moderators drop the entry from their lobby list.
+	h.notifyModerators(c.Context(), room, adminId, event, identity)
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// LobbyStatus is polled by a waiting client with its ticket. Pass ?wait=N
+// (seconds, max 30) to long-poll until a moderator decides. Once admitted the
+// response carries the LiveKit token, and the ticket is used up: a leaked
+// ticket can't be replayed for more tokens.
+func (h *RoomHandler) LobbyStatus(c *fiber.Ctx) error {
+	roomID, ticket := c.Params("roomId"), c.Params("ticket")
+	entry, err := h.roomRepo.GetLobbyEntryByTicket(roomID, ticket)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", roomID).Msg("Failed to look up lobby ticket")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up lobby ticket"})
+	}
+	if entry == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Unknown lobby ticket"})
+	}
+
+	wait := time.Duration(c.QueryInt("wait", 0)) * time.Second
+	if wait > lobbyMaxWait {
+		wait = lobbyMaxWait
+	}
+	deadline := time.Now().Add(wait)
+	ticker := time.NewTicker(lobbyPollInterval)
+	defer ticker.Stop()
+poll:
+	for entry.LobbyStatus == models.LobbyStatusPending && time.Now().Before(deadline) {
+		// fasthttp only reports a request as done when the server shuts
+		// down, not when the client goes away; lobbyMaxWait bounds the rest.
+		select {
+		case <-c.Context().Done():
+			break poll
+		case <-c.UserContext().Done():
+			break poll
+		case <-ticker.C:
+		}
+		entry, err = h.roomRepo.GetLobbyEntryByTicket(roomID, ticket)
+		if err != nil {
+			log.Error().Err(err).Str("roomID", roomID).Msg("Failed to look up lobby ticket")
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to look up lobby ticket"})
+		}
+		if entry == nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Unknown lobby ticket"})
+		}
+	}
+
+	switch entry.LobbyStatus {
+	case models.LobbyStatusDenied:
+		return c.Status(403).JSON(fiber.Map{"status": "denied", "error": "Your request to join was denied"})
+	case models.LobbyStatusAdmitted:
+	default:
+		return c.JSON(fiber.Map{"status": "waiting"})
+	}
+
+	room, adminId, err := h.resolveRoom(c, roomID)
+	if err != nil {
+		return nil
+	}
+	if entry.IsBanned {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "you are banned from this room"})
+	}
+
+	var token string
+	user, err := h.roomRepo.GetUserByID(entry.UserID)
+	switch {
+	case err == nil:
+		pub := h.publishGrantFor(room, adminId, user.ID, user.Accesses)
+		token, err = h.userJoinToken(room, user.ID, user.Name, user.Accesses, pub)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Guests have no user record.
+		pub := h.publishGrantFor(room, adminId, entry.UserID, nil)
+		token, err = h.guestJoinToken(room, entry.UserID, entry.DisplayName, pub)
+	default:
+		log.Error().Err(err).Str("userID", entry.UserID).Msg("Failed to look up admitted lobby user")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up user"})
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to sign LiveKit join token")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate room token"})
+	}
+	consumed, err := h.roomRepo.ConsumeLobbyTicket(room.ID, ticket)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to use up lobby ticket")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up lobby ticket"})
+	}
+	if !consumed {
+		// Another request was answered with a token first.
+		return c.Status(404).JSON(fiber.Map{"error": "Unknown lobby ticket"})
+	}
+	if err := h.roomRepo.AddParticipant(room.ID, entry.UserID); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Str("userID", entry.UserID).Msg("AddParticipant failed")
+	}
+
+	resp := fiber.Map{
+		"status": "admitted", "id": room.ID, "name": room.Name, "token": token, "createdBy": room.CreatedBy,
+		"adminId": adminId, "settings": room.Settings, "livekitHost": h.node(room).Host, "mode": room.Mode,
+	}
+	if !h.addE2EEKey(c, room, strings.HasPrefix(entry.UserID, "guest-"), resp) {
+		return nil
+	}
+	return c.JSON(resp)
+}
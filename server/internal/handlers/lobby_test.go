@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// setupLobbyTestApp wires the lobby routes with a switchable caller identity.
+func setupLobbyTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name:     "lobby-room",
+		settings: models.RoomSettings{RequireApproval: true},
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/lobby", h.ListLobby)
+			app.Post("/room/:roomId/lobby/:identity/admit", h.AdmitLobbyEntry)
+			app.Post("/room/:roomId/lobby/:identity/deny", h.DenyLobbyEntry)
+			app.Get("/room/:roomId/lobby/ticket/:ticket", h.LobbyStatus)
+		},
+	})
+	f.addUser("member-user", "member@ex.com", "Member")
+	return f.app, f.repo, f.room, &f.current
+}
+
+func doJSONRequest(t *testing.T, app *fiber.App, method, path string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var out map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func TestLobby_GuestWaitsThenAdmitted(t *testing.T) {
+	app, _, room, _ := setupLobbyTestApp(t)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Visitor"})
+	if status != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d (%v)", status, body)
+	}
+	if _, ok := body["token"]; ok {
+		t.Fatal("waiting guest must not receive a token")
+	}
+	ticket, _ := body["ticket"].(string)
+	identity, _ := body["identity"].(string)
+	if ticket == "" || identity == "" {
+		t.Fatalf("expected ticket and identity, got %v", body)
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/lobby/ticket/"+ticket, nil)
+	if status != http.StatusOK || body["status"] != "waiting" {
+		t.Fatalf("expected waiting, got %d %v", status, body)
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/lobby", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 listing lobby, got %d", status)
+	}
+	entries, _ := body["entries"].([]interface{})
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 lobby entry, got %d", len(entries))
+	}
+
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/lobby/"+identity+"/admit", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 on admit, got %d", status)
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/lobby/ticket/"+ticket, nil)
+	if status != http.StatusOK || body["status"] != "admitted" {
+		t.Fatalf("expected admitted, got %d %v", status, body)
+	}
+	if tok, _ := body["token"].(string); tok == "" {
+		t.Fatal("expected a token once admitted")
+	}
+
+	// The ticket hands out one token only.
+	status, _ = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/lobby/ticket/"+ticket, nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404 replaying the ticket, got %d", status)
+	}
+}
+
+func TestLobby_MemberDenied(t *testing.T) {
+	app, _, room, current := setupLobbyTestApp(t)
+	owner := *current
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d (%v)", status, body)
+	}
+	ticket, _ := body["ticket"].(string)
+
+	// A waiting member cannot moderate the lobby.
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/lobby/member-user/admit", nil)
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-moderator admit, got %d", status)
+	}
+
+	*current = owner
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/lobby/member-user/deny", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 on deny, got %d", status)
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/lobby/ticket/"+ticket, nil)
+	if status != http.StatusForbidden || body["status"] != "denied" {
+		t.Fatalf("expected denied, got %d %v", status, body)
+	}
+
+	// Denying again finds no pending entry.
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/lobby/member-user/deny", nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404 for resolved entry, got %d", status)
+	}
+}
+
+func TestLobby_OwnerBypassesAndApprovedMemberRejoins(t *testing.T) {
+	app, roomRepo, room, current := setupLobbyTestApp(t)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("owner should join directly, got %d %v", status, body)
+	}
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	if _, err := roomRepo.RequestLobbyEntry(room.ID, "member-user", "Member"); err != nil {
+		t.Fatalf("RequestLobbyEntry: %v", err)
+	}
+	if err := roomRepo.AdmitLobbyEntry(room.ID, "member-user"); err != nil {
+		t.Fatalf("AdmitLobbyEntry: %v", err)
+	}
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("approved member should join directly, got %d %v", status, body)
+	}
+}
+
+func TestLobby_UnknownTicket(t *testing.T) {
+	app, _, room, _ := setupLobbyTestApp(t)
+	status, _ := doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/lobby/ticket/nope", nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", status)
+	}
+}
+
+func TestLobby_StatusLongPollEndsWithRequestContext(t *testing.T) {
+	f := newRoomFixture(t, roomFixtureOptions{
+		name:     "lobby-room",
+		settings: models.RoomSettings{RequireApproval: true},
+		public: func(app *fiber.App, h *RoomHandler) {
+			app.Use(func(c *fiber.Ctx) error {
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+				c.SetUserContext(ctx)
+				return c.Next()
+			})
+		},
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/lobby/ticket/:ticket", h.LobbyStatus)
+		},
+	})
+	entry, err := f.repo.RequestLobbyEntry(f.room.ID, "guest-visitor", "Visitor")
+	if err != nil {
+		t.Fatalf("RequestLobbyEntry: %v", err)
+	}
+
+	start := time.Now()
+	status, body := doJSONRequest(t, f.app, http.MethodGet, "/room/"+f.room.ID+"/lobby/ticket/"+entry.LobbyTicket+"?wait=30", nil)
+	if status != http.StatusOK || body["status"] != "waiting" {
+		t.Fatalf("expected waiting, got %d %v", status, body)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the long poll to end with its context, took %v", elapsed)
+	}
+}
+
+func TestLobby_StatusFailsWhenUserLookupFails(t *testing.T) {
+	f := newRoomFixture(t, roomFixtureOptions{
+		name:     "lobby-room",
+		settings: models.RoomSettings{RequireApproval: true},
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/lobby/ticket/:ticket", h.LobbyStatus)
+		},
+	})
+	f.addUser("member-user", "member@ex.com", "Member")
+	entry, err := f.repo.RequestLobbyEntry(f.room.ID, "member-user", "Member")
+	if err != nil {
+		t.Fatalf("RequestLobbyEntry: %v", err)
+	}
+	if err := f.repo.AdmitLobbyEntry(f.room.ID, "member-user"); err != nil {
+		t.Fatalf("AdmitLobbyEntry: %v", err)
+	}
+	if err := f.db.Migrator().DropTable(&models.User{}); err != nil {
+		t.Fatalf("DropTable: %v", err)
+	}
+
+	// A failed lookup must not hand the member a guest token.
+	status, body := doJSONRequest(t, f.app, http.MethodGet, "/room/"+f.room.ID+"/lobby/ticket/"+entry.LobbyTicket, nil)
+	if status != http.StatusInternalServerError || body["token"] != nil {
+		t.Fatalf("expected 500 without a token, got %d %v", status, body)
+	}
+}
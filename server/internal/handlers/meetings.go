@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/blocklist"
+	"bedrud/internal/models"
+	"bedrud/internal/schedule"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	meetingMaxTitle       = 255
+	meetingMaxDescription = 5000
+	meetingMaxMinutes     = 24 * 60
+	meetingMaxEarlyJoin   = 7 * 24 * 60
+	// meetingOpenEndedDays is how far ahead a room is kept alive for a series
+	// without an end. Rooms are extended again whenever the series is edited.
+	meetingOpenEndedDays = 366
+)
+
+// ScheduleMeetingRequest is the body for POST /room/:roomId/meetings and
+// PUT /room/:roomId/meetings/:meetingId.
+type ScheduleMeetingRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// StartAt is an RFC 3339 timestamp, or a local date and time such as
+	// "2026-05-04T09:30" read in TimeZone.
+	StartAt         string `json:"startAt"`
+	DurationMinutes int    `json:"durationMinutes"`
+	// TimeZone is an IANA zone name such as "Europe/Berlin".
+	TimeZone string `json:"timeZone"`
+	// RRule is an optional RFC 5545 recurrence rule, e.g. "FREQ=WEEKLY;BYDAY=MO".
+	RRule string `json:"rrule"`
+	// EarlyJoinMinutes, when set, refuses joins more than this many minutes
+	// before an occurrence starts.
+	EarlyJoinMinutes *int `json:"earlyJoinMinutes"`
+}
+
+// MeetingResponse is a scheduled meeting with its current or next occurrence.
+type MeetingResponse struct {
+	models.ScheduledMeeting
+	NextStartAt *time.Time `json:"nextStartAt"`
+}
+
+// CreateMeeting schedules a one-off or recurring meeting in the room.
+func (h *RoomHandler) CreateMeeting(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	m := &models.ScheduledMeeting{RoomID: room.ID, CreatedBy: claims.UserID}
+	if !parseMeetingRequest(c, m) {
+		return nil
+	}
+	if err := h.roomRepo.CreateScheduledMeeting(m); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to create scheduled meeting")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to schedule meeting"})
+	}
+	h.keepRoomForMeeting(room, m)
+	return c.Status(201).JSON(meetingResponse(m, time.Now()))
+}
+
+// ListMeetings returns the room's schedule to anyone who may enter the room.
+func (h *RoomHandler) ListMeetings(c *fiber.Ctx) error {
+	room, ok := h.resolveMeetingRoom(c)
+	if !ok {
+		return nil
+	}
+	meetings, err := h.roomRepo.GetScheduledMeetings(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list scheduled meetings")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list meetings"})
+	}
+	now := time.Now()
+	out := make([]MeetingResponse, 0, len(meetings))
+	for i := range meetings {
+		out = append(out, meetingResponse(&meetings[i], now))
+	}
+	return c.JSON(out)
+}
+
+// UpdateMeeting replaces a scheduled meeting's details.
+func (h *RoomHandler) UpdateMeeting(c *fiber.Ctx) error {
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	m, ok := h.loadMeeting(c, room)
+	if !ok {
+		return nil
+	}
+	if !parseMeetingRequest(c, m) {
+		return nil
+	}
+	if err := h.roomRepo.UpdateScheduledMeeting(m); err != nil {
+		log.Error().Err(err).Str("meetingID", m.ID).Msg("Failed to update scheduled meeting")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update meeting"})
+	}
+	h.keepRoomForMeeting(room, m)
+	return c.JSON(meetingResponse(m, time.Now()))
+}
+
+// DeleteMeeting removes a meeting from the room's schedule.
+func (h *RoomHandler) DeleteMeeting(c *fiber.Ctx) error {
+	room, ok := h.resolveRoomAdmin(c)
+	if !ok {
+		return nil
+	}
+	found, err := h.roomRepo.DeleteScheduledMeeting(room.ID, c.Params("meetingId"))
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to delete scheduled meeting")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to delete meeting"})
+	}
+	if !found {
+		return c.Status(404).JSON(fiber.Map{"error": "Meeting not found"})
+	}
+	return c.JSON(fiber.Map{"status": "success"})
+}
+
+// MeetingICS downloads a single meeting as an .ics file.
+func (h *RoomHandler) MeetingICS(c *fiber.Ctx) error {
+	room, ok := h.resolveMeetingRoom(c)
+	if !ok {
+		return nil
+	}
+	m, ok := h.loadMeeting(c, room)
+	if !ok {
+		return nil
+	}
+	start, _, err := schedule.Series(m)
+	if err != nil {
+		log.Error().Err(err).Str("meetingID", m.ID).Msg("Stored meeting has an invalid schedule")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to render meeting"})
+	}
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="meeting-`+m.ID+`.ics"`)
+	return c.Send(schedule.Calendar("", []schedule.Event{meetingEvent(c, room, m, start)}))
+}
+
+// GetCalendarFeed creates the caller's personal calendar subscription URL on
+// first use. Only the token's hash is kept, so once the feed exists the URL
+// can't be shown again; rotating it issues a new one.
+func (h *RoomHandler) GetCalendarFeed(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	feed, err := h.roomRepo.GetCalendarFeed(claims.UserID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", claims.UserID).Msg("Failed to look up calendar feed")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up calendar feed"})
+	}
+	if feed == nil {
+		return h.issueCalendarFeed(c, claims.UserID)
+	}
+	return c.JSON(fiber.Map{"createdAt": feed.CreatedAt})
+}
+
+// RotateCalendarFeed replaces the caller's subscription URL; the old one
+// stops working.
+func (h *RoomHandler) RotateCalendarFeed(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	return h.issueCalendarFeed(c, claims.UserID)
+}
+
+func (h *RoomHandler) issueCalendarFeed(c *fiber.Ctx, userID string) error {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate secure token"})
+	}
+	token := hex.EncodeToString(b)
+	feed, err := h.roomRepo.SaveCalendarFeed(userID, token)
+	if err != nil {
+		log.Error().Err(err).Str("userID", userID).Msg("Failed to save calendar feed")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to create calendar feed"})
+	}
+	return c.JSON(fiber.Map{"url": calendarFeedURL(c, token), "createdAt": feed.CreatedAt})
+}
+
+// CalendarFeed serves a user's subscription feed: the upcoming meetings of
+// the rooms they own or are on the access list of. The token in the URL is
+// the only credential, since calendar clients can't send a bearer token.
+func (h *RoomHandler) CalendarFeed(c *fiber.Ctx) error {
+	feed, err := h.roomRepo.GetCalendarFeedByToken(c.Params("token"))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to look up calendar feed")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up calendar feed"})
+	}
+	if feed == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Calendar feed not found"})
+	}
+	user, err := h.roomRepo.GetUserByID(feed.UserID)
+	if err != nil || !user.IsActive || blocklist.Match(user.ID, user.Email, "") != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Calendar feed not found"})
+	}
+
+	owned, err := h.roomRepo.GetRoomsCreatedByUser(user.ID)
+	if err != nil {
+		log.Error().Err(err).Str("userID", user.ID).Msg("Failed to list rooms for calendar feed")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to build calendar feed"})
+	}
+	shared, err := h.roomRepo.GetRoomsSharedWithUser(user.ID, user.Accesses)
+	if err != nil {
+		log.Error().Err(err).Str("userID", user.ID).Msg("Failed to list rooms for calendar feed")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to build calendar feed"})
+	}
+	rooms := make(map[string]*models.Room)
+	ids := make([]string, 0, len(owned)+len(shared))
+	for _, list := range [][]models.Room{owned, shared} {
+		for i := range list {
+			rooms[list[i].ID] = &list[i]
+			ids = append(ids, list[i].ID)
+		}
+	}
+	meetings, err := h.roomRepo.GetScheduledMeetings(ids...)
+	if err != nil {
+		log.Error().Err(err).Str("userID", user.ID).Msg("Failed to list meetings for calendar feed")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to build calendar feed"})
+	}
+
+	now := time.Now()
+	events := make([]schedule.Event, 0, len(meetings))
+	for i := range meetings {
+		m := &meetings[i]
+		if _, upcoming := schedule.NextOccurrence(m, now); !upcoming {
+			continue
+		}
+		start, _, err := schedule.Series(m)
+		if err != nil {
+			continue
+		}
+		events = append(events, meetingEvent(c, rooms[m.RoomID], m, start))
+	}
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return c.Send(schedule.Calendar("Bedrud meetings", events))
+}
+
+// checkMeetingWindow refuses joins that come too early for the room's
+// schedule. A room is closed only while every upcoming meeting in it has an
+// early-join limit and none of them has opened yet. Writes the error response
+// itself and reports whether the join may go ahead.
+func (h *RoomHandler) checkMeetingWindow(c *fiber.Ctx, room *models.Room) bool {
+	meetings, err := h.roomRepo.GetScheduledMeetings(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to load room schedule")
+		_ = c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to check room schedule"})
+		return false
+	}
+	now := time.Now()
+	var opensAt, startsAt time.Time
+	for i := range meetings {
+		m := &meetings[i]
+		next, ok := schedule.NextOccurrence(m, now)
+		if !ok {
+			continue
+		}
+		if m.EarlyJoinMinutes == nil {
+			return true
+		}
+		open := next.Add(-time.Duration(*m.EarlyJoinMinutes) * time.Minute)
+		if !now.Before(open) {
+			return true
+		}
+		if opensAt.IsZero() || open.Before(opensAt) {
+			opensAt, startsAt = open, next
+		}
+	}
+	if opensAt.IsZero() {
+		return true
+	}
+	_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error":    "This meeting has not started yet",
+		"startsAt": startsAt,
+		"opensAt":  opensAt,
+	})
+	return false
+}
+
+// resolveMeetingRoom loads the room from the route and checks the caller may
+// enter it. Writes the error response itself.
+func (h *RoomHandler) resolveMeetingRoom(c *fiber.Ctx) (*models.Room, bool) {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil, false
+	}
+	if !room.IsPublic {
+		allowed, err := h.canEnterPrivateRoom(room, adminId, claims)
+		if err != nil {
+			log.Error().Err(err).Str("roomID", room.ID).Str("userID", claims.UserID).Msg("Failed to check room access")
+			_ = c.Status(500).JSON(fiber.Map{"error": "Failed to check room access"})
+			return nil, false
+		}
+		if !allowed {
+			_ = c.Status(403).JSON(fiber.Map{"error": "This room is private"})
+			return nil, false
+		}
+	}
+	return room, true
+}
+
+// loadMeeting loads the meeting from the route, answering 404 unless it
+// belongs to room.
+func (h *RoomHandler) loadMeeting(c *fiber.Ctx, room *models.Room) (*models.ScheduledMeeting, bool) {
+	m, err := h.roomRepo.GetScheduledMeeting(c.Params("meetingId"))
+	if err != nil {
+		log.Error().Err(err).Str("meetingID", c.Params("meetingId")).Msg("Failed to look up scheduled meeting")
+		_ = c.Status(500).JSON(fiber.Map{"error": "Failed to look up meeting"})
+		return nil, false
+	}
+	if m == nil || m.RoomID != room.ID {
+		_ = c.Status(404).JSON(fiber.Map{"error": "Meeting not found"})
+		return nil, false
+	}
+	return m, true
+}
+
+// keepRoomForMeeting pushes the room's expiry past the end of the series so
+// the room is still there when the meeting takes place.
+func (h *RoomHandler) keepRoomForMeeting(room *models.Room, m *models.ScheduledMeeting) {
+	until, ok := schedule.SeriesEnd(m)
+	if !ok {
+		until = time.Now().AddDate(0, 0, meetingOpenEndedDays)
+	}
+	if err := h.roomRepo.ExtendRoomExpiry(room.ID, until); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to extend room expiry for meeting")
+	}
+}
+
+// parseMeetingRequest validates the body into m. Writes the error response
+// itself and reports whether m was filled in.
+func parseMeetingRequest(c *fiber.Ctx, m *models.ScheduledMeeting) bool {
+	var req ScheduleMeetingRequest
+	if err := c.BodyParser(&req); err != nil {
+		_ = c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		return false
+	}
+	fail := func(msg string) bool {
+		_ = c.Status(400).JSON(fiber.Map{"error": msg})
+		return false
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	req.Description = strings.TrimSpace(req.Description)
+	req.RRule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(req.RRule)), "RRULE:")
+	switch {
+	case req.Title == "":
+		return fail("Title is required")
+	case len(req.Title) > meetingMaxTitle:
+		return fail("Title is too long")
+	case len(req.Description) > meetingMaxDescription:
+		return fail("Description is too long")
+	case req.DurationMinutes < 1 || req.DurationMinutes > meetingMaxMinutes:
+		return fail("durationMinutes must be between 1 and 1440")
+	case req.EarlyJoinMinutes != nil && (*req.EarlyJoinMinutes < 0 || *req.EarlyJoinMinutes > meetingMaxEarlyJoin):
+		return fail("earlyJoinMinutes must be between 0 and one week")
+	}
+	if req.TimeZone == "" {
+		req.TimeZone = "UTC"
+	}
+	loc, err := time.LoadLocation(req.TimeZone)
+	if err != nil || req.TimeZone == "Local" {
+		return fail("timeZone must be an IANA time zone such as Europe/Berlin")
+	}
+	start, ok := parseMeetingStart(req.StartAt, loc)
+	if !ok {
+		return fail("startAt must be an RFC 3339 timestamp or a local date and time")
+	}
+	if req.RRule != "" {
+		if _, err := schedule.ParseRule(req.RRule); err != nil {
+			return fail(err.Error())
+		}
+	}
+
+	m.Title = req.Title
+	m.Description = req.Description
+	m.StartAt = start
+	m.DurationMinutes = req.DurationMinutes
+	m.TimeZone = loc.String()
+	m.RRule = req.RRule
+	m.EarlyJoinMinutes = req.EarlyJoinMinutes
+	return true
+}
+
+func parseMeetingStart(s string, loc *time.Location) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(loc).Truncate(time.Second), true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func meetingResponse(m *models.ScheduledMeeting, now time.Time) MeetingResponse {
+	resp := MeetingResponse{ScheduledMeeting: *m}
+	if next, ok := schedule.NextOccurrence(m, now); ok {
+		resp.NextStartAt = &next
+	}
+	return resp
+}
+
+func meetingEvent(c *fiber.Ctx, room *models.Room, m *models.ScheduledMeeting, start time.Time) schedule.Event {
+	return schedule.Event{
+		UID:         m.ID + "@bedrud",
+		Summary:     m.Title,
+		Description: m.Description,
+		URL:         c.BaseURL() + "/m/" + room.Name,
+		Start:       start,
+		Duration:    m.Duration(),
+		RRule:       m.RRule,
+		Created:     m.CreatedAt,
+	}
+}
+
+func calendarFeedURL(c *fiber.Ctx, token string) string {
+	return c.BaseURL() + "/api/calendar/" + token + "/feed.ics"
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupMeetingTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "weekly-sync",
+		public: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/calendar/:token/feed.ics", h.CalendarFeed)
+		},
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/meetings", h.ListMeetings)
+			app.Post("/room/:roomId/meetings", h.CreateMeeting)
+			app.Put("/room/:roomId/meetings/:meetingId", h.UpdateMeeting)
+			app.Delete("/room/:roomId/meetings/:meetingId", h.DeleteMeeting)
+			app.Get("/room/:roomId/meetings/:meetingId/ics", h.MeetingICS)
+			app.Get("/calendar/feed", h.GetCalendarFeed)
+			app.Post("/calendar/feed/rotate", h.RotateCalendarFeed)
+		},
+	})
+	return f.app, f.repo, f.room, &f.current
+}
+
+func getBody(t *testing.T, app *fiber.App, path string) (int, string) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestMeetings_EarlyJoinWindow(t *testing.T) {
+	app, roomRepo, room, current := setupMeetingTestApp(t)
+	owner := *current
+	member := &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	start := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/meetings", map[string]interface{}{
+		"title": "Sync", "startAt": start, "durationMinutes": 30, "timeZone": "UTC", "earlyJoinMinutes": 10,
+	})
+	if status != 201 {
+		t.Fatalf("expected 201 creating meeting, got %d: %v", status, body)
+	}
+	meetingID := body["id"].(string)
+	updated, _ := roomRepo.GetRoom(room.ID)
+	if updated.ExpiresAt.Before(time.Now().Add(2 * time.Hour)) {
+		t.Errorf("expected room expiry to cover the meeting, got %v", updated.ExpiresAt)
+	}
+
+	*current = member
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != 403 || body["startsAt"] == nil {
+		t.Fatalf("expected 403 with startsAt joining early, got %d: %v", status, body)
+	}
+	*current = owner
+	if status, body = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name}); status != 200 {
+		t.Fatalf("expected the owner to join early, got %d: %v", status, body)
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodPut, "/room/"+room.ID+"/meetings/"+meetingID, map[string]interface{}{
+		"title": "Sync", "startAt": time.Now().Add(5 * time.Minute).UTC().Format(time.RFC3339),
+		"durationMinutes": 30, "timeZone": "UTC", "earlyJoinMinutes": 10,
+	})
+	if status != 200 {
+		t.Fatalf("expected 200 updating meeting, got %d: %v", status, body)
+	}
+	*current = member
+	if status, body = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name}); status != 200 {
+		t.Fatalf("expected 200 joining within the window, got %d: %v", status, body)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodDelete, "/room/"+room.ID+"/meetings/"+meetingID, nil)
+	if status != 403 {
+		t.Errorf("expected 403 for a member deleting a meeting, got %d", status)
+	}
+}
+
+func TestMeetings_Validation(t *testing.T) {
+	app, _, room, _ := setupMeetingTestApp(t)
+	for _, body := range []map[string]interface{}{
+		{"title": "", "startAt": "2026-05-04T09:30", "durationMinutes": 30},
+		{"title": "Sync", "startAt": "tomorrow", "durationMinutes": 30},
+		{"title": "Sync", "startAt": "2026-05-04T09:30", "durationMinutes": 0},
+		{"title": "Sync", "startAt": "2026-05-04T09:30", "durationMinutes": 30, "timeZone": "Mars/Olympus"},
+		{"title": "Sync", "startAt": "2026-05-04T09:30", "durationMinutes": 30, "rrule": "FREQ=HOURLY"},
+	} {
+		if status, resp := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/meetings", body); status != 400 {
+			t.Errorf("expected 400 for %v, got %d: %v", body, status, resp)
+		}
+	}
+}
+
+func TestMeetings_ICSAndFeed(t *testing.T) {
+	app, roomRepo, room, _ := setupMeetingTestApp(t)
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/meetings", map[string]interface{}{
+		"title": "Team sync", "startAt": "2026-01-05T09:30", "durationMinutes": 45,
+		"timeZone": "Europe/Berlin", "rrule": "FREQ=WEEKLY;BYDAY=MO",
+	})
+	if status != 201 {
+		t.Fatalf("expected 201 creating meeting, got %d: %v", status, body)
+	}
+	if body["nextStartAt"] == nil {
+		t.Errorf("expected an open-ended series to have a next occurrence")
+	}
+
+	status, ics := getBody(t, app, "/room/"+room.ID+"/meetings/"+body["id"].(string)+"/ics")
+	if status != 200 {
+		t.Fatalf("expected 200 downloading ics, got %d", status)
+	}
+	for _, want := range []string{"TZID:Europe/Berlin", "DTSTART;TZID=Europe/Berlin:20260105T093000", "RRULE:FREQ=WEEKLY;BYDAY=MO", "DURATION:PT45M", "/m/" + room.Name} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("ics is missing %q:\n%s", want, ics)
+		}
+	}
+
+	_, feed := doJSONRequest(t, app, http.MethodGet, "/calendar/feed", nil)
+	url, _ := feed["url"].(string)
+	path := url[strings.Index(url, "/calendar/"):]
+	if _, again := doJSONRequest(t, app, http.MethodGet, "/calendar/feed", nil); again["url"] != nil || again["createdAt"] == nil {
+		t.Errorf("expected the existing feed without its URL, got %v", again)
+	}
+	if stored, _ := roomRepo.GetCalendarFeed("owner-user"); stored == nil || strings.Contains(url, stored.TokenHash) {
+		t.Error("expected the feed token to be stored hashed")
+	}
+	if status, ics = getBody(t, app, path); status != 200 || !strings.Contains(ics, "SUMMARY:Team sync") {
+		t.Fatalf("expected the feed to list the meeting, got %d:\n%s", status, ics)
+	}
+
+	_, rotated := doJSONRequest(t, app, http.MethodPost, "/calendar/feed/rotate", nil)
+	if rotated["url"] == url {
+		t.Fatal("expected rotation to change the feed URL")
+	}
+	if status, _ = getBody(t, app, path); status != 404 {
+		t.Errorf("expected 404 for the old feed URL, got %d", status)
+	}
+}
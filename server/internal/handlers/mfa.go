@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// mfaError maps MFA service errors to responses.
+func mfaError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, auth.ErrMFAInvalidCode):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid two-factor code"})
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled), errors.Is(err, auth.ErrMFANotEnabled), errors.Is(err, auth.ErrMFAUnavailable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, auth.ErrMFARequiredByPolicy):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Error().Err(err).Msg(fallback)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+}
+
+// MFAStatus returns the caller's two-factor setup.
+func (h *AuthHandler) MFAStatus(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	status, err := h.authService.MFAStatus(claims.UserID)
+	if err != nil {
+		return mfaError(c, err, "Failed to load two-factor status")
+	}
+	return c.JSON(status)
+}
+
+// EnrollMFA creates a TOTP secret for the caller. The response carries the
+// otpauth:// URI to show as a QR code.
+func (h *AuthHandler) EnrollMFA(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	enrollment, err := h.authService.BeginMFAEnrollment(claims.UserID)
+	if err != nil {
+		return mfaError(c, err, "Failed to start two-factor enrollment")
+	}
+	return c.JSON(enrollment)
+}
+
+// EnableMFA confirms enrollment with a code and returns the recovery codes.
+func (h *AuthHandler) EnableMFA(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	var input mfaCodeRequest
+	if err := c.BodyParser(&input); err != nil || input.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code is required"})
+	}
+	codes, err := h.authService.EnableMFA(claims.UserID, input.Code)
+	if err != nil {
+		return mfaError(c, err, "Failed to enable two-factor authentication")
+	}
+	return c.JSON(fiber.Map{"recoveryCodes": codes})
+}
+
+// DisableMFA turns two-factor authentication off for the caller.
+func (h *AuthHandler) DisableMFA(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	var input mfaCodeRequest
+	if err := c.BodyParser(&input); err != nil || input.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code is required"})
+	}
+	if err := h.authService.DisableMFA(claims.UserID, input.Code); err != nil {
+		return mfaError(c, err, "Failed to disable two-factor authentication")
+	}
+	return c.JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	var input mfaCodeRequest
+	if err := c.BodyParser(&input); err != nil || input.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code is required"})
+	}
+	codes, err := h.authService.RegenerateRecoveryCodes(claims.UserID, input.Code)
+	if err != nil {
+		return mfaError(c, err, "Failed to generate recovery codes")
+	}
+	return c.JSON(fiber.Map{"recoveryCodes": codes})
+}
+
+// VerifyMFA finishes a password login with a TOTP or recovery code.
+func (h *AuthHandler) VerifyMFA(c *fiber.Ctx) error {
+	var input struct {
+		MFAToken string `json:"mfaToken"`
+		Code     string `json:"code"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.MFAToken == "" || input.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "MFA token and code are required"})
+	}
+	login, err := h.authService.VerifyMFA(input.MFAToken, input.Code, sessionInfo(c))
+	if err != nil {
+		if errors.Is(err, auth.ErrMFAInvalidCode) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid two-factor code"})
+		}
+		if errors.Is(err, auth.ErrMFALocked) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+	setAuthCookies(c, h.config, login.Token.AccessToken, login.Token.RefreshToken)
+	return c.JSON(login)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/totp"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupMFATestApp(t *testing.T) (*fiber.App, *auth.AuthService) {
+	t.Helper()
+	app, authService, cfg := setupAuthTestApp(t)
+	authHandler := NewAuthHandler(authService, cfg, nil, nil)
+
+	protected := func(c *fiber.Ctx) error {
+		claims, err := auth.ValidateToken(strings.TrimPrefix(c.Get("Authorization"), bearerPrefix), cfg)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "invalid"})
+		}
+		c.Locals("user", claims)
+		return c.Next()
+	}
+	app.Get("/api/auth/mfa", protected, authHandler.MFAStatus)
+	app.Post("/api/auth/mfa/enroll", protected, authHandler.EnrollMFA)
+	app.Post("/api/auth/mfa/enable", protected, authHandler.EnableMFA)
+	app.Post("/api/auth/mfa/verify", authHandler.VerifyMFA)
+	return app, authService
+}
+
+func TestMFA_EnrollAndSignIn(t *testing.T) {
+	app, authService := setupMFATestApp(t)
+	_, _ = authService.Register("totp@example.com", "a-long-password", "TOTP")
+	credentials := map[string]string{"email": "totp@example.com", "password": "a-long-password"}
+
+	_, raw := sessionRequest(t, app, http.MethodPost, "/api/auth/login", "", "", credentials)
+	var first auth.LoginResponse
+	_ = json.Unmarshal(raw, &first)
+
+	status, raw := sessionRequest(t, app, http.MethodPost, "/api/auth/mfa/enroll", first.Token.AccessToken, "", nil)
+	var enrollment auth.MFAEnrollment
+	_ = json.Unmarshal(raw, &enrollment)
+	if status != http.StatusOK || !strings.HasPrefix(enrollment.URI, "otpauth://totp/") {
+		t.Fatalf("expected an enrollment, got %d: %s", status, raw)
+	}
+	if status, _ = sessionRequest(t, app, http.MethodPost, "/api/auth/mfa/enable", first.Token.AccessToken, "", map[string]string{"code": "000000x"}); status != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong code to be rejected, got %d", status)
+	}
+	code, _ := totp.Code(enrollment.Secret, totp.Step(time.Now())-1)
+	status, raw = sessionRequest(t, app, http.MethodPost, "/api/auth/mfa/enable", first.Token.AccessToken, "", map[string]string{"code": code})
+	if status != http.StatusOK || !strings.Contains(string(raw), "recoveryCodes") {
+		t.Fatalf("expected recovery codes, got %d: %s", status, raw)
+	}
+
+	status, raw = sessionRequest(t, app, http.MethodPost, "/api/auth/login", "", "", credentials)
+	var challenge struct {
+		MFARequired bool   `json:"mfaRequired"`
+		MFAToken    string `json:"mfaToken"`
+	}
+	_ = json.Unmarshal(raw, &challenge)
+	if status != http.StatusOK || !challenge.MFARequired || strings.Contains(string(raw), "accessToken") {
+		t.Fatalf("expected an MFA challenge without tokens, got %d: %s", status, raw)
+	}
+
+	code, _ = totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	status, raw = sessionRequest(t, app, http.MethodPost, "/api/auth/mfa/verify", "", "", map[string]string{"mfaToken": challenge.MFAToken, "code": code})
+	var login auth.LoginResponse
+	_ = json.Unmarshal(raw, &login)
+	if status != http.StatusOK || login.Token.AccessToken == "" {
+		t.Fatalf("expected to be signed in, got %d: %s", status, raw)
+	}
+
+	status, raw = sessionRequest(t, app, http.MethodGet, "/api/auth/mfa", login.Token.AccessToken, "", nil)
+	if status != http.StatusOK || !strings.Contains(string(raw), `"enabled":true`) {
+		t.Fatalf("expected MFA to be enabled, got %d: %s", status, raw)
+	}
+}
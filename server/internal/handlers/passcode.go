@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bedrud/internal/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passcodeMinLength = 4
+	// bcrypt ignores everything past 72 bytes.
+	passcodeMaxLength = 72
+
+	// Failed attempts allowed per window for one client on one room, and for
+	// one room across all clients.
+	passcodeMaxClientFailures = 5
+	passcodeMaxRoomFailures   = 50
+	passcodeFailureWindow     = 15 * time.Minute
+)
+
+// passcodeThrottle counts failed passcode attempts in fixed windows. It sits
+// behind GuestRateLimiter, which only limits requests per IP overall.
+type passcodeThrottle struct {
+	mu       sync.Mutex
+	failures map[string]*failureWindow
+}
+
+type failureWindow struct {
+	count int
+	reset time.Time
+}
+
+func newPasscodeThrottle() *passcodeThrottle {
+	return &passcodeThrottle{failures: map[string]*failureWindow{}}
+}
+
+// blocked reports whether key has reached max failures in its current window.
+func (t *passcodeThrottle) blocked(key string, max int, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.failures[key]
+	return ok && now.Before(w.reset) && w.count >= max
+}
+
+// fail records a failed attempt against each key.
+func (t *passcodeThrottle) fail(now time.Time, keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range keys {
+		w, ok := t.failures[key]
+		if !ok || !now.Before(w.reset) {
+			w = &failureWindow{reset: now.Add(passcodeFailureWindow)}
+			t.failures[key] = w
+		}
+		w.count++
+	}
+	if len(t.failures) > 10000 {
+		for key, w := range t.failures {
+			if !now.Before(w.reset) {
+				delete(t.failures, key)
+			}
+		}
+	}
+}
+
+// clear forgets the failures recorded for key.
+func (t *passcodeThrottle) clear(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
+
+// clearRoom forgets every failure recorded for a room, e.g. after its
+// passcode was rotated.
+func (t *passcodeThrottle) clearRoom(roomID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.failures {
+		if key == roomID || strings.HasPrefix(key, roomID+"|") {
+			delete(t.failures, key)
+		}
+	}
+}
+
+// hashPasscode hashes a room passcode the same way user passwords are hashed.
+func hashPasscode(passcode string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(passcode), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// checkPasscode verifies the passcode for a room that has one. On failure it
+// writes the response and returns false; 403 responses carry
+// passcodeRequired so clients know to prompt for it.
+func (h *RoomHandler) checkPasscode(c *fiber.Ctx, room *models.Room, passcode string) bool {
+	if !room.HasPasscode {
+		return true
+	}
+	now := time.Now()
+	clientKey, roomKey := room.ID+"|"+c.IP(), room.ID
+	if h.passcodes.blocked(clientKey, passcodeMaxClientFailures, now) || h.passcodes.blocked(roomKey, passcodeMaxRoomFailures, now) {
+		_ = c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many passcode attempts, please try again later"})
+		return false
+	}
+	if passcode == "" {
+		_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "passcode required", "passcodeRequired": true})
+		return false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(room.PasscodeHash), []byte(passcode)) != nil {
+		h.passcodes.fail(now, clientKey, roomKey)
+		_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "invalid passcode", "passcodeRequired": true})
+		return false
+	}
+	h.passcodes.clear(clientKey)
+	return true
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupPasscodeTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "client-call",
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Put("/room/:roomId/settings", h.UpdateSettings)
+		},
+	})
+	status, body := doJSONRequest(t, f.app, http.MethodPut, "/room/"+f.room.ID+"/settings", map[string]string{"passcode": "open-sesame"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 setting passcode, got %d (%v)", status, body)
+	}
+	if body["hasPasscode"] != true {
+		t.Fatalf("expected hasPasscode in response, got %v", body)
+	}
+	if _, leaked := body["passcodeHash"]; leaked {
+		t.Fatal("passcode hash must not be serialized")
+	}
+	return f.app, f.repo, f.room, &f.current
+}
+
+func TestPasscode_GuestJoin(t *testing.T) {
+	app, _, room, _ := setupPasscodeTestApp(t)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client"})
+	if status != http.StatusForbidden || body["passcodeRequired"] != true {
+		t.Fatalf("expected 403 passcodeRequired, got %d (%v)", status, body)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client", "passcode": "wrong"})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for wrong passcode, got %d", status)
+	}
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client", "passcode": "open-sesame"})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("expected 200 with token, got %d (%v)", status, body)
+	}
+}
+
+func TestPasscode_UserJoinAndOwnerExempt(t *testing.T) {
+	app, _, room, current := setupPasscodeTestApp(t)
+
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusOK {
+		t.Fatalf("expected owner to join without passcode, got %d", status)
+	}
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 without passcode, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name, "passcode": "open-sesame"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 with passcode, got %d", status)
+	}
+}
+
+func TestPasscode_ThrottlesFailedAttempts(t *testing.T) {
+	app, _, room, _ := setupPasscodeTestApp(t)
+
+	for i := 0; i < passcodeMaxClientFailures; i++ {
+		status, _ := doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client", "passcode": "guess"})
+		if status != http.StatusForbidden {
+			t.Fatalf("attempt %d: expected 403, got %d", i, status)
+		}
+	}
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client", "passcode": "open-sesame"})
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once throttled, got %d", status)
+	}
+
+	// Rotating the passcode lifts the throttle.
+	status, _ = doJSONRequest(t, app, http.MethodPut, "/room/"+room.ID+"/settings", map[string]string{"passcode": "new-code"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 rotating passcode, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client", "passcode": "new-code"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 with rotated passcode, got %d", status)
+	}
+}
+
+func TestPasscode_Clear(t *testing.T) {
+	app, roomRepo, room, _ := setupPasscodeTestApp(t)
+
+	status, _ := doJSONRequest(t, app, http.MethodPut, "/room/"+room.ID+"/settings", map[string]string{"passcode": "abc"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for short passcode, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPut, "/room/"+room.ID+"/settings", map[string]string{"passcode": ""})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 clearing passcode, got %d", status)
+	}
+	updated, _ := roomRepo.GetRoom(room.ID)
+	if updated.HasPasscode || updated.PasscodeHash != "" {
+		t.Fatalf("expected passcode cleared, got %+v", updated)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Client"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 after clearing passcode, got %d", status)
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bedrud/internal/models"
+	"context"
+	"strings"
+
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/rs/zerolog/log"
+)
+
+// publishGrant is what a participant may publish, derived from the room
+// settings, the room mode and the participant's role.
+type publishGrant struct {
+	CanPublish     bool
+	Sources        []string // nil means every source
+	CanPublishData bool
+}
+
+// fullPublishGrant is used for owners, superadmins and room moderators, who are
+// exempt from the room's publish restrictions.
+var fullPublishGrant = publishGrant{CanPublish: true, CanPublishData: true}
+
+// isPublishExempt reports whether identity bypasses room publish restrictions.
+func (h *RoomHandler) isPublishExempt(room *models.Room, adminId, identity string, accesses []string) bool {
+	if identity == adminId || containsAccess(accesses, "superadmin") {
+		return true
+	}
+	isMod, err := h.roomRepo.IsRoomModerator(room.ID, identity)
+	return err == nil && isMod
+}
+
+// publishGrantFor computes the publish permissions for identity in room.
+// AllowAudio/AllowVideo restrict the track sources, AllowChat and chat blocks
+// control data packets, and stage rooms keep audience members from publishing media.
+func (h *RoomHandler) publishGrantFor(room *models.Room, adminId, identity string, accesses []string) publishGrant {
+	if h.isPublishExempt(room, adminId, identity, accesses) {
+		return fullPublishGrant
+	}
+	g := publishGrant{CanPublish: true, CanPublishData: room.Settings.AllowChat}
+	if g.CanPublishData {
+		// Chat goes through PostChatMessage; a blocked participant must not be
+		// able to publish around it.
+		if p, err := h.roomRepo.GetParticipant(room.ID, identity); err != nil || (p != nil && p.IsChatBlocked) {
+			g.CanPublishData = false
+		}
+	}
+	if room.Mode == models.RoomModeStage {
+		if onStage, err := h.roomRepo.IsParticipantOnStage(room.ID, identity); err != nil || !onStage {
+			g.CanPublish = false
+			return g
+		}
+	}
+	s := room.Settings
+	if s.AllowAudio && s.AllowVideo {
+		return g
+	}
+	g.Sources = []string{}
+	if s.AllowAudio {
+		g.Sources = append(g.Sources, "microphone")
+	}
+	if s.AllowVideo {
+		g.Sources = append(g.Sources, "camera", "screen_share", "screen_share_audio")
+	}
+	if len(g.Sources) == 0 {
+		g.CanPublish = false
+		g.Sources = nil
+	}
+	return g
+}
+
+// apply copies the grant onto a LiveKit token grant.
+func (g publishGrant) apply(vg *lkauth.VideoGrant) {
+	vg.CanPublish = boolPtr(g.CanPublish)
+	vg.CanPublishData = boolPtr(g.CanPublishData)
+	if g.CanPublish && g.Sources != nil {
+		vg.CanPublishSources = g.Sources
+	}
+}
+
+// applyPermission copies the grant onto a live participant permission,
+// leaving unrelated fields (hidden, recorder, metadata) untouched.
+func (g publishGrant) applyPermission(p *livekit.ParticipantPermission) {
+	p.CanPublish = g.CanPublish
+	p.CanPublishData = g.CanPublishData
+	p.CanPublishSources = nil
+	if g.CanPublish && g.Sources != nil {
+		for _, src := range g.Sources {
+			// Token source names are the lowercased protobuf enum names.
+			p.CanPublishSources = append(p.CanPublishSources, livekit.TrackSource(livekit.TrackSource_value[strings.ToUpper(src)]))
+		}
+	}
+}
+
+// livePermission returns a connected participant's permission with their
+// current publish grant applied.
+func (h *RoomHandler) livePermission(room *models.Room, adminId string, p *livekit.ParticipantInfo) *livekit.ParticipantPermission {
+	var accesses []string
+	if user, err := h.roomRepo.GetUserByID(p.Identity); err == nil {
+		accesses = user.Accesses
+	}
+	perm := p.Permission
+	if perm == nil {
+		perm = &livekit.ParticipantPermission{CanSubscribe: true}
+	}
+	h.publishGrantFor(room, adminId, p.Identity, accesses).applyPermission(perm)
+	return perm
+}
+
+// updateLivePermission pushes the current publish grant for a connected
+// participant to LiveKit.
+func (h *RoomHandler) updateLivePermission(ctx context.Context, room *models.Room, adminId string, p *livekit.ParticipantInfo) error {
+	_, err := h.lk(ctx).Rooms.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+		Room: room.Name, Identity: p.Identity, Permission: h.livePermission(room, adminId, p),
+	})
+	return err
+}
+
+// syncRoomPermissions re-applies publish grants to every connected participant,
+// e.g. after the room's Allow* settings change mid-meeting.
+func (h *RoomHandler) syncRoomPermissions(ctx context.Context, room *models.Room, adminId string) {
+	ctx = h.withAuth(ctx, room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	res, err := h.lk(ctx).Rooms.ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: room.Name})
+	if err != nil {
+		log.Warn().Err(err).Str("room", room.Name).Msg("Could not list participants to sync permissions")
+		return
+	}
+	for _, p := range res.Participants {
+		if err := h.updateLivePermission(ctx, room, adminId, p); err != nil {
+			log.Warn().Err(err).Str("room", room.Name).Str("identity", p.Identity).Msg("Failed to update participant permissions")
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/twitchtv/twirp"
+)
+
+// fakeRoomService records permission updates and data packets instead of
+// calling LiveKit.
+type fakeRoomService struct {
+	livekit.RoomService
+	participants []*livekit.ParticipantInfo
+	updates      []*livekit.UpdateParticipantRequest
+	sent         []*livekit.SendDataRequest
+	removed      []string
+	// removeErr is returned by RemoveParticipant when set.
+	removeErr error
+}
+
+func (f *fakeRoomService) ListParticipants(_ context.Context, _ *livekit.ListParticipantsRequest) (*livekit.ListParticipantsResponse, error) {
+	return &livekit.ListParticipantsResponse{Participants: f.participants}, nil
+}
+
+func (f *fakeRoomService) GetParticipant(_ context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	for _, p := range f.participants {
+		if p.Identity == req.Identity {
+			return p, nil
+		}
+	}
+	return nil, twirp.NotFoundError("participant not found")
+}
+
+func (f *fakeRoomService) UpdateParticipant(_ context.Context, req *livekit.UpdateParticipantRequest) (*livekit.ParticipantInfo, error) {
+	f.updates = append(f.updates, req)
+	return &livekit.ParticipantInfo{Identity: req.Identity, Permission: req.Permission}, nil
+}
+
+func (f *fakeRoomService) RemoveParticipant(_ context.Context, req *livekit.RoomParticipantIdentity) (*livekit.RemoveParticipantResponse, error) {
+	if f.removeErr != nil {
+		return nil, f.removeErr
+	}
+	f.removed = append(f.removed, req.Identity)
+	return &livekit.RemoveParticipantResponse{}, nil
+}
+
+func (f *fakeRoomService) SendData(_ context.Context, req *livekit.SendDataRequest) (*livekit.SendDataResponse, error) {
+	f.sent = append(f.sent, req)
+	return &livekit.SendDataResponse{}, nil
+}
+
+func setupPermissionsTestApp(t *testing.T, settings models.RoomSettings) (*fiber.App, *RoomHandler, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "perm-room",
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Put("/room/:roomId/settings", h.UpdateSettings)
+		},
+	})
+	f.setSettings(t, settings)
+	return f.app, f.handler, f.room, &f.current
+}
+
+func joinGrant(t *testing.T, app *fiber.App, roomName string) *lkauth.VideoGrant {
+	t.Helper()
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": roomName})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v)", status, body)
+	}
+	v, err := lkauth.ParseAPIToken(body["token"].(string))
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	_, claims, err := v.Verify("test-secret")
+	if err != nil {
+		t.Fatalf("verify token: %v", err)
+	}
+	return claims.Video
+}
+
+func TestPermissions_TokenFollowsRoomSettings(t *testing.T) {
+	app, _, room, current := setupPermissionsTestApp(t, models.RoomSettings{AllowAudio: true, AllowVideo: false, AllowChat: false})
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	grant := joinGrant(t, app, room.Name)
+	if !grant.GetCanPublish() {
+		t.Fatal("expected member to be able to publish audio")
+	}
+	if len(grant.CanPublishSources) != 1 || grant.CanPublishSources[0] != "microphone" {
+		t.Fatalf("expected microphone-only, got %v", grant.CanPublishSources)
+	}
+	if grant.GetCanPublishData() {
+		t.Fatal("expected data publishing disabled when chat is off")
+	}
+}
+
+func TestPermissions_OwnerExempt(t *testing.T) {
+	app, _, room, _ := setupPermissionsTestApp(t, models.RoomSettings{})
+
+	grant := joinGrant(t, app, room.Name)
+	if !grant.GetCanPublish() || !grant.GetCanPublishData() || len(grant.CanPublishSources) != 0 {
+		t.Fatalf("expected owner to keep full publish rights, got %+v", grant)
+	}
+}
+
+func TestPermissions_NothingAllowed(t *testing.T) {
+	app, _, room, current := setupPermissionsTestApp(t, models.RoomSettings{})
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	grant := joinGrant(t, app, room.Name)
+	if grant.GetCanPublish() {
+		t.Fatal("expected publishing disabled when audio and video are off")
+	}
+}
+
+func TestPermissions_UpdateSettingsSyncsLiveParticipants(t *testing.T) {
+	app, handler, room, _ := setupPermissionsTestApp(t, models.RoomSettings{AllowAudio: true, AllowVideo: true, AllowChat: true})
+	fake := &fakeRoomService{participants: []*livekit.ParticipantInfo{
+		{Identity: "owner-user", Permission: &livekit.ParticipantPermission{CanSubscribe: true, CanPublish: true, CanPublishData: true}},
+		{Identity: "member-user", Permission: &livekit.ParticipantPermission{CanSubscribe: true, CanPublish: true, CanPublishData: true, Hidden: true}},
+	}}
+	handler.nodes.Default().Rooms = fake
+
+	status, _ := doJSONRequest(t, app, http.MethodPut, "/room/"+room.ID+"/settings", map[string]interface{}{
+		"settings": map[string]bool{"allowAudio": true, "allowVideo": false, "allowChat": true},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(fake.updates) != 2 {
+		t.Fatalf("expected 2 permission updates, got %d", len(fake.updates))
+	}
+	for _, u := range fake.updates {
+		switch u.Identity {
+		case "owner-user":
+			if len(u.Permission.CanPublishSources) != 0 || !u.Permission.CanPublish {
+				t.Errorf("owner should stay unrestricted, got %+v", u.Permission)
+			}
+		case "member-user":
+			if len(u.Permission.CanPublishSources) != 1 || u.Permission.CanPublishSources[0] != livekit.TrackSource_MICROPHONE {
+				t.Errorf("member should be limited to microphone, got %v", u.Permission.CanPublishSources)
+			}
+			if !u.Permission.Hidden {
+				t.Error("unrelated permission fields should be preserved")
+			}
+		}
+	}
+
+	// Saving identical settings does not touch live participants.
+	fake.updates = nil
+	_, _ = doJSONRequest(t, app, http.MethodPut, "/room/"+room.ID+"/settings", map[string]interface{}{"maxParticipants": 10})
+	if len(fake.updates) != 0 {
+		t.Fatalf("expected no permission updates, got %d", len(fake.updates))
+	}
+}
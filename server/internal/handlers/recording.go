@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/rs/zerolog/log"
+)
+
+// StartRecordingRequest is the body for POST /room/:roomId/recordings/start.
+type StartRecordingRequest struct {
+	// Type is "composite" (default) for the whole room or "track" for a single track.
+	Type      string `json:"type"`
+	TrackSID  string `json:"trackSid"`
+	Layout    string `json:"layout"`
+	AudioOnly bool   `json:"audioOnly"`
+}
+
+// StartRecording starts a LiveKit egress for the room and announces it to
+// every participant with a "recording_started" system message.
+func (h *RoomHandler) StartRecording(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
+		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
+	}
+	if !h.recordingOn {
+		return c.Status(503).JSON(fiber.Map{"error": "Recording is not enabled on this server"})
+	}
+
+	// An empty body starts a default composite recording.
+	var req StartRecordingRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	if req.Type == "" {
+		req.Type = models.RecordingTypeComposite
+	}
+
+	rec := &models.Recording{
+		RoomID:    room.ID,
+		Type:      req.Type,
+		StartedBy: claims.UserID,
+		Backend:   h.recording.Backend(),
+		StartedAt: time.Now(),
+	}
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, RoomRecord: true, Room: room.Name})
+
+	var info *livekit.EgressInfo
+	switch req.Type {
+	case models.RecordingTypeComposite:
+		active, lookupErr := h.hasActiveComposite(room.ID)
+		if lookupErr != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to look up recordings"})
+		}
+		if active {
+			return c.Status(409).JSON(fiber.Map{"error": "Room is already being recorded"})
+		}
+		fileType, ext := livekit.EncodedFileType_MP4, ".mp4"
+		if req.AudioOnly {
+			fileType, ext = livekit.EncodedFileType_OGG, ".ogg"
+		}
+		out := h.recording.FileOutput(recordingKey(room.Name, ext))
+		out.FileType = fileType
+		rec.Location = out.Filepath
+		info, err = h.lk(ctx).Egress.StartRoomCompositeEgress(ctx, &livekit.RoomCompositeEgressRequest{
+			RoomName:    room.Name,
+			Layout:      req.Layout,
+			AudioOnly:   req.AudioOnly,
+			FileOutputs: []*livekit.EncodedFileOutput{out},
+		})
+	case models.RecordingTypeTrack:
+		if req.TrackSID == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "trackSid is required for track recordings"})
+		}
+		rec.TrackSID = req.TrackSID
+		// Egress appends the extension matching the track codec.
+		out := h.recording.DirectFileOutput(recordingKey(room.Name, "-"+req.TrackSID))
+		rec.Location = out.Filepath
+		info, err = h.lk(ctx).Egress.StartTrackEgress(ctx, &livekit.TrackEgressRequest{
+			RoomName: room.Name,
+			TrackId:  req.TrackSID,
+			Output:   &livekit.TrackEgressRequest_File{File: out},
+		})
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "type must be composite or track"})
+	}
+	if err != nil {
+		log.Error().Err(err).Str("room", room.Name).Str("type", req.Type).Msg("Failed to start egress")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to start recording"})
+	}
+
+	rec.EgressID = info.EgressId
+	rec.Status = egressStatus(info.Status)
+	if err := h.roomRepo.CreateRecording(rec); err != nil {
+		log.Error().Err(err).Str("egressID", info.EgressId).Msg("Failed to store recording")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to store recording"})
+	}
+
+	log.Info().Str("room", room.Name).Str("egressID", rec.EgressID).Str("by", claims.UserID).Msg("Recording started")
+	h.sendSystemMessage(ctx, room.Name, "recording_started", claims.UserID, rec.ID)
+	return c.Status(201).JSON(rec)
+}
+
+// StopRecording stops a running recording of the room.
+func (h *RoomHandler) StopRecording(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, rec, ok := h.resolveRecording(c)
+	if !ok {
+		return nil
+	}
+	if rec.IsFinished() {
+		return c.Status(409).JSON(fiber.Map{"error": "Recording has already finished"})
+	}
+
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, RoomRecord: true, Room: room.Name})
+	info, err := h.lk(ctx).Egress.StopEgress(ctx, &livekit.StopEgressRequest{EgressId: rec.EgressID})
+	if err != nil {
+		log.Error().Err(err).Str("egressID", rec.EgressID).Msg("Failed to stop egress")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to stop recording"})
+	}
+	if err := h.roomRepo.UpdateRecording(rec.ID, egressUpdates(info)); err != nil {
+		log.Error().Err(err).Str("recordingID", rec.ID).Msg("Failed to update recording")
+	}
+
+	h.sendSystemMessage(ctx, room.Name, "recording_stopped", claims.UserID, rec.ID)
+	updated, err := h.roomRepo.GetRecording(rec.ID)
+	if err != nil || updated == nil {
+		return c.JSON(rec)
+	}
+	return c.JSON(updated)
+}
+
+// ListRecordings returns all recordings of the room, newest first.
+func (h *RoomHandler) ListRecordings(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil
+	}
+	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
+		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
+	}
+	recs, err := h.roomRepo.GetRecordings(room.ID)
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to list recordings")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list recordings"})
+	}
+	if recs == nil {
+		recs = []models.Recording{}
+	}
+	return c.JSON(recs)
+}
+
+// DownloadRecording serves a finished recording from disk or redirects to a
+// presigned S3 URL.
+func (h *RoomHandler) DownloadRecording(c *fiber.Ctx) error {
+	_, rec, ok := h.resolveRecording(c)
+	if !ok {
+		return nil
+	}
+	if rec.Status != models.RecordingStatusComplete {
+		return c.Status(409).JSON(fiber.Map{"error": "Recording is not available yet"})
+	}
+	if rec.Backend != h.recording.Backend() {
+		return c.Status(410).JSON(fiber.Map{"error": "Recording is stored on a backend that is no longer configured"})
+	}
+	loc, err := h.recording.Locate(rec.Location)
+	if err != nil {
+		log.Error().Err(err).Str("recordingID", rec.ID).Msg("Failed to locate recording")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to locate recording"})
+	}
+	if loc.URL != "" {
+		return c.Redirect(loc.URL, http.StatusTemporaryRedirect)
+	}
+	return c.Download(loc.Path, filepath.Base(loc.Path))
+}
+
+// resolveRecording loads the room and recording from the route and checks the
+// caller may manage the room's recordings. Writes the error response itself.
+func (h *RoomHandler) resolveRecording(c *fiber.Ctx) (*models.Room, *models.Recording, bool) {
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, c.Params("roomId"))
+	if err != nil {
+		return nil, nil, false
+	}
+	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
+		_ = c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
+		return nil, nil, false
+	}
+	rec, err := h.roomRepo.GetRecording(c.Params("recordingId"))
+	if err != nil {
+		log.Error().Err(err).Str("recordingID", c.Params("recordingId")).Msg("Failed to look up recording")
+		_ = c.Status(500).JSON(fiber.Map{"error": "Failed to look up recording"})
+		return nil, nil, false
+	}
+	if rec == nil || rec.RoomID != room.ID {
+		_ = c.Status(404).JSON(fiber.Map{"error": "Recording not found"})
+		return nil, nil, false
+	}
+	return room, rec, true
+}
+
+// hasActiveComposite reports whether a room composite recording is running.
+func (h *RoomHandler) hasActiveComposite(roomID string) (bool, error) {
+	recs, err := h.roomRepo.GetRecordings(roomID)
+	if err != nil {
+		return false, err
+	}
+	for i := range recs {
+		if recs[i].Type == models.RecordingTypeComposite && !recs[i].IsFinished() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recordingKey builds a unique, sortable file path for a new recording.
+func recordingKey(roomName, suffix string) string {
+	return roomName + "/" + time.Now().UTC().Format("20060102-150405") + suffix
+}
+
+// egressStatus maps a LiveKit egress status onto models.RecordingStatus*.
+func egressStatus(s livekit.EgressStatus) string {
+	return strings.ToLower(strings.TrimPrefix(s.String(), "EGRESS_"))
+}
+
+// egressUpdates converts an egress report into recording column updates.
+func egressUpdates(info *livekit.EgressInfo) map[string]interface{} {
+	status := egressStatus(info.Status)
+	updates := map[string]interface{}{"status": status}
+	if info.Error != "" {
+		updates["error"] = info.Error
+	}
+	if len(info.FileResults) > 0 {
+		file := info.FileResults[0]
+		if file.Filename != "" {
+			updates["location"] = file.Filename
+		}
+		updates["size"] = file.Size
+		updates["duration_seconds"] = file.Duration / int64(time.Second)
+	}
+	if (&models.Recording{Status: status}).IsFinished() {
+		endedAt := time.Now()
+		if info.EndedAt > 0 {
+			endedAt = time.Unix(0, info.EndedAt)
+		}
+		updates["ended_at"] = endedAt
+	}
+	return updates
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bedrud/config"
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/livekit/protocol/livekit"
+)
+
+// fakeEgress records egress requests instead of calling LiveKit.
+type fakeEgress struct {
+	livekit.Egress
+	composite []*livekit.RoomCompositeEgressRequest
+	track     []*livekit.TrackEgressRequest
+	stopped   []string
+}
+
+func (f *fakeEgress) StartRoomCompositeEgress(_ context.Context, req *livekit.RoomCompositeEgressRequest) (*livekit.EgressInfo, error) {
+	f.composite = append(f.composite, req)
+	return &livekit.EgressInfo{EgressId: "EG_composite", RoomName: req.RoomName, Status: livekit.EgressStatus_EGRESS_STARTING}, nil
+}
+
+func (f *fakeEgress) StartTrackEgress(_ context.Context, req *livekit.TrackEgressRequest) (*livekit.EgressInfo, error) {
+	f.track = append(f.track, req)
+	return &livekit.EgressInfo{EgressId: "EG_track", RoomName: req.RoomName, Status: livekit.EgressStatus_EGRESS_ACTIVE}, nil
+}
+
+func (f *fakeEgress) StopEgress(_ context.Context, req *livekit.StopEgressRequest) (*livekit.EgressInfo, error) {
+	f.stopped = append(f.stopped, req.EgressId)
+	return &livekit.EgressInfo{EgressId: req.EgressId, Status: livekit.EgressStatus_EGRESS_ENDING}, nil
+}
+
+func setupRecordingTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *fakeEgress, *models.Room, **auth.Claims, string) {
+	t.Helper()
+	dir := t.TempDir()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "rec-room",
+		liveKit: func(cfg *config.LiveKitConfig) {
+			cfg.Recording = config.RecordingConfig{Enabled: true, DiskDir: dir}
+		},
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Get("/room/:roomId/recordings", h.ListRecordings)
+			app.Post("/room/:roomId/recordings/start", h.StartRecording)
+			app.Post("/room/:roomId/recordings/:recordingId/stop", h.StopRecording)
+			app.Get("/room/:roomId/recordings/:recordingId/download", h.DownloadRecording)
+		},
+	})
+	egress := &fakeEgress{}
+	f.handler.nodes.Default().Egress = egress
+	f.handler.nodes.Default().Rooms = &fakeRoomService{}
+	return f.app, f.repo, egress, f.room, &f.current, dir
+}
+
+func TestRecording_StartStopComposite(t *testing.T) {
+	app, roomRepo, egress, room, _, dir := setupRecordingTestApp(t)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/recordings/start", map[string]interface{}{"layout": "grid"})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%v)", status, body)
+	}
+	if len(egress.composite) != 1 || egress.composite[0].Layout != "grid" {
+		t.Fatalf("expected one composite egress, got %+v", egress.composite)
+	}
+	out := egress.composite[0].FileOutputs[0]
+	if filepath.Dir(filepath.Dir(out.Filepath)) != dir || filepath.Ext(out.Filepath) != ".mp4" {
+		t.Fatalf("unexpected output path %q", out.Filepath)
+	}
+	if body["status"] != models.RecordingStatusStarting || body["startedBy"] != "owner-user" {
+		t.Fatalf("unexpected recording %v", body)
+	}
+
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/recordings/start", nil)
+	if status != http.StatusConflict {
+		t.Fatalf("expected 409 for a second composite recording, got %d", status)
+	}
+
+	id := body["id"].(string)
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/recordings/"+id+"/stop", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(egress.stopped) != 1 || egress.stopped[0] != "EG_composite" {
+		t.Fatalf("expected egress to be stopped, got %v", egress.stopped)
+	}
+	if body["status"] != models.RecordingStatusEnding {
+		t.Fatalf("expected ending status, got %v", body["status"])
+	}
+
+	recs, _ := roomRepo.GetRecordings(room.ID)
+	if len(recs) != 1 {
+		t.Fatalf("expected 1 recording, got %d", len(recs))
+	}
+}
+
+func TestRecording_TrackRequiresSid(t *testing.T) {
+	app, _, egress, room, _, _ := setupRecordingTestApp(t)
+
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/recordings/start", map[string]string{"type": "track"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/recordings/start", map[string]string{"type": "track", "trackSid": "TR_abc"})
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", status)
+	}
+	if len(egress.track) != 1 || egress.track[0].TrackId != "TR_abc" || egress.track[0].GetFile() == nil {
+		t.Fatalf("expected a file track egress, got %+v", egress.track)
+	}
+}
+
+func TestRecording_NonModeratorForbidden(t *testing.T) {
+	app, _, egress, room, current, _ := setupRecordingTestApp(t)
+
+	*current = &auth.Claims{UserID: "member-user", Name: "Member", Accesses: []string{"user"}}
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/recordings/start", nil)
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", status)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodGet, "/room/"+room.ID+"/recordings", nil)
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", status)
+	}
+	if len(egress.composite) != 0 {
+		t.Fatal("egress should not have been started")
+	}
+}
+
+func TestRecording_DownloadFromDisk(t *testing.T) {
+	app, roomRepo, _, room, _, dir := setupRecordingTestApp(t)
+
+	path := filepath.Join(dir, "rec-room", "done.mp4")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("video-bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec := &models.Recording{RoomID: room.ID, EgressID: "EG_done", Type: models.RecordingTypeComposite,
+		Status: models.RecordingStatusActive, StartedBy: "owner-user", Backend: "disk", Location: path}
+	_ = roomRepo.CreateRecording(rec)
+
+	url := "/room/" + room.ID + "/recordings/" + rec.ID + "/download"
+	status, _ := doJSONRequest(t, app, http.MethodGet, url, nil)
+	if status != http.StatusConflict {
+		t.Fatalf("expected 409 while recording, got %d", status)
+	}
+
+	_ = roomRepo.UpdateRecording(rec.ID, map[string]interface{}{"status": models.RecordingStatusComplete})
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, url, nil), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if resp.ContentLength != int64(len("video-bytes")) {
+		t.Fatalf("unexpected content length %d", resp.ContentLength)
+	}
+
+	// Paths outside the recording directory are never served.
+	_ = roomRepo.UpdateRecording(rec.ID, map[string]interface{}{"location": "/etc/passwd"})
+	status, _ = doJSONRequest(t, app, http.MethodGet, url, nil)
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for escaped path, got %d", status)
+	}
+}
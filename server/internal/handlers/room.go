@@ -3,16 +3,16 @@ package handlers
 import (
 	"bedrud/config"
 	"bedrud/internal/auth"
+	"bedrud/internal/lknode"
 	"bedrud/internal/models"
 	"bedrud/internal/repository"
 	"bedrud/internal/storage"
 	"context"
 	"crypto/rand"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"net"
 	"strings"
 	"time"
 
@@ -20,7 +20,6 @@ import (
 	lkauth "github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/rs/zerolog/log"
-	"github.com/twitchtv/twirp"
 )
 
 func boolPtr(b bool) *bool { return &b }
@@ -31,66 +30,78 @@ type CreateRoomRequest struct {
 	IsPublic        bool                `json:"isPublic"`
 	Mode            string              `json:"mode"`
 	Settings        models.RoomSettings `json:"settings"`
+	// Permanent rooms never expire; allowed when the room policy permits it.
+	Permanent bool `json:"permanent"`
+	// Region prefers LiveKit nodes in that region over GeoIP placement.
+	Region string `json:"region"`
 }
 
 type JoinRoomRequest struct {
-	RoomName string `json:"roomName"`
+	RoomName    string `json:"roomName"`
+	Passcode    string `json:"passcode"`
+	InviteToken string `json:"inviteToken"`
 }
 
 type RoomHandler struct {
 	roomRepo    *repository.RoomRepository
-	livekitHost string
-	apiKey      string
-	apiSecret   string
-	client      livekit.RoomService
+	nodes       *lknode.Pool
 	uploadStore storage.ChatUploadStore
 	uploadMax   int64
+	recording   storage.RecordingStore
+	recordingOn bool
+	ingressOn   bool
+	passcodes   *passcodeThrottle
+	settings    *repository.SettingsRepository
+	// guestKeySecret signs guest key cookies.
+	guestKeySecret []byte
 }
 
 func NewRoomHandler(lkCfg *config.LiveKitConfig, chatCfg *config.ChatConfig, roomRepo *repository.RoomRepository) *RoomHandler {
-	apiHost := lkCfg.InternalHost
-	if apiHost == "" {
-		apiHost = lkCfg.Host
-	}
-	httpClient := http.DefaultClient
-	if lkCfg.SkipTLSVerify && strings.HasPrefix(apiHost, "https") {
-		httpClient = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
-	}
-	client := livekit.NewRoomServiceProtobufClient(apiHost, httpClient)
-
 	uploadMax := chatCfg.Uploads.MaxBytes
 	if uploadMax == 0 {
 		uploadMax = 10 * 1024 * 1024 // 10 MB default
 	}
 
+	// Until SetGuestKeySecret is called guest keys are signed with a random
+	// secret, so they only last until the server restarts.
+	guestKeySecret := make([]byte, 32)
+	_, _ = rand.Read(guestKeySecret)
+
 	return &RoomHandler{
-		roomRepo:    roomRepo,
-		livekitHost: lkCfg.Host,
-		apiKey:      lkCfg.APIKey,
-		apiSecret:   lkCfg.APISecret,
-		client:      client,
-		uploadStore: storage.NewChatUploadStore(&chatCfg.Uploads),
-		uploadMax:   uploadMax,
+		roomRepo:       roomRepo,
+		nodes:          lknode.NewPool(lkCfg),
+		uploadStore:    storage.NewChatUploadStore(&chatCfg.Uploads),
+		uploadMax:      uploadMax,
+		recording:      storage.NewRecordingStore(&lkCfg.Recording),
+		recordingOn:    lkCfg.Recording.Enabled,
+		ingressOn:      lkCfg.Ingress.Enabled,
+		passcodes:      newPasscodeThrottle(),
+		guestKeySecret: guestKeySecret,
 	}
 }
 
-func (h *RoomHandler) withAuth(ctx context.Context, grants ...*lkauth.VideoGrant) context.Context {
-	at := lkauth.NewAccessToken(h.apiKey, h.apiSecret)
-	for _, g := range grants {
-		at.AddGrant(g) //nolint:staticcheck // AddGrant is deprecated but VideoGrant field is not available in this version of the protocol SDK
-	}
-	token, err := at.ToJWT()
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to generate LiveKit auth token")
+// SetNodePool shares the LiveKit node pool, and so its health checks, with
+// the scheduler.
+func (h *RoomHandler) SetNodePool(nodes *lknode.Pool) {
+	h.nodes = nodes
+}
+
+// node returns the LiveKit node the room lives on.
+func (h *RoomHandler) node(room *models.Room) *lknode.Node {
+	return h.nodes.Node(room.LiveKitNode)
+}
+
+// withAuth authorizes LiveKit API calls on the room's node for the grants.
+func (h *RoomHandler) withAuth(ctx context.Context, room *models.Room, grants ...*lkauth.VideoGrant) context.Context {
+	return h.node(room).WithAuth(ctx, grants...)
+}
+
+// lk returns the node ctx was authorized for by withAuth.
+func (h *RoomHandler) lk(ctx context.Context) *lknode.Node {
+	if n := lknode.FromContext(ctx); n != nil {
+		return n
 	}
-	ctx, _ = twirp.WithHTTPRequestHeaders(ctx, http.Header{
-		"Authorization": []string{"Bearer " + token},
-	})
-	return ctx
+	return h.nodes.Default()
 }
 
 func (h *RoomHandler) CreateRoom(c *fiber.Ctx) error {
@@ -118,14 +129,26 @@ func (h *RoomHandler) CreateRoom(c *fiber.Ctx) error {
 	}
 
 	claims := c.Locals("user").(*auth.Claims)
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomCreate: true})
-	_, err := h.client.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: req.Name, MaxParticipants: uint32(req.MaxParticipants)})
+	policy := h.roomLifecycle()
+	if req.Permanent && !policy.AllowPermanent && !containsAccess(claims.Accesses, "superadmin") {
+		return c.Status(403).JSON(fiber.Map{"error": "Permanent rooms are not allowed"})
+	}
+	lk := h.nodes.Pick(req.Region, net.ParseIP(c.IP()))
+	ctx := lk.WithAuth(c.Context(), &lkauth.VideoGrant{RoomCreate: true})
+	_, err := lk.Rooms.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: req.Name, MaxParticipants: uint32(req.MaxParticipants)})
 	if err != nil {
-		log.Error().Err(err).Str("room", req.Name).Msg("LiveKit CreateRoom failed")
+		log.Error().Err(err).Str("room", req.Name).Str("node", lk.ID).Msg("LiveKit CreateRoom failed")
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to create media room"})
 	}
-	room, err := h.roomRepo.CreateRoom(claims.UserID, req.Name, req.IsPublic, req.Mode, &req.Settings)
+	room, err := h.roomRepo.CreateRoomOnNode(claims.UserID, req.Name, req.IsPublic, req.Mode, &req.Settings, lk.ID)
 	if err != nil {
+		// LiveKit hands back the existing room for a taken name; only delete
+		// the media room if it is ours.
+		if existing, lerr := h.roomRepo.GetRoomByName(req.Name); lerr == nil && existing == nil {
+			if _, derr := lk.Rooms.DeleteRoom(ctx, &livekit.DeleteRoomRequest{Room: req.Name}); derr != nil {
+				log.Warn().Err(derr).Str("room", req.Name).Str("node", lk.ID).Msg("Failed to delete orphaned LiveKit room")
+			}
+		}
 		// Map specific errors to appropriate HTTP status codes
 		if errors.Is(err, models.ErrRoomNameTaken) {
 			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
@@ -136,10 +159,17 @@ func (h *RoomHandler) CreateRoom(c *fiber.Ctx) error {
 		log.Error().Err(err).Msg("Database CreateRoom failed")
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to create room"})
 	}
+	if req.Permanent || policy.DefaultTTL != models.DefaultRoomTTL {
+		room.ExpiresAt = time.Now().Add(policy.DefaultTTL)
+		room.Permanent = req.Permanent
+		if err := h.roomRepo.RenewRoom(room.ID, room.ExpiresAt, room.Permanent); err != nil {
+			log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to apply room lifetime")
+		}
+	}
 	return c.JSON(fiber.Map{
 		"id": room.ID, "name": room.Name, "createdBy": room.CreatedBy, "isActive": room.IsActive,
 		"isPublic": room.IsPublic, "maxParticipants": room.MaxParticipants, "settings": room.Settings,
-		"livekitHost": h.livekitHost, "mode": room.Mode,
+		"livekitHost": lk.Host, "livekitNode": lk.ID, "mode": room.Mode, "expiresAt": room.ExpiresAt, "permanent": room.Permanent,
 	})
 }
 
@@ -159,13 +189,18 @@ func (h *RoomHandler) JoinRoom(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Room not found"})
 	}
 
-		// Re-activate inactive rooms on join - starts a new session.
-		if !room.IsActive {
-			room.IsActive = true
-			if err := h.roomRepo.UpdateRoom(room); err != nil {
-				log.Error().Err(err).Str("room", room.Name).Msg("Failed to re-activate room")
-			}
+	if !h.checkRoomLifecycle(c, room) {
+		return nil
+	}
+
+	// Re-activate idle rooms on join - starts a new session. Expired and
+	// archived rooms were turned away above.
+	if !room.IsActive {
+		room.IsActive = true
+		if err := h.roomRepo.UpdateRoom(room); err != nil {
+			log.Error().Err(err).Str("room", room.Name).Msg("Failed to re-activate room")
 		}
+	}
 
 	// Enforce participant limit
 	if room.MaxParticipants > 0 {
@@ -187,36 +222,86 @@ func (h *RoomHandler) JoinRoom(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "you are banned from this room"})
 	}
 
+	adminId := room.AdminID
+	if adminId == "" {
+		adminId = room.CreatedBy
+	}
+
+	// A valid invite stands in for the passcode and the lobby.
+	var invite *models.RoomInvite
+	if req.InviteToken != "" {
+		var ok bool
+		if invite, ok = h.checkInvite(c, room, req.InviteToken, claims.UserID); !ok {
+			return nil
+		}
+	}
+
+	// Private rooms admit moderators, users on the access list and invitees.
+	if !room.IsPublic && invite == nil {
+		allowed, err := h.canEnterPrivateRoom(room, adminId, claims)
+		if err != nil {
+			log.Error().Err(err).Str("roomID", room.ID).Str("userID", claims.UserID).Msg("Failed to check room access")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to check room access"})
+		}
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "This room is private", "roomId": room.ID, "canRequestAccess": true})
+		}
+	}
+
+	isModerator := isRoomModerator(claims, adminId, room.ID, h.roomRepo)
+	if !isModerator && !h.checkMeetingWindow(c, room) {
+		return nil
+	}
+	if invite == nil && !isModerator && !h.checkPasscode(c, room, req.Passcode) {
+		return nil
+	}
+
+	// Rooms that require approval send everyone but moderators through the lobby
+	// until admitted once.
+	if room.Settings.RequireApproval && !isModerator && invite == nil {
+		approved, err := h.roomRepo.IsParticipantApproved(room.ID, claims.UserID)
+		if err != nil {
+			log.Error().Err(err).Str("roomID", room.ID).Str("userID", claims.UserID).Msg("Failed to check approval status")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to check approval status"})
+		}
+		if !approved {
+			return h.enterLobby(c, room, adminId, claims.UserID, claims.Name)
+		}
+	}
+
 	if err := h.roomRepo.AddParticipant(room.ID, claims.UserID); err != nil {
 		log.Error().Err(err).Str("roomID", room.ID).Str("userID", claims.UserID).Msg("AddParticipant failed")
 	}
-
-	at := lkauth.NewAccessToken(h.apiKey, h.apiSecret)
-	at.AddGrant(&lkauth.VideoGrant{RoomJoin: true, Room: req.RoomName, CanUpdateOwnMetadata: boolPtr(true)}).SetIdentity(claims.UserID).SetName(claims.Name).SetValidFor(time.Hour) //nolint:staticcheck // AddGrant is deprecated but VideoGrant field is not available in this version of the protocol SDK
-	if meta, err := json.Marshal(map[string]interface{}{"accesses": claims.Accesses}); err == nil {
-		at.SetMetadata(string(meta))
+	if invite != nil && !h.redeemInvite(c, room, invite, claims.UserID, claims.Name) {
+		return nil
 	}
-	token, err := at.ToJWT()
+
+	pub := h.publishGrantFor(room, adminId, claims.UserID, claims.Accesses)
+	token, err := h.userJoinToken(room, claims.UserID, claims.Name, claims.Accesses, pub)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to sign LiveKit join token")
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate room token"})
 	}
-
-	adminId := room.AdminID
-	if adminId == "" {
-		adminId = room.CreatedBy
+	if err := h.roomRepo.TouchRoom(room); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to record room activity")
 	}
 
-	return c.JSON(fiber.Map{
+	resp := fiber.Map{
 		"id": room.ID, "name": room.Name, "token": token, "createdBy": room.CreatedBy, "adminId": adminId, "isActive": room.IsActive,
 		"isPublic": room.IsPublic, "maxParticipants": room.MaxParticipants, "expiresAt": room.ExpiresAt,
-		"settings": room.Settings, "livekitHost": h.livekitHost, "mode": room.Mode,
-	})
+		"settings": room.Settings, "livekitHost": h.node(room).Host, "mode": room.Mode,
+	}
+	if !h.addE2EEKey(c, room, false, resp) {
+		return nil
+	}
+	return c.JSON(resp)
 }
 
 type GuestJoinRoomRequest struct {
 	RoomName  string `json:"roomName"`
-	GuestName string `json:"guestName"`
+	GuestName   string `json:"guestName"`
+	Passcode    string `json:"passcode"`
+	InviteToken string `json:"inviteToken"`
 }
 
 func (h *RoomHandler) GuestJoinRoom(c *fiber.Ctx) error {
@@ -229,6 +314,9 @@ func (h *RoomHandler) GuestJoinRoom(c *fiber.Ctx) error {
 	if req.GuestName == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "Guest name is required"})
 	}
+	if rejectBlocked(c, "", "") {
+		return nil
+	}
 
 	room, err := h.roomRepo.GetRoomByName(req.RoomName)
 	if err != nil {
@@ -238,9 +326,25 @@ func (h *RoomHandler) GuestJoinRoom(c *fiber.Ctx) error {
 	if room == nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Room not found"})
 	}
-	if !room.IsPublic {
+
+	// An invite lets guests into private rooms and skips the passcode and lobby.
+	var invite *models.RoomInvite
+	if req.InviteToken != "" {
+		var ok bool
+		if invite, ok = h.checkInvite(c, room, req.InviteToken, ""); !ok {
+			return nil
+		}
+	}
+	if !room.IsPublic && invite == nil {
 		return c.Status(403).JSON(fiber.Map{"error": "This room is private"})
 	}
+	if room.Settings.E2EE && !room.Settings.E2EEAllowGuests {
+		return c.Status(403).JSON(fiber.Map{"error": "Guests can't join this end-to-end encrypted room"})
+	}
+
+	if !h.checkRoomLifecycle(c, room) {
+		return nil
+	}
 
 	// Enforce room active state
 	if !room.IsActive {
@@ -258,7 +362,9 @@ func (h *RoomHandler) GuestJoinRoom(c *fiber.Ctx) error {
 		}
 	}
 
-	banned, err := h.roomRepo.IsParticipantBanned(room.ID, req.GuestName)
+	// Guests are recognised across joins by the key in their signed cookie.
+	guestKey := h.guestKey(c)
+	banned, err := h.roomRepo.IsGuestBanned(room.ID, guestKey)
 	if err != nil {
 		log.Error().Err(err).Str("roomID", room.ID).Str("guestName", req.GuestName).Msg("Failed to check guest ban status")
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to check ban status"})
@@ -267,28 +373,77 @@ func (h *RoomHandler) GuestJoinRoom(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "you are banned from this room"})
 	}
 
+	adminId := room.AdminID
+	if adminId == "" {
+		adminId = room.CreatedBy
+	}
+
+	if !h.checkMeetingWindow(c, room) {
+		return nil
+	}
+	if invite == nil && !h.checkPasscode(c, room, req.Passcode) {
+		return nil
+	}
+
 	guestID := "guest-" + generateShortID()
-	at := lkauth.NewAccessToken(h.apiKey, h.apiSecret)
-	at.AddGrant(&lkauth.VideoGrant{ //nolint:staticcheck // AddGrant is deprecated but VideoGrant field is not available in this version of the protocol SDK
-		RoomJoin:             true,
-		Room:                 req.RoomName,
-		CanUpdateOwnMetadata: boolPtr(false),
-	}).SetIdentity(guestID).SetName(req.GuestName).SetValidFor(time.Hour)
-	token, err := at.ToJWT()
+	if err := h.roomRepo.RecordGuestKey(room.ID, guestID, req.GuestName, guestKey); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to record guest")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to join room"})
+	}
+	if invite != nil {
+		if !h.redeemInvite(c, room, invite, guestID, req.GuestName) {
+			return nil
+		}
+	} else if room.Settings.RequireApproval {
+		return h.enterLobby(c, room, adminId, guestID, req.GuestName)
+	}
+
+	pub := h.publishGrantFor(room, adminId, guestID, nil)
+	token, err := h.guestJoinToken(room, guestID, req.GuestName, pub)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to sign LiveKit guest token")
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate room token"})
 	}
-
-	adminId := room.AdminID
-	if adminId == "" {
-		adminId = room.CreatedBy
+	if err := h.roomRepo.TouchRoom(room); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Msg("Failed to record room activity")
 	}
 
-	return c.JSON(fiber.Map{
+	resp := fiber.Map{
 		"id": room.ID, "name": room.Name, "token": token, "adminId": adminId,
-		"livekitHost": h.livekitHost,
-	})
+		"livekitHost": h.node(room).Host,
+	}
+	if !h.addE2EEKey(c, room, true, resp) {
+		return nil
+	}
+	return c.JSON(resp)
+}
+
+// userJoinToken signs a LiveKit join token for an authenticated user. The
+// user's accesses travel in the participant metadata for client-side UI.
+func (h *RoomHandler) userJoinToken(room *models.Room, identity, name string, accesses []string, pub publishGrant) (string, error) {
+	grant := &lkauth.VideoGrant{RoomJoin: true, Room: room.Name, CanUpdateOwnMetadata: boolPtr(true)}
+	pub.apply(grant)
+	lk := h.node(room)
+	at := lkauth.NewAccessToken(lk.APIKey, lk.APISecret)
+	at.AddGrant(grant).SetIdentity(identity).SetName(name).SetValidFor(time.Hour) //nolint:staticcheck // AddGrant is deprecated but VideoGrant field is not available in this version of the protocol SDK
+	if meta, err := json.Marshal(map[string]interface{}{"accesses": accesses}); err == nil {
+		at.SetMetadata(string(meta))
+	}
+	return at.ToJWT()
+}
+
+// guestJoinToken signs a LiveKit join token for a guest identity.
+func (h *RoomHandler) guestJoinToken(room *models.Room, identity, name string, pub publishGrant) (string, error) {
+	grant := &lkauth.VideoGrant{
+		RoomJoin:             true,
+		Room:                 room.Name,
+		CanUpdateOwnMetadata: boolPtr(false),
+	}
+	pub.apply(grant)
+	lk := h.node(room)
+	at := lkauth.NewAccessToken(lk.APIKey, lk.APISecret)
+	at.AddGrant(grant).SetIdentity(identity).SetName(name).SetValidFor(time.Hour) //nolint:staticcheck // AddGrant is deprecated but VideoGrant field is not available in this version of the protocol SDK
+	return at.ToJWT()
 }
 
 func generateShortID() string {
@@ -311,6 +466,13 @@ func (h *RoomHandler) ListRooms(c *fiber.Ctx) error {
 		log.Error().Err(err).Str("userID", claims.UserID).Msg("Failed to list rooms")
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to list rooms"})
 	}
+	// Rooms whose access list includes the user follow their own.
+	shared, err := h.roomRepo.GetRoomsSharedWithUser(claims.UserID, claims.Accesses)
+	if err != nil {
+		log.Error().Err(err).Str("userID", claims.UserID).Msg("Failed to list shared rooms")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to list rooms"})
+	}
+	rooms = append(rooms, shared...)
 	if rooms == nil {
 		rooms = []models.Room{}
 	}
@@ -326,7 +488,7 @@ func (h *RoomHandler) sendSystemMessage(ctx context.Context, roomName, event, ac
 	}
 	b, _ := json.Marshal(sysMsg{Type: "system", Event: event, Actor: actor, Target: target})
 	topic := "system"
-	_, _ = h.client.SendData(ctx, &livekit.SendDataRequest{
+	_, _ = h.lk(ctx).Rooms.SendData(ctx, &livekit.SendDataRequest{
 		Room:  roomName,
 		Data:  b,
 		Kind:  livekit.DataPacket_RELIABLE,
@@ -344,7 +506,7 @@ func (h *RoomHandler) sendTargetedSystemMessage(ctx context.Context, roomName, e
 	}
 	b, _ := json.Marshal(sysMsg{Type: "system", Event: event, Actor: actor, Target: target})
 	topic := "system"
-	_, _ = h.client.SendData(ctx, &livekit.SendDataRequest{
+	_, _ = h.lk(ctx).Rooms.SendData(ctx, &livekit.SendDataRequest{
 		Room:                  roomName,
 		Data:                  b,
 		Kind:                  livekit.DataPacket_RELIABLE,
@@ -382,8 +544,8 @@ func (h *RoomHandler) PromoteParticipant(c *fiber.Ctx) error {
 	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
 		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	p, err := h.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	p, err := h.lk(ctx).Rooms.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Participant not found"})
 	}
@@ -399,7 +561,7 @@ func (h *RoomHandler) PromoteParticipant(c *fiber.Ctx) error {
 	}
 	meta["accesses"] = append(accesses, "moderator")
 	newMeta, _ := json.Marshal(meta)
-	_, err = h.client.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+	_, err = h.lk(ctx).Rooms.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
 		Room: room.Name, Identity: identity, Metadata: string(newMeta),
 	})
 	if err != nil {
@@ -420,8 +582,8 @@ func (h *RoomHandler) DemoteParticipant(c *fiber.Ctx) error {
 	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
 		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	p, err := h.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	p, err := h.lk(ctx).Rooms.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Participant not found"})
 	}
@@ -438,7 +600,7 @@ func (h *RoomHandler) DemoteParticipant(c *fiber.Ctx) error {
 	}
 	meta["accesses"] = filtered
 	newMeta, _ := json.Marshal(meta)
-	_, err = h.client.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+	_, err = h.lk(ctx).Rooms.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
 		Room: room.Name, Identity: identity, Metadata: string(newMeta),
 	})
 	if err != nil {
@@ -449,7 +611,20 @@ func (h *RoomHandler) DemoteParticipant(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"status": "success"})
 }
 
+// BlockChat stops a participant from posting chat messages.
 func (h *RoomHandler) BlockChat(c *fiber.Ctx) error {
+	return h.setChatBlocked(c, true)
+}
+
+// UnblockChat lets a blocked participant post chat messages again.
+func (h *RoomHandler) UnblockChat(c *fiber.Ctx) error {
+	return h.setChatBlocked(c, false)
+}
+
+// setChatBlocked persists the block (enforced by PostChatMessage) and mirrors
+// it into the participant's metadata and data permission for clients that
+// are connected.
+func (h *RoomHandler) setChatBlocked(c *fiber.Ctx, blocked bool) error {
 	roomID, identity := c.Params("roomId"), c.Params("identity")
 	claims := c.Locals("user").(*auth.Claims)
 	room, adminId, err := h.resolveRoom(c, roomID)
@@ -459,8 +634,12 @@ func (h *RoomHandler) BlockChat(c *fiber.Ctx) error {
 	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
 		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	p, err := h.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	if err := h.roomRepo.SetChatBlocked(room.ID, identity, blocked); err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Str("identity", identity).Msg("Failed to update chat block")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update chat block"})
+	}
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	p, err := h.lk(ctx).Rooms.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Participant not found"})
 	}
@@ -468,10 +647,12 @@ func (h *RoomHandler) BlockChat(c *fiber.Ctx) error {
 	if err := json.Unmarshal([]byte(p.Metadata), &meta); err != nil || meta == nil {
 		meta = map[string]interface{}{}
 	}
-	meta["chatBlocked"] = true
+	meta["chatBlocked"] = blocked
 	newMeta, _ := json.Marshal(meta)
-	_, err = h.client.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
-		Room: room.Name, Identity: identity, Metadata: string(newMeta),
+	// Data packets follow the block, so it can't be bypassed by publishing
+	// chat directly.
+	_, err = h.lk(ctx).Rooms.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+		Room: room.Name, Identity: identity, Metadata: string(newMeta), Permission: h.livePermission(room, adminId, p),
 	})
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -489,7 +670,7 @@ func (h *RoomHandler) DeafenParticipant(c *fiber.Ctx) error {
 	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
 		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
 	h.sendTargetedSystemMessage(ctx, room.Name, "deafen", claims.UserID, identity)
 	return c.JSON(fiber.Map{"status": "success"})
 }
@@ -504,7 +685,7 @@ func (h *RoomHandler) UndeafenParticipant(c *fiber.Ctx) error {
 	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
 		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
 	h.sendTargetedSystemMessage(ctx, room.Name, "undeafen", claims.UserID, identity)
 	return c.JSON(fiber.Map{"status": "success"})
 }
@@ -523,7 +704,7 @@ func (h *RoomHandler) AskParticipantAction(c *fiber.Ctx) error {
 		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
 	}
 	event := "ask_" + action
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
 	h.sendTargetedSystemMessage(ctx, room.Name, event, claims.UserID, identity)
 	return c.JSON(fiber.Map{"status": "success"})
 }
@@ -538,7 +719,7 @@ func (h *RoomHandler) SpotlightParticipant(c *fiber.Ctx) error {
 	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
 		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
 	// Broadcast to entire room so all clients pin this participant
 	h.sendSystemMessage(ctx, room.Name, "spotlight", claims.UserID, identity)
 	return c.JSON(fiber.Map{"status": "success"})
@@ -554,14 +735,14 @@ func (h *RoomHandler) StopScreenShare(c *fiber.Ctx) error {
 	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
 		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	p, err := h.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	p, err := h.lk(ctx).Rooms.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Participant not found"})
 	}
 	for _, track := range p.Tracks {
 		if track.Source == livekit.TrackSource_SCREEN_SHARE || track.Source == livekit.TrackSource_SCREEN_SHARE_AUDIO {
-			_, _ = h.client.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+			_, _ = h.lk(ctx).Rooms.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
 				Room: room.Name, Identity: identity, TrackSid: track.Sid, Muted: true,
 			})
 		}
@@ -580,8 +761,8 @@ func (h *RoomHandler) GetParticipantInfo(c *fiber.Ctx) error {
 	if claims.UserID != identity && !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
 		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	p, err := h.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	p, err := h.lk(ctx).Rooms.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Participant not found"})
 	}
@@ -627,12 +808,13 @@ func (h *RoomHandler) KickParticipant(c *fiber.Ctx) error {
 	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
 		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	_, err = h.client.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	_, err = h.lk(ctx).Rooms.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	h.sendSystemMessage(ctx, room.Name, "kick", claims.UserID, identity)
+	h.revokeE2EEKey(ctx, room, identity, claims.UserID)
 	return c.JSON(fiber.Map{"status": "success"})
 }
 
@@ -652,9 +834,12 @@ func (h *RoomHandler) DeleteRoom(c *fiber.Ctx) error {
 		return c.Status(403).JSON(fiber.Map{"error": "Only the room creator can delete this room"})
 	}
 
-	// Delete from LiveKit
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomCreate: true})
-	_, _ = h.client.DeleteRoom(ctx, &livekit.DeleteRoomRequest{Room: room.Name})
+	// Delete from LiveKit, breakout rooms included
+	breakouts, _ := h.roomRepo.GetBreakoutRooms(room.ID)
+	for i := range breakouts {
+		h.deleteLiveKitRoom(c.Context(), &breakouts[i])
+	}
+	h.deleteLiveKitRoom(c.Context(), room)
 
 	// Delete from database (superadmin bypass skips creator check)
 	var deleteErr error
@@ -689,14 +874,14 @@ func (h *RoomHandler) MuteParticipant(c *fiber.Ctx) error {
 	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
 		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	p, err := h.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	p, err := h.lk(ctx).Rooms.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Participant not found"})
 	}
 	for _, track := range p.Tracks {
 		if track.Type == livekit.TrackType_AUDIO {
-			_, _ = h.client.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+			_, _ = h.lk(ctx).Rooms.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
 				Room: room.Name, Identity: identity, TrackSid: track.Sid, Muted: true,
 			})
 		}
@@ -704,35 +889,6 @@ func (h *RoomHandler) MuteParticipant(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"status": "success"})
 }
 
-func (h *RoomHandler) BanParticipant(c *fiber.Ctx) error {
-	roomID, identity := c.Params("roomId"), c.Params("identity")
-	claims := c.Locals("user").(*auth.Claims)
-	room, err := h.roomRepo.GetRoom(roomID)
-	if err != nil {
-		log.Error().Err(err).Str("roomId", roomID).Msg("Failed to look up room")
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up room"})
-	}
-	if room == nil {
-		return c.SendStatus(404)
-	}
-	adminId := room.AdminID
-	if adminId == "" {
-		adminId = room.CreatedBy
-	}
-	if claims.UserID != adminId && !containsAccess(claims.Accesses, "superadmin") {
-		return c.Status(403).JSON(fiber.Map{"error": "Insufficient permissions"})
-	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	var removeErr error
-	_, removeErr = h.client.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
-	if removeErr != nil {
-		return c.Status(500).JSON(fiber.Map{"error": removeErr.Error()})
-	}
-	h.sendSystemMessage(ctx, room.Name, "ban", claims.UserID, identity)
-	_ = h.roomRepo.KickParticipant(room.ID, identity)
-	return c.JSON(fiber.Map{"status": "success"})
-}
-
 func containsAccess(accesses []string, target string) bool {
 	for _, a := range accesses {
 		if a == target {
@@ -752,26 +908,20 @@ func (h *RoomHandler) DisableParticipantVideo(c *fiber.Ctx) error {
 	if !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
 		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	p, err := h.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	p, err := h.lk(ctx).Rooms.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Participant not found"})
 	}
 	for _, track := range p.Tracks {
 		if track.Type == livekit.TrackType_VIDEO && track.Source == livekit.TrackSource_CAMERA {
-			_, _ = h.client.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+			_, _ = h.lk(ctx).Rooms.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
 				Room: room.Name, Identity: identity, TrackSid: track.Sid, Muted: true,
 			})
 		}
 	}
 	return c.JSON(fiber.Map{"status": "success"})
 }
-func (h *RoomHandler) BringToStage(c *fiber.Ctx) error {
-	return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "not yet implemented"})
-}
-func (h *RoomHandler) RemoveFromStage(c *fiber.Ctx) error {
-	return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "not yet implemented"})
-}
 
 func (h *RoomHandler) UpdateSettings(c *fiber.Ctx) error {
 	roomID := c.Params("roomId")
@@ -781,6 +931,8 @@ func (h *RoomHandler) UpdateSettings(c *fiber.Ctx) error {
 		IsPublic        *bool                `json:"isPublic"`
 		MaxParticipants *int                 `json:"maxParticipants"`
 		Settings        *models.RoomSettings `json:"settings"`
+		// Passcode sets or rotates the join passcode; an empty string clears it.
+		Passcode *string `json:"passcode"`
 	}
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid body"})
@@ -809,14 +961,44 @@ func (h *RoomHandler) UpdateSettings(c *fiber.Ctx) error {
 	if input.MaxParticipants != nil {
 		room.MaxParticipants = *input.MaxParticipants
 	}
+	prev := room.Settings
 	if input.Settings != nil {
+		if input.Settings.ChatRetentionDays < 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "chatRetentionDays cannot be negative"})
+		}
 		room.Settings = *input.Settings
 	}
+	if input.Passcode != nil {
+		if *input.Passcode == "" {
+			room.PasscodeHash, room.HasPasscode = "", false
+		} else {
+			if len(*input.Passcode) < passcodeMinLength || len(*input.Passcode) > passcodeMaxLength {
+				return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("passcode must be between %d and %d characters", passcodeMinLength, passcodeMaxLength)})
+			}
+			hashed, err := hashPasscode(*input.Passcode)
+			if err != nil {
+				log.Error().Err(err).Str("roomId", roomID).Msg("Failed to hash room passcode")
+				return c.Status(500).JSON(fiber.Map{"error": "Failed to update room settings"})
+			}
+			room.PasscodeHash, room.HasPasscode = hashed, true
+		}
+		h.passcodes.clearRoom(room.ID)
+	}
 
 	if err := h.roomRepo.UpdateRoom(room); err != nil {
 		log.Error().Err(err).Str("roomId", roomID).Msg("Failed to update room settings")
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to update room settings"})
 	}
+	if input.Passcode != nil {
+		if err := h.roomRepo.SetBreakoutPasscode(room.ID, room.PasscodeHash, room.HasPasscode); err != nil {
+			log.Error().Err(err).Str("roomId", roomID).Msg("Failed to update breakout room passcodes")
+		}
+	}
+
+	// Push changed publish restrictions to everyone already connected.
+	if prev.AllowAudio != room.Settings.AllowAudio || prev.AllowVideo != room.Settings.AllowVideo || prev.AllowChat != room.Settings.AllowChat {
+		h.syncRoomPermissions(c.Context(), room, adminID)
+	}
 
 	return c.JSON(room)
 }
@@ -831,8 +1013,133 @@ func (h *RoomHandler) AdminListRooms(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"rooms": rooms, "total": total, "page": page, "limit": limit})
 }
 
+// Admin-minted token lifetime bounds.
+const (
+	adminTokenDefaultTTL = time.Hour
+	adminTokenMaxTTL     = 7 * 24 * time.Hour
+)
+
+// validPublishSources are the LiveKit track sources accepted in canPublishSources.
+var validPublishSources = map[string]bool{
+	"camera": true, "microphone": true, "screen_share": true, "screen_share_audio": true,
+}
+
+type AdminGenerateTokenRequest struct {
+	Identity          string   `json:"identity"`
+	Name              string   `json:"name"`
+	Metadata          string   `json:"metadata"`
+	TTLSeconds        int      `json:"ttlSeconds"`
+	Hidden            bool     `json:"hidden"`
+	Recorder          bool     `json:"recorder"`
+	SubscribeOnly     bool     `json:"subscribeOnly"`
+	CanPublishSources []string `json:"canPublishSources"`
+}
+
+// AdminGenerateToken mints a LiveKit join token with a custom identity and
+// grant set, for bots, agents and monitoring probes. Every issued token is
+// recorded for auditing; the token itself is only returned once.
 func (h *RoomHandler) AdminGenerateToken(c *fiber.Ctx) error {
-	return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": "not yet implemented"})
+	var req AdminGenerateTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	claims := c.Locals("user").(*auth.Claims)
+	room, err := h.roomRepo.GetRoom(c.Params("roomId"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch room"})
+	}
+	if room == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Room not found"})
+	}
+
+	req.Identity = strings.TrimSpace(req.Identity)
+	if req.Identity == "" {
+		req.Identity = "bot-" + generateShortID()
+	}
+	if req.Name == "" {
+		req.Name = req.Identity
+	}
+	ttl := adminTokenDefaultTTL
+	if req.TTLSeconds < 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "ttlSeconds must be positive"})
+	}
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > adminTokenMaxTTL {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("ttlSeconds may not exceed %d", int(adminTokenMaxTTL.Seconds()))})
+	}
+	for i, src := range req.CanPublishSources {
+		src = strings.ToLower(strings.TrimSpace(src))
+		if !validPublishSources[src] {
+			return c.Status(400).JSON(fiber.Map{"error": "Unknown publish source: " + src})
+		}
+		req.CanPublishSources[i] = src
+	}
+
+	grant := &lkauth.VideoGrant{
+		RoomJoin:     true,
+		Room:         room.Name,
+		CanSubscribe: boolPtr(true),
+		Hidden:       req.Hidden,
+		Recorder:     req.Recorder,
+	}
+	if req.SubscribeOnly {
+		grant.CanPublish = boolPtr(false)
+		grant.CanPublishData = boolPtr(false)
+		req.CanPublishSources = nil
+	} else if len(req.CanPublishSources) > 0 {
+		grant.CanPublish = boolPtr(true)
+		grant.CanPublishSources = req.CanPublishSources
+	}
+
+	lk := h.node(room)
+	at := lkauth.NewAccessToken(lk.APIKey, lk.APISecret)
+	at.AddGrant(grant).SetIdentity(req.Identity).SetName(req.Name).SetValidFor(ttl) //nolint:staticcheck // AddGrant is deprecated but VideoGrant field is not available in this version of the protocol SDK
+	if req.Metadata != "" {
+		at.SetMetadata(req.Metadata)
+	}
+	token, err := at.ToJWT()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to sign admin LiveKit token")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to generate room token"})
+	}
+
+	record := &models.IssuedRoomToken{
+		RoomID:            room.ID,
+		RoomName:          room.Name,
+		Identity:          req.Identity,
+		Name:              req.Name,
+		Metadata:          req.Metadata,
+		Hidden:            req.Hidden,
+		Recorder:          req.Recorder,
+		SubscribeOnly:     req.SubscribeOnly,
+		CanPublishSources: req.CanPublishSources,
+		IssuedBy:          claims.UserID,
+		ExpiresAt:         time.Now().Add(ttl),
+	}
+	if err := h.roomRepo.RecordIssuedToken(record); err != nil {
+		log.Error().Err(err).Str("roomId", room.ID).Msg("Failed to record issued token")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to record issued token"})
+	}
+	log.Info().Str("roomId", room.ID).Str("identity", req.Identity).Str("issuedBy", claims.UserID).Dur("ttl", ttl).Msg("Admin issued LiveKit token")
+
+	return c.JSON(fiber.Map{
+		"id": record.ID, "token": token, "identity": req.Identity, "room": room.Name,
+		"expiresAt": record.ExpiresAt, "livekitHost": lk.Host,
+	})
+}
+
+// AdminListIssuedTokens returns the audit records of admin-minted tokens for a room.
+func (h *RoomHandler) AdminListIssuedTokens(c *fiber.Ctx) error {
+	tokens, err := h.roomRepo.GetIssuedTokens(c.Params("roomId"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch issued tokens"})
+	}
+	if tokens == nil {
+		tokens = []models.IssuedRoomToken{}
+	}
+	return c.JSON(fiber.Map{"tokens": tokens})
 }
 
 func (h *RoomHandler) AdminCloseRoom(c *fiber.Ctx) error {
@@ -844,8 +1151,8 @@ func (h *RoomHandler) AdminCloseRoom(c *fiber.Ctx) error {
 	if room == nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Room not found"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomCreate: true})
-	_, _ = h.client.DeleteRoom(ctx, &livekit.DeleteRoomRequest{Room: room.Name})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomCreate: true})
+	_, _ = h.lk(ctx).Rooms.DeleteRoom(ctx, &livekit.DeleteRoomRequest{Room: room.Name})
 	room.IsActive = false
 	if err := h.roomRepo.UpdateRoom(room); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to close room"})
@@ -885,37 +1192,62 @@ func (h *RoomHandler) GetOnlineCount(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"count": count})
 }
 
-// AdminLiveKitStats returns aggregate stats from the LiveKit server.
+// AdminLiveKitStats returns aggregate stats across all LiveKit nodes. Nodes
+// that can't be reached are reported in nodes and left out of the totals.
 func (h *RoomHandler) AdminLiveKitStats(c *fiber.Ctx) error {
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomList: true})
-	resp, err := h.client.ListRooms(ctx, &livekit.ListRoomsRequest{})
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch LiveKit stats"})
-	}
-
 	type RoomStat struct {
 		Name            string `json:"name"`
+		Node            string `json:"node"`
 		NumParticipants uint32 `json:"numParticipants"`
 		NumPublishers   uint32 `json:"numPublishers"`
 		CreationTime    int64  `json:"creationTime"`
 	}
+	type NodeStat struct {
+		ID          string `json:"id"`
+		Region      string `json:"region"`
+		Host        string `json:"host"`
+		Healthy     bool   `json:"healthy"`
+		ActiveRooms int    `json:"activeRooms"`
+		Error       string `json:"error,omitempty"`
+	}
 	var totalParticipants, totalPublishers uint32
-	rooms := make([]RoomStat, 0, len(resp.Rooms))
-	for _, r := range resp.Rooms {
-		totalParticipants += r.NumParticipants
-		totalPublishers += r.NumPublishers
-		rooms = append(rooms, RoomStat{
-			Name:            r.Name,
-			NumParticipants: r.NumParticipants,
-			NumPublishers:   r.NumPublishers,
-			CreationTime:    r.CreationTime,
-		})
+	rooms := []RoomStat{}
+	nodes := []NodeStat{}
+	reached := 0
+	for _, n := range h.nodes.Nodes() {
+		stat := NodeStat{ID: n.ID, Region: n.Region, Host: n.Host, Healthy: n.Healthy()}
+		ctx := n.WithAuth(c.Context(), &lkauth.VideoGrant{RoomList: true})
+		resp, err := n.Rooms.ListRooms(ctx, &livekit.ListRoomsRequest{})
+		if err != nil {
+			log.Warn().Err(err).Str("node", n.ID).Msg("Failed to list LiveKit rooms")
+			stat.Healthy, stat.Error = false, "unreachable"
+			nodes = append(nodes, stat)
+			continue
+		}
+		reached++
+		stat.ActiveRooms = len(resp.Rooms)
+		nodes = append(nodes, stat)
+		for _, r := range resp.Rooms {
+			totalParticipants += r.NumParticipants
+			totalPublishers += r.NumPublishers
+			rooms = append(rooms, RoomStat{
+				Name:            r.Name,
+				Node:            n.ID,
+				NumParticipants: r.NumParticipants,
+				NumPublishers:   r.NumPublishers,
+				CreationTime:    r.CreationTime,
+			})
+		}
+	}
+	if reached == 0 {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch LiveKit stats"})
 	}
 	return c.JSON(fiber.Map{
 		"totalParticipants": totalParticipants,
 		"totalPublishers":   totalPublishers,
-		"activeRooms":       len(resp.Rooms),
+		"activeRooms":       len(rooms),
 		"rooms":             rooms,
+		"nodes":             nodes,
 	})
 }
 
@@ -930,8 +1262,8 @@ func (h *RoomHandler) AdminGetRoomParticipants(c *fiber.Ctx) error {
 		return c.Status(404).JSON(fiber.Map{"error": "Room not found"})
 	}
 
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	resp, err := h.client.ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: room.Name})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	resp, err := h.lk(ctx).Rooms.ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: room.Name})
 	if err != nil {
 		// Room may not be active in LiveKit — return empty list
 		return c.JSON(fiber.Map{"participants": []struct{}{}, "room": room})
@@ -1034,12 +1366,13 @@ func (h *RoomHandler) AdminKickParticipant(c *fiber.Ctx) error {
 	if room == nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Room not found"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
-	_, err := h.client.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	_, err := h.lk(ctx).Rooms.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	h.sendSystemMessage(ctx, room.Name, "kick", claims.UserID, identity)
+	h.revokeE2EEKey(ctx, room, identity, claims.UserID)
 	return c.JSON(fiber.Map{"status": "success"})
 }
 
@@ -1051,16 +1384,16 @@ func (h *RoomHandler) AdminMuteParticipant(c *fiber.Ctx) error {
 	if room == nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Room not found"})
 	}
-	ctx := h.withAuth(c.Context(), &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
 
 	// Get participant to find their audio track SIDs
-	p, err := h.client.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	p, err := h.lk(ctx).Rooms.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Participant not found"})
 	}
 	for _, track := range p.Tracks {
 		if track.Type == livekit.TrackType_AUDIO {
-			_, _ = h.client.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+			_, _ = h.lk(ctx).Rooms.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
 				Room:     room.Name,
 				Identity: identity,
 				TrackSid: track.Sid,
@@ -11,9 +11,11 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
 )
 
 // setupRoomTestApp builds a Fiber app wired to the RoomHandler with an in-memory DB.
@@ -50,6 +52,8 @@ func setupRoomTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *au
 	app.Get("/admin/rooms", handler.AdminListRooms)
 	app.Put("/admin/rooms/:roomId", handler.AdminUpdateRoom)
 	app.Post("/admin/rooms/:roomId/close", handler.AdminCloseRoom)
+	app.Post("/admin/rooms/:roomId/token", handler.AdminGenerateToken)
+	app.Get("/admin/rooms/:roomId/tokens", handler.AdminListIssuedTokens)
 	app.Get("/online-count", handler.GetOnlineCount)
 
 	// Seed a user so room creation via repo doesn't violate FK
@@ -497,3 +501,184 @@ func TestGuestJoinRoom_PrivateRoomBlocked(t *testing.T) {
 		t.Fatalf("expected 403 for guest joining private room, got %d", resp.StatusCode)
 	}
 }
+
+func TestRoomHandler_AdminGenerateToken_CustomGrants(t *testing.T) {
+	app, roomRepo, _ := setupRoomTestApp(t)
+	room, _ := roomRepo.CreateRoom("creator-user", "bot-room", true, "standard", &models.RoomSettings{})
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/admin/rooms/"+room.ID+"/token", map[string]interface{}{
+		"identity":          "radio-agent",
+		"name":              "Radio",
+		"metadata":          `{"kind":"agent"}`,
+		"ttlSeconds":        600,
+		"hidden":            true,
+		"canPublishSources": []string{"microphone"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v)", status, body)
+	}
+
+	v, err := lkauth.ParseAPIToken(body["token"].(string))
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	_, grants, err := v.Verify("test-secret")
+	if err != nil {
+		t.Fatalf("verify token: %v", err)
+	}
+	if v.Identity() != "radio-agent" || grants.Name != "Radio" || grants.Metadata != `{"kind":"agent"}` {
+		t.Fatalf("unexpected identity/name/metadata: %s %s %s", v.Identity(), grants.Name, grants.Metadata)
+	}
+	if !grants.Video.Hidden || grants.Video.Room != room.Name {
+		t.Fatalf("unexpected video grant: %+v", grants.Video)
+	}
+	if len(grants.Video.CanPublishSources) != 1 || grants.Video.CanPublishSources[0] != "microphone" {
+		t.Fatalf("expected microphone-only sources, got %v", grants.Video.CanPublishSources)
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodGet, "/admin/rooms/"+room.ID+"/tokens", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	tokens, _ := body["tokens"].([]interface{})
+	if len(tokens) != 1 {
+		t.Fatalf("expected 1 issued token record, got %d", len(tokens))
+	}
+	rec := tokens[0].(map[string]interface{})
+	if rec["identity"] != "radio-agent" || rec["issuedBy"] != "creator-user" {
+		t.Fatalf("unexpected audit record: %v", rec)
+	}
+	if _, leaked := rec["token"]; leaked {
+		t.Fatal("audit record must not contain the token")
+	}
+}
+
+func TestRoomHandler_AdminGenerateToken_SubscribeOnly(t *testing.T) {
+	app, roomRepo, _ := setupRoomTestApp(t)
+	room, _ := roomRepo.CreateRoom("creator-user", "probe-room", true, "standard", &models.RoomSettings{})
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/admin/rooms/"+room.ID+"/token", map[string]interface{}{
+		"subscribeOnly": true,
+	})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if !strings.HasPrefix(body["identity"].(string), "bot-") {
+		t.Fatalf("expected generated bot identity, got %v", body["identity"])
+	}
+	if tokenCanPublish(t, body["token"].(string)) {
+		t.Fatal("subscribe-only token must not allow publishing")
+	}
+}
+
+func TestRoomHandler_AdminGenerateToken_Invalid(t *testing.T) {
+	app, roomRepo, _ := setupRoomTestApp(t)
+	room, _ := roomRepo.CreateRoom("creator-user", "bad-token-room", true, "standard", &models.RoomSettings{})
+
+	cases := map[string]map[string]interface{}{
+		"unknown source": {"canPublishSources": []string{"hologram"}},
+		"ttl too long":   {"ttlSeconds": 60 * 60 * 24 * 30},
+	}
+	for name, payload := range cases {
+		status, _ := doJSONRequest(t, app, http.MethodPost, "/admin/rooms/"+room.ID+"/token", payload)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, status)
+		}
+	}
+
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/admin/rooms/missing/token", map[string]interface{}{})
+	if status != http.StatusNotFound {
+		t.Errorf("expected 404 for missing room, got %d", status)
+	}
+}
+
+func TestCreateRoom_PlacesRoomOnRegionNode(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+	lkCfg := config.LiveKitConfig{Nodes: []config.LiveKitNodeConfig{
+		{ID: "eu-1", Region: "eu", Host: "wss://eu.example.com", APIKey: "eu-key", APISecret: "eu-secret"},
+		{ID: "us-1", Region: "us", Host: "wss://us.example.com", APIKey: "us-key", APISecret: "us-secret"},
+	}}
+	handler := NewRoomHandler(&lkCfg, &config.ChatConfig{}, roomRepo)
+	eu, us := &fakeBreakoutRoomService{}, &fakeBreakoutRoomService{}
+	handler.nodes.Node("eu-1").Rooms = eu
+	handler.nodes.Node("us-1").Rooms = us
+
+	claims := &auth.Claims{UserID: "creator-user", Name: "Creator", Accesses: []string{"user"}}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", claims)
+		return c.Next()
+	})
+	app.Post("/room/create", handler.CreateRoom)
+	app.Post("/room/join", handler.JoinRoom)
+	db.Create(&models.User{ID: "creator-user", Email: "creator@ex.com", Name: "Creator", Provider: "local", IsActive: true})
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/create", map[string]string{"name": "us-standup", "region": "us"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v)", status, body)
+	}
+	if body["livekitNode"] != "us-1" || body["livekitHost"] != "wss://us.example.com" {
+		t.Fatalf("expected the room on us-1, got %v", body)
+	}
+	if len(us.created) != 1 || len(eu.created) != 0 {
+		t.Fatalf("expected LiveKit CreateRoom on us-1 only, got us=%v eu=%v", us.created, eu.created)
+	}
+	room, _ := roomRepo.GetRoomByName("us-standup")
+	if room.LiveKitNode != "us-1" {
+		t.Fatalf("expected the node to be stored, got %q", room.LiveKitNode)
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": "us-standup"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 joining, got %d (%v)", status, body)
+	}
+	if body["livekitHost"] != "wss://us.example.com" {
+		t.Errorf("expected the join to point at us-1, got %v", body["livekitHost"])
+	}
+	v, err := lkauth.ParseAPIToken(body["token"].(string))
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	if _, _, err := v.Verify("us-secret"); err != nil || v.APIKey() != "us-key" {
+		t.Errorf("expected the token to be signed with us-1's key, got %s (%v)", v.APIKey(), err)
+	}
+}
+
+func TestCreateRoom_DeletesMediaRoomWhenStoreFails(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+	lkCfg := config.LiveKitConfig{Host: "http://localhost:9999", APIKey: "test-key", APISecret: "test-secret"}
+	handler := NewRoomHandler(&lkCfg, &config.ChatConfig{}, roomRepo)
+	fake := &fakeBreakoutRoomService{}
+	handler.nodes.Default().Rooms = fake
+
+	claims := &auth.Claims{UserID: "creator-user", Name: "Creator", Accesses: []string{"user"}}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", claims)
+		return c.Next()
+	})
+	app.Post("/room/create", handler.CreateRoom)
+	if _, err := roomRepo.CreateRoom("creator-user", "taken-name", true, models.RoomModeStandard, &models.RoomSettings{}); err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+
+	// A taken name must leave the live media room alone.
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/create", map[string]string{"name": "taken-name"})
+	if status != http.StatusConflict || len(fake.deleted) != 0 {
+		t.Fatalf("expected 409 without deleting the media room, got %d (deleted %v)", status, fake.deleted)
+	}
+
+	// Make the insert fail.
+	if err := db.Migrator().DropTable(&models.RoomPermissions{}); err != nil {
+		t.Fatalf("drop table: %v", err)
+	}
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/create", map[string]string{"name": "doomed-room"})
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", status)
+	}
+	if len(fake.deleted) != 1 || fake.deleted[0] != "doomed-room" {
+		t.Fatalf("expected the orphaned media room to be deleted, got %v", fake.deleted)
+	}
+}
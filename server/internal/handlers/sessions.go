@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// SessionResponse is a signed-in device. Current marks the session the
+// request was made from.
+type SessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// ListSessions returns the caller's signed-in devices.
+func (h *AuthHandler) ListSessions(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	sessions, err := h.authService.ListSessions(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to list sessions"})
+	}
+	resp := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, SessionResponse{Session: s, Current: s.ID == claims.SessionID})
+	}
+	return c.JSON(resp)
+}
+
+// RevokeSession signs one of the caller's devices out.
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	found, err := h.authService.SignOutSession(claims.UserID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign out session"})
+	}
+	if !found {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Session not found"})
+	}
+	if c.Params("id") == claims.SessionID {
+		clearAuthCookies(c, h.config)
+	}
+	return c.JSON(fiber.Map{"message": "Session signed out"})
+}
+
+// RevokeOtherSessions signs the caller out everywhere except the current
+// device.
+func (h *AuthHandler) RevokeOtherSessions(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	n, err := h.authService.SignOutEverywhere(claims.UserID, claims.SessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign out sessions"})
+	}
+	return c.JSON(fiber.Map{"message": "Other sessions signed out", "count": n})
+}
+
+// AdminRevokeUserSessions signs a user out on every device.
+func (h *AuthHandler) AdminRevokeUserSessions(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*auth.Claims)
+	userID := c.Params("id")
+	user, err := h.authService.GetUserByID(userID)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+	n, err := h.authService.SignOutEverywhere(userID, "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign out sessions"})
+	}
+	log.Info().Str("admin", claims.UserID).Str("userId", userID).Int("sessions", n).Msg("Admin signed user out everywhere")
+	return c.JSON(fiber.Map{"message": "User signed out everywhere", "count": n})
+}
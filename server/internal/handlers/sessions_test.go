@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupSessionsTestApp(t *testing.T) (*fiber.App, *auth.AuthService) {
+	t.Helper()
+	app, authService, cfg := setupAuthTestApp(t)
+	authHandler := NewAuthHandler(authService, cfg, nil, nil)
+
+	protected := func(c *fiber.Ctx) error {
+		claims, err := auth.ValidateToken(strings.TrimPrefix(c.Get("Authorization"), bearerPrefix), cfg)
+		if err != nil {
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{"error": "invalid"})
+		}
+		c.Locals("user", claims)
+		return c.Next()
+	}
+	app.Post("/api/auth/refresh", authHandler.RefreshToken)
+	app.Get("/api/auth/sessions", protected, authHandler.ListSessions)
+	app.Delete("/api/auth/sessions", protected, authHandler.RevokeOtherSessions)
+	app.Delete("/api/auth/sessions/:id", protected, authHandler.RevokeSession)
+	app.Delete("/api/admin/users/:id/sessions", protected, authHandler.AdminRevokeUserSessions)
+	return app, authService
+}
+
+// sessionRequest sends a request with an optional bearer token and device name.
+func sessionRequest(t *testing.T, app *fiber.App, method, path, token, device string, body interface{}) (int, []byte) {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-agent")
+	if token != "" {
+		req.Header.Set("Authorization", bearerPrefix+token)
+	}
+	if device != "" {
+		req.Header.Set("X-Device-Name", device)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(resp.Body)
+	return resp.StatusCode, buf.Bytes()
+}
+
+func loginDevice(t *testing.T, app *fiber.App, device string) auth.LoginResponse {
+	t.Helper()
+	status, raw := sessionRequest(t, app, http.MethodPost, "/api/auth/login", "", device,
+		map[string]string{"email": "devices@example.com", "password": "a-long-password"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 logging in on %s, got %d: %s", device, status, raw)
+	}
+	var login auth.LoginResponse
+	_ = json.Unmarshal(raw, &login)
+	return login
+}
+
+func TestSessions_ListAndSignOutOthers(t *testing.T) {
+	app, authService := setupSessionsTestApp(t)
+	_, _ = authService.Register("devices@example.com", "a-long-password", "Devices")
+
+	laptop := loginDevice(t, app, "Laptop")
+	phone := loginDevice(t, app, "Phone")
+
+	status, raw := sessionRequest(t, app, http.MethodGet, "/api/auth/sessions", phone.Token.AccessToken, "", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200 listing sessions, got %d", status)
+	}
+	var sessions []SessionResponse
+	_ = json.Unmarshal(raw, &sessions)
+	if len(sessions) != 2 {
+		t.Fatalf("expected both devices to stay signed in, got %d sessions", len(sessions))
+	}
+	for _, s := range sessions {
+		if s.Current != (s.DeviceName == "Phone") || s.UserAgent != "test-agent" {
+			t.Errorf("unexpected session %+v", s)
+		}
+	}
+
+	if status, _ = sessionRequest(t, app, http.MethodDelete, "/api/auth/sessions", phone.Token.AccessToken, "", nil); status != http.StatusOK {
+		t.Fatalf("expected 200 signing out other sessions, got %d", status)
+	}
+	if status, _ = sessionRequest(t, app, http.MethodGet, "/api/auth/sessions", laptop.Token.AccessToken, "", nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected the laptop to be signed out, got %d", status)
+	}
+	if status, _ = sessionRequest(t, app, http.MethodGet, "/api/auth/sessions", phone.Token.AccessToken, "", nil); status != http.StatusOK {
+		t.Fatalf("expected the phone to stay signed in, got %d", status)
+	}
+}
+
+func TestSessions_RevokeOneAndAdminSignOut(t *testing.T) {
+	app, authService := setupSessionsTestApp(t)
+	user, _ := authService.Register("devices@example.com", "a-long-password", "Devices")
+
+	laptop := loginDevice(t, app, "Laptop")
+	phone := loginDevice(t, app, "Phone")
+
+	sessions, _ := authService.ListSessions(user.ID)
+	var phoneID string
+	for _, s := range sessions {
+		if s.DeviceName == "Phone" {
+			phoneID = s.ID
+		}
+	}
+	if status, _ := sessionRequest(t, app, http.MethodDelete, "/api/auth/sessions/"+phoneID, laptop.Token.AccessToken, "", nil); status != http.StatusOK {
+		t.Fatalf("expected 200 signing the phone out, got %d", status)
+	}
+	if status, _ := sessionRequest(t, app, http.MethodDelete, "/api/auth/sessions/"+phoneID, laptop.Token.AccessToken, "", nil); status != http.StatusNotFound {
+		t.Fatalf("expected 404 for a session already signed out, got %d", status)
+	}
+	if status, _ := sessionRequest(t, app, http.MethodPost, "/api/auth/refresh", "", "", map[string]string{"refresh_token": phone.Token.RefreshToken}); status != http.StatusUnauthorized {
+		t.Fatalf("expected the phone's refresh token to stop working, got %d", status)
+	}
+
+	status, raw := sessionRequest(t, app, http.MethodDelete, "/api/admin/users/"+user.ID+"/sessions", laptop.Token.AccessToken, "", nil)
+	if status != http.StatusOK || !strings.Contains(string(raw), `"count":1`) {
+		t.Fatalf("expected the admin to sign out the last session, got %d: %s", status, raw)
+	}
+	if status, _ = sessionRequest(t, app, http.MethodGet, "/api/auth/sessions", laptop.Token.AccessToken, "", nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected the laptop to be signed out, got %d", status)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/rs/zerolog/log"
+)
+
+// BringToStage lets an audience member publish in a stage room.
+func (h *RoomHandler) BringToStage(c *fiber.Ctx) error {
+	return h.setStage(c, true)
+}
+
+// RemoveFromStage revokes publishing for a participant and unpublishes their
+// tracks. Participants may also step down from the stage themselves.
+func (h *RoomHandler) RemoveFromStage(c *fiber.Ctx) error {
+	return h.setStage(c, false)
+}
+
+func (h *RoomHandler) setStage(c *fiber.Ctx, onStage bool) error {
+	roomID, identity := c.Params("roomId"), c.Params("identity")
+	claims := c.Locals("user").(*auth.Claims)
+	room, adminId, err := h.resolveRoom(c, roomID)
+	if err != nil {
+		return nil
+	}
+	selfLeave := !onStage && claims.UserID == identity
+	if !selfLeave && !isRoomModerator(claims, adminId, room.ID, h.roomRepo) {
+		return c.Status(403).JSON(fiber.Map{"error": "not authorized for this room"})
+	}
+	if room.Mode != models.RoomModeStage {
+		return c.Status(400).JSON(fiber.Map{"error": "Room is not in stage mode"})
+	}
+
+	if onStage {
+		err = h.roomRepo.BringToStage(room.ID, identity)
+	} else {
+		err = h.roomRepo.RemoveFromStage(room.ID, identity)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("roomID", room.ID).Str("identity", identity).Msg("Failed to update stage status")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to update stage status"})
+	}
+
+	ctx := h.withAuth(c.Context(), room, &lkauth.VideoGrant{RoomAdmin: true, Room: room.Name})
+	p, err := h.lk(ctx).Rooms.GetParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room.Name, Identity: identity})
+	if err != nil {
+		// Not connected right now; the stored stage flag applies on their next join.
+		return c.JSON(fiber.Map{"status": "success"})
+	}
+
+	if err := h.updateLivePermission(ctx, room, adminId, p); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	event := "stage_join"
+	if !onStage {
+		event = "stage_leave"
+		// LiveKit unpublishes on permission loss; mute anything still live so
+		// nothing leaks in the meantime.
+		for _, track := range p.Tracks {
+			_, _ = h.lk(ctx).Rooms.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+				Room: room.Name, Identity: identity, TrackSid: track.Sid, Muted: true,
+			})
+		}
+	}
+	h.sendSystemMessage(ctx, room.Name, event, claims.UserID, identity)
+	return c.JSON(fiber.Map{"status": "success"})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bedrud/internal/auth"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+)
+
+func setupStageTestApp(t *testing.T, mode string) (*fiber.App, *repository.RoomRepository, *models.Room, **auth.Claims) {
+	t.Helper()
+	f := newRoomFixture(t, roomFixtureOptions{
+		name: "stage-room",
+		mode: mode,
+		routes: func(app *fiber.App, h *RoomHandler) {
+			app.Post("/room/:roomId/stage/:identity/bring", h.BringToStage)
+			app.Post("/room/:roomId/stage/:identity/remove", h.RemoveFromStage)
+		},
+	})
+	return f.app, f.repo, f.room, &f.current
+}
+
+// tokenCanPublish decodes a LiveKit join token and returns its publish grant.
+func tokenCanPublish(t *testing.T, token string) bool {
+	t.Helper()
+	v, err := lkauth.ParseAPIToken(token)
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	_, claims, err := v.Verify("test-secret")
+	if err != nil {
+		t.Fatalf("verify token: %v", err)
+	}
+	return claims.Video.GetCanPublish()
+}
+
+func TestStage_AudienceJoinsWithoutPublish(t *testing.T) {
+	app, _, room, current := setupStageTestApp(t, models.RoomModeStage)
+
+	status, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if !tokenCanPublish(t, body["token"].(string)) {
+		t.Error("owner should be able to publish in a stage room")
+	}
+
+	*current = &auth.Claims{UserID: "audience-user", Name: "Audience", Accesses: []string{"user"}}
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if tokenCanPublish(t, body["token"].(string)) {
+		t.Error("audience member should not be able to publish")
+	}
+
+	status, body = doJSONRequest(t, app, http.MethodPost, "/room/guest-join", map[string]string{"roomName": room.Name, "guestName": "Guest"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if tokenCanPublish(t, body["token"].(string)) {
+		t.Error("guest should join the audience")
+	}
+}
+
+func TestStage_BringAndRemove(t *testing.T) {
+	app, roomRepo, room, current := setupStageTestApp(t, models.RoomModeStage)
+	owner := *current
+	_ = roomRepo.AddParticipant(room.ID, "audience-user")
+
+	*current = &auth.Claims{UserID: "audience-user", Name: "Audience", Accesses: []string{"user"}}
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/stage/audience-user/bring", nil)
+	if status != http.StatusForbidden {
+		t.Fatalf("expected 403 for self-promotion, got %d", status)
+	}
+
+	*current = owner
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/stage/audience-user/bring", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if on, _ := roomRepo.IsParticipantOnStage(room.ID, "audience-user"); !on {
+		t.Fatal("expected participant on stage")
+	}
+
+	*current = &auth.Claims{UserID: "audience-user", Name: "Audience", Accesses: []string{"user"}}
+	_, body := doJSONRequest(t, app, http.MethodPost, "/room/join", map[string]string{"roomName": room.Name})
+	if !tokenCanPublish(t, body["token"].(string)) {
+		t.Error("on-stage participant should be able to publish")
+	}
+
+	// Participants may step down themselves.
+	status, _ = doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/stage/audience-user/remove", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if on, _ := roomRepo.IsParticipantOnStage(room.ID, "audience-user"); on {
+		t.Fatal("expected participant off stage")
+	}
+}
+
+func TestStage_BringWithoutParticipantRecord(t *testing.T) {
+	app, roomRepo, room, _ := setupStageTestApp(t, models.RoomModeStage)
+
+	// Participants joined with an issued token have no record until the
+	// LiveKit webhook arrives.
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/stage/radio-agent/bring", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if on, _ := roomRepo.IsParticipantOnStage(room.ID, "radio-agent"); !on {
+		t.Fatal("expected the stage flag to be recorded")
+	}
+	p, _ := roomRepo.GetParticipant(room.ID, "radio-agent")
+	if p == nil || p.IsActive {
+		t.Fatalf("expected an inactive participant record, got %+v", p)
+	}
+}
+
+func TestStage_NotStageRoom(t *testing.T) {
+	app, _, room, _ := setupStageTestApp(t, models.RoomModeStandard)
+	status, _ := doJSONRequest(t, app, http.MethodPost, "/room/"+room.ID+"/stage/someone/bring", nil)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", status)
+	}
+}
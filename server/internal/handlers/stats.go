@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	statsDefaultRange = 7 * 24 * time.Hour
+	// statsMaxHourlyRange matches how long the scheduler keeps hourly buckets.
+	statsMaxHourlyRange = 31 * 24 * time.Hour
+)
+
+// StatsHandler serves the usage history sampled by the scheduler.
+type StatsHandler struct {
+	repo *repository.StatsRepository
+}
+
+func NewStatsHandler(repo *repository.StatsRepository) *StatsHandler {
+	return &StatsHandler{repo: repo}
+}
+
+// UsagePoint is one bucket in GET /admin/stats/history.
+type UsagePoint struct {
+	Start              time.Time `json:"start"`
+	Samples            int       `json:"samples"`
+	ParticipantsMax    int       `json:"participantsMax"`
+	ParticipantsAvg    float64   `json:"participantsAvg"`
+	RoomsMax           int       `json:"roomsMax"`
+	RoomsAvg           float64   `json:"roomsAvg"`
+	PublishersMax      int       `json:"publishersMax"`
+	PublishersAvg      float64   `json:"publishersAvg"`
+	ParticipantMinutes float64   `json:"participantMinutes"`
+	RoomMinutes        float64   `json:"roomMinutes"`
+}
+
+// History returns usage aggregates between ?from and ?to (RFC 3339 or
+// YYYY-MM-DD, default the last 7 days) in ?bucket=hour|day|week|month
+// buckets. Without a bucket, ranges up to two days are served hourly and
+// longer ones daily. Weeks start on Monday; all buckets are aligned to UTC.
+func (h *StatsHandler) History(c *fiber.Ctx) error {
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		t, ok := parseStatsTime(v)
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid to time"})
+		}
+		to = t
+	}
+	from := to.Add(-statsDefaultRange)
+	if v := c.Query("from"); v != "" {
+		t, ok := parseStatsTime(v)
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid from time"})
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return c.Status(400).JSON(fiber.Map{"error": "from must be before to"})
+	}
+
+	bucket := c.Query("bucket")
+	if bucket == "" {
+		bucket = models.UsageBucketDay
+		if to.Sub(from) <= 2*24*time.Hour {
+			bucket = models.UsageBucketHour
+		}
+	}
+	stored := models.UsageBucketDay
+	switch bucket {
+	case models.UsageBucketHour:
+		if to.Sub(from) > statsMaxHourlyRange {
+			return c.Status(400).JSON(fiber.Map{"error": "Hourly stats are only kept for 31 days"})
+		}
+		stored = models.UsageBucketHour
+	case models.UsageBucketDay, "week", "month":
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "bucket must be hour, day, week or month"})
+	}
+
+	from = statsBucketStart(bucket, from)
+	stats, err := h.repo.GetUsageStats(stored, from, to)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load usage stats")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load usage stats"})
+	}
+
+	points := []UsagePoint{}
+	var cur *models.UsageStat
+	flush := func() {
+		if cur != nil {
+			points = append(points, usagePoint(cur))
+		}
+	}
+	for i := range stats {
+		start := statsBucketStart(bucket, stats[i].BucketStart)
+		if cur == nil || !cur.BucketStart.Equal(start) {
+			flush()
+			cur = &models.UsageStat{Bucket: bucket, BucketStart: start}
+		}
+		cur.Merge(&stats[i])
+	}
+	flush()
+
+	return c.JSON(fiber.Map{"bucket": bucket, "from": from, "to": to.UTC(), "points": points})
+}
+
+func usagePoint(s *models.UsageStat) UsagePoint {
+	participants, rooms, publishers := s.Averages()
+	return UsagePoint{
+		Start:              s.BucketStart,
+		Samples:            s.Samples,
+		ParticipantsMax:    s.ParticipantsMax,
+		ParticipantsAvg:    participants,
+		RoomsMax:           s.RoomsMax,
+		RoomsAvg:           rooms,
+		PublishersMax:      s.PublishersMax,
+		PublishersAvg:      publishers,
+		ParticipantMinutes: s.ParticipantMinutes,
+		RoomMinutes:        s.RoomMinutes,
+	}
+}
+
+// statsBucketStart extends models.UsageBucketStart with the week and month
+// buckets that are only assembled at query time.
+func statsBucketStart(bucket string, t time.Time) time.Time {
+	day := models.UsageBucketStart(models.UsageBucketDay, t)
+	switch bucket {
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return models.UsageBucketStart(bucket, t)
+}
+
+func parseStatsTime(v string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"bedrud/internal/testutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestStatsHistory_Buckets(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := repository.NewStatsRepository(db)
+	app := fiber.New()
+	app.Get("/admin/stats/history", NewStatsHandler(repo).History)
+
+	// Monday 2026-03-02 and the Sunday after it.
+	mon := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	for _, s := range []struct {
+		at           time.Time
+		participants int
+		rooms        int
+	}{
+		{mon, 4, 1}, {mon.Add(time.Minute), 8, 2}, {mon.Add(time.Hour), 2, 1}, {mon.AddDate(0, 0, 6), 6, 3},
+	} {
+		if err := repo.RecordUsageSample(s.at, models.UsageSample{Participants: s.participants, Rooms: s.rooms, Interval: time.Minute}); err != nil {
+			t.Fatalf("record sample: %v", err)
+		}
+	}
+
+	status, body := doJSONRequest(t, app, http.MethodGet, "/admin/stats/history?from=2026-03-02T00:00:00Z&to=2026-03-03T00:00:00Z", nil)
+	if status != 200 || body["bucket"] != "hour" {
+		t.Fatalf("expected hourly stats for a one-day range, got %d: %v", status, body)
+	}
+	points := body["points"].([]interface{})
+	first := points[0].(map[string]interface{})
+	if len(points) != 2 || first["participantsMax"] != float64(8) || first["participantsAvg"] != float64(6) || first["participantMinutes"] != float64(12) {
+		t.Fatalf("unexpected hourly points: %v", points)
+	}
+
+	_, body = doJSONRequest(t, app, http.MethodGet, "/admin/stats/history?from=2026-03-01&to=2026-03-10&bucket=day", nil)
+	if points = body["points"].([]interface{}); len(points) != 2 {
+		t.Fatalf("expected two daily points, got %v", points)
+	}
+
+	_, body = doJSONRequest(t, app, http.MethodGet, "/admin/stats/history?from=2026-03-04&to=2026-03-10&bucket=week", nil)
+	points = body["points"].([]interface{})
+	week := points[0].(map[string]interface{})
+	if len(points) != 1 || week["start"] != "2026-03-02T00:00:00Z" || week["samples"] != float64(4) || week["roomMinutes"] != float64(7) {
+		t.Fatalf("expected one week starting Monday with every sample, got %v", points)
+	}
+
+	for _, q := range []string{"?bucket=minute", "?from=yesterday", "?from=2026-03-05&to=2026-03-01", "?from=2026-01-01&to=2026-03-01&bucket=hour"} {
+		if status, _ := doJSONRequest(t, app, http.MethodGet, "/admin/stats/history"+q, nil); status != 400 {
+			t.Errorf("expected 400 for %s, got %d", q, status)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bedrud/config"
+	"bedrud/internal/repository"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Sentinel errors for webhook verification
+var (
+	ErrWebhookNoAuth      = errors.New("missing webhook authorization")
+	ErrWebhookUnknownKey  = errors.New("webhook signed with unknown API key")
+	ErrWebhookBadChecksum = errors.New("webhook body checksum mismatch")
+)
+
+// WebhookHandler receives LiveKit server webhooks and mirrors room and
+// participant state into the database.
+type WebhookHandler struct {
+	roomRepo *repository.RoomRepository
+	// secrets maps each LiveKit node's API key to its secret.
+	secrets map[string]string
+}
+
+func NewWebhookHandler(lkCfg *config.LiveKitConfig, roomRepo *repository.RoomRepository) *WebhookHandler {
+	secrets := make(map[string]string)
+	for _, nc := range lkCfg.NodeConfigs() {
+		secrets[nc.APIKey] = nc.APISecret
+	}
+	return &WebhookHandler{roomRepo: roomRepo, secrets: secrets}
+}
+
+// verify checks the LiveKit-signed JWT in the Authorization header against the
+// API key/secret of any configured node and confirms its sha256 claim matches
+// the body.
+func (h *WebhookHandler) verify(body []byte, authToken string) error {
+	if authToken == "" {
+		return ErrWebhookNoAuth
+	}
+	v, err := lkauth.ParseAPIToken(authToken)
+	if err != nil {
+		return err
+	}
+	secret, ok := h.secrets[v.APIKey()]
+	if !ok {
+		return ErrWebhookUnknownKey
+	}
+	_, claims, err := v.Verify(secret)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	hash := base64.StdEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(claims.Sha256), []byte(hash)) != 1 {
+		return ErrWebhookBadChecksum
+	}
+	return nil
+}
+
+// LiveKitWebhook handles POST /api/livekit/webhook.
+// Events for rooms unknown to Bedrud are acknowledged and ignored so LiveKit
+// does not keep retrying them.
+func (h *WebhookHandler) LiveKitWebhook(c *fiber.Ctx) error {
+	body := c.Body()
+	if err := h.verify(body, c.Get("Authorization")); err != nil {
+		log.Warn().Err(err).Str("ip", c.IP()).Msg("Rejected LiveKit webhook")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid webhook signature"})
+	}
+
+	var event livekit.WebhookEvent
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true, AllowPartial: true}).Unmarshal(body, &event); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid webhook payload"})
+	}
+	if event.EgressInfo != nil {
+		return h.applyEgress(c, event.Event, event.EgressInfo)
+	}
+	if event.Room == nil {
+		return c.JSON(fiber.Map{"status": "ignored"})
+	}
+
+	room, err := h.roomRepo.GetRoomByName(event.Room.Name)
+	if err != nil {
+		log.Error().Err(err).Str("room", event.Room.Name).Msg("Webhook: failed to look up room")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up room"})
+	}
+	if room == nil {
+		return c.JSON(fiber.Map{"status": "ignored"})
+	}
+
+	// Recorders join as hidden egress participants; they aren't attendees.
+	var identity, name string
+	if event.Participant != nil && !isRecorder(event.Participant) {
+		identity, name = event.Participant.Identity, event.Participant.Name
+	}
+	at := eventTime(&event)
+
+	switch event.Event {
+	case "room_started":
+		if err = h.roomRepo.SetRoomActive(room.ID); err == nil {
+			_, err = h.roomRepo.StartRoomSession(room.ID, at)
+		}
+	case "room_finished":
+		// Mark the room idle right away instead of waiting for the idle-room scheduler.
+		if err = h.roomRepo.DeactivateAllParticipants(room.ID); err == nil {
+			err = h.roomRepo.SetRoomIdle(room.ID)
+		}
+		if err == nil {
+			err = h.roomRepo.EndRoomSession(room.ID, at)
+		}
+		// The next meeting gets a fresh E2EE key.
+		if err == nil && room.Settings.E2EE {
+			err = h.roomRepo.DeleteE2EEKeys(room.ID)
+		}
+	case "participant_joined":
+		if identity != "" {
+			err = h.roomRepo.AddParticipant(room.ID, identity)
+			if errors.Is(err, repository.ErrParticipantBanned) {
+				// LiveKit already let them in; retrying won't change that.
+				log.Warn().Str("room", room.Name).Str("identity", identity).Msg("Webhook: banned participant joined")
+				return c.JSON(fiber.Map{"status": "ignored"})
+			}
+			if err == nil {
+				err = h.roomRepo.RecordAttendanceJoin(room.ID, identity, name, at)
+			}
+		}
+	case "participant_left":
+		if identity != "" {
+			if err = h.roomRepo.RemoveParticipant(room.ID, identity); err == nil {
+				err = h.roomRepo.RecordAttendanceLeave(room.ID, identity, at)
+			}
+		}
+	case "track_published":
+		if identity != "" && event.Track != nil {
+			err = h.applyTrackState(room.ID, identity, event.Track)
+		}
+	default:
+		return c.JSON(fiber.Map{"status": "ignored"})
+	}
+	if err != nil {
+		log.Error().Err(err).Str("event", event.Event).Str("room", room.Name).Str("identity", identity).Msg("Webhook: failed to apply event")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to apply event"})
+	}
+
+	log.Debug().Str("event", event.Event).Str("room", room.Name).Str("identity", identity).Msg("Applied LiveKit webhook")
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// isRecorder reports whether p is an egress recording the room. Servers that
+// predate participant kinds still give egresses their EG_ egress ID.
+func isRecorder(p *livekit.ParticipantInfo) bool {
+	return p.Kind == livekit.ParticipantInfo_EGRESS || strings.HasPrefix(p.Identity, "EG_")
+}
+
+// eventTime is when LiveKit emitted the event, falling back to now for
+// events that don't carry a timestamp.
+func eventTime(event *livekit.WebhookEvent) time.Time {
+	if event.CreatedAt > 0 {
+		return time.Unix(event.CreatedAt, 0)
+	}
+	return time.Now()
+}
+
+// applyTrackState mirrors a published track's mute state onto the participant's
+// is_muted / is_video_off flags.
+func (h *WebhookHandler) applyTrackState(roomID, identity string, track *livekit.TrackInfo) error {
+	switch {
+	case track.Type == livekit.TrackType_AUDIO && track.Source != livekit.TrackSource_SCREEN_SHARE_AUDIO:
+		return h.roomRepo.UpdateParticipantStatus(roomID, identity, map[string]interface{}{"is_muted": track.Muted})
+	case track.Type == livekit.TrackType_VIDEO && track.Source == livekit.TrackSource_CAMERA:
+		return h.roomRepo.UpdateParticipantStatus(roomID, identity, map[string]interface{}{"is_video_off": track.Muted})
+	}
+	return nil
+}
+
+// applyEgress mirrors egress_started/updated/ended events onto the matching
+// recording. Egress jobs not started through Bedrud are ignored.
+func (h *WebhookHandler) applyEgress(c *fiber.Ctx, event string, info *livekit.EgressInfo) error {
+	rec, err := h.roomRepo.GetRecordingByEgressID(info.EgressId)
+	if err != nil {
+		log.Error().Err(err).Str("egressID", info.EgressId).Msg("Webhook: failed to look up recording")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to look up recording"})
+	}
+	if rec == nil {
+		return c.JSON(fiber.Map{"status": "ignored"})
+	}
+	if err := h.roomRepo.UpdateRecording(rec.ID, egressUpdates(info)); err != nil {
+		log.Error().Err(err).Str("event", event).Str("egressID", info.EgressId).Msg("Webhook: failed to update recording")
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to apply event"})
+	}
+	log.Debug().Str("event", event).Str("egressID", info.EgressId).Msg("Applied LiveKit egress webhook")
+	return c.JSON(fiber.Map{"status": "ok"})
+}
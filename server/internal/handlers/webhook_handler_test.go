@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"bedrud/config"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"bedrud/internal/testutil"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	webhookTestKey    = "test-key"
+	webhookTestSecret = "test-secret-that-is-long-enough-1234"
+)
+
+func setupWebhookTestApp(t *testing.T) (*fiber.App, *repository.RoomRepository, *models.Room) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+
+	lkCfg := config.LiveKitConfig{APIKey: webhookTestKey, APISecret: webhookTestSecret}
+	handler := NewWebhookHandler(&lkCfg, roomRepo)
+
+	app := fiber.New()
+	app.Post("/livekit/webhook", handler.LiveKitWebhook)
+
+	db.Create(&models.User{ID: "creator-user", Email: "creator@ex.com", Name: "Creator", Provider: "local", IsActive: true, Accesses: models.StringArray{"user"}})
+	room, err := roomRepo.CreateRoom("creator-user", "hook-room", true, "standard", &models.RoomSettings{})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+	return app, roomRepo, room
+}
+
+// signedWebhookRequest builds a webhook request signed the same way LiveKit does.
+func signedWebhookRequest(t *testing.T, event *livekit.WebhookEvent, key, secret string) *http.Request {
+	t.Helper()
+	body, err := protojson.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	sum := sha256.Sum256(body)
+	token, err := lkauth.NewAccessToken(key, secret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		t.Fatalf("sign webhook: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/livekit/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/webhook+json")
+	req.Header.Set("Authorization", token)
+	return req
+}
+
+func TestWebhook_ParticipantJoinedAndLeft(t *testing.T) {
+	app, roomRepo, room := setupWebhookTestApp(t)
+
+	joined := &livekit.WebhookEvent{
+		Event:       "participant_joined",
+		Room:        &livekit.Room{Name: room.Name},
+		Participant: &livekit.ParticipantInfo{Identity: "guest-abc"},
+	}
+	resp, err := app.Test(signedWebhookRequest(t, joined, webhookTestKey, webhookTestSecret), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	count, _ := roomRepo.GetParticipantCount(room.ID)
+	if count != 2 {
+		t.Fatalf("expected 2 active participants (creator + guest), got %d", count)
+	}
+
+	left := &livekit.WebhookEvent{
+		Event:       "participant_left",
+		Room:        &livekit.Room{Name: room.Name},
+		Participant: &livekit.ParticipantInfo{Identity: "guest-abc"},
+	}
+	resp, err = app.Test(signedWebhookRequest(t, left, webhookTestKey, webhookTestSecret), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	participants, _ := roomRepo.GetActiveParticipants(room.ID)
+	for _, p := range participants {
+		if p.UserID == "guest-abc" {
+			t.Fatal("expected guest to be inactive after participant_left")
+		}
+	}
+}
+
+func TestWebhook_BannedParticipantJoinedIgnored(t *testing.T) {
+	app, roomRepo, room := setupWebhookTestApp(t)
+	_ = roomRepo.AddParticipant(room.ID, "guest-abc")
+	if err := roomRepo.BanParticipant(&models.RoomBan{RoomID: room.ID, Identity: "guest-abc"}); err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+
+	joined := &livekit.WebhookEvent{
+		Event:       "participant_joined",
+		Room:        &livekit.Room{Name: room.Name},
+		Participant: &livekit.ParticipantInfo{Identity: "guest-abc"},
+	}
+	resp, err := app.Test(signedWebhookRequest(t, joined, webhookTestKey, webhookTestSecret), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 so LiveKit stops retrying, got %d", resp.StatusCode)
+	}
+	if banned, _ := roomRepo.IsParticipantBanned(room.ID, "guest-abc"); !banned {
+		t.Error("expected the participant to stay banned")
+	}
+}
+
+func TestWebhook_RoomFinished(t *testing.T) {
+	app, roomRepo, room := setupWebhookTestApp(t)
+
+	event := &livekit.WebhookEvent{Event: "room_finished", Room: &livekit.Room{Name: room.Name}}
+	resp, err := app.Test(signedWebhookRequest(t, event, webhookTestKey, webhookTestSecret), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	updated, _ := roomRepo.GetRoom(room.ID)
+	if updated.IsActive {
+		t.Error("expected room to be idle after room_finished")
+	}
+	participants, _ := roomRepo.GetActiveParticipants(room.ID)
+	if len(participants) != 0 {
+		t.Errorf("expected no active participants, got %d", len(participants))
+	}
+}
+
+func TestWebhook_RecordsSessionAttendance(t *testing.T) {
+	app, roomRepo, room := setupWebhookTestApp(t)
+	start := time.Now().Add(-time.Hour).Unix()
+
+	for _, event := range []*livekit.WebhookEvent{
+		{Event: "room_started", Room: &livekit.Room{Name: room.Name}, CreatedAt: start},
+		// A recorder isn't an attendee.
+		{Event: "participant_joined", Room: &livekit.Room{Name: room.Name}, Participant: &livekit.ParticipantInfo{Identity: "EG_rec", Kind: livekit.ParticipantInfo_EGRESS}, CreatedAt: start + 30},
+		{Event: "participant_joined", Room: &livekit.Room{Name: room.Name}, Participant: &livekit.ParticipantInfo{Identity: "guest-abc", Name: "Gus"}, CreatedAt: start + 60},
+		{Event: "participant_left", Room: &livekit.Room{Name: room.Name}, Participant: &livekit.ParticipantInfo{Identity: "guest-abc"}, CreatedAt: start + 660},
+		{Event: "room_finished", Room: &livekit.Room{Name: room.Name}, CreatedAt: start + 900},
+	} {
+		resp, err := app.Test(signedWebhookRequest(t, event, webhookTestKey, webhookTestSecret), -1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", event.Event, resp.StatusCode)
+		}
+	}
+
+	sessions, _ := roomRepo.GetRoomSessions(room.ID)
+	if len(sessions) != 1 || sessions[0].EndedAt == nil || sessions[0].Duration(time.Now()) != 15*time.Minute {
+		t.Fatalf("expected one finished 15 minute session, got %+v", sessions)
+	}
+	segments, _ := roomRepo.GetAttendanceSegments(sessions[0].ID)
+	if len(segments) != 1 || segments[0].DisplayName != "Gus" || segments[0].Duration(time.Now()) != 10*time.Minute {
+		t.Fatalf("expected one 10 minute segment for Gus, got %+v", segments)
+	}
+}
+
+func TestWebhook_TrackPublishedMuted(t *testing.T) {
+	app, roomRepo, room := setupWebhookTestApp(t)
+
+	event := &livekit.WebhookEvent{
+		Event:       "track_published",
+		Room:        &livekit.Room{Name: room.Name},
+		Participant: &livekit.ParticipantInfo{Identity: "creator-user"},
+		Track:       &livekit.TrackInfo{Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_MICROPHONE, Muted: true},
+	}
+	resp, err := app.Test(signedWebhookRequest(t, event, webhookTestKey, webhookTestSecret), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	participants, _ := roomRepo.GetActiveParticipants(room.ID)
+	if len(participants) != 1 || !participants[0].IsMuted {
+		t.Fatalf("expected creator to be marked muted, got %+v", participants)
+	}
+}
+
+func TestWebhook_UnknownRoomIgnored(t *testing.T) {
+	app, _, _ := setupWebhookTestApp(t)
+
+	event := &livekit.WebhookEvent{Event: "room_started", Room: &livekit.Room{Name: "not-a-bedrud-room"}}
+	resp, err := app.Test(signedWebhookRequest(t, event, webhookTestKey, webhookTestSecret), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhook_InvalidSignature(t *testing.T) {
+	app, _, room := setupWebhookTestApp(t)
+
+	event := &livekit.WebhookEvent{Event: "room_started", Room: &livekit.Room{Name: room.Name}}
+	cases := map[string]*http.Request{
+		"wrong secret": signedWebhookRequest(t, event, webhookTestKey, "some-other-secret-that-is-long-enough"),
+		"wrong key":    signedWebhookRequest(t, event, "other-key", webhookTestSecret),
+		"missing auth": httptest.NewRequest(http.MethodPost, "/livekit/webhook", bytes.NewReader([]byte(`{}`))),
+	}
+	for name, req := range cases {
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, resp.StatusCode)
+		}
+	}
+}
+
+func TestWebhook_TamperedBody(t *testing.T) {
+	app, _, room := setupWebhookTestApp(t)
+
+	event := &livekit.WebhookEvent{Event: "room_started", Room: &livekit.Room{Name: room.Name}}
+	signed := signedWebhookRequest(t, event, webhookTestKey, webhookTestSecret)
+
+	req := httptest.NewRequest(http.MethodPost, "/livekit/webhook",
+		bytes.NewReader([]byte(`{"event":"room_finished","room":{"name":"hook-room"}}`)))
+	req.Header.Set("Authorization", signed.Header.Get("Authorization"))
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhook_EgressEndedCompletesRecording(t *testing.T) {
+	app, roomRepo, room := setupWebhookTestApp(t)
+	rec := &models.Recording{RoomID: room.ID, EgressID: "EG_test", Type: models.RecordingTypeComposite,
+		Status: models.RecordingStatusActive, StartedBy: "creator-user", Backend: "disk", StartedAt: time.Now()}
+	if err := roomRepo.CreateRecording(rec); err != nil {
+		t.Fatalf("create recording: %v", err)
+	}
+
+	ended := &livekit.WebhookEvent{
+		Event: "egress_ended",
+		EgressInfo: &livekit.EgressInfo{
+			EgressId:    "EG_test",
+			RoomName:    room.Name,
+			Status:      livekit.EgressStatus_EGRESS_COMPLETE,
+			FileResults: []*livekit.FileInfo{{Filename: "data/recordings/hook-room/out.mp4", Size: 2048, Duration: int64(90 * time.Second)}},
+		},
+	}
+	resp, err := app.Test(signedWebhookRequest(t, ended, webhookTestKey, webhookTestSecret), -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	got, _ := roomRepo.GetRecording(rec.ID)
+	if got.Status != models.RecordingStatusComplete || got.Size != 2048 || got.DurationSeconds != 90 {
+		t.Fatalf("recording not updated: %+v", got)
+	}
+	if got.Location != "data/recordings/hook-room/out.mp4" || got.EndedAt == nil {
+		t.Fatalf("expected location and end time, got %+v", got)
+	}
+}
+
+func TestWebhook_AcceptsAnyNodeKey(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+	lkCfg := config.LiveKitConfig{Nodes: []config.LiveKitNodeConfig{
+		{ID: "eu-1", APIKey: "eu-key", APISecret: "eu-secret-that-is-long-enough-1234"},
+		{ID: "us-1", APIKey: "us-key", APISecret: "us-secret-that-is-long-enough-1234"},
+	}}
+	handler := NewWebhookHandler(&lkCfg, roomRepo)
+	app := fiber.New()
+	app.Post("/livekit/webhook", handler.LiveKitWebhook)
+
+	event := &livekit.WebhookEvent{Event: "room_started", Room: &livekit.Room{Name: "unknown-room"}}
+	cases := map[string]struct {
+		key, secret string
+		want        int
+	}{
+		"first node":      {"eu-key", "eu-secret-that-is-long-enough-1234", http.StatusOK},
+		"second node":     {"us-key", "us-secret-that-is-long-enough-1234", http.StatusOK},
+		"crossed secrets": {"us-key", "eu-secret-that-is-long-enough-1234", http.StatusUnauthorized},
+	}
+	for name, tc := range cases {
+		resp, err := app.Test(signedWebhookRequest(t, event, tc.key, tc.secret), -1)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, resp.StatusCode)
+		}
+	}
+}
@@ -21,6 +21,10 @@ type InstallConfig struct {
 	ExternalLKURL       string
 	LKDomain            string
 	LKIP                string
+	// Ingress configures LiveKit Ingress so RTMP/WHIP encoders can stream into rooms.
+	Ingress         bool
+	IngressRTMPPort string
+	IngressWHIPPort string
 }
 
 // SetDefaults populates empty fields with their default values.
@@ -50,4 +54,10 @@ func (c *InstallConfig) SetDefaults() {
 	if c.LKUDPPortRangeEnd == "" {
 		c.LKUDPPortRangeEnd = "60000"
 	}
+	if c.IngressRTMPPort == "" {
+		c.IngressRTMPPort = "1935"
+	}
+	if c.IngressWHIPPort == "" {
+		c.IngressWHIPPort = "8080"
+	}
 }
@@ -0,0 +1,31 @@
+package install
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// openFirewallPorts allows the given ports (e.g. "1935/tcp") through ufw or
+// firewalld, whichever is installed. Hosts without either are left alone and
+// the ports are only printed.
+func openFirewallPorts(ports []string) {
+	if _, err := exec.LookPath("ufw"); err == nil {
+		for _, p := range ports {
+			if out, err := exec.Command("ufw", "allow", p).CombinedOutput(); err != nil {
+				fmt.Printf("⚠ Warning: ufw allow %s failed: %s\n", p, strings.TrimSpace(string(out)))
+			}
+		}
+		return
+	}
+	if _, err := exec.LookPath("firewall-cmd"); err == nil {
+		for _, p := range ports {
+			if out, err := exec.Command("firewall-cmd", "--permanent", "--add-port="+p).CombinedOutput(); err != nil {
+				fmt.Printf("⚠ Warning: firewall-cmd --add-port=%s failed: %s\n", p, strings.TrimSpace(string(out)))
+			}
+		}
+		_ = exec.Command("firewall-cmd", "--reload").Run()
+		return
+	}
+	fmt.Println("➜ No ufw or firewalld found; make sure these ports are reachable:", strings.Join(ports, ", "))
+}
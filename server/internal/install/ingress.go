@@ -0,0 +1,95 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const ingressBinary = "livekit-ingress"
+
+const ingressSystemdService = `[Unit]
+Description=LiveKit Ingress (RTMP/WHIP streaming into Bedrud rooms)
+Documentation=https://docs.livekit.io/home/ingress/overview/
+After=network.target network-online.target livekit.service redis.service redis-server.service
+Wants=network-online.target
+
+[Service]
+User=bedrud
+Group=bedrud
+Type=simple
+ExecStart=%s --config /etc/bedrud/ingress.yaml
+Restart=on-failure
+RestartSec=5s
+WorkingDirectory=/etc/bedrud
+StandardOutput=journal
+StandardError=journal
+SyslogIdentifier=livekit-ingress
+
+NoNewPrivileges=true
+PrivateTmp=true
+ProtectSystem=strict
+ReadWritePaths=/var/lib/bedrud /var/log/bedrud /etc/bedrud
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// checkIngressPrerequisites fails --ingress before anything is installed when
+// the host can't run LiveKit Ingress: it needs the bundled LiveKit server,
+// Redis and the livekit-ingress binary, and is only run as a service under
+// systemd.
+func checkIngressPrerequisites(cfg *InstallConfig, initSystem string) error {
+	if cfg.ExternalLKURL != "" {
+		return errors.New("--ingress configures the bundled LiveKit server and can't be combined with --external-livekit; set up ingress on the external LiveKit deployment instead")
+	}
+	if initSystem != InitSystemSystemd && initSystem != InitSystemNone {
+		return fmt.Errorf("--ingress is only supported with systemd (detected %s); install without --ingress and run livekit-ingress yourself", initSystem)
+	}
+	if _, err := exec.LookPath(ingressBinary); err != nil {
+		return errors.New("--ingress needs the livekit-ingress binary on PATH; install it from https://github.com/livekit/ingress/releases and re-run")
+	}
+	if _, err := exec.LookPath("redis-server"); err != nil && !redisListening() {
+		return errors.New("--ingress needs Redis on 127.0.0.1:6379; install it (e.g. apt install redis-server) and re-run")
+	}
+	return nil
+}
+
+func redisListening() bool {
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:6379", time.Second)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// writeIngressService writes the systemd unit for livekit-ingress.
+func writeIngressService() error {
+	bin, err := exec.LookPath(ingressBinary)
+	if err != nil {
+		return fmt.Errorf("livekit-ingress not found: %w", err)
+	}
+	unit := fmt.Sprintf(ingressSystemdService, bin)
+	if err := os.WriteFile("/etc/systemd/system/livekit-ingress.service", []byte(unit), 0o644); err != nil {
+		return fmt.Errorf("failed to write livekit-ingress.service: %w", err)
+	}
+	return nil
+}
+
+// enableRedis enables and starts the distribution's Redis service, which is
+// called redis-server on Debian and Ubuntu and redis elsewhere.
+func enableRedis() error {
+	if redisListening() {
+		return nil
+	}
+	for _, svc := range []string{"redis-server", "redis"} {
+		if err := exec.Command("systemctl", "enable", "--now", svc).Run(); err == nil {
+			return nil
+		}
+	}
+	return errors.New("could not start Redis; start it so it listens on 127.0.0.1:6379 and restart livekit-ingress")
+}
@@ -10,6 +10,7 @@ import (
 type serviceConfig struct {
 	HasLivekit     bool
 	LivekitManaged bool
+	HasIngress     bool
 	ConfigPath     string
 	Services       []string
 }
@@ -17,8 +18,10 @@ type serviceConfig struct {
 var systemdServiceFiles = []string{
 	"/etc/systemd/system/bedrud.service",
 	"/etc/systemd/system/livekit.service",
+	"/etc/systemd/system/livekit-ingress.service",
 	"/etc/systemd/system/multi-user.target.wants/bedrud.service",
 	"/etc/systemd/system/multi-user.target.wants/livekit.service",
+	"/etc/systemd/system/multi-user.target.wants/livekit-ingress.service",
 }
 
 var initdScripts = []string{
@@ -134,16 +137,20 @@ func cleanupAllServiceFiles() {
 	}
 }
 
-func buildServiceConfig(isExternalLK bool) serviceConfig {
+func buildServiceConfig(isExternalLK, ingress bool) serviceConfig {
 	cfg := serviceConfig{
 		HasLivekit:     !isExternalLK,
 		LivekitManaged: !isExternalLK,
+		HasIngress:     ingress && !isExternalLK,
 		ConfigPath:     "/etc/bedrud/config.yaml",
 		Services:       []string{"bedrud"},
 	}
 	if cfg.HasLivekit {
 		cfg.Services = []string{"livekit", "bedrud"}
 	}
+	if cfg.HasIngress {
+		cfg.Services = []string{"livekit", "livekit-ingress", "bedrud"}
+	}
 	return cfg
 }
 
@@ -165,6 +172,11 @@ func enableAndStartServices(initSystem string, cfg *serviceConfig) error {
 
 func enableStartSystemd(cfg *serviceConfig) error {
 	_ = exec.Command("systemctl", "daemon-reload").Run()
+	if cfg.HasIngress {
+		if err := enableRedis(); err != nil {
+			return err
+		}
+	}
 	_ = exec.Command("systemctl", append([]string{"enable"}, cfg.Services...)...).Run()
 	_ = exec.Command("systemctl", append([]string{"restart"}, cfg.Services...)...).Run()
 	return nil
@@ -211,6 +223,11 @@ func writeSystemdFiles(cfg *serviceConfig, lkService, serviceContent string) err
 			return fmt.Errorf("failed to write livekit.service: %w", err)
 		}
 	}
+	if cfg.HasIngress {
+		if err := writeIngressService(); err != nil {
+			return err
+		}
+	}
 	if err := os.WriteFile("/etc/systemd/system/bedrud.service", []byte(serviceContent), 0o644); err != nil {
 		return fmt.Errorf("failed to write bedrud.service: %w", err)
 	}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -42,6 +43,9 @@ type installConfigYAML struct {
 		ConfigPath    string `yaml:"configPath,omitempty"`
 		SkipTLSVerify bool   `yaml:"skipTLSVerify"`
 		External      bool   `yaml:"external"`
+		Ingress       struct {
+			Enabled bool `yaml:"enabled"`
+		} `yaml:"ingress,omitempty"`
 	} `yaml:"livekit"`
 	Auth struct {
 		JWTSecret     string `yaml:"jwtSecret"`
@@ -82,6 +86,31 @@ type livekitConfigYAML struct {
 		JSON  bool   `yaml:"json"`
 		Level string `yaml:"level"`
 	} `yaml:"logging"`
+	Redis   *redisConfigYAML `yaml:"redis,omitempty"`
+	Ingress *ingressURLsYAML `yaml:"ingress,omitempty"`
+}
+
+// ingressURLsYAML tells the LiveKit server which URLs to hand out for new ingresses.
+type ingressURLsYAML struct {
+	RTMPBaseURL string `yaml:"rtmp_base_url"`
+	WHIPBaseURL string `yaml:"whip_base_url"`
+}
+
+type redisConfigYAML struct {
+	Address string `yaml:"address"`
+}
+
+// ingressConfigYAML is the config file for the livekit-ingress service.
+type ingressConfigYAML struct {
+	APIKey    string          `yaml:"api_key"`
+	APISecret string          `yaml:"api_secret"`
+	WSURL     string          `yaml:"ws_url"`
+	Redis     redisConfigYAML `yaml:"redis"`
+	RTMPPort  int             `yaml:"rtmp_port"`
+	WHIPPort  int             `yaml:"whip_port"`
+	Logging   struct {
+		Level string `yaml:"level"`
+	} `yaml:"logging"`
 }
 
 func LinuxInstall(cfg *InstallConfig) error {
@@ -112,6 +141,12 @@ func LinuxInstall(cfg *InstallConfig) error {
 		cfg.OverrideIP = utils.OutboundIP().String()
 	}
 
+	if cfg.Ingress {
+		if err := checkIngressPrerequisites(cfg, detectInitSystem()); err != nil {
+			return err
+		}
+	}
+
 	fmt.Println("➜ Preparing Bedrud installation...")
 	fmt.Println("➜ Using IP:", cfg.OverrideIP)
 	if cfg.Domain != "" {
@@ -137,7 +172,7 @@ func LinuxInstall(cfg *InstallConfig) error {
 
 	// 1. Stop existing services and remove binary to avoid ETXTBSY
 	fmt.Println("➜ Stopping existing services...")
-	stopAllInitSystems([]string{"bedrud", "livekit"})
+	stopAllInitSystems([]string{"bedrud", "livekit-ingress", "livekit"})
 	_ = os.Remove("/usr/local/bin/bedrud")
 
 	// Chown directories to bedrud:bedrud
@@ -248,6 +283,7 @@ func LinuxInstall(cfg *InstallConfig) error {
 	}
 	configYAML.LiveKit.SkipTLSVerify = true
 	configYAML.LiveKit.External = isExternalLK || hasSeparateLKDomain
+	configYAML.LiveKit.Ingress.Enabled = cfg.Ingress
 
 	configYAML.Auth.JWTSecret = jwtSecret
 	configYAML.Auth.SessionSecret = sessionSecret
@@ -304,6 +340,14 @@ func LinuxInstall(cfg *InstallConfig) error {
 		}
 		lkYAML.Logging.JSON = true
 		lkYAML.Logging.Level = "debug"
+		if cfg.Ingress {
+			// Ingress talks to the LiveKit server over Redis.
+			lkYAML.Redis = &redisConfigYAML{Address: "127.0.0.1:6379"}
+			lkYAML.Ingress = &ingressURLsYAML{
+				RTMPBaseURL: fmt.Sprintf("rtmp://%s:%s/x", hostForLK, cfg.IngressRTMPPort),
+				WHIPBaseURL: fmt.Sprintf("%s://%s:%s/w", protocol, hostForLK, cfg.IngressWHIPPort),
+			}
+		}
 
 		lkData, err := yaml.Marshal(&lkYAML)
 		if err != nil {
@@ -313,6 +357,12 @@ func LinuxInstall(cfg *InstallConfig) error {
 			return fmt.Errorf("failed to write livekit.yaml: %w", err)
 		}
 		_ = exec.Command("chown", "bedrud:bedrud", "/etc/bedrud/livekit.yaml").Run()
+
+		if cfg.Ingress {
+			if err := writeIngressConfig(cfg, apiKey, apiSecret); err != nil {
+				return err
+			}
+		}
 	}
 
 	if cfg.EnableTLS && cfg.CertPath == "" && cfg.KeyPath == "" {
@@ -324,6 +374,11 @@ func LinuxInstall(cfg *InstallConfig) error {
 		}
 	}
 
+	if cfg.Ingress {
+		fmt.Println("➜ Opening ingress ports...")
+		openFirewallPorts([]string{cfg.IngressRTMPPort + "/tcp", cfg.IngressWHIPPort + "/tcp"})
+	}
+
 	// 4. Detect init system and install services
 	initSystem := detectInitSystem()
 	fmt.Println("➜ Detected init system:", initSystem)
@@ -331,7 +386,7 @@ func LinuxInstall(cfg *InstallConfig) error {
 	if initSystem == InitSystemNone {
 		fmt.Println("➜ Skipping service file installation (container environment)")
 	} else {
-		serviceCfg := buildServiceConfig(isExternalLK)
+		serviceCfg := buildServiceConfig(isExternalLK, cfg.Ingress)
 		cleanupStaleServiceFiles(initSystem)
 
 		lkManagedEnv := ""
@@ -431,7 +486,44 @@ WantedBy=multi-user.target
 		if cfg.LKUDPPortRangeStart != "" && cfg.LKUDPPortRangeEnd != "" {
 			fmt.Println("  LiveKit UDP range:", cfg.LKUDPPortRangeStart+"-"+cfg.LKUDPPortRangeEnd)
 		}
+		if cfg.Ingress {
+			fmt.Println("  Ingress ports: RTMP", cfg.IngressRTMPPort+", WHIP", cfg.IngressWHIPPort)
+			if initSystem == InitSystemNone {
+				fmt.Println("  Start Redis and run: livekit-ingress --config /etc/bedrud/ingress.yaml")
+			}
+		}
+	}
+	return nil
+}
+
+// writeIngressConfig writes the livekit-ingress service config next to livekit.yaml.
+func writeIngressConfig(cfg *InstallConfig, apiKey, apiSecret string) error {
+	rtmpPort, err := strconv.Atoi(cfg.IngressRTMPPort)
+	if err != nil {
+		return fmt.Errorf("invalid ingress RTMP port %q: %w", cfg.IngressRTMPPort, err)
+	}
+	whipPort, err := strconv.Atoi(cfg.IngressWHIPPort)
+	if err != nil {
+		return fmt.Errorf("invalid ingress WHIP port %q: %w", cfg.IngressWHIPPort, err)
+	}
+	ingYAML := ingressConfigYAML{
+		APIKey:    apiKey,
+		APISecret: apiSecret,
+		WSURL:     fmt.Sprintf("ws://127.0.0.1:%s", cfg.LKPort),
+		Redis:     redisConfigYAML{Address: "127.0.0.1:6379"},
+		RTMPPort:  rtmpPort,
+		WHIPPort:  whipPort,
+	}
+	ingYAML.Logging.Level = "info"
+
+	data, err := yaml.Marshal(&ingYAML)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ingress.yaml: %w", err)
+	}
+	if err := os.WriteFile("/etc/bedrud/ingress.yaml", data, 0o600); err != nil {
+		return fmt.Errorf("failed to write ingress.yaml: %w", err)
 	}
+	_ = exec.Command("chown", "bedrud:bedrud", "/etc/bedrud/ingress.yaml").Run()
 	return nil
 }
 
@@ -535,7 +627,7 @@ func LinuxUninstall() error {
 	fmt.Println("\n--- Bedrud Uninstallation ---")
 	fmt.Println("➜ Stopping and disabling services...")
 
-	svcs := []string{"bedrud", "livekit"}
+	svcs := []string{"bedrud", "livekit-ingress", "livekit"}
 
 	stopAllInitSystems(svcs)
 	disableAllInitSystems(svcs)
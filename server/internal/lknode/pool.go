@@ -0,0 +1,272 @@
+// Package lknode keeps the set of LiveKit servers rooms can be placed on,
+// picks a node for new rooms and tracks which nodes are healthy.
+package lknode
+
+import (
+	"bedrud/config"
+	"bedrud/internal/geoip"
+	"context"
+	"crypto/tls"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	lkauth "github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/rs/zerolog/log"
+	"github.com/twitchtv/twirp"
+)
+
+// healthCheckTimeout bounds each node's health probe.
+const healthCheckTimeout = 5 * time.Second
+
+// Node is one LiveKit server and the API clients for it.
+type Node struct {
+	ID     string
+	Region string
+	// Host is the URL clients connect to.
+	Host      string
+	APIKey    string
+	APISecret string
+	Weight    int
+
+	Rooms   livekit.RoomService
+	Egress  livekit.Egress
+	Ingress livekit.Ingress
+
+	down atomic.Bool
+}
+
+// Healthy reports whether the node answered its last health check. Nodes
+// are assumed healthy until checked.
+func (n *Node) Healthy() bool {
+	return !n.down.Load()
+}
+
+type nodeKey struct{}
+
+// WithAuth binds ctx to the node and authorizes LiveKit API calls made with
+// it for the given grants.
+func (n *Node) WithAuth(ctx context.Context, grants ...*lkauth.VideoGrant) context.Context {
+	at := lkauth.NewAccessToken(n.APIKey, n.APISecret)
+	for _, g := range grants {
+		at.AddGrant(g) //nolint:staticcheck // AddGrant is deprecated but VideoGrant field is not available in this version of the protocol SDK
+	}
+	token, err := at.ToJWT()
+	if err != nil {
+		log.Error().Err(err).Str("node", n.ID).Msg("Failed to generate LiveKit auth token")
+	}
+	ctx, _ = twirp.WithHTTPRequestHeaders(ctx, http.Header{
+		"Authorization": []string{"Bearer " + token},
+	})
+	return context.WithValue(ctx, nodeKey{}, n)
+}
+
+// FromContext returns the node ctx was bound to by WithAuth, or nil.
+func FromContext(ctx context.Context) *Node {
+	n, _ := ctx.Value(nodeKey{}).(*Node)
+	return n
+}
+
+// Pool is the set of configured LiveKit nodes.
+type Pool struct {
+	nodes   []*Node
+	byID    map[string]*Node
+	regions map[string]config.LiveKitRegionConfig
+	// regionNames are the region names sorted, so that a country or
+	// continent listed in several regions always lands in the same one.
+	regionNames []string
+	geo         *geoip.Reader
+}
+
+// NewPool builds API clients for every configured node. A GeoIP database that
+// can't be opened is logged and placement falls back to region hints.
+func NewPool(cfg *config.LiveKitConfig) *Pool {
+	p := &Pool{byID: make(map[string]*Node), regions: cfg.Regions}
+	for name := range cfg.Regions {
+		p.regionNames = append(p.regionNames, name)
+	}
+	sort.Strings(p.regionNames)
+	for _, nc := range cfg.NodeConfigs() {
+		apiHost := nc.InternalHost
+		if apiHost == "" {
+			apiHost = nc.Host
+		}
+		httpClient := http.DefaultClient
+		if nc.SkipTLSVerify && strings.HasPrefix(apiHost, "https") {
+			httpClient = &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				},
+			}
+		}
+		p.Add(&Node{
+			ID:        nc.ID,
+			Region:    nc.Region,
+			Host:      nc.Host,
+			APIKey:    nc.APIKey,
+			APISecret: nc.APISecret,
+			Weight:    nc.Weight,
+			Rooms:     livekit.NewRoomServiceProtobufClient(apiHost, httpClient),
+			Egress:    livekit.NewEgressProtobufClient(apiHost, httpClient),
+			Ingress:   livekit.NewIngressProtobufClient(apiHost, httpClient),
+		})
+	}
+	if cfg.GeoIPDatabase != "" {
+		geo, err := geoip.Open(cfg.GeoIPDatabase)
+		if err != nil {
+			log.Warn().Err(err).Str("path", cfg.GeoIPDatabase).Msg("Could not load GeoIP database; placing rooms by region hint only")
+		} else {
+			p.geo = geo
+		}
+	}
+	return p
+}
+
+// Add adds a node to the pool. The first node added is the default.
+func (p *Pool) Add(n *Node) {
+	if n.Weight <= 0 {
+		n.Weight = 1
+	}
+	p.nodes = append(p.nodes, n)
+	p.byID[n.ID] = n
+}
+
+// Nodes returns every node in configuration order.
+func (p *Pool) Nodes() []*Node {
+	return p.nodes
+}
+
+// Default returns the first configured node.
+func (p *Pool) Default() *Node {
+	return p.nodes[0]
+}
+
+// Node returns the node with the given ID. Rooms created before nodes were
+// configured have no node ID, and rooms whose node was removed from the
+// configuration are served by the default node.
+func (p *Pool) Node(id string) *Node {
+	if n, ok := p.byID[id]; ok {
+		return n
+	}
+	if id != "" {
+		log.Warn().Str("node", id).Msg("Room is on an unknown LiveKit node; using the default node")
+	}
+	return p.Default()
+}
+
+// Pick chooses a node for a new room: healthy nodes in the hinted region, or
+// else in the region GeoIP places ip in, weighted by node weight. When no
+// region matches any healthy node, all healthy nodes are candidates, and when
+// every node is down all of them are.
+func (p *Pool) Pick(regionHint string, ip net.IP) *Node {
+	candidates := make([]*Node, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		if n.Healthy() {
+			candidates = append(candidates, n)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = p.nodes
+	}
+
+	if local := inRegion(candidates, strings.TrimSpace(regionHint)); len(local) > 0 {
+		candidates = local
+	} else if local := inRegion(candidates, p.regionFor(ip)); len(local) > 0 {
+		candidates = local
+	}
+	return weighted(candidates)
+}
+
+// regionFor returns the configured region GeoIP places ip in, if any.
+func (p *Pool) regionFor(ip net.IP) string {
+	if p.geo == nil || ip == nil {
+		return ""
+	}
+	loc, ok, err := p.geo.Lookup(ip)
+	if err != nil {
+		log.Debug().Err(err).Str("ip", ip.String()).Msg("GeoIP lookup failed")
+		return ""
+	}
+	if !ok {
+		return ""
+	}
+	return p.regionOf(loc)
+}
+
+// regionOf returns the configured region for a GeoIP location. Countries are
+// matched before continents, and overlapping regions in name order.
+func (p *Pool) regionOf(loc geoip.Location) string {
+	for _, name := range p.regionNames {
+		if containsFold(p.regions[name].Countries, loc.Country) {
+			return name
+		}
+	}
+	for _, name := range p.regionNames {
+		if containsFold(p.regions[name].Continents, loc.Continent) {
+			return name
+		}
+	}
+	return ""
+}
+
+// CheckHealth probes every node with a ListRooms call and records which are
+// down.
+func (p *Pool) CheckHealth(ctx context.Context) {
+	for _, n := range p.nodes {
+		probeCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		_, err := n.Rooms.ListRooms(n.WithAuth(probeCtx, &lkauth.VideoGrant{RoomList: true}), &livekit.ListRoomsRequest{})
+		cancel()
+		wasDown := n.down.Swap(err != nil)
+		switch {
+		case err != nil && !wasDown:
+			log.Warn().Err(err).Str("node", n.ID).Msg("LiveKit node is down")
+		case err == nil && wasDown:
+			log.Info().Str("node", n.ID).Msg("LiveKit node is back up")
+		}
+	}
+}
+
+func inRegion(nodes []*Node, region string) []*Node {
+	if region == "" {
+		return nil
+	}
+	var out []*Node
+	for _, n := range nodes {
+		if strings.EqualFold(n.Region, region) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func weighted(nodes []*Node) *Node {
+	total := 0
+	for _, n := range nodes {
+		total += n.Weight
+	}
+	r := rand.IntN(total)
+	for _, n := range nodes {
+		if r < n.Weight {
+			return n
+		}
+		r -= n.Weight
+	}
+	return nodes[len(nodes)-1]
+}
+
+func containsFold(list []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
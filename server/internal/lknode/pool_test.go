@@ -0,0 +1,139 @@
+package lknode
+
+import (
+	"bedrud/config"
+	"bedrud/internal/geoip"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+type fakeRooms struct {
+	livekit.RoomService
+	err error
+}
+
+func (f *fakeRooms) ListRooms(context.Context, *livekit.ListRoomsRequest) (*livekit.ListRoomsResponse, error) {
+	return &livekit.ListRoomsResponse{}, f.err
+}
+
+func testPool(t *testing.T) *Pool {
+	t.Helper()
+	p := NewPool(&config.LiveKitConfig{
+		Nodes: []config.LiveKitNodeConfig{
+			{ID: "eu-1", Region: "eu", Host: "wss://eu-1.example.com"},
+			{ID: "eu-2", Region: "eu", Host: "wss://eu-2.example.com"},
+			{ID: "us-1", Region: "us", Host: "wss://us-1.example.com"},
+		},
+	})
+	for _, n := range p.Nodes() {
+		n.Rooms = &fakeRooms{}
+	}
+	return p
+}
+
+func TestPick_RegionHint(t *testing.T) {
+	p := testPool(t)
+	for i := 0; i < 20; i++ {
+		if n := p.Pick("US", nil); n.ID != "us-1" {
+			t.Fatalf("expected us-1 for the us hint, got %s", n.ID)
+		}
+		if n := p.Pick("eu", nil); n.Region != "eu" {
+			t.Fatalf("expected an eu node for the eu hint, got %s", n.ID)
+		}
+	}
+	// An unknown region falls back to any node.
+	if n := p.Pick("mars", nil); n == nil {
+		t.Fatal("expected a node for an unknown region")
+	}
+}
+
+func TestRegionOf_OverlappingRegions(t *testing.T) {
+	p := NewPool(&config.LiveKitConfig{
+		Regions: map[string]config.LiveKitRegionConfig{
+			"eu-west":    {Countries: []string{"DE", "FR"}, Continents: []string{"EU"}},
+			"eu-central": {Countries: []string{"de", "PL"}, Continents: []string{"EU"}},
+			"global":     {Continents: []string{"EU", "NA"}},
+		},
+	})
+	for i := 0; i < 20; i++ {
+		if got := p.regionOf(geoip.Location{Country: "DE", Continent: "EU"}); got != "eu-central" {
+			t.Fatalf("expected DE in eu-central, the first matching region by name, got %q", got)
+		}
+		if got := p.regionOf(geoip.Location{Country: "IT", Continent: "EU"}); got != "eu-central" {
+			t.Fatalf("expected IT in eu-central by continent, got %q", got)
+		}
+		if got := p.regionOf(geoip.Location{Country: "US", Continent: "NA"}); got != "global" {
+			t.Fatalf("expected US in global, got %q", got)
+		}
+	}
+}
+
+func TestPick_SkipsUnhealthyNodes(t *testing.T) {
+	p := testPool(t)
+	p.Node("us-1").Rooms = &fakeRooms{err: errors.New("unreachable")}
+	p.CheckHealth(context.Background())
+
+	if p.Node("us-1").Healthy() {
+		t.Fatal("expected us-1 to be marked down")
+	}
+	for i := 0; i < 20; i++ {
+		if n := p.Pick("us", nil); n.ID == "us-1" {
+			t.Fatal("expected the down node to be skipped even when its region is hinted")
+		}
+	}
+
+	p.Node("us-1").Rooms = &fakeRooms{}
+	p.CheckHealth(context.Background())
+	if !p.Node("us-1").Healthy() {
+		t.Fatal("expected us-1 to be back up")
+	}
+}
+
+func TestPick_AllNodesDown(t *testing.T) {
+	p := testPool(t)
+	for _, n := range p.Nodes() {
+		n.Rooms = &fakeRooms{err: errors.New("unreachable")}
+	}
+	p.CheckHealth(context.Background())
+	if n := p.Pick("us", nil); n == nil || n.ID != "us-1" {
+		t.Fatalf("expected placement to fall back to all nodes, got %v", n)
+	}
+}
+
+func TestNode_FallsBackToDefault(t *testing.T) {
+	p := testPool(t)
+	if n := p.Node(""); n.ID != "eu-1" {
+		t.Errorf("expected rooms without a node to use the first node, got %s", n.ID)
+	}
+	if n := p.Node("removed"); n.ID != "eu-1" {
+		t.Errorf("expected an unknown node to fall back to the first node, got %s", n.ID)
+	}
+	if n := p.Node("us-1"); n.ID != "us-1" {
+		t.Errorf("expected us-1, got %s", n.ID)
+	}
+}
+
+func TestNewPool_SingleNode(t *testing.T) {
+	p := NewPool(&config.LiveKitConfig{Host: "wss://lk.example.com", APIKey: "key", APISecret: "secret"})
+	if len(p.Nodes()) != 1 {
+		t.Fatalf("expected one node from the top-level settings, got %d", len(p.Nodes()))
+	}
+	n := p.Default()
+	if n.ID != config.DefaultNodeID || n.Host != "wss://lk.example.com" || n.APIKey != "key" {
+		t.Errorf("unexpected default node: %+v", n)
+	}
+}
+
+func TestWithAuth_BindsNode(t *testing.T) {
+	p := testPool(t)
+	n := p.Node("eu-2")
+	if got := FromContext(n.WithAuth(context.Background())); got != n {
+		t.Errorf("expected the context to carry eu-2, got %v", got)
+	}
+	if got := FromContext(context.Background()); got != nil {
+		t.Errorf("expected no node on a bare context, got %v", got)
+	}
+}
@@ -0,0 +1,219 @@
+// Package mailer sends outbound email. Messages are queued and delivered in
+// the background, and failed deliveries are retried with backoff.
+package mailer
+
+import (
+	"bedrud/internal/models"
+	"bedrud/internal/templates"
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"regexp"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DriverLog writes messages to the server log instead of sending them.
+	DriverLog = "log"
+	// DriverSMTP sends messages through an SMTP server.
+	DriverSMTP = "smtp"
+
+	defaultFrom = "Bedrud <no-reply@localhost>"
+	queueSize   = 256
+	maxAttempts = 5
+)
+
+// retryDelay is the wait before the first retry. It doubles after every
+// failed attempt.
+var retryDelay = 10 * time.Second
+
+var (
+	// ErrQueueFull is returned by Send when too many messages are waiting.
+	ErrQueueFull = errors.New("mail queue is full")
+	// ErrInvalidMessage is returned by Send for a message without a recipient
+	// or with line breaks in its headers.
+	ErrInvalidMessage = errors.New("invalid mail message")
+)
+
+// Message is an email with a plain text body and an optional HTML body.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers a message.
+type Sender interface {
+	Send(from string, msg *Message) error
+}
+
+type job struct {
+	msg      *Message
+	attempts int
+}
+
+var (
+	mu      sync.RWMutex
+	sender  Sender = logSender{}
+	from           = defaultFrom
+	queue          = make(chan *job, queueSize)
+	pending sync.WaitGroup
+	started sync.Once
+)
+
+// Configure selects the driver from effective settings. Call it at startup
+// and whenever settings change; queued messages use the new driver.
+func Configure(s *models.SystemSettings) {
+	var snd Sender
+	switch strings.ToLower(s.MailDriver) {
+	case "", DriverLog:
+		snd = logSender{}
+	case DriverSMTP:
+		snd = &smtpSender{
+			host:     s.MailHost,
+			port:     s.MailPort,
+			username: s.MailUsername,
+			password: s.MailPassword,
+			tls:      strings.ToLower(s.MailTLS),
+		}
+	default:
+		log.Warn().Str("driver", s.MailDriver).Msg("Unknown mail driver, logging messages instead")
+		snd = logSender{}
+	}
+	f := s.MailFrom
+	if f == "" {
+		f = defaultFrom
+	}
+	mu.Lock()
+	sender, from = snd, f
+	mu.Unlock()
+}
+
+// SetSenderForTest replaces the configured driver. Tests only.
+func SetSenderForTest(s Sender) {
+	mu.Lock()
+	sender = s
+	mu.Unlock()
+}
+
+// Start starts the delivery worker. Until then messages only queue up.
+func Start() {
+	started.Do(func() {
+		go func() {
+			for j := range queue {
+				deliver(j)
+			}
+		}()
+	})
+}
+
+// Stop gives queued messages a few seconds to go out before shutdown.
+func Stop() {
+	if !Flush(10 * time.Second) {
+		log.Warn().Msg("Shutting down with undelivered mail in the queue")
+	}
+}
+
+// Flush waits up to timeout for every queued message to be delivered or
+// given up on, and reports whether the queue drained.
+func Flush(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Send queues msg for delivery.
+func Send(msg *Message) error {
+	if msg.To == "" || strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return ErrInvalidMessage
+	}
+	pending.Add(1)
+	select {
+	case queue <- &job{msg: msg}:
+		return nil
+	default:
+		pending.Done()
+		return ErrQueueFull
+	}
+}
+
+func deliver(j *job) {
+	mu.RLock()
+	snd, f := sender, from
+	mu.RUnlock()
+
+	j.attempts++
+	err := snd.Send(f, j.msg)
+	if err == nil {
+		pending.Done()
+		return
+	}
+	if j.attempts >= maxAttempts {
+		log.Error().Err(err).Str("to", j.msg.To).Str("subject", j.msg.Subject).Int("attempts", j.attempts).Msg("Giving up on email")
+		pending.Done()
+		return
+	}
+	delay := retryDelay << (j.attempts - 1)
+	log.Warn().Err(err).Str("to", j.msg.To).Dur("retryIn", delay).Msg("Failed to send email, will retry")
+	time.AfterFunc(delay, func() { queue <- j })
+}
+
+// Render builds a message from the named .txt and .html templates in
+// internal/templates/email. Templates can use .Subject besides data.
+func Render(name, to, subject string, data map[string]interface{}) (*Message, error) {
+	vars := map[string]interface{}{"Subject": subject}
+	for k, v := range data {
+		vars[k] = v
+	}
+
+	text, err := texttemplate.ParseFS(templates.Email, "email/"+name+".txt")
+	if err != nil {
+		return nil, err
+	}
+	var textBuf bytes.Buffer
+	if err := text.Execute(&textBuf, vars); err != nil {
+		return nil, err
+	}
+
+	html, err := htmltemplate.ParseFS(templates.Email, "email/layout.html", "email/"+name+".html")
+	if err != nil {
+		return nil, err
+	}
+	var htmlBuf bytes.Buffer
+	if err := html.ExecuteTemplate(&htmlBuf, "layout", vars); err != nil {
+		return nil, err
+	}
+
+	return &Message{To: to, Subject: subject, Text: textBuf.String(), HTML: htmlBuf.String()}, nil
+}
+
+// logSender is the development driver. Anyone who can read the server log
+// could use a verification or password reset link, so link tokens are only
+// logged at debug level.
+type logSender struct{}
+
+var linkToken = regexp.MustCompile(`([?&]token=)[^&\s]+`)
+
+func (logSender) Send(from string, msg *Message) error {
+	text := msg.Text
+	if zerolog.GlobalLevel() > zerolog.DebugLevel {
+		text = linkToken.ReplaceAllString(text, "${1}REDACTED")
+	}
+	log.Info().Str("from", from).Str("to", msg.To).Str("subject", msg.Subject).Msg("Email (log driver):\n" + text)
+	return nil
+}
@@ -0,0 +1,255 @@
+package mailer
+
+import (
+	"bedrud/internal/models"
+	"bufio"
+	"bytes"
+	"errors"
+	"mime"
+	"net"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// smtpSink is a minimal SMTP server that records what it receives, standing
+// in for MailHog.
+type smtpSink struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	from []string
+	rcpt []string
+	data []string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpSink{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) port() int { return s.ln.Addr().(*net.TCPAddr).Port }
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 sink ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO" || cmd == "HELO":
+			reply("250 sink")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = append(s.from, line[len("MAIL FROM:"):])
+			s.mu.Unlock()
+			reply("250 ok")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, line[len("RCPT TO:"):])
+			s.mu.Unlock()
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var body strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				body.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = append(s.data, body.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestRender_VerifyEmail(t *testing.T) {
+	msg, err := Render("verify_email", "ada@example.com", "Verify your email", map[string]interface{}{
+		"Name":      "Ada <script>",
+		"Email":     "ada@example.com",
+		"Link":      "https://bedrud.example/auth/verify-email?token=abc",
+		"ExpiresIn": "48 hours",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(msg.Text, "https://bedrud.example/auth/verify-email?token=abc") || !strings.Contains(msg.Text, "Ada <script>") {
+		t.Fatalf("unexpected text body: %s", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "<title>Verify your email</title>") || strings.Contains(msg.HTML, "<script>") {
+		t.Fatalf("expected an escaped HTML body with the subject, got: %s", msg.HTML)
+	}
+}
+
+func TestLogSender_RedactsLinkTokens(t *testing.T) {
+	var buf bytes.Buffer
+	logger, level := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(&buf)
+	t.Cleanup(func() {
+		log.Logger = logger
+		zerolog.SetGlobalLevel(level)
+	})
+	msg := &Message{To: "ada@example.com", Subject: "Reset", Text: "Open https://bedrud.example/auth/reset-password?token=s3cret&x=1"}
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	if err := (logSender{}).Send(defaultFrom, msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if strings.Contains(buf.String(), "s3cret") || !strings.Contains(buf.String(), "token=REDACTED&x=1") {
+		t.Fatalf("expected the token redacted, got %s", buf.String())
+	}
+
+	buf.Reset()
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	if err := (logSender{}).Send(defaultFrom, msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if !strings.Contains(buf.String(), "token=s3cret") {
+		t.Fatalf("expected the full link at debug level, got %s", buf.String())
+	}
+}
+
+func TestSMTPSender_DeliversToSink(t *testing.T) {
+	sink := newSMTPSink(t)
+	snd := &smtpSender{host: "127.0.0.1", port: sink.port(), tls: TLSNone}
+
+	err := snd.Send("Bedrud <no-reply@bedrud.test>", &Message{
+		To:      "ada@example.com",
+		Subject: "Héllo",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.data) != 1 || sink.from[0] != "<no-reply@bedrud.test>" || sink.rcpt[0] != "<ada@example.com>" {
+		t.Fatalf("unexpected envelope: from %v rcpt %v, %d messages", sink.from, sink.rcpt, len(sink.data))
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(sink.data[0]))
+	if err != nil {
+		t.Fatalf("unparseable message: %v", err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); subject != "Héllo" {
+		t.Errorf("expected the subject to round-trip, got %q", subject)
+	}
+	if !strings.HasPrefix(parsed.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("expected a multipart message, got %q", parsed.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(sink.data[0], "plain body") || !strings.Contains(sink.data[0], "<p>html body</p>") {
+		t.Errorf("expected both bodies in %s", sink.data[0])
+	}
+}
+
+// TestSMTPSender_MailHog sends a real message when MAIL_TEST_SMTP points at
+// a local sink, e.g. MAIL_TEST_SMTP=localhost:1025 for MailHog.
+func TestSMTPSender_MailHog(t *testing.T) {
+	addr := os.Getenv("MAIL_TEST_SMTP")
+	if addr == "" {
+		t.Skip("MAIL_TEST_SMTP not set")
+	}
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("invalid MAIL_TEST_SMTP: %v", err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	msg, err := Render("reset_password", "test@example.com", "Reset your Bedrud password", map[string]interface{}{
+		"Name": "Test", "Link": "http://localhost:8090/auth/reset-password?token=test", "ExpiresIn": "1 hour",
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if err := (&smtpSender{host: host, port: port, tls: TLSNone}).Send(defaultFrom, msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+}
+
+type flakySender struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+}
+
+func (f *flakySender) Send(from string, msg *Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.attempts <= f.failures {
+		return errors.New("temporary failure")
+	}
+	return nil
+}
+
+func TestSend_RetriesFailedDeliveries(t *testing.T) {
+	old := retryDelay
+	retryDelay = time.Millisecond
+	t.Cleanup(func() {
+		retryDelay = old
+		Configure(&models.SystemSettings{})
+	})
+	Start()
+
+	flaky := &flakySender{failures: 2}
+	SetSenderForTest(flaky)
+	if err := Send(&Message{To: "ada@example.com", Subject: "Hi", Text: "hello"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !Flush(5 * time.Second) {
+		t.Fatal("expected the queue to drain")
+	}
+	if flaky.attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", flaky.attempts)
+	}
+
+	failing := &flakySender{failures: maxAttempts + 1}
+	SetSenderForTest(failing)
+	_ = Send(&Message{To: "ada@example.com", Subject: "Hi", Text: "hello"})
+	if !Flush(5*time.Second) || failing.attempts != maxAttempts {
+		t.Fatalf("expected delivery to stop after %d attempts, got %d", maxAttempts, failing.attempts)
+	}
+
+	if err := Send(&Message{To: "ada@example.com\r\nBcc: eve@example.com", Subject: "Hi"}); err != ErrInvalidMessage {
+		t.Fatalf("expected header injection to be rejected, got: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"bedrud/config"
 	"bedrud/internal/auth"
+	"bedrud/internal/blocklist"
 	"strings"
 
 	"bedrud/internal/models" // Add this import
@@ -44,6 +45,13 @@ func Protected() fiber.Handler {
 			})
 		}
 
+		// c.IP() honours the trusted-proxy settings configured on the app.
+		if blocklist.Match(claims.UserID, claims.Email, c.IP()) != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Access denied",
+			})
+		}
+
 		// Add claims to context for use in protected routes
 		c.Locals("user", claims)
 		return c.Next()
@@ -57,6 +65,14 @@ func RequireAccess(requiredAccess models.AccessLevel) fiber.Handler {
 
 		for _, access := range claims.Accesses {
 			if access == string(requiredAccess) {
+				// Sessions that skipped the second factor can't use
+				// accesses the MFA policy covers.
+				if !claims.MFA && auth.MFARequired([]string{access}) {
+					return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+						"error":       "Two-factor authentication required",
+						"mfaRequired": true,
+					})
+				}
 				return c.Next()
 			}
 		}
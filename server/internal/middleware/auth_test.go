@@ -3,7 +3,10 @@ package middleware
 import (
 	"bedrud/config"
 	"bedrud/internal/auth"
+	"bedrud/internal/blocklist"
 	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"bedrud/internal/testutil"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -110,6 +113,31 @@ func TestProtected_Real_NoBearerPrefix(t *testing.T) {
 	}
 }
 
+func TestProtected_Real_BlockedUser(t *testing.T) {
+	cfg := getTestConfig()
+	repo := repository.NewBlocklistRepository(testutil.SetupTestDB(t))
+	_ = repo.CreateEntry(&models.BlocklistEntry{Type: models.BlockTypeUser, Value: "blocked-user"})
+	if err := blocklist.Init(repo); err != nil {
+		t.Fatalf("init blocklist: %v", err)
+	}
+	t.Cleanup(func() { _ = blocklist.Init(nil) })
+
+	app := fiber.New()
+	app.Use(Protected())
+	app.Get("/test", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	for userID, want := range map[string]int{"blocked-user": fiber.StatusForbidden, "u1": http.StatusOK} {
+		token, _ := auth.GenerateToken(userID, userID+"@ex.com", "T", "local", []string{"user"}, cfg)
+		req := httptest.NewRequest(http.MethodGet, "/test", http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, _ := app.Test(req)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("%s: expected %d, got %d", userID, want, resp.StatusCode)
+		}
+	}
+}
+
 func TestRequireAccess_Real_HasAccess(t *testing.T) {
 	app := fiber.New()
 	app.Use(func(c *fiber.Ctx) error {
@@ -144,6 +172,32 @@ func TestRequireAccess_Real_InsufficientAccess(t *testing.T) {
 	}
 }
 
+func TestRequireAccess_Real_MFAPolicy(t *testing.T) {
+	auth.ApplyMFAPolicy(&models.SystemSettings{RequireAdminMFA: true})
+	t.Cleanup(func() { auth.ApplyMFAPolicy(&models.SystemSettings{}) })
+
+	for _, mfa := range []bool{false, true} {
+		app := fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("user", &auth.Claims{UserID: "u1", Accesses: []string{"admin"}, MFA: mfa})
+			return c.Next()
+		})
+		app.Use(RequireAccess(models.AccessAdmin))
+		app.Get("/admin", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+		req := httptest.NewRequest(http.MethodGet, "/admin", http.NoBody)
+		resp, _ := app.Test(req)
+		resp.Body.Close()
+		want := http.StatusOK
+		if !mfa {
+			want = fiber.StatusForbidden
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("mfa=%v: expected %d, got %d", mfa, want, resp.StatusCode)
+		}
+	}
+}
+
 func TestProtected_NoAuthHeader(t *testing.T) {
 	app := fiber.New()
 	// We need to inject config for middleware to work. The middleware uses config.Get()
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Site-wide blocklist entry types.
+const (
+	BlockTypeUser   = "user"
+	BlockTypeEmail  = "email"
+	BlockTypeDomain = "domain"
+	BlockTypeIP     = "ip"
+)
+
+// BlocklistEntry keeps a user, email address, email domain or IP range off the
+// whole site. IP entries are stored in CIDR form; a single address is a /32 or
+// /128.
+type BlocklistEntry struct {
+	ID        string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Type      string     `gorm:"not null;type:varchar(16);uniqueIndex:idx_blocklist_entry,priority:1" json:"type"`
+	Value     string     `gorm:"not null;type:varchar(255);uniqueIndex:idx_blocklist_entry,priority:2" json:"value"`
+	Reason    string     `gorm:"type:text" json:"reason"`
+	CreatedBy string     `gorm:"type:varchar(36)" json:"createdBy"`
+	ExpiresAt *time.Time `gorm:"index" json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Active reports whether the entry is still in force at now.
+func (e *BlocklistEntry) Active(now time.Time) bool {
+	return e.ExpiresAt == nil || now.Before(*e.ExpiresAt)
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ChatAttachment is an uploaded image referenced by a chat message.
+type ChatAttachment struct {
+	URL    string `json:"url"`
+	Mime   string `json:"mime"`
+	Size   int64  `json:"size"`
+	Width  int    `json:"w"`
+	Height int    `json:"h"`
+}
+
+// ChatMessage is a persisted in-room chat message. Messages are kept until the
+// room's ChatRetentionDays elapse (0 keeps them for the lifetime of the room).
+type ChatMessage struct {
+	ID             string           `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID         string           `gorm:"not null;type:varchar(36);index:idx_chat_room_created,priority:1" json:"roomId"`
+	SenderIdentity string           `gorm:"not null;type:varchar(255)" json:"senderIdentity"`
+	SenderName     string           `gorm:"type:varchar(255)" json:"senderName"`
+	Message        string           `gorm:"type:text" json:"message"`
+	Attachments    []ChatAttachment `gorm:"serializer:json;type:text" json:"attachments"`
+	CreatedAt      time.Time        `gorm:"index:idx_chat_room_created,priority:2" json:"createdAt"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Email token purposes.
+const (
+	EmailTokenVerify = "verify_email"
+	EmailTokenReset  = "reset_password"
+)
+
+// EmailToken is a single-use token mailed to a user, for verifying their
+// address or resetting their password. Only its SHA-256 hash is stored.
+// Email is the address the token was sent to.
+type EmailToken struct {
+	TokenHash string    `json:"-" gorm:"primaryKey;type:varchar(64)"`
+	UserID    string    `json:"userId" gorm:"type:varchar(36);not null;index"`
+	Purpose   string    `json:"purpose" gorm:"type:varchar(20);not null"`
+	Email     string    `json:"email" gorm:"type:varchar(255);not null"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"not null;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;not null"`
+}
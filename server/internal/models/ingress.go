@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Ingress input types.
+const (
+	IngressTypeRTMP = "rtmp"
+	IngressTypeWHIP = "whip"
+)
+
+// IngressIdentityPrefix marks participants that are external streams pushed
+// in through LiveKit Ingress, so clients can render them differently.
+const IngressIdentityPrefix = "ingress-"
+
+// RoomIngress is a LiveKit Ingress endpoint that streams an external source
+// (OBS, a hardware encoder) into a room.
+type RoomIngress struct {
+	ID                  string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID              string    `gorm:"index;not null;type:varchar(36)" json:"roomId"`
+	IngressID           string    `gorm:"uniqueIndex;not null;type:varchar(64)" json:"ingressId"`
+	Type                string    `gorm:"not null;type:varchar(16)" json:"type"`
+	Name                string    `gorm:"type:varchar(255)" json:"name"`
+	ParticipantIdentity string    `gorm:"not null;type:varchar(255)" json:"participantIdentity"`
+	URL                 string    `gorm:"type:text" json:"url"`
+	StreamKey           string    `gorm:"type:varchar(255)" json:"streamKey"`
+	CreatedBy           string    `gorm:"not null;type:varchar(36)" json:"createdBy"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// IssuedRoomToken is the audit record of a LiveKit join token minted through
+// the admin API for bots, agents or probes. The token itself is never stored.
+type IssuedRoomToken struct {
+	ID                string      `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID            string      `gorm:"index;not null;type:varchar(36)" json:"roomId"`
+	RoomName          string      `gorm:"type:varchar(255)" json:"roomName"`
+	Identity          string      `gorm:"not null;type:varchar(255)" json:"identity"`
+	Name              string      `gorm:"type:varchar(255)" json:"name"`
+	Metadata          string      `gorm:"type:text" json:"metadata"`
+	Hidden            bool        `gorm:"not null;default:false" json:"hidden"`
+	Recorder          bool        `gorm:"not null;default:false" json:"recorder"`
+	SubscribeOnly     bool        `gorm:"not null;default:false" json:"subscribeOnly"`
+	CanPublishSources StringArray `json:"canPublishSources"`
+	IssuedBy          string      `gorm:"not null;type:varchar(36)" json:"issuedBy"`
+	ExpiresAt         time.Time   `json:"expiresAt"`
+	CreatedAt         time.Time   `json:"createdAt"`
+}
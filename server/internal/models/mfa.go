@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// MFARecoveryCodeCount is how many recovery codes a user is given at a time.
+const MFARecoveryCodeCount = 10
+
+// MFARecoveryCode is a one-time code that stands in for a TOTP code when the
+// user has lost their authenticator. Only its SHA-256 hash is stored.
+type MFARecoveryCode struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID    string     `json:"userId" gorm:"type:varchar(36);not null;index"`
+	CodeHash  string     `json:"-" gorm:"type:varchar(64);not null;index"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime;not null"`
+}
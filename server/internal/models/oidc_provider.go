@@ -0,0 +1,109 @@
+package models
+
+import (
+	"bedrud/config"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// OIDCProvider is a generic OpenID Connect login provider, such as Keycloak
+// or Authentik. Endpoints are found through discovery on IssuerURL.
+type OIDCProvider struct {
+	// ID names the provider in URLs (/api/auth/{id}/login) and is stored as
+	// the provider of users who sign in with it.
+	ID string `json:"id"`
+	// DisplayName labels the login button. Default: ID.
+	DisplayName  string   `json:"displayName"`
+	IssuerURL    string   `json:"issuerUrl"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	RedirectURL  string   `json:"redirectUrl"`
+	Scopes       []string `json:"scopes"`
+	// EmailClaim, NameClaim and GroupsClaim name the ID token claims to read.
+	// Default: email, name and groups.
+	EmailClaim  string `json:"emailClaim"`
+	NameClaim   string `json:"nameClaim"`
+	GroupsClaim string `json:"groupsClaim"`
+	// GroupAccess maps groups to the access level their members get. When it
+	// is set, a user's accesses are recomputed from their groups at every
+	// sign-in; when empty, users get the default user access.
+	GroupAccess map[string]AccessLevel `json:"groupAccess"`
+}
+
+var oidcProviderID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,19}$`)
+
+// reservedProviderIDs are provider names that are taken by built-in sign-in
+// methods.
+var reservedProviderIDs = map[string]bool{
+	"google": true, "github": true, "twitter": true,
+	ProviderLocal: true, ProviderPasskey: true, ProviderGuest: true,
+}
+
+// Validate checks that the provider can be used.
+func (p *OIDCProvider) Validate() error {
+	if !oidcProviderID.MatchString(p.ID) {
+		return fmt.Errorf("OIDC provider id %q must be 1-20 lowercase letters, digits, - or _", p.ID)
+	}
+	if reservedProviderIDs[p.ID] {
+		return fmt.Errorf("OIDC provider id %q is reserved", p.ID)
+	}
+	u, err := url.Parse(p.IssuerURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("OIDC provider %q needs an http(s) issuer URL", p.ID)
+	}
+	if p.ClientID == "" {
+		return fmt.Errorf("OIDC provider %q needs a client ID", p.ID)
+	}
+	for group, level := range p.GroupAccess {
+		switch level {
+		case AccessSuperAdmin, AccessAdmin, AccessMod, AccessUser:
+		default:
+			return fmt.Errorf("OIDC provider %q maps group %q to unknown access level %q", p.ID, group, level)
+		}
+	}
+	return nil
+}
+
+// ValidateOIDCProviders checks every provider and that their IDs are unique.
+func ValidateOIDCProviders(providers []OIDCProvider) error {
+	seen := make(map[string]bool, len(providers))
+	for i := range providers {
+		if err := providers[i].Validate(); err != nil {
+			return err
+		}
+		if seen[providers[i].ID] {
+			return errors.New("duplicate OIDC provider id " + providers[i].ID)
+		}
+		seen[providers[i].ID] = true
+	}
+	return nil
+}
+
+// OIDCProvidersFromConfig converts the providers listed in config.yaml.
+func OIDCProvidersFromConfig(list []config.OIDCProviderConfig) []OIDCProvider {
+	var providers []OIDCProvider
+	for _, c := range list {
+		p := OIDCProvider{
+			ID:           c.ID,
+			DisplayName:  c.DisplayName,
+			IssuerURL:    c.IssuerURL,
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			RedirectURL:  c.RedirectURL,
+			Scopes:       c.Scopes,
+			EmailClaim:   c.EmailClaim,
+			NameClaim:    c.NameClaim,
+			GroupsClaim:  c.GroupsClaim,
+		}
+		if len(c.GroupAccess) > 0 {
+			p.GroupAccess = make(map[string]AccessLevel, len(c.GroupAccess))
+			for group, level := range c.GroupAccess {
+				p.GroupAccess[group] = AccessLevel(level)
+			}
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Recording types.
+const (
+	RecordingTypeComposite = "composite" // the whole room mixed into one file
+	RecordingTypeTrack     = "track"     // a single published track
+)
+
+// Recording states. Mirrors the LiveKit egress status, lowercased and without
+// the EGRESS_ prefix.
+const (
+	RecordingStatusStarting     = "starting"
+	RecordingStatusActive       = "active"
+	RecordingStatusEnding       = "ending"
+	RecordingStatusComplete     = "complete"
+	RecordingStatusFailed       = "failed"
+	RecordingStatusAborted      = "aborted"
+	RecordingStatusLimitReached = "limit_reached"
+)
+
+// Recording is a meeting recording produced by LiveKit Egress.
+type Recording struct {
+	ID              string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID          string     `gorm:"index;not null;type:varchar(36)" json:"roomId"`
+	EgressID        string     `gorm:"index;type:varchar(64)" json:"egressId"`
+	Type            string     `gorm:"not null;type:varchar(16)" json:"type"`
+	TrackSID        string     `gorm:"type:varchar(64)" json:"trackSid,omitempty"`
+	Status          string     `gorm:"not null;type:varchar(16)" json:"status"`
+	StartedBy       string     `gorm:"not null;type:varchar(36)" json:"startedBy"`
+	Backend         string     `gorm:"not null;type:varchar(16)" json:"backend"`
+	Location        string     `gorm:"type:text" json:"-"`
+	Size            int64      `json:"size"`
+	DurationSeconds int64      `json:"durationSeconds"`
+	Error           string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt       time.Time  `json:"startedAt"`
+	EndedAt         *time.Time `json:"endedAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// IsFinished reports whether egress is done with the recording.
+func (r *Recording) IsFinished() bool {
+	switch r.Status {
+	case RecordingStatusComplete, RecordingStatusFailed, RecordingStatusAborted, RecordingStatusLimitReached:
+		return true
+	}
+	return false
+}
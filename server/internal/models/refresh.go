@@ -14,3 +14,17 @@ type BlockedRefreshToken struct {
 func (BlockedRefreshToken) TableName() string {
 	return "blocked_refresh_tokens"
 }
+
+// RevokedAccessToken records an access token revoked before its expiry, e.g.
+// on logout. Only the SHA-256 hash of the token is stored.
+type RevokedAccessToken struct {
+	TokenHash string    `json:"-" gorm:"primaryKey;type:varchar(64)"`
+	UserID    string    `json:"userId" gorm:"type:varchar(36);index"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"not null;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;not null"`
+}
+
+// TableName specifies the table name for GORM
+func (RevokedAccessToken) TableName() string {
+	return "revoked_access_tokens"
+}
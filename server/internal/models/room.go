@@ -17,10 +17,26 @@ const (
 
 // Sentinel errors for room operations
 var (
-	ErrRoomNameInvalid  = errors.New("room name must contain only lowercase letters, numbers, and hyphens")
-	ErrRoomNameTooShort = fmt.Errorf("room name must be at least %d characters", RoomNameMinLength)
-	ErrRoomNameTooLong  = fmt.Errorf("room name must be at most %d characters", RoomNameMaxLength)
-	ErrRoomNameTaken    = errors.New("a room with this name already exists")
+	ErrRoomNameInvalid    = errors.New("room name must contain only lowercase letters, numbers, and hyphens")
+	ErrRoomNameTooShort   = fmt.Errorf("room name must be at least %d characters", RoomNameMinLength)
+	ErrRoomNameTooLong    = fmt.Errorf("room name must be at most %d characters", RoomNameMaxLength)
+	ErrRoomNameTaken      = errors.New("a room with this name already exists")
+	ErrLobbyEntryNotFound = errors.New("no pending lobby entry for this participant")
+)
+
+// Room modes. In stage mode only participants brought on stage (and
+// moderators) may publish media; everyone else joins as audience.
+const (
+	RoomModeStandard = "standard"
+	RoomModeStage    = "stage"
+)
+
+// Lobby states for RoomParticipant.LobbyStatus. Only used when the room has
+// RequireApproval enabled; an empty status means the participant never waited.
+const (
+	LobbyStatusPending  = "pending"
+	LobbyStatusAdmitted = "admitted"
+	LobbyStatusDenied   = "denied"
 )
 
 // validRoomNameRegex allows only lowercase alphanumeric and hyphens,
@@ -88,6 +104,29 @@ type Room struct {
 	IsPublic        bool         `json:"isPublic" gorm:"not null;default:false"`
 	Settings        RoomSettings `json:"settings" gorm:"embedded;embeddedPrefix:settings_"`
 	Mode            string       `json:"mode" gorm:"not null;default:'standard';type:varchar(20)"` // Room mode (e.g. 'standard')
+	// PasscodeHash is the bcrypt hash of the join passcode; empty when none is set.
+	PasscodeHash string `json:"-" gorm:"type:varchar(255)"`
+	HasPasscode  bool   `json:"hasPasscode" gorm:"not null;default:false"`
+	// ParentRoomID links a breakout room to its main room; empty for main rooms.
+	ParentRoomID string `json:"parentRoomId,omitempty" gorm:"type:varchar(36);index"`
+	// Permanent rooms ignore ExpiresAt and are never archived for being idle.
+	Permanent bool `json:"permanent" gorm:"not null;default:false"`
+	// LastActivityAt is when someone last joined; nil until the first join.
+	LastActivityAt *time.Time `json:"lastActivityAt" gorm:"index"`
+	// ArchivedAt is set once the room expired or sat idle too long. Archived
+	// rooms can't be joined until the owner renews them.
+	ArchivedAt *time.Time `json:"archivedAt" gorm:"index"`
+	// LiveKitNode is the ID of the LiveKit node the room was placed on;
+	// empty means the default node.
+	LiveKitNode string `json:"livekitNode" gorm:"type:varchar(64);index"`
+}
+
+// IsBreakout reports whether the room is a breakout of another room.
+func (r *Room) IsBreakout() bool { return r.ParentRoomID != "" }
+
+// Expired reports whether the room is past its expiry at now.
+func (r *Room) Expired(now time.Time) bool {
+	return !r.Permanent && !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
 }
 
 // RoomSettings represents the global settings for a room
@@ -97,6 +136,11 @@ type RoomSettings struct {
 	AllowAudio      bool `json:"allowAudio" gorm:"not null;default:true"`
 	RequireApproval bool `json:"requireApproval" gorm:"not null;default:false"`
 	E2EE            bool `json:"e2ee" gorm:"not null;default:false"`
+	// E2EEAllowGuests lets guests into E2EE rooms and hands them the key;
+	// otherwise E2EE rooms are for signed-in users only.
+	E2EEAllowGuests bool `json:"e2eeAllowGuests" gorm:"not null;default:false"`
+	// ChatRetentionDays deletes chat history older than this many days; 0 keeps it.
+	ChatRetentionDays int `json:"chatRetentionDays" gorm:"not null;default:0"`
 }
 
 // RoomParticipant represents a user in a room
@@ -114,11 +158,21 @@ type RoomParticipant struct {
 	IsBanned      bool             `json:"isBanned" gorm:"not null;default:false"`
 	IsOnStage     bool             `json:"isOnStage" gorm:"not null;default:false"`
 	IsModerator   bool             `json:"isModerator" gorm:"not null;default:false"`
+	DisplayName   string           `json:"displayName" gorm:"type:varchar(255)"`
+	LobbyStatus   string           `json:"lobbyStatus" gorm:"type:varchar(16);index"`
+	LobbyTicket   string           `json:"-" gorm:"type:varchar(64);index"`
+	GuestKey      string           `json:"-" gorm:"type:varchar(64);index"`
 	User          *User            `json:"user" gorm:"foreignKey:UserID"`
 	Room          *Room            `json:"room" gorm:"foreignKey:RoomID"`
 	Permission    *RoomPermissions `json:"permission" gorm:"-"`
 }
 
+// Admitted reports whether the participant is past the lobby: either let in
+// by a moderator or never asked to wait.
+func (p *RoomParticipant) Admitted() bool {
+	return p.LobbyStatus == "" || p.LobbyStatus == LobbyStatusAdmitted
+}
+
 // RoomPermissions represents the permissions a participant has in a room
 type RoomPermissions struct {
 	ID              string           `json:"id" gorm:"primaryKey;type:varchar(36)"`
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Room ACL entry types. Group entries match the labels in User.Accesses.
+const (
+	RoomACLTypeUser   = "user"
+	RoomACLTypeDomain = "domain"
+	RoomACLTypeGroup  = "group"
+)
+
+// RoomACLEntry lets matching users join a private room. User entries hold a
+// user ID or email address, domain entries an email domain such as
+// "example.com".
+type RoomACLEntry struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID    string    `gorm:"not null;type:varchar(36);uniqueIndex:idx_room_acl_entry,priority:1" json:"roomId"`
+	Type      string    `gorm:"not null;type:varchar(16);uniqueIndex:idx_room_acl_entry,priority:2" json:"type"`
+	Value     string    `gorm:"not null;type:varchar(255);uniqueIndex:idx_room_acl_entry,priority:3" json:"value"`
+	CreatedBy string    `gorm:"not null;type:varchar(36)" json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Room access request states.
+const (
+	AccessRequestPending  = "pending"
+	AccessRequestApproved = "approved"
+	AccessRequestDenied   = "denied"
+)
+
+// RoomAccessRequest is a user asking the room admin to be let into a private room.
+type RoomAccessRequest struct {
+	ID         string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID     string     `gorm:"index;not null;type:varchar(36)" json:"roomId"`
+	UserID     string     `gorm:"not null;type:varchar(36)" json:"userId"`
+	Name       string     `gorm:"type:varchar(255)" json:"name"`
+	Email      string     `gorm:"type:varchar(255)" json:"email"`
+	Message    string     `gorm:"type:text" json:"message"`
+	Status     string     `gorm:"not null;type:varchar(16);default:'pending'" json:"status"`
+	ResolvedBy string     `gorm:"type:varchar(36)" json:"resolvedBy,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
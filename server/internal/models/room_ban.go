@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RoomBan keeps a participant out of a room until it is lifted or expires.
+// Guest identities change on every join, so guest bans also match on
+// GuestKey, the stable ID carried in the guest's signed cookie.
+type RoomBan struct {
+	ID          string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID      string     `gorm:"index;not null;type:varchar(36)" json:"roomId"`
+	Identity    string     `gorm:"index;not null;type:varchar(255)" json:"identity"`
+	GuestKey    string     `gorm:"index;type:varchar(64)" json:"-"`
+	DisplayName string     `gorm:"type:varchar(255)" json:"displayName"`
+	Reason      string     `gorm:"type:text" json:"reason"`
+	BannedBy    string     `gorm:"not null;type:varchar(36)" json:"bannedBy"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expiresAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// Active reports whether the ban is still in force at now.
+func (b *RoomBan) Active(now time.Time) bool {
+	return b.ExpiresAt == nil || now.Before(*b.ExpiresAt)
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// E2EEKeySize is the length in bytes of a room's shared encryption key.
+const E2EEKeySize = 32
+
+// RoomE2EEKey is the shared end-to-end encryption key for a room with
+// RoomSettings.E2EE set. Each meeting gets a fresh key, and the key is
+// replaced with the next version whenever someone is kicked or banned so
+// they can't decrypt what follows. Only the current version is kept.
+type RoomE2EEKey struct {
+	ID      string `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID  string `gorm:"not null;type:varchar(36);uniqueIndex:idx_room_e2ee_version" json:"roomId"`
+	Version int    `gorm:"not null;uniqueIndex:idx_room_e2ee_version" json:"version"`
+	// Key is never serialized; handlers hand it out explicitly.
+	Key       []byte    `gorm:"not null" json:"-"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
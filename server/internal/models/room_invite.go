@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Roles granted by redeeming a room invite.
+const (
+	RoomInviteRoleParticipant = "participant"
+	RoomInviteRoleModerator   = "moderator"
+	RoomInviteRoleStage       = "stage"
+)
+
+// RoomInvite is a shareable link into one room. Redeeming it skips the room's
+// visibility, passcode and lobby checks and grants Role on join.
+type RoomInvite struct {
+	ID     string `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID string `gorm:"index;not null;type:varchar(36)" json:"roomId"`
+	Token  string `gorm:"uniqueIndex;not null;type:varchar(64)" json:"token"`
+	// Email restricts the invite to the signed-in user with this address.
+	Email string `gorm:"type:varchar(255)" json:"email,omitempty"`
+	Role  string `gorm:"not null;type:varchar(20);default:'participant'" json:"role"`
+	// MaxUses caps redemptions; 0 means unlimited.
+	MaxUses   int        `gorm:"not null;default:0" json:"maxUses"`
+	Uses      int        `gorm:"not null;default:0" json:"uses"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	CreatedBy string     `gorm:"not null;type:varchar(36)" json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Usable reports whether the invite can still be redeemed at now.
+func (i *RoomInvite) Usable(now time.Time) bool {
+	return i.RevokedAt == nil && now.Before(i.ExpiresAt) && (i.MaxUses == 0 || i.Uses < i.MaxUses)
+}
+
+// RoomInviteRedemption records one use of a room invite.
+type RoomInviteRedemption struct {
+	ID         string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	InviteID   string    `gorm:"index;not null;type:varchar(36)" json:"inviteId"`
+	RoomID     string    `gorm:"not null;type:varchar(36)" json:"roomId"`
+	Identity   string    `gorm:"not null;type:varchar(255)" json:"identity"`
+	Name       string    `gorm:"type:varchar(255)" json:"name"`
+	IP         string    `gorm:"type:varchar(64)" json:"ip"`
+	RedeemedAt time.Time `gorm:"autoCreateTime" json:"redeemedAt"`
+}
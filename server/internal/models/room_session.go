@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// RoomSession is one meeting held in a room, from the moment the media room
+// starts until it finishes or the idle scheduler closes it. A room that is
+// reused for many meetings has one session per meeting.
+type RoomSession struct {
+	ID        string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID    string     `gorm:"index;not null;type:varchar(36)" json:"roomId"`
+	StartedAt time.Time  `gorm:"index;not null" json:"startedAt"`
+	EndedAt   *time.Time `gorm:"index" json:"endedAt"`
+}
+
+// Duration is how long the session lasted, or has lasted so far at now.
+func (s *RoomSession) Duration(now time.Time) time.Duration {
+	return span(s.StartedAt, s.EndedAt, now)
+}
+
+// AttendanceSegment is one stretch of a participant's time in a session,
+// from join to leave. A participant who reconnects gets a new segment.
+type AttendanceSegment struct {
+	ID          string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	SessionID   string     `gorm:"index;not null;type:varchar(36)" json:"sessionId"`
+	RoomID      string     `gorm:"index;not null;type:varchar(36)" json:"roomId"`
+	Identity    string     `gorm:"index;not null;type:varchar(255)" json:"identity"`
+	DisplayName string     `gorm:"type:varchar(255)" json:"displayName"`
+	JoinedAt    time.Time  `gorm:"not null" json:"joinedAt"`
+	LeftAt      *time.Time `json:"leftAt"`
+}
+
+// Duration is how long the participant stayed, or has stayed so far at now.
+func (a *AttendanceSegment) Duration(now time.Time) time.Duration {
+	return span(a.JoinedAt, a.LeftAt, now)
+}
+
+func span(from time.Time, to *time.Time, now time.Time) time.Duration {
+	end := now
+	if to != nil {
+		end = *to
+	}
+	if end.Before(from) {
+		return 0
+	}
+	return end.Sub(from)
+}
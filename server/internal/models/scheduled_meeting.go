@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ScheduledMeeting is a one-off or recurring meeting held in a room. StartAt
+// is the first occurrence; its wall-clock time in TimeZone is kept for every
+// occurrence of an RRule series.
+type ScheduledMeeting struct {
+	ID              string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	RoomID          string    `gorm:"index;not null;type:varchar(36)" json:"roomId"`
+	Title           string    `gorm:"not null;type:varchar(255)" json:"title"`
+	Description     string    `gorm:"type:text" json:"description"`
+	StartAt         time.Time `gorm:"not null" json:"startAt"`
+	DurationMinutes int       `gorm:"not null" json:"durationMinutes"`
+	TimeZone        string    `gorm:"not null;type:varchar(64)" json:"timeZone"`
+	RRule           string    `gorm:"type:varchar(512)" json:"rrule"`
+	// EarlyJoinMinutes, when set, refuses joins earlier than this many
+	// minutes before an occurrence starts.
+	EarlyJoinMinutes *int      `json:"earlyJoinMinutes"`
+	CreatedBy        string    `gorm:"not null;type:varchar(36)" json:"createdBy"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Duration returns how long each occurrence lasts.
+func (m *ScheduledMeeting) Duration() time.Duration {
+	return time.Duration(m.DurationMinutes) * time.Minute
+}
+
+// CalendarFeed holds the secret token of a user's personal ICS subscription
+// URL. Only its SHA-256 hash is stored, so the URL is shown once, when it is
+// issued. Rotating the token revokes the old URL.
+type CalendarFeed struct {
+	UserID    string    `gorm:"primaryKey;type:varchar(36)" json:"-"`
+	TokenHash string    `gorm:"uniqueIndex;not null;type:varchar(64)" json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session is one signed-in device. Each session owns a refresh token family:
+// refreshing replaces RefreshTokenHash with the hash of a new token, and
+// presenting an earlier token from the family revokes the session. MFA is set
+// when the sign-in was completed with a second factor or a passkey.
+type Session struct {
+	ID               string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID           string    `json:"userId" gorm:"type:varchar(36);not null;index"`
+	DeviceName       string    `json:"deviceName" gorm:"type:varchar(100)"`
+	UserAgent        string    `json:"userAgent" gorm:"type:text"`
+	IP               string    `json:"ip" gorm:"type:varchar(45)"`
+	RefreshTokenHash string    `json:"-" gorm:"type:varchar(64);not null"`
+	MFA              bool      `json:"mfa" gorm:"not null;default:false"`
+	ExpiresAt        time.Time `json:"expiresAt" gorm:"not null;index"`
+	CreatedAt        time.Time `json:"createdAt" gorm:"autoCreateTime;not null"`
+	LastUsedAt       time.Time `json:"lastUsedAt" gorm:"not null"`
+}
@@ -9,6 +9,11 @@ type SystemSettings struct {
 
 	// Auth
 	PasskeysEnabled      bool   `gorm:"not null;default:true" json:"passkeysEnabled"`
+	// RequireAdminMFA makes admin and superadmin access depend on signing in
+	// with a second factor or a passkey. Admins who sign in through OAuth or
+	// OIDC have no password to pair a TOTP code with, so they need a passkey
+	// and PasskeysEnabled.
+	RequireAdminMFA      bool   `gorm:"not null;default:false" json:"requireAdminMfa"`
 	GoogleClientID       string `gorm:"size:512" json:"googleClientId"`
 	GoogleClientSecret   string `gorm:"size:512" json:"googleClientSecret"`
 	GoogleRedirectURL    string `gorm:"size:512" json:"googleRedirectUrl"`
@@ -18,6 +23,9 @@ type SystemSettings struct {
 	TwitterClientID      string `gorm:"size:512" json:"twitterClientId"`
 	TwitterClientSecret  string `gorm:"size:512" json:"twitterClientSecret"`
 	TwitterRedirectURL   string `gorm:"size:512" json:"twitterRedirectUrl"`
+	// OIDCProviders are generic OpenID Connect providers. Their client
+	// secrets are masked in API responses like the fields in SecretFields.
+	OIDCProviders        []OIDCProvider `gorm:"serializer:json;type:text" json:"oidcProviders"`
 	JWTSecret            string `gorm:"size:512" json:"jwtSecret"`
 	TokenDuration        int    `gorm:"default:24" json:"tokenDuration"`
 	SessionSecret        string `gorm:"size:512" json:"sessionSecret"`
@@ -59,9 +67,24 @@ type SystemSettings struct {
 	ChatUploadS3SecretKey string `gorm:"size:255" json:"chatUploadS3SecretKey"`
 	ChatUploadS3PublicURL string `gorm:"size:512" json:"chatUploadS3PublicUrl"`
 
+	// Mail
+	MailDriver   string `gorm:"size:20" json:"mailDriver"`
+	MailHost     string `gorm:"size:255" json:"mailHost"`
+	MailPort     int    `json:"mailPort"`
+	MailUsername string `gorm:"size:255" json:"mailUsername"`
+	MailPassword string `gorm:"size:512" json:"mailPassword"`
+	MailFrom     string `gorm:"size:255" json:"mailFrom"`
+	MailTLS      string `gorm:"size:20" json:"mailTls"`
+
 	// Logger
 	LogLevel string `gorm:"size:20" json:"logLevel"`
 
+	// Room lifecycle
+	RoomDefaultTTLHours        int  `json:"roomDefaultTtlHours"`
+	RoomAllowPermanent         bool `json:"roomAllowPermanent"`
+	RoomArchiveAfterIdleDays   int  `json:"roomArchiveAfterIdleDays"`
+	RoomDeleteAfterArchiveDays int  `json:"roomDeleteAfterArchiveDays"`
+
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
@@ -74,6 +97,7 @@ var SecretFields = []string{
 	"sessionSecret",
 	"livekitApiSecret",
 	"chatUploadS3SecretKey",
+	"mailPassword",
 }
 
 // IsOAuthProviderConfigured returns true if the given provider has both
@@ -100,3 +124,29 @@ func (s *SystemSettings) ConfiguredOAuthProviders() []string {
 	}
 	return providers
 }
+
+// DefaultRoomTTL is how long a room lasts when no TTL is configured.
+const DefaultRoomTTL = 24 * time.Hour
+
+// RoomLifecycle is the room lifecycle policy with defaults filled in. A zero
+// ArchiveAfterIdle or DeleteAfterArchive disables that step.
+type RoomLifecycle struct {
+	DefaultTTL         time.Duration
+	AllowPermanent     bool
+	ArchiveAfterIdle   time.Duration
+	DeleteAfterArchive time.Duration
+}
+
+// RoomLifecycle returns the room lifecycle policy in the settings.
+func (s *SystemSettings) RoomLifecycle() RoomLifecycle {
+	p := RoomLifecycle{
+		DefaultTTL:         DefaultRoomTTL,
+		AllowPermanent:     s.RoomAllowPermanent,
+		ArchiveAfterIdle:   time.Duration(s.RoomArchiveAfterIdleDays) * 24 * time.Hour,
+		DeleteAfterArchive: time.Duration(s.RoomDeleteAfterArchiveDays) * 24 * time.Hour,
+	}
+	if s.RoomDefaultTTLHours > 0 {
+		p.DefaultTTL = time.Duration(s.RoomDefaultTTLHours) * time.Hour
+	}
+	return p
+}
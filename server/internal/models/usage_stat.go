@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// Usage stat bucket sizes. Every sample is folded into the hour and the day
+// it was taken in, so no raw samples are kept.
+const (
+	UsageBucketHour = "hour"
+	UsageBucketDay  = "day"
+)
+
+// UsageStat aggregates the LiveKit usage samples taken during one bucket.
+// Sums are kept alongside the maxima so averages can be derived and further
+// samples folded in.
+type UsageStat struct {
+	ID                 uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	Bucket             string    `gorm:"uniqueIndex:idx_usage_stat_bucket;not null;type:varchar(8)" json:"bucket"`
+	BucketStart        time.Time `gorm:"uniqueIndex:idx_usage_stat_bucket;not null" json:"start"`
+	Samples            int       `gorm:"not null" json:"samples"`
+	ParticipantsMax    int       `gorm:"not null" json:"participantsMax"`
+	ParticipantsSum    int64     `gorm:"not null" json:"-"`
+	RoomsMax           int       `gorm:"not null" json:"roomsMax"`
+	RoomsSum           int64     `gorm:"not null" json:"-"`
+	PublishersMax      int       `gorm:"not null" json:"publishersMax"`
+	PublishersSum      int64     `gorm:"not null" json:"-"`
+	ParticipantMinutes float64   `gorm:"not null" json:"participantMinutes"`
+	RoomMinutes        float64   `gorm:"not null" json:"roomMinutes"`
+}
+
+// UsageSampleClaim marks a sampling minute as recorded. Every server replica
+// runs the sampler over every LiveKit node; the first to claim a minute
+// records it and the others skip it, so usage isn't counted twice.
+type UsageSampleClaim struct {
+	Minute time.Time `gorm:"primaryKey"`
+}
+
+// UsageSample is one reading of LiveKit usage.
+type UsageSample struct {
+	Participants int
+	Rooms        int
+	Publishers   int
+	// Interval is the time the sample stands for, used for meeting minutes.
+	Interval time.Duration
+}
+
+// Add folds a sample into the bucket.
+func (s *UsageStat) Add(sample UsageSample) {
+	s.Samples++
+	s.ParticipantsSum += int64(sample.Participants)
+	s.RoomsSum += int64(sample.Rooms)
+	s.PublishersSum += int64(sample.Publishers)
+	s.ParticipantsMax = max(s.ParticipantsMax, sample.Participants)
+	s.RoomsMax = max(s.RoomsMax, sample.Rooms)
+	s.PublishersMax = max(s.PublishersMax, sample.Publishers)
+	s.ParticipantMinutes += float64(sample.Participants) * sample.Interval.Minutes()
+	s.RoomMinutes += float64(sample.Rooms) * sample.Interval.Minutes()
+}
+
+// Merge folds another bucket into this one, e.g. to serve hourly buckets as
+// coarser ones.
+func (s *UsageStat) Merge(o *UsageStat) {
+	s.Samples += o.Samples
+	s.ParticipantsSum += o.ParticipantsSum
+	s.RoomsSum += o.RoomsSum
+	s.PublishersSum += o.PublishersSum
+	s.ParticipantsMax = max(s.ParticipantsMax, o.ParticipantsMax)
+	s.RoomsMax = max(s.RoomsMax, o.RoomsMax)
+	s.PublishersMax = max(s.PublishersMax, o.PublishersMax)
+	s.ParticipantMinutes += o.ParticipantMinutes
+	s.RoomMinutes += o.RoomMinutes
+}
+
+// Averages returns the mean concurrent participants, rooms and publishers
+// over the bucket's samples.
+func (s *UsageStat) Averages() (participants, rooms, publishers float64) {
+	if s.Samples == 0 {
+		return 0, 0, 0
+	}
+	n := float64(s.Samples)
+	return float64(s.ParticipantsSum) / n, float64(s.RoomsSum) / n, float64(s.PublishersSum) / n
+}
+
+// UsageBucketStart returns the start of the bucket that t falls in. Buckets
+// are aligned to UTC.
+func UsageBucketStart(bucket string, t time.Time) time.Time {
+	t = t.UTC()
+	if bucket == UsageBucketDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
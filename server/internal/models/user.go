@@ -73,17 +73,29 @@ func (StringArray) GormDataType() string {
 }
 
 type User struct {
-	ID           string      `json:"id" gorm:"primaryKey;type:varchar(36)"`
-	Email        string      `json:"email" gorm:"uniqueIndex:idx_email_provider;not null;type:varchar(255)"`
-	Name         string      `json:"name" gorm:"not null;type:varchar(255)"`
-	Provider     string      `json:"provider" gorm:"uniqueIndex:idx_email_provider;type:varchar(20);default:'local'"`
-	AvatarURL    string      `json:"avatarUrl" gorm:"column:avatar_url;type:varchar(255)"`
-	Password     string      `json:"-" gorm:"type:varchar(255)"`
-	RefreshToken string      `json:"-" gorm:"column:refresh_token;type:text"`
-	Accesses     StringArray `json:"accesses" gorm:"type:text[]"`
-	IsActive     bool        `json:"isActive" gorm:"not null;default:true"`
-	CreatedAt    time.Time   `json:"createdAt" gorm:"autoCreateTime;not null"`
-	UpdatedAt    time.Time   `json:"updatedAt" gorm:"autoUpdateTime;not null"`
+	ID        string      `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	Email     string      `json:"email" gorm:"uniqueIndex:idx_email_provider;not null;type:varchar(255)"`
+	Name      string      `json:"name" gorm:"not null;type:varchar(255)"`
+	Provider  string      `json:"provider" gorm:"uniqueIndex:idx_email_provider;type:varchar(20);default:'local'"`
+	AvatarURL string      `json:"avatarUrl" gorm:"column:avatar_url;type:varchar(255)"`
+	Password  string      `json:"-" gorm:"type:varchar(255)"`
+	Accesses  StringArray `json:"accesses" gorm:"type:text[]"`
+	IsActive  bool        `json:"isActive" gorm:"not null;default:true"`
+	// EmailVerified is set once the user has opened a verification link
+	// sent to Email.
+	EmailVerified bool `json:"emailVerified" gorm:"not null;default:false"`
+	// MFAEnabled is set once the user has confirmed a TOTP authenticator.
+	// MFASecret may hold a pending secret before that. MFALastStep is the
+	// last TOTP time step accepted, so a code cannot be used twice.
+	MFAEnabled  bool   `json:"mfaEnabled" gorm:"not null;default:false"`
+	MFASecret   string `json:"-" gorm:"type:varchar(64)"`
+	MFALastStep int64  `json:"-" gorm:"not null;default:0"`
+	// MFAFailures counts wrong codes entered at sign-in since the last right
+	// one. Too many lock code entry until MFALockedUntil.
+	MFAFailures    int        `json:"-" gorm:"not null;default:0"`
+	MFALockedUntil *time.Time `json:"-"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"autoCreateTime;not null"`
+	UpdatedAt      time.Time  `json:"updatedAt" gorm:"autoUpdateTime;not null"`
 }
 
 // TableName specifies the table name for GORM
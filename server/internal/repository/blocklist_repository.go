@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"bedrud/internal/models"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrBlocklistEntryExists is returned when adding an entry that is already blocked.
+var ErrBlocklistEntryExists = errors.New("blocklist entry already exists")
+
+type BlocklistRepository struct {
+	db *gorm.DB
+}
+
+func NewBlocklistRepository(db *gorm.DB) *BlocklistRepository {
+	return &BlocklistRepository{db: db}
+}
+
+// CreateEntry adds an entry to the blocklist. An expired entry with the same
+// type and value is replaced.
+func (r *BlocklistRepository) CreateEntry(entry *models.BlocklistEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.BlocklistEntry
+		err := tx.Where("type = ? AND value = ?", entry.Type, entry.Value).First(&existing).Error
+		if err == nil {
+			if existing.Active(time.Now()) {
+				return ErrBlocklistEntryExists
+			}
+			if err := tx.Delete(&existing).Error; err != nil {
+				return err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+// GetActiveEntries returns the entries in force, newest first.
+func (r *BlocklistRepository) GetActiveEntries() ([]models.BlocklistEntry, error) {
+	var entries []models.BlocklistEntry
+	err := r.db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("created_at desc").Find(&entries).Error
+	return entries, err
+}
+
+// DeleteEntry removes an entry by ID. It reports whether the entry existed.
+func (r *BlocklistRepository) DeleteEntry(id string) (bool, error) {
+	res := r.db.Where("id = ?", id).Delete(&models.BlocklistEntry{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteEntryByValue removes the entry with the given type and value. It
+// reports whether the entry existed.
+func (r *BlocklistRepository) DeleteEntryByValue(typ, value string) (bool, error) {
+	res := r.db.Where("type = ? AND value = ?", typ, value).Delete(&models.BlocklistEntry{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// PurgeExpiredEntries deletes entries that expired before now and returns how
+// many were deleted.
+func (r *BlocklistRepository) PurgeExpiredEntries(now time.Time) (int64, error) {
+	res := r.db.Where("expires_at IS NOT NULL AND expires_at <= ?", now).Delete(&models.BlocklistEntry{})
+	return res.RowsAffected, res.Error
+}
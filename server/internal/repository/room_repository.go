@@ -2,6 +2,7 @@ package repository
 
 import (
 	"bedrud/internal/models"
+	"crypto/rand"
 	"errors"
 	"strings"
 	"time"
@@ -22,6 +23,12 @@ func NewRoomRepository(db *gorm.DB) *RoomRepository {
 // If name is empty, a random URL-safe name is generated.
 // The name is validated to contain only lowercase letters, numbers, and hyphens.
 func (r *RoomRepository) CreateRoom(createdBy, name string, isPublic bool, mode string, settings *models.RoomSettings) (*models.Room, error) {
+	return r.CreateRoomOnNode(createdBy, name, isPublic, mode, settings, "")
+}
+
+// CreateRoomOnNode is CreateRoom for a room placed on the LiveKit node with
+// ID nodeID; empty means the default node.
+func (r *RoomRepository) CreateRoomOnNode(createdBy, name string, isPublic bool, mode string, settings *models.RoomSettings, nodeID string) (*models.Room, error) {
 	// Normalize the name: trim whitespace and lowercase
 	name = strings.TrimSpace(strings.ToLower(name))
 
@@ -53,15 +60,16 @@ func (r *RoomRepository) CreateRoom(createdBy, name string, isPublic bool, mode
 	err = r.db.Transaction(func(tx *gorm.DB) error {
 		// Create room first
 		newRoom := &models.Room{
-			ID:        uuid.New().String(),
-			Name:      name,
-			CreatedBy: createdBy,
-			AdminID:   createdBy,
-			IsActive:  true,
-			IsPublic:  isPublic,
-			Settings:  *settings,
-			Mode:      mode,
-			ExpiresAt: time.Now().Add(24 * time.Hour),
+			ID:          uuid.New().String(),
+			Name:        name,
+			CreatedBy:   createdBy,
+			AdminID:     createdBy,
+			IsActive:    true,
+			IsPublic:    isPublic,
+			Settings:    *settings,
+			Mode:        mode,
+			ExpiresAt:   time.Now().Add(models.DefaultRoomTTL),
+			LiveKitNode: nodeID,
 		}
 
 		if err := tx.Create(newRoom).Error; err != nil {
@@ -134,6 +142,10 @@ func (r *RoomRepository) GetRoomByName(name string) (*models.Room, error) {
 	return &room, nil
 }
 
+// ErrParticipantBanned is returned when a banned participant is added back to
+// a room.
+var ErrParticipantBanned = errors.New("user is banned from this room")
+
 // AddParticipant adds a participant to a room or reactivates them if they already exist
 func (r *RoomRepository) AddParticipant(roomID, userID string) error {
 	// Check if participant already exists
@@ -143,7 +155,7 @@ func (r *RoomRepository) AddParticipant(roomID, userID string) error {
 	if err == nil {
 		// Check if participant is banned
 		if existing.IsBanned {
-			return errors.New("user is banned from this room")
+			return ErrParticipantBanned
 		}
 
 		// Participant exists, update their status
@@ -191,11 +203,78 @@ func (r *RoomRepository) GetActiveParticipants(roomID string) ([]models.RoomPart
 	return participants, err
 }
 
-// CleanupExpiredRooms marks rooms as inactive if they've expired
-func (r *RoomRepository) CleanupExpiredRooms() error {
+// inMeeting matches rooms someone is connected to, going by the open
+// attendance segments, counting their breakout rooms.
+const inMeeting = `EXISTS (SELECT 1 FROM attendance_segments a WHERE a.left_at IS NULL AND
+	(a.room_id = rooms.id OR a.room_id IN (SELECT b.id FROM rooms b WHERE b.parent_room_id = rooms.id)))`
+
+// CleanupExpiredRooms archives rooms that are past their expiry, which also
+// marks them inactive, and returns them. Permanent rooms never expire, and
+// rooms with a meeting still running are left until it ends.
+func (r *RoomRepository) CleanupExpiredRooms() ([]models.Room, error) {
+	now := time.Now()
+	var rooms []models.Room
+	err := r.db.Where("expires_at < ? AND expires_at > ? AND permanent = ? AND archived_at IS NULL", now, time.Time{}, false).
+		Where("NOT " + inMeeting).
+		Find(&rooms).Error
+	if err != nil || len(rooms) == 0 {
+		return nil, err
+	}
+	ids := make([]string, len(rooms))
+	for i := range rooms {
+		ids[i] = rooms[i].ID
+	}
+	err = r.db.Model(&models.Room{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{"is_active": false, "archived_at": now}).Error
+	if err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+// GetIdleRooms returns the main rooms nobody has joined since before cutoff,
+// counting from creation for rooms never joined. Permanent and archived rooms
+// are left out, as are rooms with a meeting still running.
+func (r *RoomRepository) GetIdleRooms(cutoff time.Time) ([]models.Room, error) {
+	var rooms []models.Room
+	err := r.db.Where("(parent_room_id = '' OR parent_room_id IS NULL) AND permanent = ? AND archived_at IS NULL", false).
+		Where("COALESCE(last_activity_at, created_at) < ?", cutoff).
+		Where("NOT " + inMeeting).
+		Find(&rooms).Error
+	return rooms, err
+}
+
+// ArchiveRoom archives a room and its breakout rooms.
+func (r *RoomRepository) ArchiveRoom(roomID string) error {
 	return r.db.Model(&models.Room{}).
-		Where("expires_at < ? AND is_active = ?", time.Now(), true).
-		Update("is_active", false).Error
+		Where("(id = ? OR parent_room_id = ?) AND archived_at IS NULL", roomID, roomID).
+		Updates(map[string]interface{}{"is_active": false, "archived_at": time.Now()}).Error
+}
+
+// GetRoomsArchivedBefore returns the main rooms archived before cutoff.
+func (r *RoomRepository) GetRoomsArchivedBefore(cutoff time.Time) ([]models.Room, error) {
+	var rooms []models.Room
+	err := r.db.Where("(parent_room_id = '' OR parent_room_id IS NULL) AND archived_at < ?", cutoff).
+		Find(&rooms).Error
+	return rooms, err
+}
+
+// RenewRoom sets a new expiry on a room and its breakout rooms and brings
+// them back from the archive.
+func (r *RoomRepository) RenewRoom(roomID string, expiresAt time.Time, permanent bool) error {
+	return r.db.Model(&models.Room{}).
+		Where("id = ? OR parent_room_id = ?", roomID, roomID).
+		Updates(map[string]interface{}{"expires_at": expiresAt, "permanent": permanent, "archived_at": nil}).Error
+}
+
+// TouchRoom records activity in a room, and in its main room for breakouts,
+// so it isn't archived as idle.
+func (r *RoomRepository) TouchRoom(room *models.Room) error {
+	ids := []string{room.ID}
+	if room.IsBreakout() {
+		ids = append(ids, room.ParentRoomID)
+	}
+	return r.db.Model(&models.Room{}).Where("id IN ?", ids).Update("last_activity_at", time.Now()).Error
 }
 
 // UpdateParticipantPermissions updates a participant's permissions
@@ -206,16 +285,39 @@ func (r *RoomRepository) UpdateParticipantPermissions(roomID, userID string, per
 
 // BringToStage brings a participant to the stage
 func (r *RoomRepository) BringToStage(roomID, userID string) error {
-	return r.db.Model(&models.RoomParticipant{}).
-		Where("room_id = ? AND user_id = ?", roomID, userID).
-		Update("is_on_stage", true).Error
+	return r.setParticipantFlags(roomID, userID, map[string]interface{}{"is_on_stage": true})
 }
 
 // RemoveFromStage removes a participant from the stage
 func (r *RoomRepository) RemoveFromStage(roomID, userID string) error {
-	return r.db.Model(&models.RoomParticipant{}).
-		Where("room_id = ? AND user_id = ?", roomID, userID).
-		Update("is_on_stage", false).Error
+	return r.setParticipantFlags(roomID, userID, map[string]interface{}{"is_on_stage": false})
+}
+
+// setParticipantFlags updates a participant's record, creating it first for
+// participants LiveKit knows but we have no record of yet, such as guests
+// and bots joined with an issued token. Otherwise the change would be lost
+// and their publish grant computed without it.
+func (r *RoomRepository) setParticipantFlags(roomID, userID string, updates map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.RoomParticipant{}).Where("room_id = ? AND user_id = ?", roomID, userID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			participant := &models.RoomParticipant{ID: uuid.New().String(), RoomID: roomID, UserID: userID, JoinedAt: time.Now()}
+			if err := tx.Create(participant).Error; err != nil {
+				return err
+			}
+			// GORM skips zero values on create, so force the participant
+			// inactive until the LiveKit webhook reports them joined.
+			if err := tx.Model(participant).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.RoomParticipant{}).Where("room_id = ? AND user_id = ?", roomID, userID).
+			Updates(updates).Error
+	})
 }
 
 // IsParticipantOnStage checks if a participant is on stage
@@ -272,13 +374,7 @@ func (r *RoomRepository) DeleteRoom(roomID, userID string) error {
 		if err := tx.Where("id = ? AND created_by = ?", roomID, userID).First(&room).Error; err != nil {
 			return err
 		}
-		if err := tx.Where("room_id = ?", roomID).Delete(&models.RoomPermissions{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("room_id = ?", roomID).Delete(&models.RoomParticipant{}).Error; err != nil {
-			return err
-		}
-		return tx.Delete(&room).Error
+		return deleteRoomTree(tx, &room)
 	})
 }
 
@@ -290,14 +386,59 @@ func (r *RoomRepository) AdminDeleteRoom(roomID string) error {
 		if err := tx.Where("id = ?", roomID).First(&room).Error; err != nil {
 			return err
 		}
-		if err := tx.Where("room_id = ?", roomID).Delete(&models.RoomPermissions{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("room_id = ?", roomID).Delete(&models.RoomParticipant{}).Error; err != nil {
+		return deleteRoomTree(tx, &room)
+	})
+}
+
+// deleteRoomTree deletes a room, its breakout rooms and everything that
+// belongs to them.
+func deleteRoomTree(tx *gorm.DB, room *models.Room) error {
+	var children []models.Room
+	if err := tx.Where("parent_room_id = ?", room.ID).Find(&children).Error; err != nil {
+		return err
+	}
+	for i := range children {
+		if err := deleteRoomTree(tx, &children[i]); err != nil {
 			return err
 		}
-		return tx.Delete(&room).Error
-	})
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomPermissions{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomParticipant{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.ChatMessage{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomInviteRedemption{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomInvite{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomACLEntry{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomAccessRequest{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomBan{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.ScheduledMeeting{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.AttendanceSegment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomSession{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("room_id = ?", room.ID).Delete(&models.RoomE2EEKey{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(room).Error
 }
 
 func (r *RoomRepository) GetAllRooms() ([]models.Room, error) {
@@ -334,6 +475,23 @@ func (r *RoomRepository) SetRoomIdle(roomID string) error {
 	return r.db.Model(&models.Room{}).Where("id = ?", roomID).Update("is_active", false).Error
 }
 
+// SetRoomActive marks a room as active, e.g. when LiveKit reports room_started.
+func (r *RoomRepository) SetRoomActive(roomID string) error {
+	return r.db.Model(&models.Room{}).Where("id = ?", roomID).Update("is_active", true).Error
+}
+
+// DeactivateAllParticipants marks every active participant in a room as having
+// left. Used when the media room closes so no participant stays active forever.
+func (r *RoomRepository) DeactivateAllParticipants(roomID string) error {
+	now := time.Now()
+	return r.db.Model(&models.RoomParticipant{}).
+		Where("room_id = ? AND is_active = ?", roomID, true).
+		Updates(map[string]interface{}{
+			"is_active": false,
+			"left_at":   now,
+		}).Error
+}
+
 func (r *RoomRepository) GetRoomParticipantsWithUsers(roomID string) ([]models.RoomParticipant, error) {
 	var participants []models.RoomParticipant
 	err := r.db.Preload("User").Where("room_id = ?", roomID).Find(&participants).Error
@@ -349,10 +507,35 @@ func (r *RoomRepository) GetUserByID(userID string) (*models.User, error) {
 	return &user, nil
 }
 
+// GetUsersByIDs returns the users with the given IDs. IDs without a user are
+// skipped.
+func (r *RoomRepository) GetUsersByIDs(ids ...string) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
 // GetRoomsCreatedByUser retrieves rooms created by a specific user
 func (r *RoomRepository) GetRoomsCreatedByUser(userID string) ([]models.Room, error) {
 	var rooms []models.Room
-	err := r.db.Where("created_by = ?", userID).Order("created_at desc").Find(&rooms).Error
+	err := r.db.Where("created_by = ? AND (parent_room_id = '' OR parent_room_id IS NULL)", userID).Order("created_at desc").Find(&rooms).Error
+	return rooms, err
+}
+
+// GetRoomsSharedWithUser retrieves main rooms other users created whose ACL
+// admits the user by ID, verified email, email domain or group.
+func (r *RoomRepository) GetRoomsSharedWithUser(userID string, groups []string) ([]models.Room, error) {
+	matches, err := r.aclMatches(userID, groups)
+	if err != nil {
+		return nil, err
+	}
+	var rooms []models.Room
+	err = r.db.Where("id IN (?) AND created_by <> ? AND (parent_room_id = '' OR parent_room_id IS NULL)",
+		matches.Select("room_id"), userID).
+		Order("created_at desc").Find(&rooms).Error
 	return rooms, err
 }
 
@@ -388,12 +571,20 @@ func (r *RoomRepository) CountActiveParticipants() (int64, error) {
 	return count, err
 }
 
-// IsParticipantBanned returns true when a participant record exists for the given
-// room and user identity with is_banned = true.
+// IsParticipantBanned reports whether a ban in force matches the identity. A
+// timed ban stops counting once it expires, even before the scheduler lifts it.
+// Participants flagged banned without a ban record are banned for good.
 func (r *RoomRepository) IsParticipantBanned(roomID, userID string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.RoomParticipant{}).
+	err := r.db.Model(&models.RoomBan{}).
+		Where("room_id = ? AND identity = ? AND (expires_at IS NULL OR expires_at > ?)", roomID, userID, time.Now()).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+	err = r.db.Model(&models.RoomParticipant{}).
 		Where("room_id = ? AND user_id = ? AND is_banned = ?", roomID, userID, true).
+		Where("NOT EXISTS (SELECT 1 FROM room_bans b WHERE b.room_id = room_participants.room_id AND b.identity = room_participants.user_id)").
 		Count(&count).Error
 	return count > 0, err
 }
@@ -415,11 +606,975 @@ func (r *RoomRepository) SetRoomModerator(roomID, userID string, isMod bool) err
 		Update("is_moderator", isMod).Error
 }
 
-// GetParticipantCount returns the number of non-banned participants for a room.
+// GetParticipantCount returns the number of active, non-banned participants for a room.
 func (r *RoomRepository) GetParticipantCount(roomID string) (int, error) {
 	var count int64
 	err := r.db.Model(&models.RoomParticipant{}).
-		Where("room_id = ? AND is_banned = ?", roomID, false).
+		Where("room_id = ? AND is_active = ? AND is_banned = ?", roomID, true, false).
 		Count(&count).Error
 	return int(count), err
 }
+
+// IsParticipantApproved reports whether the user has been admitted to the room,
+// either as its creator or through the lobby.
+func (r *RoomRepository) IsParticipantApproved(roomID, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RoomParticipant{}).
+		Where("room_id = ? AND user_id = ? AND is_approved = ?", roomID, userID, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RequestLobbyEntry puts a participant in the room's lobby and returns the entry
+// with its ticket. Repeated requests while still pending keep the same ticket.
+func (r *RoomRepository) RequestLobbyEntry(roomID, userID, displayName string) (*models.RoomParticipant, error) {
+	var existing models.RoomParticipant
+	err := r.db.Where("room_id = ? AND user_id = ?", roomID, userID).First(&existing).Error
+	if err == nil {
+		if existing.IsBanned {
+			return nil, ErrParticipantBanned
+		}
+		ticket := existing.LobbyTicket
+		if existing.LobbyStatus != models.LobbyStatusPending || ticket == "" {
+			ticket = uuid.New().String()
+		}
+		err = r.db.Model(&existing).Updates(map[string]interface{}{
+			"lobby_status": models.LobbyStatusPending,
+			"lobby_ticket": ticket,
+			"display_name": displayName,
+			"is_approved":  false,
+			"joined_at":    time.Now(),
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+		existing.LobbyStatus = models.LobbyStatusPending
+		existing.LobbyTicket = ticket
+		existing.DisplayName = displayName
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	participant := &models.RoomParticipant{
+		ID:          uuid.New().String(),
+		RoomID:      roomID,
+		UserID:      userID,
+		DisplayName: displayName,
+		JoinedAt:    time.Now(),
+		LobbyStatus: models.LobbyStatusPending,
+		LobbyTicket: uuid.New().String(),
+	}
+	if err := r.db.Create(participant).Error; err != nil {
+		return nil, err
+	}
+	// GORM skips zero values on create, so force the waiting participant inactive.
+	if err := r.db.Model(participant).Update("is_active", false).Error; err != nil {
+		return nil, err
+	}
+	participant.IsActive = false
+	return participant, nil
+}
+
+// GetLobbyEntries returns the pending lobby entries for a room, oldest first.
+func (r *RoomRepository) GetLobbyEntries(roomID string) ([]models.RoomParticipant, error) {
+	var entries []models.RoomParticipant
+	err := r.db.Where("room_id = ? AND lobby_status = ?", roomID, models.LobbyStatusPending).
+		Order("joined_at asc").
+		Find(&entries).Error
+	return entries, err
+}
+
+// GetLobbyEntryByTicket looks up a lobby entry by its ticket. Returns nil if not found.
+func (r *RoomRepository) GetLobbyEntryByTicket(roomID, ticket string) (*models.RoomParticipant, error) {
+	if ticket == "" {
+		return nil, nil
+	}
+	var entry models.RoomParticipant
+	result := r.db.First(&entry, "room_id = ? AND lobby_ticket = ?", roomID, ticket)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &entry, nil
+}
+
+// ConsumeLobbyTicket clears the ticket of an admitted lobby entry, so it hands
+// out a join token only once. It reports whether this call consumed it.
+func (r *RoomRepository) ConsumeLobbyTicket(roomID, ticket string) (bool, error) {
+	if ticket == "" {
+		return false, nil
+	}
+	result := r.db.Model(&models.RoomParticipant{}).
+		Where("room_id = ? AND lobby_ticket = ? AND lobby_status = ?", roomID, ticket, models.LobbyStatusAdmitted).
+		Update("lobby_ticket", "")
+	return result.RowsAffected > 0, result.Error
+}
+
+// AdmitLobbyEntry approves a pending lobby entry.
+func (r *RoomRepository) AdmitLobbyEntry(roomID, userID string) error {
+	return r.resolveLobbyEntry(roomID, userID, map[string]interface{}{
+		"lobby_status": models.LobbyStatusAdmitted,
+		"is_approved":  true,
+	})
+}
+
+// DenyLobbyEntry rejects a pending lobby entry.
+func (r *RoomRepository) DenyLobbyEntry(roomID, userID string) error {
+	return r.resolveLobbyEntry(roomID, userID, map[string]interface{}{
+		"lobby_status": models.LobbyStatusDenied,
+		"is_approved":  false,
+	})
+}
+
+func (r *RoomRepository) resolveLobbyEntry(roomID, userID string, updates map[string]interface{}) error {
+	result := r.db.Model(&models.RoomParticipant{}).
+		Where("room_id = ? AND user_id = ? AND lobby_status = ?", roomID, userID, models.LobbyStatusPending).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrLobbyEntryNotFound
+	}
+	return nil
+}
+
+// GetRoomModeratorIDs returns the identities of active room-scoped moderators.
+func (r *RoomRepository) GetRoomModeratorIDs(roomID string) ([]string, error) {
+	var ids []string
+	err := r.db.Model(&models.RoomParticipant{}).
+		Where("room_id = ? AND is_moderator = ? AND is_active = ?", roomID, true, true).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// RecordIssuedToken stores the audit record for an admin-minted join token.
+func (r *RoomRepository) RecordIssuedToken(t *models.IssuedRoomToken) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return r.db.Create(t).Error
+}
+
+// GetIssuedTokens lists admin-minted join tokens for a room, newest first.
+func (r *RoomRepository) GetIssuedTokens(roomID string) ([]models.IssuedRoomToken, error) {
+	var tokens []models.IssuedRoomToken
+	err := r.db.Where("room_id = ?", roomID).Order("created_at desc").Find(&tokens).Error
+	return tokens, err
+}
+
+// CreateRecording stores a new recording record.
+func (r *RoomRepository) CreateRecording(rec *models.Recording) error {
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	return r.db.Create(rec).Error
+}
+
+// GetRecording returns a recording by ID, or nil if it does not exist.
+func (r *RoomRepository) GetRecording(id string) (*models.Recording, error) {
+	var rec models.Recording
+	err := r.db.Where("id = ?", id).First(&rec).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// GetRecordingByEgressID returns the recording driven by a LiveKit egress, or
+// nil if none matches.
+func (r *RoomRepository) GetRecordingByEgressID(egressID string) (*models.Recording, error) {
+	var rec models.Recording
+	err := r.db.Where("egress_id = ?", egressID).First(&rec).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// GetRecordings lists a room's recordings, newest first.
+func (r *RoomRepository) GetRecordings(roomID string) ([]models.Recording, error) {
+	var recs []models.Recording
+	err := r.db.Where("room_id = ?", roomID).Order("created_at desc").Find(&recs).Error
+	return recs, err
+}
+
+// UpdateRecording applies the given column updates to a recording.
+func (r *RoomRepository) UpdateRecording(id string, updates map[string]interface{}) error {
+	return r.db.Model(&models.Recording{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// CreateRoomIngress stores an ingress endpoint bound to a room.
+func (r *RoomRepository) CreateRoomIngress(ing *models.RoomIngress) error {
+	if ing.ID == "" {
+		ing.ID = uuid.New().String()
+	}
+	return r.db.Create(ing).Error
+}
+
+// GetRoomIngress returns an ingress by ID, or nil if it does not exist.
+func (r *RoomRepository) GetRoomIngress(id string) (*models.RoomIngress, error) {
+	var ing models.RoomIngress
+	err := r.db.Where("id = ?", id).First(&ing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ing, nil
+}
+
+// GetRoomIngresses lists a room's ingress endpoints, oldest first.
+func (r *RoomRepository) GetRoomIngresses(roomID string) ([]models.RoomIngress, error) {
+	var ings []models.RoomIngress
+	err := r.db.Where("room_id = ?", roomID).Order("created_at asc").Find(&ings).Error
+	return ings, err
+}
+
+// DeleteRoomIngress removes an ingress record.
+func (r *RoomRepository) DeleteRoomIngress(id string) error {
+	return r.db.Where("id = ?", id).Delete(&models.RoomIngress{}).Error
+}
+
+// GetParticipant returns the participant record for a user in a room, or nil
+// if the user never joined it.
+func (r *RoomRepository) GetParticipant(roomID, userID string) (*models.RoomParticipant, error) {
+	var p models.RoomParticipant
+	err := r.db.Where("room_id = ? AND user_id = ?", roomID, userID).First(&p).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SetChatBlocked sets or clears the is_chat_blocked flag for a participant.
+func (r *RoomRepository) SetChatBlocked(roomID, userID string, blocked bool) error {
+	return r.setParticipantFlags(roomID, userID, map[string]interface{}{"is_chat_blocked": blocked})
+}
+
+// CreateChatMessage stores a chat message.
+func (r *RoomRepository) CreateChatMessage(msg *models.ChatMessage) error {
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	}
+	return r.db.Create(msg).Error
+}
+
+// GetChatMessages returns up to limit messages of a room older than the
+// message with ID before (or the newest ones when before is empty), newest first.
+func (r *RoomRepository) GetChatMessages(roomID, before string, limit int) ([]models.ChatMessage, error) {
+	q := r.db.Where("room_id = ?", roomID)
+	if before != "" {
+		var cursor models.ChatMessage
+		if err := r.db.Where("id = ? AND room_id = ?", before, roomID).First(&cursor).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return []models.ChatMessage{}, nil
+			}
+			return nil, err
+		}
+		// Break created_at ties on id so pages never skip or repeat messages.
+		q = q.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+	var msgs []models.ChatMessage
+	err := q.Order("created_at desc").Order("id desc").Limit(limit).Find(&msgs).Error
+	return msgs, err
+}
+
+// PurgeExpiredChatMessages deletes chat messages older than each room's
+// ChatRetentionDays and returns how many were removed.
+func (r *RoomRepository) PurgeExpiredChatMessages(now time.Time) (int64, error) {
+	var rooms []models.Room
+	if err := r.db.Where("settings_chat_retention_days > ?", 0).Find(&rooms).Error; err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, room := range rooms {
+		cutoff := now.AddDate(0, 0, -room.Settings.ChatRetentionDays)
+		res := r.db.Where("room_id = ? AND created_at < ?", room.ID, cutoff).Delete(&models.ChatMessage{})
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+	}
+	return total, nil
+}
+
+// CreateBreakoutRooms creates one breakout room per name under parent. The
+// breakouts share the parent's owner, visibility, mode and settings.
+func (r *RoomRepository) CreateBreakoutRooms(parent *models.Room, names []string) ([]models.Room, error) {
+	adminID := parent.AdminID
+	if adminID == "" {
+		adminID = parent.CreatedBy
+	}
+	rooms := make([]models.Room, 0, len(names))
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range names {
+			room := models.Room{
+				ID:              uuid.New().String(),
+				Name:            name,
+				CreatedBy:       parent.CreatedBy,
+				AdminID:         adminID,
+				IsActive:        true,
+				IsPublic:        parent.IsPublic,
+				MaxParticipants: parent.MaxParticipants,
+				Settings:        parent.Settings,
+				Mode:            parent.Mode,
+				ExpiresAt:       parent.ExpiresAt,
+				Permanent:       parent.Permanent,
+				ParentRoomID:    parent.ID,
+				LiveKitNode:     parent.LiveKitNode,
+				PasscodeHash:    parent.PasscodeHash,
+				HasPasscode:     parent.HasPasscode,
+			}
+			if err := tx.Create(&room).Error; err != nil {
+				return err
+			}
+			// Create lets column defaults win over false flags; write the inherited settings as-is.
+			room.Settings = parent.Settings
+			if err := tx.Save(&room).Error; err != nil {
+				return err
+			}
+			rooms = append(rooms, room)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+// SetBreakoutPasscode gives the breakout rooms of a room the parent's
+// passcode, so a changed passcode can't be bypassed by joining a breakout.
+func (r *RoomRepository) SetBreakoutPasscode(parentID, hash string, has bool) error {
+	return r.db.Model(&models.Room{}).Where("parent_room_id = ?", parentID).
+		Updates(map[string]interface{}{"passcode_hash": hash, "has_passcode": has}).Error
+}
+
+// GetBreakoutRooms returns the breakout rooms of a room in creation order.
+func (r *RoomRepository) GetBreakoutRooms(parentID string) ([]models.Room, error) {
+	var rooms []models.Room
+	err := r.db.Where("parent_room_id = ?", parentID).Order("created_at asc, name asc").Find(&rooms).Error
+	return rooms, err
+}
+
+// ErrInviteUnavailable is returned when an invite is revoked, expired or used up.
+var ErrInviteUnavailable = errors.New("invite is no longer valid")
+
+// CreateRoomInvite stores a new room invite.
+func (r *RoomRepository) CreateRoomInvite(inv *models.RoomInvite) error {
+	if inv.ID == "" {
+		inv.ID = uuid.New().String()
+	}
+	return r.db.Create(inv).Error
+}
+
+// GetRoomInvite returns an invite by ID, or nil if it doesn't exist.
+func (r *RoomRepository) GetRoomInvite(id string) (*models.RoomInvite, error) {
+	var inv models.RoomInvite
+	if err := r.db.Where("id = ?", id).First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// GetRoomInviteByToken returns an invite by its token, or nil if none matches.
+func (r *RoomRepository) GetRoomInviteByToken(token string) (*models.RoomInvite, error) {
+	var inv models.RoomInvite
+	if err := r.db.Where("token = ?", token).First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// GetRoomInvites returns all invites of a room, newest first.
+func (r *RoomRepository) GetRoomInvites(roomID string) ([]models.RoomInvite, error) {
+	var invites []models.RoomInvite
+	err := r.db.Where("room_id = ?", roomID).Order("created_at desc").Find(&invites).Error
+	return invites, err
+}
+
+// RevokeRoomInvite marks an invite as revoked so it can no longer be redeemed.
+func (r *RoomRepository) RevokeRoomInvite(id string) error {
+	return r.db.Model(&models.RoomInvite{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RedeemRoomInvite counts one use of an invite and records the redemption.
+// The use count is checked and incremented atomically so concurrent joins
+// cannot exceed MaxUses. Returns ErrInviteUnavailable when the invite can't be used.
+func (r *RoomRepository) RedeemRoomInvite(inviteID string, red *models.RoomInviteRedemption) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.RoomInvite{}).
+			Where("id = ? AND revoked_at IS NULL AND expires_at > ? AND (max_uses = 0 OR uses < max_uses)", inviteID, time.Now()).
+			Update("uses", gorm.Expr("uses + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInviteUnavailable
+		}
+		if red.ID == "" {
+			red.ID = uuid.New().String()
+		}
+		red.InviteID = inviteID
+		return tx.Create(red).Error
+	})
+}
+
+// GetRoomInviteRedemptions returns the redemptions of an invite, newest first.
+func (r *RoomRepository) GetRoomInviteRedemptions(inviteID string) ([]models.RoomInviteRedemption, error) {
+	var reds []models.RoomInviteRedemption
+	err := r.db.Where("invite_id = ?", inviteID).Order("redeemed_at desc").Find(&reds).Error
+	return reds, err
+}
+
+// ErrACLEntryExists is returned when adding an ACL entry the room already has.
+var ErrACLEntryExists = errors.New("access list entry already exists")
+
+// aclMatches selects the ACL entries that admit a user. Email and domain
+// entries only match a verified address: anyone can sign up with an address
+// they don't own.
+func (r *RoomRepository) aclMatches(userID string, groups []string) (*gorm.DB, error) {
+	var user models.User
+	if err := r.db.Select("email", "email_verified").Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return nil, err
+	}
+	email, domain := "", ""
+	if user.EmailVerified {
+		email = strings.ToLower(user.Email)
+		if at := strings.LastIndex(email, "@"); at >= 0 {
+			domain = email[at+1:]
+		}
+	}
+	if len(groups) == 0 {
+		groups = []string{""}
+	}
+	return r.db.Model(&models.RoomACLEntry{}).Where(
+		"(type = ? AND value IN ?) OR (type = ? AND value = ?) OR (type = ? AND value IN ?)",
+		models.RoomACLTypeUser, []string{userID, email},
+		models.RoomACLTypeDomain, domain,
+		models.RoomACLTypeGroup, groups,
+	), nil
+}
+
+// IsAllowedByACL reports whether the room's ACL admits the user.
+func (r *RoomRepository) IsAllowedByACL(roomID, userID string, groups []string) (bool, error) {
+	matches, err := r.aclMatches(userID, groups)
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	err = matches.Where("room_id = ?", roomID).Count(&count).Error
+	return count > 0, err
+}
+
+// AddRoomACLEntry adds an entry to a room's ACL.
+func (r *RoomRepository) AddRoomACLEntry(entry *models.RoomACLEntry) error {
+	var count int64
+	if err := r.db.Model(&models.RoomACLEntry{}).
+		Where("room_id = ? AND type = ? AND value = ?", entry.RoomID, entry.Type, entry.Value).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrACLEntryExists
+	}
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	return r.db.Create(entry).Error
+}
+
+// GetRoomACLEntries returns a room's ACL, oldest entry first.
+func (r *RoomRepository) GetRoomACLEntries(roomID string) ([]models.RoomACLEntry, error) {
+	var entries []models.RoomACLEntry
+	err := r.db.Where("room_id = ?", roomID).Order("created_at asc").Find(&entries).Error
+	return entries, err
+}
+
+// DeleteRoomACLEntry removes an entry from a room's ACL. It reports whether
+// the entry existed.
+func (r *RoomRepository) DeleteRoomACLEntry(roomID, entryID string) (bool, error) {
+	res := r.db.Where("id = ? AND room_id = ?", entryID, roomID).Delete(&models.RoomACLEntry{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// CreateRoomAccessRequest stores a new pending access request.
+func (r *RoomRepository) CreateRoomAccessRequest(req *models.RoomAccessRequest) error {
+	if req.ID == "" {
+		req.ID = uuid.New().String()
+	}
+	req.Status = models.AccessRequestPending
+	return r.db.Create(req).Error
+}
+
+// GetRoomAccessRequest returns an access request by ID, or nil if it doesn't exist.
+func (r *RoomRepository) GetRoomAccessRequest(id string) (*models.RoomAccessRequest, error) {
+	var req models.RoomAccessRequest
+	if err := r.db.Where("id = ?", id).First(&req).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// HasPendingRoomAccessRequest reports whether the user already has a request
+// waiting for the room admin.
+func (r *RoomRepository) HasPendingRoomAccessRequest(roomID, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RoomAccessRequest{}).
+		Where("room_id = ? AND user_id = ? AND status = ?", roomID, userID, models.AccessRequestPending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetPendingRoomAccessRequests returns a room's pending requests, oldest first.
+func (r *RoomRepository) GetPendingRoomAccessRequests(roomID string) ([]models.RoomAccessRequest, error) {
+	var reqs []models.RoomAccessRequest
+	err := r.db.Where("room_id = ? AND status = ?", roomID, models.AccessRequestPending).
+		Order("created_at asc").Find(&reqs).Error
+	return reqs, err
+}
+
+// ResolveRoomAccessRequest approves or denies a pending request.
+func (r *RoomRepository) ResolveRoomAccessRequest(id, status, resolvedBy string) error {
+	return r.db.Model(&models.RoomAccessRequest{}).
+		Where("id = ? AND status = ?", id, models.AccessRequestPending).
+		Updates(map[string]interface{}{"status": status, "resolved_by": resolvedBy, "resolved_at": time.Now()}).Error
+}
+
+// RecordGuestKey stores the stable guest key for a guest identity before it
+// connects, creating an inactive participant record if needed. Bans on the
+// identity are then carried over to the key.
+func (r *RoomRepository) RecordGuestKey(roomID, identity, displayName, guestKey string) error {
+	res := r.db.Model(&models.RoomParticipant{}).
+		Where("room_id = ? AND user_id = ?", roomID, identity).
+		Update("guest_key", guestKey)
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+	participant := &models.RoomParticipant{
+		ID:          uuid.New().String(),
+		RoomID:      roomID,
+		UserID:      identity,
+		DisplayName: displayName,
+		GuestKey:    guestKey,
+		JoinedAt:    time.Now(),
+	}
+	if err := r.db.Create(participant).Error; err != nil {
+		return err
+	}
+	// GORM skips zero values on create, so force the guest inactive until the
+	// LiveKit webhook reports them joined.
+	return r.db.Model(participant).Update("is_active", false).Error
+}
+
+// bannedParticipants scopes a participant query to the records covered by a ban.
+func bannedParticipants(tx *gorm.DB, ban *models.RoomBan) *gorm.DB {
+	q := tx.Model(&models.RoomParticipant{}).Where("room_id = ?", ban.RoomID)
+	if ban.GuestKey != "" {
+		return q.Where("user_id = ? OR guest_key = ?", ban.Identity, ban.GuestKey)
+	}
+	return q.Where("user_id = ?", ban.Identity)
+}
+
+// BanParticipant stores a ban, replacing any earlier ban on the same identity,
+// and marks the matching participant records banned and inactive.
+func (r *RoomRepository) BanParticipant(ban *models.RoomBan) error {
+	if ban.ID == "" {
+		ban.ID = uuid.New().String()
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("room_id = ? AND identity = ?", ban.RoomID, ban.Identity).Delete(&models.RoomBan{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(ban).Error; err != nil {
+			return err
+		}
+		return bannedParticipants(tx, ban).Updates(map[string]interface{}{
+			"is_active": false,
+			"is_banned": true,
+			"left_at":   time.Now(),
+		}).Error
+	})
+}
+
+// GetRoomBan returns a ban by ID, or nil if it doesn't exist.
+func (r *RoomRepository) GetRoomBan(id string) (*models.RoomBan, error) {
+	var ban models.RoomBan
+	if err := r.db.Where("id = ?", id).First(&ban).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ban, nil
+}
+
+// GetActiveRoomBans returns the bans in force for a room, newest first.
+func (r *RoomRepository) GetActiveRoomBans(roomID string) ([]models.RoomBan, error) {
+	var bans []models.RoomBan
+	err := r.db.Where("room_id = ? AND (expires_at IS NULL OR expires_at > ?)", roomID, time.Now()).
+		Order("created_at desc").Find(&bans).Error
+	return bans, err
+}
+
+// IsGuestBanned reports whether a ban in force matches the guest key.
+func (r *RoomRepository) IsGuestBanned(roomID, guestKey string) (bool, error) {
+	if guestKey == "" {
+		return false, nil
+	}
+	var count int64
+	err := r.db.Model(&models.RoomBan{}).
+		Where("room_id = ? AND guest_key = ? AND (expires_at IS NULL OR expires_at > ?)", roomID, guestKey, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// LiftRoomBan deletes a ban and clears the banned flag on the participant
+// records it covered.
+func (r *RoomRepository) LiftRoomBan(ban *models.RoomBan) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(ban).Error; err != nil {
+			return err
+		}
+		return bannedParticipants(tx, ban).Update("is_banned", false).Error
+	})
+}
+
+// LiftExpiredRoomBans lifts every ban whose expiry is before now and returns
+// how many were lifted.
+func (r *RoomRepository) LiftExpiredRoomBans(now time.Time) (int, error) {
+	var bans []models.RoomBan
+	if err := r.db.Where("expires_at IS NOT NULL AND expires_at <= ?", now).Find(&bans).Error; err != nil {
+		return 0, err
+	}
+	for i := range bans {
+		if err := r.LiftRoomBan(&bans[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(bans), nil
+}
+
+// CreateScheduledMeeting stores a new scheduled meeting.
+func (r *RoomRepository) CreateScheduledMeeting(m *models.ScheduledMeeting) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	return r.db.Create(m).Error
+}
+
+// GetScheduledMeeting returns a scheduled meeting by ID, or nil if it doesn't exist.
+func (r *RoomRepository) GetScheduledMeeting(id string) (*models.ScheduledMeeting, error) {
+	var m models.ScheduledMeeting
+	if err := r.db.Where("id = ?", id).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetScheduledMeetings returns the meetings scheduled in the given rooms,
+// earliest first.
+func (r *RoomRepository) GetScheduledMeetings(roomIDs ...string) ([]models.ScheduledMeeting, error) {
+	var meetings []models.ScheduledMeeting
+	if len(roomIDs) == 0 {
+		return meetings, nil
+	}
+	err := r.db.Where("room_id IN ?", roomIDs).Order("start_at asc").Find(&meetings).Error
+	return meetings, err
+}
+
+// UpdateScheduledMeeting saves changes to a scheduled meeting.
+func (r *RoomRepository) UpdateScheduledMeeting(m *models.ScheduledMeeting) error {
+	return r.db.Save(m).Error
+}
+
+// DeleteScheduledMeeting removes a meeting from a room's schedule. It reports
+// whether the meeting existed.
+func (r *RoomRepository) DeleteScheduledMeeting(roomID, id string) (bool, error) {
+	res := r.db.Where("id = ? AND room_id = ?", id, roomID).Delete(&models.ScheduledMeeting{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ExtendRoomExpiry pushes a room's expiry out to until; an expiry already
+// later than that is left alone. An archived room is brought back and counts
+// as active now, so the idle policy does not archive it again right away.
+func (r *RoomRepository) ExtendRoomExpiry(roomID string, until time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Room{}).
+			Where("id = ? AND expires_at < ?", roomID, until).
+			Update("expires_at", until).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Room{}).
+			Where("id = ? AND archived_at IS NOT NULL", roomID).
+			Updates(map[string]interface{}{"archived_at": nil, "is_active": true, "last_activity_at": time.Now()}).Error
+	})
+}
+
+// GetCalendarFeed returns a user's calendar feed, or nil if they have none yet.
+func (r *RoomRepository) GetCalendarFeed(userID string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	if err := r.db.Where("user_id = ?", userID).First(&feed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// GetCalendarFeedByToken returns the calendar feed with the token, or nil if
+// none matches.
+func (r *RoomRepository) GetCalendarFeedByToken(token string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	if err := r.db.Where("token_hash = ?", hashToken(token)).First(&feed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// SaveCalendarFeed creates or replaces a user's calendar feed token, storing
+// only its hash.
+func (r *RoomRepository) SaveCalendarFeed(userID, token string) (*models.CalendarFeed, error) {
+	feed := &models.CalendarFeed{UserID: userID, TokenHash: hashToken(token), CreatedAt: time.Now()}
+	if err := r.db.Save(feed).Error; err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// openRoomSession returns the room's running session, or nil if there is none.
+func openRoomSession(tx *gorm.DB, roomID string) (*models.RoomSession, error) {
+	var s models.RoomSession
+	err := tx.Where("room_id = ? AND ended_at IS NULL", roomID).Order("started_at DESC").First(&s).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func startRoomSession(tx *gorm.DB, roomID string, at time.Time) (*models.RoomSession, error) {
+	s, err := openRoomSession(tx, roomID)
+	if err != nil || s != nil {
+		return s, err
+	}
+	s = &models.RoomSession{ID: uuid.New().String(), RoomID: roomID, StartedAt: at}
+	return s, tx.Create(s).Error
+}
+
+// StartRoomSession opens a session for the room, or returns the one already
+// running so repeated room_started events don't split a meeting.
+func (r *RoomRepository) StartRoomSession(roomID string, at time.Time) (*models.RoomSession, error) {
+	var s *models.RoomSession
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		s, err = startRoomSession(tx, roomID, at)
+		return err
+	})
+	return s, err
+}
+
+// EndRoomSession closes the room's running session and every attendance
+// segment still open in it. It is a no-op when no session is running.
+func (r *RoomRepository) EndRoomSession(roomID string, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		s, err := openRoomSession(tx, roomID)
+		if err != nil || s == nil {
+			return err
+		}
+		if err := tx.Model(&models.AttendanceSegment{}).
+			Where("session_id = ? AND left_at IS NULL", s.ID).
+			Update("left_at", at).Error; err != nil {
+			return err
+		}
+		return tx.Model(s).Update("ended_at", at).Error
+	})
+}
+
+// RecordAttendanceJoin opens an attendance segment for the identity, starting
+// a session first if the join arrived before room_started.
+func (r *RoomRepository) RecordAttendanceJoin(roomID, identity, displayName string, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		s, err := startRoomSession(tx, roomID, at)
+		if err != nil {
+			return err
+		}
+		// A join without a matching leave closes the earlier segment.
+		if err := tx.Model(&models.AttendanceSegment{}).
+			Where("session_id = ? AND identity = ? AND left_at IS NULL", s.ID, identity).
+			Update("left_at", at).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AttendanceSegment{
+			ID:          uuid.New().String(),
+			SessionID:   s.ID,
+			RoomID:      roomID,
+			Identity:    identity,
+			DisplayName: displayName,
+			JoinedAt:    at,
+		}).Error
+	})
+}
+
+// RecordAttendanceLeave closes the identity's open attendance segment in the
+// room, if any.
+func (r *RoomRepository) RecordAttendanceLeave(roomID, identity string, at time.Time) error {
+	return r.db.Model(&models.AttendanceSegment{}).
+		Where("room_id = ? AND identity = ? AND left_at IS NULL", roomID, identity).
+		Update("left_at", at).Error
+}
+
+// GetRoomSession returns a session by ID, or nil if it doesn't exist.
+func (r *RoomRepository) GetRoomSession(id string) (*models.RoomSession, error) {
+	var s models.RoomSession
+	if err := r.db.Where("id = ?", id).First(&s).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetRoomSessions returns a room's sessions, newest first.
+func (r *RoomRepository) GetRoomSessions(roomID string) ([]models.RoomSession, error) {
+	var sessions []models.RoomSession
+	err := r.db.Where("room_id = ?", roomID).Order("started_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// CountSessionAttendees returns the number of distinct participants seen in
+// each of the given sessions.
+func (r *RoomRepository) CountSessionAttendees(sessionIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(sessionIDs))
+	if len(sessionIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		SessionID string
+		Attendees int
+	}
+	err := r.db.Model(&models.AttendanceSegment{}).
+		Select("session_id, COUNT(DISTINCT identity) AS attendees").
+		Where("session_id IN ?", sessionIDs).
+		Group("session_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.SessionID] = row.Attendees
+	}
+	return counts, err
+}
+
+// GetAttendanceSegments returns a session's attendance segments in join order.
+func (r *RoomRepository) GetAttendanceSegments(sessionID string) ([]models.AttendanceSegment, error) {
+	var segments []models.AttendanceSegment
+	err := r.db.Where("session_id = ?", sessionID).Order("joined_at ASC, identity ASC").Find(&segments).Error
+	return segments, err
+}
+
+// GetE2EEKey returns the room's current encryption key, generating the first
+// version when the room has none.
+func (r *RoomRepository) GetE2EEKey(roomID string) (*models.RoomE2EEKey, error) {
+	key, err := r.latestE2EEKey(roomID)
+	if err != nil || key != nil {
+		return key, err
+	}
+	key, err = newE2EEKey(roomID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.Create(key).Error; err != nil {
+		// Another join may have created it first.
+		if existing, lookupErr := r.latestE2EEKey(roomID); lookupErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// RotateE2EEKey replaces the room's encryption key with a new version and
+// drops the old ones.
+func (r *RoomRepository) RotateE2EEKey(roomID string) (*models.RoomE2EEKey, error) {
+	var key *models.RoomE2EEKey
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var version int
+		if err := tx.Model(&models.RoomE2EEKey{}).Where("room_id = ?", roomID).
+			Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+			return err
+		}
+		var err error
+		if key, err = newE2EEKey(roomID, version+1); err != nil {
+			return err
+		}
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		return tx.Where("room_id = ? AND version < ?", roomID, key.Version).Delete(&models.RoomE2EEKey{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeleteE2EEKeys drops the room's encryption keys, so the next meeting
+// starts with a new one.
+func (r *RoomRepository) DeleteE2EEKeys(roomID string) error {
+	return r.db.Where("room_id = ?", roomID).Delete(&models.RoomE2EEKey{}).Error
+}
+
+func (r *RoomRepository) latestE2EEKey(roomID string) (*models.RoomE2EEKey, error) {
+	var key models.RoomE2EEKey
+	if err := r.db.Where("room_id = ?", roomID).Order("version desc").First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func newE2EEKey(roomID string, version int) (*models.RoomE2EEKey, error) {
+	b := make([]byte, models.E2EEKeySize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &models.RoomE2EEKey{ID: uuid.New().String(), RoomID: roomID, Version: version, Key: b}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 )
 
 const testUserIDRoom = "user-1"
@@ -636,10 +637,13 @@ func TestRoomRepository_CleanupExpiredRooms(t *testing.T) {
 	// Create a room that hasn't expired yet
 	_, _ = repo.CreateRoom(testUserIDRoom, "active-room", false, "standard", &models.RoomSettings{})
 
-	err := repo.CleanupExpiredRooms()
+	archived, err := repo.CleanupExpiredRooms()
 	if err != nil {
 		t.Fatalf("failed to cleanup: %v", err)
 	}
+	if len(archived) != 1 || archived[0].ID != room.ID {
+		t.Fatalf("expected only the expired room to be archived, got %+v", archived)
+	}
 
 	// Expired room should be inactive
 	expired, _ := repo.GetRoom(room.ID)
@@ -655,10 +659,37 @@ func TestRoomRepository_CleanupExpiredRooms_NoExpired(t *testing.T) {
 	db.Create(&models.User{ID: testUserIDRoom, Email: "u1@ex.com", Name: "U1", Provider: "local", IsActive: true})
 	_, _ = repo.CreateRoom(testUserIDRoom, "future-room", false, "standard", &models.RoomSettings{})
 
-	err := repo.CleanupExpiredRooms()
+	archived, err := repo.CleanupExpiredRooms()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if len(archived) != 0 {
+		t.Fatalf("expected no rooms to be archived, got %+v", archived)
+	}
+}
+
+func TestRoomRepository_ExtendRoomExpiry_RevivesArchivedRoom(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewRoomRepository(db)
+
+	db.Create(&models.User{ID: testUserIDRoom, Email: "u1@ex.com", Name: "U1", Provider: "local", IsActive: true})
+	room, _ := repo.CreateRoom(testUserIDRoom, "archived-room", false, "standard", &models.RoomSettings{})
+	db.Model(&models.Room{}).Where("id = ?", room.ID).Update("expires_at", time.Now().Add(-time.Hour))
+	if _, err := repo.CleanupExpiredRooms(); err != nil {
+		t.Fatalf("failed to cleanup: %v", err)
+	}
+
+	until := time.Now().Add(7 * 24 * time.Hour)
+	if err := repo.ExtendRoomExpiry(room.ID, until); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, _ := repo.GetRoom(room.ID)
+	if got.ArchivedAt != nil || !got.IsActive || got.ExpiresAt.Before(until.Add(-time.Second)) {
+		t.Fatalf("expected the room to be revived until %v, got archived %v active %v expires %v", until, got.ArchivedAt, got.IsActive, got.ExpiresAt)
+	}
+	if got.LastActivityAt == nil || time.Since(*got.LastActivityAt) > time.Minute {
+		t.Fatal("expected a revived room to count as active now")
+	}
 }
 
 func TestRoomRepository_CreateRoom_PrivateRoom(t *testing.T) {
@@ -1311,3 +1342,73 @@ func TestRoomRepository_CountActiveParticipants_Empty(t *testing.T) {
 		t.Fatalf("expected 0 participants in empty DB, got %d", count)
 	}
 }
+
+func TestRoomRepository_LobbyLifecycle(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewRoomRepository(db)
+	db.Create(&models.User{ID: testUserIDRoom, Email: "user@ex.com", Name: "Creator", Provider: "local", IsActive: true})
+	room, _ := repo.CreateRoom(testUserIDRoom, "lobby-room", true, "standard", &models.RoomSettings{RequireApproval: true})
+
+	entry, err := repo.RequestLobbyEntry(room.ID, "guest-abc", "Visitor")
+	if err != nil {
+		t.Fatalf("RequestLobbyEntry: %v", err)
+	}
+	if entry.LobbyTicket == "" || entry.IsActive {
+		t.Fatalf("expected inactive entry with ticket, got %+v", entry)
+	}
+	again, _ := repo.RequestLobbyEntry(room.ID, "guest-abc", "Visitor")
+	if again.LobbyTicket != entry.LobbyTicket {
+		t.Fatal("expected pending entry to keep its ticket")
+	}
+
+	count, _ := repo.GetParticipantCount(room.ID)
+	if count != 1 {
+		t.Fatalf("waiting participants must not count toward capacity, got %d", count)
+	}
+
+	if err := repo.AdmitLobbyEntry(room.ID, "guest-abc"); err != nil {
+		t.Fatalf("AdmitLobbyEntry: %v", err)
+	}
+	if approved, _ := repo.IsParticipantApproved(room.ID, "guest-abc"); !approved {
+		t.Fatal("expected participant to be approved")
+	}
+	if err := repo.DenyLobbyEntry(room.ID, "guest-abc"); !errors.Is(err, models.ErrLobbyEntryNotFound) {
+		t.Fatalf("expected ErrLobbyEntryNotFound, got %v", err)
+	}
+	pending, _ := repo.GetLobbyEntries(room.ID)
+	if len(pending) != 0 {
+		t.Fatalf("expected empty lobby, got %d", len(pending))
+	}
+}
+
+func TestRoomRepository_E2EEKeyRotation(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewRoomRepository(db)
+	db.Create(&models.User{ID: testUserIDRoom, Email: "user@ex.com", Name: "Creator", Provider: "local", IsActive: true})
+	room, _ := repo.CreateRoom(testUserIDRoom, "e2ee-room", true, "standard", &models.RoomSettings{E2EE: true})
+
+	first, err := repo.GetE2EEKey(room.ID)
+	if err != nil || first.Version != 1 || len(first.Key) != models.E2EEKeySize {
+		t.Fatalf("expected a fresh version 1 key, got %+v (%v)", first, err)
+	}
+	if again, _ := repo.GetE2EEKey(room.ID); again.ID != first.ID {
+		t.Fatal("expected the same key until it is rotated")
+	}
+
+	rotated, err := repo.RotateE2EEKey(room.ID)
+	if err != nil || rotated.Version != 2 || string(rotated.Key) == string(first.Key) {
+		t.Fatalf("expected a new version 2 key, got %+v (%v)", rotated, err)
+	}
+	var count int64
+	db.Model(&models.RoomE2EEKey{}).Where("room_id = ?", room.ID).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected old key versions to be dropped, got %d keys", count)
+	}
+
+	if err := repo.DeleteE2EEKeys(room.ID); err != nil {
+		t.Fatalf("DeleteE2EEKeys: %v", err)
+	}
+	if next, _ := repo.GetE2EEKey(room.ID); next.Version != 1 || string(next.Key) == string(rotated.Key) {
+		t.Fatalf("expected the next meeting to start over with a new key, got %+v", next)
+	}
+}
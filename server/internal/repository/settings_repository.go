@@ -76,6 +76,9 @@ func mergeFromConfig(s *models.SystemSettings, cfg *config.Config) {
 	if s.TwitterRedirectURL == "" && cfg.Auth.Twitter.RedirectURL != "" {
 		s.TwitterRedirectURL = cfg.Auth.Twitter.RedirectURL
 	}
+	if len(s.OIDCProviders) == 0 {
+		s.OIDCProviders = models.OIDCProvidersFromConfig(cfg.Auth.OIDC)
+	}
 	if s.JWTSecret == "" {
 		s.JWTSecret = cfg.Auth.JWTSecret
 	}
@@ -181,8 +184,45 @@ func mergeFromConfig(s *models.SystemSettings, cfg *config.Config) {
 		s.ChatUploadS3PublicURL = cfg.Chat.Uploads.S3.PublicBaseURL
 	}
 
+	// Mail
+	if s.MailDriver == "" {
+		s.MailDriver = cfg.Mail.Driver
+	}
+	if s.MailHost == "" {
+		s.MailHost = cfg.Mail.Host
+	}
+	if s.MailPort == 0 {
+		s.MailPort = cfg.Mail.Port
+	}
+	if s.MailUsername == "" {
+		s.MailUsername = cfg.Mail.Username
+	}
+	if s.MailPassword == "" {
+		s.MailPassword = cfg.Mail.Password
+	}
+	if s.MailFrom == "" {
+		s.MailFrom = cfg.Mail.From
+	}
+	if s.MailTLS == "" {
+		s.MailTLS = cfg.Mail.TLS
+	}
+
 	// Logger
 	if s.LogLevel == "" {
 		s.LogLevel = cfg.Logger.Level
 	}
+
+	// Room lifecycle
+	if s.RoomDefaultTTLHours == 0 {
+		s.RoomDefaultTTLHours = cfg.Rooms.DefaultTTLHours
+	}
+	if !s.RoomAllowPermanent && cfg.Rooms.AllowPermanent {
+		s.RoomAllowPermanent = cfg.Rooms.AllowPermanent
+	}
+	if s.RoomArchiveAfterIdleDays == 0 {
+		s.RoomArchiveAfterIdleDays = cfg.Rooms.ArchiveAfterIdleDays
+	}
+	if s.RoomDeleteAfterArchiveDays == 0 {
+		s.RoomDeleteAfterArchiveDays = cfg.Rooms.DeleteAfterArchiveDays
+	}
 }
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"bedrud/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StatsRepository struct {
+	db *gorm.DB
+}
+
+func NewStatsRepository(db *gorm.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// RecordUsageSample folds a sample taken at `at` into its hourly and daily
+// buckets. Only the first sample of a minute is recorded: other replicas
+// sampling the same minute are skipped.
+func (r *StatsRepository) RecordUsageSample(at time.Time, sample models.UsageSample) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		claim := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.UsageSampleClaim{Minute: at.UTC().Truncate(time.Minute)})
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return nil
+		}
+		for _, bucket := range []string{models.UsageBucketHour, models.UsageBucketDay} {
+			start := models.UsageBucketStart(bucket, at)
+			var stat models.UsageStat
+			err := tx.Where("bucket = ? AND bucket_start = ?", bucket, start).First(&stat).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err != nil {
+				stat = models.UsageStat{Bucket: bucket, BucketStart: start}
+			}
+			stat.Add(sample)
+			if err := tx.Save(&stat).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetUsageStats returns the buckets of the given size starting in [from, to),
+// oldest first.
+func (r *StatsRepository) GetUsageStats(bucket string, from, to time.Time) ([]models.UsageStat, error) {
+	var stats []models.UsageStat
+	err := r.db.Where("bucket = ? AND bucket_start >= ? AND bucket_start < ?", bucket, from.UTC(), to.UTC()).
+		Order("bucket_start ASC").Find(&stats).Error
+	return stats, err
+}
+
+// PurgeUsageStats deletes buckets of the given size that started before
+// cutoff and returns how many were removed.
+func (r *StatsRepository) PurgeUsageStats(bucket string, cutoff time.Time) (int64, error) {
+	res := r.db.Where("bucket = ? AND bucket_start < ?", bucket, cutoff.UTC()).Delete(&models.UsageStat{})
+	return res.RowsAffected, res.Error
+}
+
+// PurgeUsageSampleClaims forgets the minutes sampled before cutoff.
+func (r *StatsRepository) PurgeUsageSampleClaims(cutoff time.Time) (int64, error) {
+	res := r.db.Where("minute < ?", cutoff.UTC()).Delete(&models.UsageSampleClaim{})
+	return res.RowsAffected, res.Error
+}
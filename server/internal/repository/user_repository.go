@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -41,6 +42,31 @@ func (r *UserRepository) CreateOrUpdateUser(user *models.User) error {
 	return nil
 }
 
+// CreateOrUpdateUserByID creates the user, or refreshes the profile of the
+// user with the same ID. OIDC users are keyed by their subject rather than
+// their email address, which the identity provider may let them change.
+func (r *UserRepository) CreateOrUpdateUserByID(user *models.User) error {
+	user.UpdatedAt = time.Now()
+
+	result := r.db.Where("id = ?", user.ID).
+		Assign(map[string]interface{}{
+			"email":          user.Email,
+			"name":           user.Name,
+			"avatar_url":     user.AvatarURL,
+			"accesses":       user.Accesses,
+			"email_verified": user.EmailVerified,
+			"updated_at":     user.UpdatedAt,
+		}).
+		FirstOrCreate(user)
+
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("Failed to create or update user")
+		return result.Error
+	}
+
+	return nil
+}
+
 func (r *UserRepository) GetUserByEmailAndProvider(email, provider string) (*models.User, error) {
 	var user models.User
 	result := r.db.Where("email = ? AND provider = ?", email, provider).First(&user)
@@ -82,29 +108,6 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 	return nil
 }
 
-func (r *UserRepository) UpdateRefreshToken(userID, refreshToken string) error {
-	result := r.db.Model(&models.User{}).
-		Where("id = ?", userID).
-		Update("refresh_token", hashToken(refreshToken))
-
-	if result.Error != nil {
-		log.Error().Err(result.Error).Msg("Failed to update refresh token")
-		return result.Error
-	}
-	return nil
-}
-
-// MatchRefreshToken checks whether the provided raw token matches the stored hash for the user.
-func (r *UserRepository) MatchRefreshToken(userID, rawToken string) bool {
-	var stored string
-	r.db.Model(&models.User{}).
-		Where("id = ?", userID).
-		Select("refresh_token").
-		Row().
-		Scan(&stored)
-	return stored == hashToken(rawToken)
-}
-
 func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
 	var user models.User
 	result := r.db.Where("id = ?", id).First(&user)
@@ -147,6 +150,256 @@ func (r *UserRepository) CleanupBlockedTokens() error {
 	return result.Error
 }
 
+// RevokeAccessToken stores the hash of an access token so it is rejected by
+// every instance until expiresAt. Revoking a token twice is not an error.
+func (r *UserRepository) RevokeAccessToken(hash, userID string, expiresAt time.Time) error {
+	revoked := &models.RevokedAccessToken{TokenHash: hash, UserID: userID, ExpiresAt: expiresAt}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(revoked).Error
+}
+
+// GetAccessTokenRevocation returns when a revoked access token expires, or
+// nil if the token has not been revoked or the revocation has lapsed.
+func (r *UserRepository) GetAccessTokenRevocation(hash string, now time.Time) (*time.Time, error) {
+	var revoked models.RevokedAccessToken
+	err := r.db.Where("token_hash = ? AND expires_at > ?", hash, now).Limit(1).Find(&revoked).Error
+	if err != nil || revoked.TokenHash == "" {
+		return nil, err
+	}
+	return &revoked.ExpiresAt, nil
+}
+
+// PurgeRevokedAccessTokens deletes revocations of tokens that have expired
+// anyway and returns how many were removed.
+func (r *UserRepository) PurgeRevokedAccessTokens(now time.Time) (int64, error) {
+	res := r.db.Where("expires_at <= ?", now).Delete(&models.RevokedAccessToken{})
+	return res.RowsAffected, res.Error
+}
+
+// CreateSession stores a new sign-in session.
+func (r *UserRepository) CreateSession(session *models.Session) error {
+	if session.ID == "" {
+		session.ID = uuid.New().String()
+	}
+	return r.db.Create(session).Error
+}
+
+// GetSession returns a session by ID, or nil if it does not exist.
+func (r *UserRepository) GetSession(id string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Where("id = ?", id).Limit(1).Find(&session).Error
+	if err != nil || session.ID == "" {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateSession replaces the session's refresh token hash with newHash if it
+// is still oldHash, and records the device's latest address. It reports
+// false if another refresh rotated the token first.
+func (r *UserRepository) RotateSession(id, oldHash, newHash, ip, userAgent string, expiresAt, now time.Time) (bool, error) {
+	res := r.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", id, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": newHash,
+			"ip":                 ip,
+			"user_agent":         userAgent,
+			"expires_at":         expiresAt,
+			"last_used_at":       now,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// GetActiveSessions returns the user's unexpired sessions, most recently used
+// first.
+func (r *UserRepository) GetActiveSessions(userID string, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ? AND expires_at > ?", userID, now).
+		Order("last_used_at desc").Find(&sessions).Error
+	return sessions, err
+}
+
+// DeleteSession removes one of the user's sessions. It reports whether the
+// session existed.
+func (r *UserRepository) DeleteSession(userID, id string) (bool, error) {
+	res := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Session{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteUserSessions removes every session of the user except exceptID, which
+// may be empty, and returns the IDs of the removed sessions.
+func (r *UserRepository) DeleteUserSessions(userID, exceptID string) ([]string, error) {
+	var ids []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&models.Session{}).Where("user_id = ? AND id <> ?", userID, exceptID)
+		if err := q.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.Session{}).Error
+	})
+	return ids, err
+}
+
+// PurgeExpiredSessions deletes sessions whose refresh token has expired and
+// returns how many were removed.
+func (r *UserRepository) PurgeExpiredSessions(now time.Time) (int64, error) {
+	res := r.db.Where("expires_at <= ?", now).Delete(&models.Session{})
+	return res.RowsAffected, res.Error
+}
+
+// SetPendingMFASecret stores a TOTP secret the user has not confirmed yet.
+// It does nothing once MFA is enabled.
+func (r *UserRepository) SetPendingMFASecret(userID, secret string) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ? AND mfa_enabled = ?", userID, false).
+		Updates(map[string]interface{}{"mfa_secret": secret, "mfa_last_step": 0}).Error
+}
+
+// EnableMFA turns MFA on with the pending secret and replaces the user's
+// recovery codes with codeHashes.
+func (r *UserRepository) EnableMFA(userID string, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("mfa_enabled", true).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// DisableMFA turns MFA off and removes the secret and recovery codes.
+func (r *UserRepository) DisableMFA(userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"mfa_enabled": false, "mfa_secret": "", "mfa_last_step": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&models.MFARecoveryCode{}, "user_id = ?", userID).Error
+	})
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores
+// codeHashes instead.
+func (r *UserRepository) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID string, codeHashes []string) error {
+	if err := tx.Delete(&models.MFARecoveryCode{}, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		code := &models.MFARecoveryCode{ID: uuid.New().String(), UserID: userID, CodeHash: h}
+		if err := tx.Create(code).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseMFAStep records step as the user's last accepted TOTP time step. It
+// reports false if that step or a later one was already used, so each code
+// works once.
+func (r *UserRepository) UseMFAStep(userID string, step int64) (bool, error) {
+	res := r.db.Model(&models.User{}).
+		Where("id = ? AND mfa_last_step < ?", userID, step).
+		Update("mfa_last_step", step)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RecordMFAFailure counts a wrong sign-in code. When the user reaches max
+// failures the count starts over and code entry is locked until lockUntil.
+func (r *UserRepository) RecordMFAFailure(userID string, max int, lockUntil time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("mfa_failures", gorm.Expr("mfa_failures + 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ? AND mfa_failures >= ?", userID, max).
+			Updates(map[string]interface{}{"mfa_failures": 0, "mfa_locked_until": lockUntil}).Error
+	})
+}
+
+// ResetMFAFailures forgets the user's wrong sign-in codes.
+func (r *UserRepository) ResetMFAFailures(userID string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"mfa_failures": 0, "mfa_locked_until": nil}).Error
+}
+
+// UseRecoveryCode marks the user's unused recovery code with codeHash as
+// used. It reports whether there was such a code.
+func (r *UserRepository) UseRecoveryCode(userID, codeHash string, now time.Time) (bool, error) {
+	res := r.db.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes the user has left.
+func (r *UserRepository) CountUnusedRecoveryCodes(userID string) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+// CreateEmailToken stores token, replacing any earlier token the user has
+// for the same purpose so only the latest link works.
+func (r *UserRepository) CreateEmailToken(token *models.EmailToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.EmailToken{}, "user_id = ? AND purpose = ?", token.UserID, token.Purpose).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// ConsumeEmailToken deletes and returns the unexpired token with hash and
+// purpose, or nil if there is none. Each token can be consumed once.
+func (r *UserRepository) ConsumeEmailToken(hash, purpose string, now time.Time) (*models.EmailToken, error) {
+	var token models.EmailToken
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("token_hash = ? AND purpose = ? AND expires_at > ?", hash, purpose, now).Limit(1).Find(&token)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		del := tx.Delete(&models.EmailToken{}, "token_hash = ?", hash)
+		if del.Error != nil {
+			return del.Error
+		}
+		if del.RowsAffected == 0 {
+			// Consumed concurrently.
+			token = models.EmailToken{}
+		}
+		return nil
+	})
+	if err != nil || token.TokenHash == "" {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// PurgeExpiredEmailTokens deletes email tokens that have expired.
+func (r *UserRepository) PurgeExpiredEmailTokens(now time.Time) (int64, error) {
+	res := r.db.Where("expires_at <= ?", now).Delete(&models.EmailToken{})
+	return res.RowsAffected, res.Error
+}
+
+// MarkEmailVerified marks the user's address verified, provided it is still
+// email. It reports whether the user was updated.
+func (r *UserRepository) MarkEmailVerified(userID, email string) (bool, error) {
+	res := r.db.Model(&models.User{}).
+		Where("id = ? AND email = ?", userID, email).
+		Update("email_verified", true)
+	return res.RowsAffected > 0, res.Error
+}
+
 func (r *UserRepository) UpdateUserAccesses(userID string, accesses []string) error {
 	result := r.db.Model(&models.User{}).
 		Where("id = ?", userID).
@@ -185,6 +438,18 @@ func (r *UserRepository) DeleteUser(userID string) error {
 	if err := r.db.Delete(&models.BlockedRefreshToken{}, "user_id = ?", userID).Error; err != nil {
 		return err
 	}
+	if err := r.db.Delete(&models.RevokedAccessToken{}, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
+	if err := r.db.Delete(&models.Session{}, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
+	if err := r.db.Delete(&models.MFARecoveryCode{}, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
+	if err := r.db.Delete(&models.EmailToken{}, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
 	// Finally delete the user
 	return r.db.Delete(&models.User{}, "id = ?", userID).Error
 }
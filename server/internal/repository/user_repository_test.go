@@ -113,23 +113,6 @@ func TestUserRepository_GetUserByID_NotFound(t *testing.T) {
 	}
 }
 
-func TestUserRepository_UpdateRefreshToken(t *testing.T) {
-	db := testutil.SetupTestDB(t)
-	repo := NewUserRepository(db)
-
-	user := &models.User{ID: "user-1", Email: "refresh@example.com", Name: "Refresh", Provider: "local", IsActive: true}
-	_ = repo.CreateUser(user)
-
-	err := repo.UpdateRefreshToken("user-1", "new-refresh-token")
-	if err != nil {
-		t.Fatalf("failed to update refresh token: %v", err)
-	}
-
-	if !repo.MatchRefreshToken("user-1", "new-refresh-token") {
-		t.Fatal("expected MatchRefreshToken to return true for the stored token")
-	}
-}
-
 func TestUserRepository_UpdateUser(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	repo := NewUserRepository(db)
@@ -229,6 +212,33 @@ func TestUserRepository_CreateOrUpdateUser(t *testing.T) {
 	}
 }
 
+func TestUserRepository_CreateOrUpdateUserByID(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserRepository(db)
+
+	// Someone else already holds the address at the same provider.
+	_ = repo.CreateUser(&models.User{ID: "other", Email: "old@example.com", Name: "Other", Provider: "sso", IsActive: true})
+
+	user := &models.User{ID: "oidc-1", Email: "new@example.com", Name: "Ada", Provider: "sso", EmailVerified: true}
+	if err := repo.CreateOrUpdateUserByID(user); err != nil {
+		t.Fatalf("CreateOrUpdateUserByID: %v", err)
+	}
+
+	// The address changes at the identity provider; the same account follows.
+	changed := &models.User{ID: "oidc-1", Email: "ada@example.com", Name: "Ada L", Provider: "sso"}
+	if err := repo.CreateOrUpdateUserByID(changed); err != nil {
+		t.Fatalf("CreateOrUpdateUserByID: %v", err)
+	}
+	found, _ := repo.GetUserByID("oidc-1")
+	if found == nil || found.Email != "ada@example.com" || found.Name != "Ada L" || found.EmailVerified {
+		t.Fatalf("expected the account to be updated in place, got %+v", found)
+	}
+	other, _ := repo.GetUserByID("other")
+	if other == nil || other.Name != "Other" {
+		t.Fatalf("expected the other account untouched, got %+v", other)
+	}
+}
+
 func TestUserRepository_GetUserByEmailAndProvider(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	repo := NewUserRepository(db)
@@ -335,6 +345,159 @@ func TestUserRepository_CleanupBlockedTokens(t *testing.T) {
 	}
 }
 
+func TestUserRepository_RevokeAccessToken(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserRepository(db)
+	now := time.Now()
+
+	if err := repo.RevokeAccessToken("hash-1", "user-1", now.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if err := repo.RevokeAccessToken("hash-1", "user-1", now.Add(time.Hour)); err != nil {
+		t.Fatalf("expected revoking twice to succeed, got: %v", err)
+	}
+	_ = repo.RevokeAccessToken("hash-2", "user-1", now.Add(-time.Hour))
+
+	if exp, err := repo.GetAccessTokenRevocation("hash-1", now); err != nil || exp == nil {
+		t.Fatalf("expected hash-1 to be revoked, got %v, %v", exp, err)
+	}
+	if exp, _ := repo.GetAccessTokenRevocation("hash-2", now); exp != nil {
+		t.Fatal("expected a lapsed revocation to be ignored")
+	}
+	if exp, _ := repo.GetAccessTokenRevocation("unknown", now); exp != nil {
+		t.Fatal("expected an unknown token not to be revoked")
+	}
+
+	n, err := repo.PurgeRevokedAccessTokens(now)
+	if err != nil || n != 1 {
+		t.Fatalf("expected to purge 1 revocation, got %d, %v", n, err)
+	}
+}
+
+func TestUserRepository_Sessions(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserRepository(db)
+	now := time.Now()
+
+	for _, s := range []*models.Session{
+		{ID: "s-1", UserID: "user-1", RefreshTokenHash: "h1", ExpiresAt: now.Add(time.Hour), LastUsedAt: now},
+		{ID: "s-2", UserID: "user-1", RefreshTokenHash: "h2", ExpiresAt: now.Add(time.Hour), LastUsedAt: now.Add(-time.Minute)},
+		{ID: "s-3", UserID: "user-1", RefreshTokenHash: "h3", ExpiresAt: now.Add(-time.Hour), LastUsedAt: now},
+	} {
+		if err := repo.CreateSession(s); err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+	}
+
+	if ok, err := repo.RotateSession("s-1", "h1", "h1b", "10.0.0.1", "agent", now.Add(2*time.Hour), now); err != nil || !ok {
+		t.Fatalf("expected the rotation to succeed, got %v, %v", ok, err)
+	}
+	if ok, _ := repo.RotateSession("s-1", "h1", "h1c", "10.0.0.1", "agent", now.Add(2*time.Hour), now); ok {
+		t.Fatal("expected a rotation from a stale hash to fail")
+	}
+	if s, _ := repo.GetSession("s-1"); s == nil || s.RefreshTokenHash != "h1b" || s.IP != "10.0.0.1" {
+		t.Fatalf("unexpected session after rotation: %+v", s)
+	}
+
+	active, _ := repo.GetActiveSessions("user-1", now)
+	if len(active) != 2 || active[0].ID != "s-1" {
+		t.Fatalf("expected 2 active sessions, most recent first, got %+v", active)
+	}
+
+	ids, err := repo.DeleteUserSessions("user-1", "s-1")
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("expected 2 sessions deleted, got %v, %v", ids, err)
+	}
+	if s, _ := repo.GetSession("s-2"); s != nil {
+		t.Fatal("expected s-2 to be deleted")
+	}
+	if found, _ := repo.DeleteSession("other-user", "s-1"); found {
+		t.Fatal("expected another user's session to be left alone")
+	}
+}
+
+func TestUserRepository_MFA(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserRepository(db)
+	now := time.Now()
+	_ = repo.CreateUser(&models.User{ID: "mfa-user", Email: "mfa@ex.com", Name: "MFA", Provider: "local", IsActive: true})
+
+	if err := repo.SetPendingMFASecret("mfa-user", "SECRET"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.EnableMFA("mfa-user", []string{"c1", "c2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u, _ := repo.GetUserByID("mfa-user"); !u.MFAEnabled || u.MFASecret != "SECRET" {
+		t.Fatalf("expected MFA to be enabled, got %+v", u)
+	}
+
+	if ok, _ := repo.UseMFAStep("mfa-user", 10); !ok {
+		t.Fatal("expected a new step to be accepted")
+	}
+	if ok, _ := repo.UseMFAStep("mfa-user", 10); ok {
+		t.Fatal("expected a used step to be rejected")
+	}
+	if ok, _ := repo.UseRecoveryCode("mfa-user", "c1", now); !ok {
+		t.Fatal("expected the recovery code to be accepted")
+	}
+	if ok, _ := repo.UseRecoveryCode("mfa-user", "c1", now); ok {
+		t.Fatal("expected a used recovery code to be rejected")
+	}
+	if n, _ := repo.CountUnusedRecoveryCodes("mfa-user"); n != 1 {
+		t.Fatalf("expected 1 unused recovery code, got %d", n)
+	}
+
+	if err := repo.DisableMFA("mfa-user"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, _ := repo.CountUnusedRecoveryCodes("mfa-user"); n != 0 {
+		t.Fatalf("expected recovery codes to be removed, got %d", n)
+	}
+}
+
+func TestUserRepository_EmailTokens(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	repo := NewUserRepository(db)
+	now := time.Now()
+	_ = repo.CreateUser(&models.User{ID: "mail-user", Email: "mail@ex.com", Name: "Mail", Provider: "local", IsActive: true})
+
+	for _, hash := range []string{"old", "new"} {
+		err := repo.CreateEmailToken(&models.EmailToken{TokenHash: hash, UserID: "mail-user", Purpose: models.EmailTokenVerify, Email: "mail@ex.com", ExpiresAt: now.Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	_ = repo.CreateEmailToken(&models.EmailToken{TokenHash: "expired", UserID: "mail-user", Purpose: models.EmailTokenReset, Email: "mail@ex.com", ExpiresAt: now.Add(-time.Minute)})
+
+	if tok, _ := repo.ConsumeEmailToken("old", models.EmailTokenVerify, now); tok != nil {
+		t.Fatal("expected a new token to replace the old one")
+	}
+	if tok, _ := repo.ConsumeEmailToken("new", models.EmailTokenReset, now); tok != nil {
+		t.Fatal("expected a token to only work for its purpose")
+	}
+	tok, err := repo.ConsumeEmailToken("new", models.EmailTokenVerify, now)
+	if err != nil || tok == nil || tok.UserID != "mail-user" {
+		t.Fatalf("expected to consume the token, got %+v, %v", tok, err)
+	}
+	if tok, _ := repo.ConsumeEmailToken("new", models.EmailTokenVerify, now); tok != nil {
+		t.Fatal("expected a token to be consumed only once")
+	}
+	if tok, _ := repo.ConsumeEmailToken("expired", models.EmailTokenReset, now); tok != nil {
+		t.Fatal("expected an expired token to be rejected")
+	}
+
+	if ok, _ := repo.MarkEmailVerified("mail-user", "other@ex.com"); ok {
+		t.Fatal("expected verification of a stale address to be ignored")
+	}
+	if ok, _ := repo.MarkEmailVerified("mail-user", "mail@ex.com"); !ok {
+		t.Fatal("expected the address to be verified")
+	}
+	if n, _ := repo.PurgeExpiredEmailTokens(now); n != 1 {
+		t.Fatalf("expected to purge 1 expired token, got %d", n)
+	}
+}
+
 func TestUserRepository_UpdateUserAccesses(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	repo := NewUserRepository(db)
@@ -0,0 +1,270 @@
+package schedule
+
+import (
+	"bedrud/internal/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const icsDateTime = "20060102T150405"
+
+// Event is one VEVENT in a calendar. A non-empty RRule makes it recurring.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	Duration    time.Duration
+	RRule       string
+	Created     time.Time
+}
+
+// Series returns a meeting's first occurrence in its own time zone and its
+// parsed recurrence rule, nil for a one-off meeting.
+func Series(m *models.ScheduledMeeting) (time.Time, *Rule, error) {
+	loc, err := time.LoadLocation(m.TimeZone)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("unknown time zone %q", m.TimeZone)
+	}
+	start := m.StartAt.In(loc)
+	if m.RRule == "" {
+		return start, nil, nil
+	}
+	rule, err := ParseRule(m.RRule)
+	return start, rule, err
+}
+
+// NextOccurrence returns the start of the meeting's current or next
+// occurrence, the first one still running at now. It returns false once the
+// series is over.
+func NextOccurrence(m *models.ScheduledMeeting, now time.Time) (time.Time, bool) {
+	start, rule, err := Series(m)
+	if err != nil {
+		return time.Time{}, false
+	}
+	from := now.Add(-m.Duration() + time.Nanosecond)
+	occ := rule.Between(start, from, now.AddDate(100, 0, 0), 1)
+	if len(occ) == 0 {
+		return time.Time{}, false
+	}
+	return occ[0], true
+}
+
+// SeriesEnd returns when the meeting's last occurrence ends, or false for a
+// series without an end.
+func SeriesEnd(m *models.ScheduledMeeting) (time.Time, bool) {
+	start, rule, err := Series(m)
+	if err != nil {
+		return time.Time{}, false
+	}
+	last, ok := rule.Last(start)
+	if !ok {
+		return time.Time{}, false
+	}
+	return last.Add(m.Duration()), true
+}
+
+// tzHorizon is how far past the latest event start a VTIMEZONE lists offset
+// changes. Clients carry the last one forward beyond it.
+const tzHorizon = 10 * 365 * 24 * time.Hour
+
+// Calendar renders events as an iCalendar document. Start times keep their
+// zone, so recurrences follow daylight saving time, and each zone is
+// described by a VTIMEZONE as RFC 5545 requires.
+func Calendar(name string, events []Event) []byte {
+	var b strings.Builder
+	line := func(s string) { writeFolded(&b, s) }
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Bedrud//Meetings//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if name != "" {
+		line("X-WR-CALNAME:" + escapeText(name))
+	}
+	now := time.Now()
+	for _, z := range eventZones(events, now) {
+		writeTimeZone(line, z.loc, z.from, z.until)
+	}
+	stamp := now.UTC().Format(icsDateTime) + "Z"
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + stamp)
+		if !e.Created.IsZero() {
+			line("CREATED:" + e.Created.UTC().Format(icsDateTime) + "Z")
+		}
+		if loc := e.Start.Location(); isUTC(loc) {
+			line("DTSTART:" + e.Start.UTC().Format(icsDateTime) + "Z")
+		} else {
+			line("DTSTART;TZID=" + loc.String() + ":" + e.Start.Format(icsDateTime))
+		}
+		line("DURATION:" + formatDuration(e.Duration))
+		if e.RRule != "" {
+			line("RRULE:" + strings.TrimPrefix(e.RRule, "RRULE:"))
+		}
+		line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.URL != "" {
+			line("URL:" + e.URL)
+			line("LOCATION:" + escapeText(e.URL))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return []byte(b.String())
+}
+
+type zoneSpan struct {
+	loc         *time.Location
+	from, until time.Time
+}
+
+// eventZones returns the time zones the events start in, other than UTC, with
+// the span of time each VTIMEZONE has to cover.
+func eventZones(events []Event, now time.Time) []zoneSpan {
+	var zones []zoneSpan
+	index := make(map[string]int)
+	for _, e := range events {
+		loc := e.Start.Location()
+		if isUTC(loc) {
+			continue
+		}
+		last := e.Start
+		if now.After(last) {
+			last = now
+		}
+		i, ok := index[loc.String()]
+		if !ok {
+			index[loc.String()] = len(zones)
+			zones = append(zones, zoneSpan{loc: loc, from: e.Start, until: last.Add(tzHorizon)})
+			continue
+		}
+		if e.Start.Before(zones[i].from) {
+			zones[i].from = e.Start
+		}
+		if until := last.Add(tzHorizon); until.After(zones[i].until) {
+			zones[i].until = until
+		}
+	}
+	return zones
+}
+
+func isUTC(loc *time.Location) bool {
+	name := loc.String()
+	return name == "UTC" || name == ""
+}
+
+// observance is one STANDARD or DAYLIGHT component of a VTIMEZONE: every
+// change to the same offset and abbreviation.
+type observance struct {
+	daylight   bool
+	name       string
+	fromOffset int
+	toOffset   int
+	onsets     []string
+}
+
+// writeTimeZone writes a VTIMEZONE for loc listing each offset change from
+// the one in effect at from until until, so clients don't need to know the
+// IANA zone name.
+func writeTimeZone(line func(string), loc *time.Location, from, until time.Time) {
+	var obs []*observance
+	add := func(t time.Time, prevOffset int) {
+		name, offset := t.Zone()
+		// An onset is given in the local time of the offset it replaces.
+		onset := t.UTC().Add(time.Duration(prevOffset) * time.Second).Format(icsDateTime)
+		for _, o := range obs {
+			if o.daylight == t.IsDST() && o.name == name && o.fromOffset == prevOffset && o.toOffset == offset {
+				o.onsets = append(o.onsets, onset)
+				return
+			}
+		}
+		obs = append(obs, &observance{daylight: t.IsDST(), name: name, fromOffset: prevOffset, toOffset: offset, onsets: []string{onset}})
+	}
+
+	t := from.In(loc)
+	start, end := t.ZoneBounds()
+	_, offset := t.Zone()
+	if start.IsZero() {
+		add(time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC).In(loc), offset)
+	} else {
+		_, prev := start.Add(-time.Second).Zone()
+		add(start, prev)
+	}
+	for !end.IsZero() && end.Before(until) {
+		t = end.In(loc)
+		prev := offset
+		_, offset = t.Zone()
+		add(t, prev)
+		_, end = t.ZoneBounds()
+	}
+
+	line("BEGIN:VTIMEZONE")
+	line("TZID:" + loc.String())
+	for _, o := range obs {
+		kind := "STANDARD"
+		if o.daylight {
+			kind = "DAYLIGHT"
+		}
+		line("BEGIN:" + kind)
+		line("DTSTART:" + o.onsets[0])
+		if len(o.onsets) > 1 {
+			line("RDATE:" + strings.Join(o.onsets[1:], ","))
+		}
+		line("TZOFFSETFROM:" + formatOffset(o.fromOffset))
+		line("TZOFFSETTO:" + formatOffset(o.toOffset))
+		line("TZNAME:" + escapeText(o.name))
+		line("END:" + kind)
+	}
+	line("END:VTIMEZONE")
+}
+
+// formatOffset renders a UTC offset in seconds as +hhmm.
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// formatDuration renders d as an RFC 5545 duration such as PT1H30M.
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	s := "PT"
+	if h := minutes / 60; h > 0 {
+		s += fmt.Sprintf("%dH", h)
+	}
+	if m := minutes % 60; m > 0 || minutes == 0 {
+		s += fmt.Sprintf("%dM", m)
+	}
+	return s
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeFolded writes a content line, folding it at 75 octets without
+// splitting UTF-8 sequences.
+func writeFolded(b *strings.Builder, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // the leading space counts
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,342 @@
+// Package schedule expands recurring meetings and renders them as iCalendar
+// (RFC 5545) data.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies supported in an RRULE.
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// maxPeriods bounds how many periods (days, weeks, months or years) are
+// walked when expanding a rule, so rules that never match can't loop forever.
+const maxPeriods = 10000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// WeekdayNum is a BYDAY value such as "MO" or, in monthly and yearly rules,
+// "2TU" or "-1FR". N is 0 when there is no ordinal.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed RRULE. It covers the subset calendar clients commonly
+// produce: FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH and WKST.
+// WKST is accepted but weeks always start on Monday.
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+// ParseRule parses an RRULE value, with or without the "RRULE:" prefix.
+func ParseRule(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return nil, errors.New("rrule is empty")
+	}
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("rrule: malformed part %q", part)
+		}
+		var err error
+		switch name {
+		case "FREQ":
+			switch value {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				r.Freq = value
+			default:
+				return nil, fmt.Errorf("rrule: unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(value); err != nil || r.Interval < 1 {
+				return nil, errors.New("rrule: INTERVAL must be a positive number")
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(value); err != nil || r.Count < 1 {
+				return nil, errors.New("rrule: COUNT must be a positive number")
+			}
+		case "UNTIL":
+			if r.Until, err = parseUntil(value); err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(v)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				d, err := strconv.Atoi(v)
+				if err != nil || d == 0 || d < -31 || d > 31 {
+					return nil, fmt.Errorf("rrule: invalid BYMONTHDAY %q", v)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, d)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				m, err := strconv.Atoi(v)
+				if err != nil || m < 1 || m > 12 {
+					return nil, fmt.Errorf("rrule: invalid BYMONTH %q", v)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "WKST":
+			if _, ok := weekdays[value]; !ok {
+				return nil, fmt.Errorf("rrule: invalid WKST %q", value)
+			}
+		default:
+			return nil, fmt.Errorf("rrule: %s is not supported", name)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("rrule: FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, errors.New("rrule: COUNT and UNTIL cannot both be set")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != FreqMonthly && r.Freq != FreqYearly {
+			return nil, errors.New("rrule: BYDAY ordinals need FREQ=MONTHLY or YEARLY")
+		}
+	}
+	if r.Freq == FreqYearly && len(r.ByDay) > 0 && len(r.ByMonth) == 0 {
+		return nil, errors.New("rrule: yearly BYDAY needs BYMONTH")
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != FreqMonthly && r.Freq != FreqYearly {
+		return nil, errors.New("rrule: BYMONTHDAY needs FREQ=MONTHLY or YEARLY")
+	}
+	return r, nil
+}
+
+func parseUntil(v string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			if layout == "20060102" {
+				// A date UNTIL includes the whole day.
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("rrule: invalid UNTIL %q", v)
+}
+
+func parseWeekdayNum(v string) (WeekdayNum, error) {
+	if len(v) < 2 {
+		return WeekdayNum{}, fmt.Errorf("rrule: invalid BYDAY %q", v)
+	}
+	day, ok := weekdays[v[len(v)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("rrule: invalid BYDAY %q", v)
+	}
+	wd := WeekdayNum{Day: day}
+	if prefix := v[:len(v)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("rrule: invalid BYDAY %q", v)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+// Between returns the start times of the occurrences of a series beginning
+// at start that fall in [from, to), at most limit of them (0 for no limit).
+// Occurrences keep start's wall-clock time in start's location, across DST
+// changes. A nil rule is a single occurrence.
+func (r *Rule) Between(start, from, to time.Time, limit int) []time.Time {
+	var out []time.Time
+	if r == nil {
+		if !start.Before(from) && start.Before(to) {
+			out = append(out, start)
+		}
+		return out
+	}
+	seen := 0
+	for period := 0; period < maxPeriods; period++ {
+		cands := r.period(start, period)
+		for _, t := range cands {
+			if t.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return out
+			}
+			seen++
+			if r.Count > 0 && seen > r.Count {
+				return out
+			}
+			if !t.Before(to) {
+				return out
+			}
+			if !t.Before(from) {
+				out = append(out, t)
+				if limit > 0 && len(out) >= limit {
+					return out
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Last returns the final occurrence of a bounded series, or false when the
+// series has no end.
+func (r *Rule) Last(start time.Time) (time.Time, bool) {
+	if r == nil {
+		return start, true
+	}
+	if r.Count == 0 && r.Until.IsZero() {
+		return time.Time{}, false
+	}
+	end := r.Until.Add(time.Second)
+	if r.Until.IsZero() {
+		end = start.AddDate(maxPeriods, 0, 0)
+	}
+	occ := r.Between(start, start, end, 0)
+	if len(occ) == 0 {
+		return start, true
+	}
+	return occ[len(occ)-1], true
+}
+
+// period returns the candidate occurrences in the n-th period of the rule,
+// in order.
+func (r *Rule) period(start time.Time, n int) []time.Time {
+	loc := start.Location()
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, 0, loc)
+	}
+	step := n * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		t := at(y, m, d+step)
+		if r.matchesMonth(t.Month()) && r.matchesWeekday(t.Weekday()) {
+			days = append(days, t)
+		}
+	case FreqWeekly:
+		offset := (int(start.Weekday()) + 6) % 7 // days since Monday
+		monday := at(y, m, d-offset+7*step)
+		if len(r.ByDay) == 0 {
+			days = append(days, at(y, m, d+7*step))
+			break
+		}
+		for i := 0; i < 7; i++ {
+			t := at(monday.Year(), monday.Month(), monday.Day()+i)
+			if r.matchesWeekday(t.Weekday()) && r.matchesMonth(t.Month()) {
+				days = append(days, t)
+			}
+		}
+	case FreqMonthly:
+		first := at(y, m+time.Month(step), 1)
+		if r.matchesMonth(first.Month()) {
+			days = r.daysInMonth(first, d, at)
+		}
+	case FreqYearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{m}
+		}
+		for _, month := range months {
+			days = append(days, r.daysInMonth(at(y+step, month, 1), d, at)...)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// daysInMonth expands BYMONTHDAY and BYDAY within the month of first,
+// defaulting to the series' day of month.
+func (r *Rule) daysInMonth(first time.Time, defaultDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	y, m := first.Year(), first.Month()
+	last := at(y, m+1, 0).Day()
+	var days []time.Time
+	add := func(d int) {
+		if d >= 1 && d <= last {
+			days = append(days, at(y, m, d))
+		}
+	}
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = last + d + 1
+			}
+			if len(r.ByDay) == 0 || r.matchesWeekday(at(y, m, d).Weekday()) {
+				add(d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			firstDay := 1 + (int(wd.Day)-int(first.Weekday())+7)%7
+			switch {
+			case wd.N > 0:
+				add(firstDay + 7*(wd.N-1))
+			case wd.N < 0:
+				lastDay := firstDay + 7*((last-firstDay)/7)
+				add(lastDay + 7*(wd.N+1))
+			default:
+				for d := firstDay; d <= last; d += 7 {
+					add(d)
+				}
+			}
+		}
+	default:
+		add(defaultDay)
+	}
+	return days
+}
+
+func (r *Rule) matchesWeekday(d time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == d {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonth(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.ByMonth {
+		if bm == m {
+			return true
+		}
+	}
+	return false
+}
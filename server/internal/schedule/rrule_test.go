@@ -0,0 +1,159 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func days(ts []time.Time) []string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Format("2006-01-02 15:04 MST")
+	}
+	return out
+}
+
+func TestParseRule_Rejects(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20260101",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=WEEKLY;BYMONTHDAY=3",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=MONTHLY;BYDAY=XX",
+	} {
+		if _, err := ParseRule(s); err == nil {
+			t.Errorf("ParseRule(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	utc := time.UTC
+	cases := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []string
+	}{
+		{
+			name:  "daily count",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: time.Date(2026, 3, 30, 9, 0, 0, 0, utc),
+			want:  []string{"2026-03-30 09:00 UTC", "2026-03-31 09:00 UTC", "2026-04-01 09:00 UTC"},
+		},
+		{
+			name:  "weekly by day",
+			rule:  "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			start: time.Date(2026, 1, 7, 10, 0, 0, 0, utc), // a Wednesday
+			want:  []string{"2026-01-07 10:00 UTC", "2026-01-12 10:00 UTC", "2026-01-14 10:00 UTC", "2026-01-19 10:00 UTC"},
+		},
+		{
+			name:  "biweekly until",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;UNTIL=20260129",
+			start: time.Date(2026, 1, 1, 8, 0, 0, 0, utc),
+			want:  []string{"2026-01-01 08:00 UTC", "2026-01-15 08:00 UTC", "2026-01-29 08:00 UTC"},
+		},
+		{
+			name:  "last friday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start: time.Date(2026, 1, 30, 16, 0, 0, 0, utc),
+			want:  []string{"2026-01-30 16:00 UTC", "2026-02-27 16:00 UTC", "2026-03-27 16:00 UTC"},
+		},
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "FREQ=MONTHLY;COUNT=3",
+			start: time.Date(2026, 1, 31, 12, 0, 0, 0, utc),
+			want:  []string{"2026-01-31 12:00 UTC", "2026-03-31 12:00 UTC", "2026-05-31 12:00 UTC"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := ParseRule(tc.rule)
+			if err != nil {
+				t.Fatalf("ParseRule: %v", err)
+			}
+			got := days(r.Between(tc.start, tc.start, tc.start.AddDate(1, 0, 0), 0))
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBetween_KeepsWallClockAcrossDST(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	r, _ := ParseRule("FREQ=WEEKLY")
+	start := time.Date(2026, 3, 23, 9, 0, 0, 0, berlin)
+	occ := r.Between(start, start, start.AddDate(0, 0, 8), 0)
+	if len(occ) != 2 {
+		t.Fatalf("expected 2 occurrences, got %d", len(occ))
+	}
+	if occ[1].Hour() != 9 || occ[1].Sub(occ[0]) != 7*24*time.Hour-time.Hour {
+		t.Errorf("expected 09:00 local after the DST change, got %v", occ[1])
+	}
+}
+
+func TestBetween_WindowAndLast(t *testing.T) {
+	r, _ := ParseRule("FREQ=DAILY;COUNT=5")
+	start := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	got := r.Between(start, start.AddDate(0, 0, 2), start.AddDate(1, 0, 0), 1)
+	if len(got) != 1 || !got[0].Equal(start.AddDate(0, 0, 2)) {
+		t.Errorf("expected the third occurrence, got %v", got)
+	}
+	last, ok := r.Last(start)
+	if !ok || !last.Equal(start.AddDate(0, 0, 4)) {
+		t.Errorf("expected the fifth occurrence as last, got %v %v", last, ok)
+	}
+	open, _ := ParseRule("FREQ=DAILY")
+	if _, ok := open.Last(start); ok {
+		t.Error("expected an open-ended series to have no last occurrence")
+	}
+}
+
+func TestCalendar_EscapesAndFolds(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	out := string(Calendar("Team", []Event{{
+		UID:         "m1@bedrud",
+		Summary:     "Standup; daily, quick",
+		Description: strings.Repeat("long line ", 20),
+		Start:       time.Date(2026, 5, 4, 9, 30, 0, 0, berlin),
+		Duration:    90 * time.Minute,
+		RRule:       "FREQ=DAILY",
+	}}))
+	for _, want := range []string{
+		"SUMMARY:Standup\\; daily\\, quick\r\n",
+		"DTSTART;TZID=Europe/Berlin:20260504T093000\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\nBEGIN:DAYLIGHT\r\nDTSTART:20260329T020000\r\n",
+		"TZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20261025T030000\r\n",
+		"DURATION:PT1H30M\r\n",
+		"RRULE:FREQ=DAILY\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(strings.ReplaceAll(out, "\r\n ", ""), "DESCRIPTION:"+strings.Repeat("long line ", 20)) {
+		t.Errorf("folded description doesn't unfold to the original:\n%s", out)
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+}
@@ -1,45 +1,70 @@
 package scheduler
 
 import (
-	"bedrud/config"
+	"bedrud/internal/auth"
+	"bedrud/internal/blocklist"
+	"bedrud/internal/lknode"
+	"bedrud/internal/models"
 	"bedrud/internal/repository"
+	"bedrud/internal/schedule"
 	"context"
-	"crypto/tls"
-	"net/http"
-	"strings"
 	"time"
 
 	"github.com/go-co-op/gocron"
 	lkauth "github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/rs/zerolog/log"
-	"github.com/twitchtv/twirp"
 )
 
 var scheduler *gocron.Scheduler
 
-// Initialize creates and starts the scheduler with idle room detection.
-func Initialize(roomRepo *repository.RoomRepository, lkCfg *config.LiveKitConfig) {
+// Usage stats are sampled every usageSampleInterval. Hourly buckets are kept
+// for usageHourlyRetention and daily buckets for usageDailyRetention.
+const (
+	usageSampleInterval  = time.Minute
+	usageHourlyRetention = 31 * 24 * time.Hour
+	usageDailyRetention  = 2 * 365 * 24 * time.Hour
+)
+
+// Initialize creates and starts the scheduler with LiveKit node health
+// checks, idle room detection, usage sampling, the room lifecycle policy, chat
+// history retention, expiry of timed room bans, blocklist refreshes and the
+// purge of expired sessions and access token revocations.
+func Initialize(roomRepo *repository.RoomRepository, settingsRepo *repository.SettingsRepository, statsRepo *repository.StatsRepository, nodes *lknode.Pool) {
 	scheduler = gocron.NewScheduler(time.Local)
 
-	apiHost := lkCfg.InternalHost
-	if apiHost == "" {
-		apiHost = lkCfg.Host
-	}
-	var httpClient *http.Client
-	if lkCfg.SkipTLSVerify && strings.HasPrefix(apiHost, "https") {
-		httpClient = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
+	_, _ = scheduler.Every(30).Seconds().Do(func() {
+		if nodes != nil {
+			nodes.CheckHealth(context.Background())
 		}
-	} else {
-		httpClient = http.DefaultClient
-	}
-	lkClient := livekit.NewRoomServiceProtobufClient(apiHost, httpClient)
-
+	})
+	_, _ = scheduler.Every(1).Minute().Do(func() {
+		checkIdleRooms(roomRepo, nodes)
+	})
+	_, _ = scheduler.Every(usageSampleInterval).Do(func() {
+		sampleUsage(statsRepo, nodes, time.Now())
+	})
+	_, _ = scheduler.Every(1).Hour().Do(func() {
+		purgeUsageStats(statsRepo, time.Now())
+	})
+	_, _ = scheduler.Every(10).Minutes().Do(func() {
+		enforceRoomLifecycle(roomRepo, settingsRepo, nodes, time.Now())
+	})
+	_, _ = scheduler.Every(1).Hour().Do(func() {
+		purgeChatHistory(roomRepo)
+	})
 	_, _ = scheduler.Every(1).Minute().Do(func() {
-		checkIdleRooms(roomRepo, lkCfg, lkClient)
+		liftExpiredBans(roomRepo)
+	})
+	_, _ = scheduler.Every(1).Minute().Do(func() {
+		if err := blocklist.PurgeExpired(); err != nil {
+			log.Error().Err(err).Msg("Scheduler: failed to refresh blocklist")
+		}
+	})
+	_, _ = scheduler.Every(1).Hour().Do(func() {
+		if err := auth.PruneRevokedTokens(); err != nil {
+			log.Error().Err(err).Msg("Scheduler: failed to purge expired sessions and token revocations")
+		}
 	})
 
 	scheduler.StartAsync()
@@ -53,9 +78,10 @@ func Stop() {
 }
 
 // checkIdleRooms marks active DB rooms as idle when they have 0 participants in LiveKit.
-// Rooms created within the last 5 minutes are skipped to avoid false positives.
-func checkIdleRooms(roomRepo *repository.RoomRepository, cfg *config.LiveKitConfig, client livekit.RoomService) {
-	if roomRepo == nil {
+// Rooms created within the last 5 minutes are skipped to avoid false positives,
+// as are rooms on nodes that can't be reached right now.
+func checkIdleRooms(roomRepo *repository.RoomRepository, nodes *lknode.Pool) {
+	if roomRepo == nil || nodes == nil {
 		return
 	}
 	rooms, err := roomRepo.GetAllActiveRooms()
@@ -63,27 +89,18 @@ func checkIdleRooms(roomRepo *repository.RoomRepository, cfg *config.LiveKitConf
 		return
 	}
 
-	at := lkauth.NewAccessToken(cfg.APIKey, cfg.APISecret)
-	at.AddGrant(&lkauth.VideoGrant{RoomList: true}) //nolint:staticcheck // AddGrant is deprecated but VideoGrant field is not available in this version of the protocol SDK
-	token, err := at.ToJWT()
-	if err != nil {
-		log.Error().Err(err).Msg("Scheduler: failed to generate LiveKit token")
-		return
-	}
-	ctx, _ := twirp.WithHTTPRequestHeaders(context.Background(), http.Header{
-		"Authorization": []string{"Bearer " + token},
-	})
-
-	resp, err := client.ListRooms(ctx, &livekit.ListRoomsRequest{})
-	if err != nil {
-		log.Debug().Err(err).Msg("Scheduler: failed to list LiveKit rooms")
-		return
-	}
-
-	// Build map of room name -> participant count from LiveKit
-	lkRooms := make(map[string]uint32, len(resp.Rooms))
-	for _, r := range resp.Rooms {
-		lkRooms[r.Name] = r.NumParticipants
+	// Map of node -> room name -> participant count, for reachable nodes only
+	lkRooms := make(map[*lknode.Node]map[string]uint32)
+	for _, n := range nodes.Nodes() {
+		resp, err := listNodeRooms(n)
+		if err != nil {
+			continue
+		}
+		counts := make(map[string]uint32, len(resp.Rooms))
+		for _, r := range resp.Rooms {
+			counts[r.Name] = r.NumParticipants
+		}
+		lkRooms[n] = counts
 	}
 
 	grace := 5 * time.Minute
@@ -92,11 +109,198 @@ func checkIdleRooms(roomRepo *repository.RoomRepository, cfg *config.LiveKitConf
 		if time.Since(room.CreatedAt) < grace {
 			continue
 		}
-		count, exists := lkRooms[room.Name]
+		counts, reachable := lkRooms[nodes.Node(room.LiveKitNode)]
+		if !reachable {
+			continue
+		}
+		count, exists := counts[room.Name]
 		if !exists || count == 0 {
 			if err := roomRepo.SetRoomIdle(room.ID); err == nil {
 				log.Info().Str("room", room.Name).Msg("Room set to idle (no participants)")
 			}
+			// Close the meeting in case LiveKit's room_finished never arrived.
+			if err := roomRepo.EndRoomSession(room.ID, time.Now()); err != nil {
+				log.Error().Err(err).Str("room", room.Name).Msg("Scheduler: failed to end room session")
+			}
+		}
+	}
+}
+
+// listNodeRooms lists the rooms currently open on a LiveKit node.
+func listNodeRooms(n *lknode.Node) (*livekit.ListRoomsResponse, error) {
+	ctx := n.WithAuth(context.Background(), &lkauth.VideoGrant{RoomList: true})
+	resp, err := n.Rooms.ListRooms(ctx, &livekit.ListRoomsRequest{})
+	if err != nil {
+		log.Debug().Err(err).Str("node", n.ID).Msg("Scheduler: failed to list LiveKit rooms")
+		return nil, err
+	}
+	return resp, nil
+}
+
+// sampleUsage records how many rooms, participants and publishers LiveKit
+// has right now, summed over all nodes. Nothing is recorded while no node is
+// reachable, so gaps show up as missing samples rather than as zero usage.
+func sampleUsage(statsRepo *repository.StatsRepository, nodes *lknode.Pool, now time.Time) {
+	if statsRepo == nil || nodes == nil {
+		return
+	}
+	sample := models.UsageSample{Interval: usageSampleInterval}
+	reached := false
+	for _, n := range nodes.Nodes() {
+		resp, err := listNodeRooms(n)
+		if err != nil {
+			continue
+		}
+		reached = true
+		sample.Rooms += len(resp.Rooms)
+		for _, r := range resp.Rooms {
+			sample.Participants += int(r.NumParticipants)
+			sample.Publishers += int(r.NumPublishers)
+		}
+	}
+	if !reached {
+		return
+	}
+	if err := statsRepo.RecordUsageSample(now, sample); err != nil {
+		log.Error().Err(err).Msg("Scheduler: failed to record usage sample")
+	}
+}
+
+// purgeUsageStats drops usage buckets that are past their retention, and
+// the record of which minutes were sampled once no replica can still be
+// sampling them.
+func purgeUsageStats(statsRepo *repository.StatsRepository, now time.Time) {
+	if statsRepo == nil {
+		return
+	}
+	if _, err := statsRepo.PurgeUsageSampleClaims(now.Add(-time.Hour)); err != nil {
+		log.Error().Err(err).Msg("Scheduler: failed to purge usage sample claims")
+	}
+	for bucket, keep := range map[string]time.Duration{
+		models.UsageBucketHour: usageHourlyRetention,
+		models.UsageBucketDay:  usageDailyRetention,
+	} {
+		if _, err := statsRepo.PurgeUsageStats(bucket, now.Add(-keep)); err != nil {
+			log.Error().Err(err).Str("bucket", bucket).Msg("Scheduler: failed to purge usage stats")
+		}
+	}
+}
+
+// enforceRoomLifecycle archives expired rooms and rooms idle for longer than
+// the policy allows, closing their LiveKit rooms, then deletes rooms archived
+// past the retention period. Rooms with a meeting in progress are not
+// archived, and rooms with an upcoming scheduled meeting are not archived for
+// being idle.
+func enforceRoomLifecycle(roomRepo *repository.RoomRepository, settingsRepo *repository.SettingsRepository, nodes *lknode.Pool, now time.Time) {
+	if roomRepo == nil {
+		return
+	}
+	policy := (&models.SystemSettings{}).RoomLifecycle()
+	if settingsRepo != nil {
+		settings, err := settingsRepo.GetEffectiveSettings()
+		if err != nil {
+			log.Error().Err(err).Msg("Scheduler: failed to load room lifecycle policy")
+			return
+		}
+		policy = settings.RoomLifecycle()
+	}
+
+	expired, err := roomRepo.CleanupExpiredRooms()
+	if err != nil {
+		log.Error().Err(err).Msg("Scheduler: failed to archive expired rooms")
+	}
+	for i := range expired {
+		closeLiveKitRoom(nodes, &expired[i])
+		log.Info().Str("room", expired[i].Name).Msg("Archived expired room")
+	}
+
+	if policy.ArchiveAfterIdle > 0 {
+		rooms, err := roomRepo.GetIdleRooms(now.Add(-policy.ArchiveAfterIdle))
+		if err != nil {
+			log.Error().Err(err).Msg("Scheduler: failed to list idle rooms")
+		}
+		for i := range rooms {
+			if hasUpcomingMeeting(roomRepo, rooms[i].ID, now) {
+				continue
+			}
+			if err := roomRepo.ArchiveRoom(rooms[i].ID); err != nil {
+				log.Error().Err(err).Str("room", rooms[i].Name).Msg("Scheduler: failed to archive idle room")
+				continue
+			}
+			closeLiveKitRoom(nodes, &rooms[i])
+			log.Info().Str("room", rooms[i].Name).Msg("Archived idle room")
 		}
 	}
+
+	if policy.DeleteAfterArchive > 0 {
+		rooms, err := roomRepo.GetRoomsArchivedBefore(now.Add(-policy.DeleteAfterArchive))
+		if err != nil {
+			log.Error().Err(err).Msg("Scheduler: failed to list archived rooms")
+		}
+		for i := range rooms {
+			if err := roomRepo.AdminDeleteRoom(rooms[i].ID); err != nil {
+				log.Error().Err(err).Str("room", rooms[i].Name).Msg("Scheduler: failed to delete archived room")
+				continue
+			}
+			log.Info().Str("room", rooms[i].Name).Msg("Deleted archived room")
+		}
+	}
+}
+
+// closeLiveKitRoom deletes an archived room's LiveKit room so nobody can stay
+// connected to it. Most archived rooms have no LiveKit room left, so failures
+// are only logged at debug level.
+func closeLiveKitRoom(nodes *lknode.Pool, room *models.Room) {
+	if nodes == nil {
+		return
+	}
+	n := nodes.Node(room.LiveKitNode)
+	ctx := n.WithAuth(context.Background(), &lkauth.VideoGrant{RoomCreate: true})
+	if _, err := n.Rooms.DeleteRoom(ctx, &livekit.DeleteRoomRequest{Room: room.Name}); err != nil {
+		log.Debug().Err(err).Str("room", room.Name).Msg("Scheduler: failed to close LiveKit room of archived room")
+	}
+}
+
+func hasUpcomingMeeting(roomRepo *repository.RoomRepository, roomID string, now time.Time) bool {
+	meetings, err := roomRepo.GetScheduledMeetings(roomID)
+	if err != nil {
+		// Err on the side of keeping the room.
+		return true
+	}
+	for i := range meetings {
+		if _, ok := schedule.NextOccurrence(&meetings[i], now); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// purgeChatHistory deletes chat messages that are past their room's retention period.
+func purgeChatHistory(roomRepo *repository.RoomRepository) {
+	if roomRepo == nil {
+		return
+	}
+	n, err := roomRepo.PurgeExpiredChatMessages(time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("Scheduler: failed to purge chat history")
+		return
+	}
+	if n > 0 {
+		log.Info().Int64("deleted", n).Msg("Purged expired chat messages")
+	}
+}
+
+// liftExpiredBans lifts timed room bans that have run out.
+func liftExpiredBans(roomRepo *repository.RoomRepository) {
+	if roomRepo == nil {
+		return
+	}
+	n, err := roomRepo.LiftExpiredRoomBans(time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("Scheduler: failed to lift expired room bans")
+		return
+	}
+	if n > 0 {
+		log.Info().Int("lifted", n).Msg("Lifted expired room bans")
+	}
 }
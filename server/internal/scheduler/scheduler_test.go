@@ -2,10 +2,14 @@ package scheduler
 
 import (
 	"bedrud/config"
+	"bedrud/internal/lknode"
 	"bedrud/internal/models"
 	"bedrud/internal/repository"
 	"bedrud/internal/testutil"
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"testing"
 	"time"
 
@@ -14,7 +18,7 @@ import (
 
 func TestInitialize_DoesNotPanic(t *testing.T) {
 	// Initialize should not panic with nil deps
-	Initialize(nil, &config.LiveKitConfig{})
+	Initialize(nil, nil, nil, nil)
 	// Stop should not panic either
 	Stop()
 }
@@ -26,7 +30,7 @@ func TestStop_BeforeInitialize(t *testing.T) {
 
 func TestCheckIdleRooms_NilRepo(t *testing.T) {
 	// Should return early without panic
-	checkIdleRooms(nil, &config.LiveKitConfig{}, nil)
+	checkIdleRooms(nil, nil)
 }
 
 func TestCheckIdleRooms_EmptyRooms(t *testing.T) {
@@ -34,7 +38,7 @@ func TestCheckIdleRooms_EmptyRooms(t *testing.T) {
 	roomRepo := repository.NewRoomRepository(db)
 
 	// No rooms in DB → should return without panic
-	checkIdleRooms(roomRepo, &config.LiveKitConfig{}, nil)
+	checkIdleRooms(roomRepo, lknode.NewPool(&config.LiveKitConfig{}))
 }
 
 func TestCheckIdleRooms_RoomsWithinGracePeriod(t *testing.T) {
@@ -52,8 +56,7 @@ func TestCheckIdleRooms_RoomsWithinGracePeriod(t *testing.T) {
 	db.Create(room)
 
 	// Should NOT call LiveKit nor mark idle; exits early due to grace period
-	lkClient := livekit.NewRoomServiceProtobufClient("http://localhost:9999", http.DefaultClient)
-	checkIdleRooms(roomRepo, &config.LiveKitConfig{Host: "http://localhost:9999"}, lkClient)
+	checkIdleRooms(roomRepo, lknode.NewPool(&config.LiveKitConfig{Host: "http://localhost:9999"}))
 
 	// Room should still be active
 	updated, _ := roomRepo.GetRoom("grace-room-1")
@@ -77,10 +80,9 @@ func TestCheckIdleRooms_OldRoomLiveKitUnavailable(t *testing.T) {
 	db.Create(room)
 
 	// LiveKit is unreachable — checkIdleRooms should handle this gracefully
-	lkClient := livekit.NewRoomServiceProtobufClient("http://localhost:9999", http.DefaultClient)
-	checkIdleRooms(roomRepo, &config.LiveKitConfig{
+	checkIdleRooms(roomRepo, lknode.NewPool(&config.LiveKitConfig{
 		Host: "http://localhost:9999", // nothing listening here
-	}, lkClient)
+	}))
 
 	// Room stays active since LiveKit reported an error
 	updated, _ := roomRepo.GetRoom("old-room-1")
@@ -88,3 +90,216 @@ func TestCheckIdleRooms_OldRoomLiveKitUnavailable(t *testing.T) {
 		t.Fatal("room should stay active when LiveKit call fails")
 	}
 }
+
+func TestPurgeChatHistory_RespectsRetention(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+
+	kept, _ := roomRepo.CreateRoom("user-1", "keep-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	short, _ := roomRepo.CreateRoom("user-1", "short-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	short.Settings.ChatRetentionDays = 7
+	_ = roomRepo.UpdateRoom(short)
+
+	old := time.Now().AddDate(0, 0, -30)
+	for _, roomID := range []string{kept.ID, short.ID} {
+		_ = roomRepo.CreateChatMessage(&models.ChatMessage{RoomID: roomID, SenderIdentity: "user-1", Message: "old", CreatedAt: old})
+		_ = roomRepo.CreateChatMessage(&models.ChatMessage{RoomID: roomID, SenderIdentity: "user-1", Message: "new"})
+	}
+
+	purgeChatHistory(roomRepo)
+
+	if msgs, _ := roomRepo.GetChatMessages(kept.ID, "", 10); len(msgs) != 2 {
+		t.Errorf("expected room without retention to keep 2 messages, got %d", len(msgs))
+	}
+	msgs, _ := roomRepo.GetChatMessages(short.ID, "", 10)
+	if len(msgs) != 1 || msgs[0].Message != "new" {
+		t.Errorf("expected only the recent message to remain, got %+v", msgs)
+	}
+}
+
+func TestLiftExpiredBans(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+
+	room, _ := roomRepo.CreateRoom("user-1", "ban-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	_ = roomRepo.AddParticipant(room.ID, "user-2")
+	expired := time.Now().Add(-time.Minute)
+	_ = roomRepo.BanParticipant(&models.RoomBan{RoomID: room.ID, Identity: "user-2", BannedBy: "user-1", ExpiresAt: &expired})
+	_ = roomRepo.BanParticipant(&models.RoomBan{RoomID: room.ID, Identity: "user-3", BannedBy: "user-1"})
+
+	liftExpiredBans(roomRepo)
+
+	bans, _ := roomRepo.GetActiveRoomBans(room.ID)
+	if len(bans) != 1 || bans[0].Identity != "user-3" {
+		t.Fatalf("expected only the permanent ban to remain, got %+v", bans)
+	}
+	if p, _ := roomRepo.GetParticipant(room.ID, "user-2"); p == nil || p.IsBanned {
+		t.Fatalf("expected user-2 to be unbanned, got %+v", p)
+	}
+	if err := roomRepo.AddParticipant(room.ID, "user-2"); err != nil {
+		t.Fatalf("expected user-2 to rejoin, got %v", err)
+	}
+}
+
+func TestEnforceRoomLifecycle(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	settingsRepo.SetConfig(&config.Config{Rooms: config.RoomsConfig{ArchiveAfterIdleDays: 30, DeleteAfterArchiveDays: 90}})
+	now := time.Now()
+	longAgo := now.AddDate(0, 0, -60)
+
+	expired, _ := roomRepo.CreateRoom("user-1", "expired-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	db.Model(&models.Room{}).Where("id = ?", expired.ID).Update("expires_at", now.Add(-time.Hour))
+	idle, _ := roomRepo.CreateRoom("user-1", "idle-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	permanent, _ := roomRepo.CreateRoom("user-1", "permanent-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	_ = roomRepo.RenewRoom(permanent.ID, now.Add(time.Hour), true)
+	scheduled, _ := roomRepo.CreateRoom("user-1", "scheduled-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	_ = roomRepo.CreateScheduledMeeting(&models.ScheduledMeeting{
+		RoomID: scheduled.ID, Title: "Monthly", StartAt: now.AddDate(0, 0, 7), DurationMinutes: 60, TimeZone: "UTC", CreatedBy: "user-1",
+	})
+	busy, _ := roomRepo.CreateRoom("user-1", "busy-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	meeting, _ := roomRepo.CreateRoom("user-1", "meeting-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	db.Model(&models.Room{}).Where("id = ?", meeting.ID).Update("expires_at", now.Add(-time.Hour))
+	breakout, _ := roomRepo.CreateRoom("user-1", "meeting-room-breakout", true, models.RoomModeStandard, &models.RoomSettings{})
+	db.Model(&models.Room{}).Where("id = ?", breakout.ID).Update("parent_room_id", meeting.ID)
+	_ = roomRepo.RecordAttendanceJoin(breakout.ID, "user-2", "User 2", now)
+	for _, id := range []string{idle.ID, permanent.ID, scheduled.ID, busy.ID} {
+		db.Model(&models.Room{}).Where("id = ?", id).Update("created_at", longAgo)
+	}
+	_ = roomRepo.TouchRoom(busy)
+	old, _ := roomRepo.CreateRoom("user-1", "old-room", true, models.RoomModeStandard, &models.RoomSettings{})
+	db.Model(&models.Room{}).Where("id = ?", old.ID).Update("archived_at", now.AddDate(0, 0, -91))
+
+	lk := &fakeLiveKitRooms{}
+	enforceRoomLifecycle(roomRepo, settingsRepo, fakePool(lk), now)
+
+	for _, tc := range []struct {
+		room     *models.Room
+		archived bool
+	}{
+		{expired, true}, {idle, true}, {permanent, false}, {scheduled, false}, {busy, false}, {meeting, false},
+	} {
+		got, _ := roomRepo.GetRoom(tc.room.ID)
+		if got == nil || (got.ArchivedAt != nil) != tc.archived {
+			t.Errorf("%s: expected archived=%v, got %+v", tc.room.Name, tc.archived, got)
+		}
+	}
+	if got, _ := roomRepo.GetRoom(old.ID); got != nil {
+		t.Error("expected the room archived past retention to be deleted")
+	}
+	if want := []string{"expired-room", "idle-room"}; !slices.Equal(lk.deleted, want) {
+		t.Errorf("expected LiveKit rooms %v to be closed, got %v", want, lk.deleted)
+	}
+}
+
+type fakeLiveKitRooms struct {
+	livekit.RoomService
+	rooms   []*livekit.Room
+	err     error
+	deleted []string
+}
+
+func (f *fakeLiveKitRooms) ListRooms(context.Context, *livekit.ListRoomsRequest) (*livekit.ListRoomsResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &livekit.ListRoomsResponse{Rooms: f.rooms}, nil
+}
+
+func (f *fakeLiveKitRooms) DeleteRoom(_ context.Context, req *livekit.DeleteRoomRequest) (*livekit.DeleteRoomResponse, error) {
+	f.deleted = append(f.deleted, req.Room)
+	return &livekit.DeleteRoomResponse{}, nil
+}
+
+// fakePool returns a pool with one node per client, with IDs node-0, node-1, ...
+func fakePool(clients ...*fakeLiveKitRooms) *lknode.Pool {
+	cfg := &config.LiveKitConfig{}
+	for i := range clients {
+		cfg.Nodes = append(cfg.Nodes, config.LiveKitNodeConfig{ID: fmt.Sprintf("node-%d", i), Host: "http://localhost:9999", APIKey: "key", APISecret: "secret"})
+	}
+	pool := lknode.NewPool(cfg)
+	for i, n := range pool.Nodes() {
+		n.Rooms = clients[i]
+	}
+	return pool
+}
+
+func TestSampleAndPurgeUsage(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	statsRepo := repository.NewStatsRepository(db)
+	nodes := fakePool(
+		&fakeLiveKitRooms{rooms: []*livekit.Room{{Name: "a", NumParticipants: 3, NumPublishers: 2}}},
+		&fakeLiveKitRooms{rooms: []*livekit.Room{{Name: "b", NumParticipants: 1, NumPublishers: 1}}},
+		&fakeLiveKitRooms{err: errors.New("unreachable")},
+	)
+	now := time.Now()
+	old := now.AddDate(0, 0, -40)
+
+	sampleUsage(statsRepo, nodes, old)
+	sampleUsage(statsRepo, nodes, now)
+
+	hourly, _ := statsRepo.GetUsageStats(models.UsageBucketHour, now.Add(-time.Hour), now.Add(time.Hour))
+	if len(hourly) != 1 || hourly[0].ParticipantsMax != 4 || hourly[0].PublishersMax != 3 || hourly[0].RoomMinutes != 2 {
+		t.Fatalf("unexpected hourly stats: %+v", hourly)
+	}
+
+	purgeUsageStats(statsRepo, now)
+
+	if hourly, _ = statsRepo.GetUsageStats(models.UsageBucketHour, old.Add(-time.Hour), now.Add(time.Hour)); len(hourly) != 1 {
+		t.Errorf("expected the 40 day old hourly bucket to be purged, got %d buckets", len(hourly))
+	}
+	if daily, _ := statsRepo.GetUsageStats(models.UsageBucketDay, old.AddDate(0, 0, -1), now.Add(time.Hour)); len(daily) != 2 {
+		t.Errorf("expected daily buckets to be kept, got %d", len(daily))
+	}
+}
+
+func TestSampleUsage_OncePerMinuteAcrossReplicas(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	statsRepo := repository.NewStatsRepository(db)
+	nodes := fakePool(&fakeLiveKitRooms{rooms: []*livekit.Room{{Name: "a", NumParticipants: 3}}})
+	minute := time.Now().UTC().Truncate(time.Minute)
+
+	// Two replicas sample the same minute.
+	sampleUsage(statsRepo, nodes, minute.Add(time.Second))
+	sampleUsage(statsRepo, nodes, minute.Add(2*time.Second))
+	sampleUsage(statsRepo, nodes, minute.Add(time.Minute))
+
+	hourly, _ := statsRepo.GetUsageStats(models.UsageBucketHour, minute.Add(-2*time.Hour), minute.Add(2*time.Hour))
+	samples, participantMinutes := 0, 0.0
+	for _, h := range hourly {
+		samples += h.Samples
+		participantMinutes += h.ParticipantMinutes
+	}
+	if samples != 2 || participantMinutes != 6 {
+		t.Fatalf("expected one sample per minute, got %d samples and %v participant minutes", samples, participantMinutes)
+	}
+
+	purgeUsageStats(statsRepo, minute.Add(2*time.Hour))
+	var claims int64
+	db.Model(&models.UsageSampleClaim{}).Count(&claims)
+	if claims != 0 {
+		t.Fatalf("expected old sample claims purged, got %d", claims)
+	}
+}
+
+func TestCheckIdleRooms_SkipsUnreachableNodes(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	roomRepo := repository.NewRoomRepository(db)
+	nodes := fakePool(&fakeLiveKitRooms{}, &fakeLiveKitRooms{err: errors.New("unreachable")})
+
+	old := time.Now().Add(-10 * time.Minute)
+	onUp := &models.Room{ID: "room-up", Name: "room-up", CreatedBy: "user-1", IsActive: true, CreatedAt: old, LiveKitNode: "node-0"}
+	onDown := &models.Room{ID: "room-down", Name: "room-down", CreatedBy: "user-1", IsActive: true, CreatedAt: old, LiveKitNode: "node-1"}
+	db.Create(onUp)
+	db.Create(onDown)
+
+	checkIdleRooms(roomRepo, nodes)
+
+	if got, _ := roomRepo.GetRoom("room-up"); got == nil || got.IsActive {
+		t.Error("expected the empty room on the reachable node to be marked idle")
+	}
+	if got, _ := roomRepo.GetRoom("room-down"); got == nil || !got.IsActive {
+		t.Error("expected the room on the unreachable node to stay active")
+	}
+}
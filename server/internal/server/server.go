@@ -19,9 +19,12 @@ package server
 import (
 	"bedrud/config"
 	"bedrud/internal/auth"
+	"bedrud/internal/blocklist"
 	"bedrud/internal/database"
 	"bedrud/internal/handlers"
 	"bedrud/internal/livekit"
+	"bedrud/internal/lknode"
+	"bedrud/internal/mailer"
 	"bedrud/internal/middleware"
 	"bedrud/internal/models"
 	"bedrud/internal/repository"
@@ -108,9 +111,24 @@ func Run(configPath string) error {
 		log.Error().Err(err).Msg("Failed to run database migrations")
 	}
 	roomRepo := repository.NewRoomRepository(database.GetDB())
-	scheduler.Initialize(roomRepo, &cfg.LiveKit)
-	defer scheduler.Stop()
+	blocklistRepo := repository.NewBlocklistRepository(database.GetDB())
+	if err := blocklist.Init(blocklistRepo); err != nil {
+		log.Error().Err(err).Msg("Failed to load blocklist")
+	}
 	auth.Init(cfg)
+	settingsRepo := repository.NewSettingsRepository(database.GetDB())
+	settingsRepo.SetConfig(cfg)
+	if effective, err := settingsRepo.GetEffectiveSettings(); err == nil {
+		auth.ReloadProviders(effective)
+		auth.ApplyMFAPolicy(effective)
+		mailer.Configure(effective)
+	}
+	statsRepo := repository.NewStatsRepository(database.GetDB())
+	nodes := lknode.NewPool(&cfg.LiveKit)
+	scheduler.Initialize(roomRepo, settingsRepo, statsRepo, nodes)
+	defer scheduler.Stop()
+	mailer.Start()
+	defer mailer.Stop()
 
 	fiberCfg := fiber.Config{AppName: "Bedrud API"}
 	// Enable trusted-proxy mode when: explicit trustedProxies list is set,
@@ -176,13 +194,15 @@ func Run(configPath string) error {
 
 	api := app.Group("/api")
 	userRepo := repository.NewUserRepository(database.GetDB())
+	auth.InitRevocations(userRepo)
 	passkeyRepo := repository.NewPasskeyRepository(database.GetDB())
-	settingsRepo := repository.NewSettingsRepository(database.GetDB())
-	settingsRepo.SetConfig(cfg)
 	inviteTokenRepo := repository.NewInviteTokenRepository(database.GetDB())
 	authService := auth.NewAuthService(userRepo, passkeyRepo)
 	authHandler := handlers.NewAuthHandler(authService, cfg, settingsRepo, inviteTokenRepo)
 	roomHandler := handlers.NewRoomHandler(&cfg.LiveKit, &cfg.Chat, roomRepo)
+	roomHandler.SetNodePool(nodes)
+	roomHandler.SetSettingsRepository(settingsRepo)
+	roomHandler.SetGuestKeySecret(cfg.Auth.JWTSecret)
 
 	api.Post("/auth/register", authHandler.Register)
 	api.Post("/auth/login", authHandler.Login)
@@ -192,6 +212,19 @@ func Run(configPath string) error {
 	api.Get("/auth/me", middleware.Protected(), authHandler.GetMe)
 	api.Put("/auth/me", middleware.Protected(), authHandler.UpdateProfile)
 	api.Put("/auth/password", middleware.Protected(), authHandler.ChangePassword)
+	api.Get("/auth/sessions", middleware.Protected(), authHandler.ListSessions)
+	api.Delete("/auth/sessions", middleware.Protected(), authHandler.RevokeOtherSessions)
+	api.Delete("/auth/sessions/:id", middleware.Protected(), authHandler.RevokeSession)
+	api.Get("/auth/mfa", middleware.Protected(), authHandler.MFAStatus)
+	api.Post("/auth/mfa/enroll", middleware.Protected(), authHandler.EnrollMFA)
+	api.Post("/auth/mfa/enable", middleware.Protected(), authHandler.EnableMFA)
+	api.Post("/auth/mfa/disable", middleware.Protected(), authHandler.DisableMFA)
+	api.Post("/auth/mfa/recovery-codes", middleware.Protected(), authHandler.RegenerateRecoveryCodes)
+	api.Post("/auth/mfa/verify", middleware.AuthRateLimiter(), authHandler.VerifyMFA)
+	api.Post("/auth/verify-email", authHandler.VerifyEmail)
+	api.Post("/auth/verify-email/resend", middleware.Protected(), authHandler.ResendVerificationEmail)
+	api.Post("/auth/password/forgot", authHandler.ForgotPassword)
+	api.Post("/auth/password/reset", authHandler.ResetPassword)
 
 	prefsRepo := repository.NewUserPreferencesRepository(database.GetDB())
 	preferencesHandler := handlers.NewPreferencesHandler(prefsRepo)
@@ -210,13 +243,17 @@ func Run(configPath string) error {
 	api.Post("/room/join", middleware.Protected(), roomHandler.JoinRoom)
 	api.Post("/room/guest-join", roomHandler.GuestJoinRoom)
 	api.Get("/room/list", middleware.Protected(), roomHandler.ListRooms)
+	api.Get("/room/invite/:token", roomHandler.GetRoomInviteInfo)
 	api.Post("/room/:roomId/kick/:identity", middleware.Protected(), roomHandler.KickParticipant)
 	api.Post("/room/:roomId/mute/:identity", middleware.Protected(), roomHandler.MuteParticipant)
 	api.Post("/room/:roomId/ban/:identity", middleware.Protected(), roomHandler.BanParticipant)
+	api.Get("/room/:roomId/bans", middleware.Protected(), roomHandler.ListBans)
+	api.Delete("/room/:roomId/bans/:banId", middleware.Protected(), roomHandler.LiftBan)
 	api.Post("/room/:roomId/video/:identity/off", middleware.Protected(), roomHandler.DisableParticipantVideo)
 	api.Post("/room/:roomId/promote/:identity", middleware.Protected(), roomHandler.PromoteParticipant)
 	api.Post("/room/:roomId/demote/:identity", middleware.Protected(), roomHandler.DemoteParticipant)
 	api.Post("/room/:roomId/chat/:identity/block", middleware.Protected(), roomHandler.BlockChat)
+	api.Post("/room/:roomId/chat/:identity/unblock", middleware.Protected(), roomHandler.UnblockChat)
 	api.Post("/room/:roomId/deafen/:identity", middleware.Protected(), roomHandler.DeafenParticipant)
 	api.Post("/room/:roomId/undeafen/:identity", middleware.Protected(), roomHandler.UndeafenParticipant)
 	api.Post("/room/:roomId/ask/:identity/:action", middleware.Protected(), roomHandler.AskParticipantAction)
@@ -228,6 +265,51 @@ func Run(configPath string) error {
 	api.Put("/room/:roomId/settings", middleware.Protected(), roomHandler.UpdateSettings)
 	api.Delete("/room/:roomId", middleware.Protected(), roomHandler.DeleteRoom)
 	api.Post("/room/:roomId/chat/upload", middleware.Protected(), roomHandler.UploadChatImage)
+	api.Get("/room/:roomId/messages", middleware.Protected(), roomHandler.ListChatMessages)
+	api.Post("/room/:roomId/messages", middleware.Protected(), roomHandler.PostChatMessage)
+	api.Get("/room/:roomId/lobby", middleware.Protected(), roomHandler.ListLobby)
+	api.Post("/room/:roomId/lobby/:identity/admit", middleware.Protected(), roomHandler.AdmitLobbyEntry)
+	api.Post("/room/:roomId/lobby/:identity/deny", middleware.Protected(), roomHandler.DenyLobbyEntry)
+	api.Get("/room/:roomId/lobby/ticket/:ticket", roomHandler.LobbyStatus)
+	api.Get("/room/:roomId/e2ee-key", middleware.Protected(), roomHandler.E2EEKey)
+	api.Get("/room/:roomId/e2ee-key/guest/:identity", roomHandler.GuestE2EEKey)
+	api.Get("/room/:roomId/recordings", middleware.Protected(), roomHandler.ListRecordings)
+	api.Post("/room/:roomId/recordings/start", middleware.Protected(), roomHandler.StartRecording)
+	api.Post("/room/:roomId/recordings/:recordingId/stop", middleware.Protected(), roomHandler.StopRecording)
+	api.Get("/room/:roomId/recordings/:recordingId/download", middleware.Protected(), roomHandler.DownloadRecording)
+	api.Get("/room/:roomId/ingress", middleware.Protected(), roomHandler.ListIngresses)
+	api.Post("/room/:roomId/ingress", middleware.Protected(), roomHandler.CreateIngress)
+	api.Delete("/room/:roomId/ingress/:ingressId", middleware.Protected(), roomHandler.DeleteIngress)
+	api.Get("/room/:roomId/breakouts", middleware.Protected(), roomHandler.ListBreakouts)
+	api.Post("/room/:roomId/breakouts", middleware.Protected(), roomHandler.CreateBreakouts)
+	api.Post("/room/:roomId/breakouts/move", middleware.Protected(), roomHandler.MoveBreakoutParticipant)
+	api.Post("/room/:roomId/breakouts/close", middleware.Protected(), roomHandler.CloseBreakouts)
+	api.Get("/room/:roomId/invites", middleware.Protected(), roomHandler.ListRoomInvites)
+	api.Post("/room/:roomId/invites", middleware.Protected(), roomHandler.CreateRoomInvite)
+	api.Delete("/room/:roomId/invites/:inviteId", middleware.Protected(), roomHandler.RevokeRoomInvite)
+	api.Get("/room/:roomId/invites/:inviteId/redemptions", middleware.Protected(), roomHandler.ListRoomInviteRedemptions)
+	api.Post("/room/:roomId/renew", middleware.Protected(), roomHandler.RenewRoom)
+	api.Get("/room/:roomId/sessions", middleware.Protected(), roomHandler.ListSessions)
+	api.Get("/room/:roomId/sessions/:sessionId/attendance", middleware.Protected(), roomHandler.SessionAttendance)
+	api.Get("/room/:roomId/meetings", middleware.Protected(), roomHandler.ListMeetings)
+	api.Post("/room/:roomId/meetings", middleware.Protected(), roomHandler.CreateMeeting)
+	api.Put("/room/:roomId/meetings/:meetingId", middleware.Protected(), roomHandler.UpdateMeeting)
+	api.Delete("/room/:roomId/meetings/:meetingId", middleware.Protected(), roomHandler.DeleteMeeting)
+	api.Get("/room/:roomId/meetings/:meetingId/ics", middleware.Protected(), roomHandler.MeetingICS)
+	api.Get("/calendar/feed", middleware.Protected(), roomHandler.GetCalendarFeed)
+	api.Post("/calendar/feed/rotate", middleware.Protected(), roomHandler.RotateCalendarFeed)
+	api.Get("/calendar/:token/feed.ics", roomHandler.CalendarFeed)
+	api.Get("/room/:roomId/acl", middleware.Protected(), roomHandler.ListACL)
+	api.Post("/room/:roomId/acl", middleware.Protected(), roomHandler.AddACLEntry)
+	api.Delete("/room/:roomId/acl/:entryId", middleware.Protected(), roomHandler.DeleteACLEntry)
+	api.Get("/room/:roomId/access-requests", middleware.Protected(), roomHandler.ListAccessRequests)
+	api.Post("/room/:roomId/access-requests", middleware.Protected(), roomHandler.RequestAccess)
+	api.Post("/room/:roomId/access-requests/:requestId/approve", middleware.Protected(), roomHandler.ApproveAccessRequest)
+	api.Post("/room/:roomId/access-requests/:requestId/deny", middleware.Protected(), roomHandler.DenyAccessRequest)
+
+	// LiveKit server webhooks (authenticated by the signed Authorization header)
+	webhookHandler := handlers.NewWebhookHandler(&cfg.LiveKit, roomRepo)
+	api.Post("/livekit/webhook", webhookHandler.LiveKitWebhook)
 
 	// Serve disk-backed chat image uploads as static files.
 	// Inline (base64) and S3-hosted images are not served from here.
@@ -252,11 +334,15 @@ func Run(configPath string) error {
 	adminGroup.Put("/users/:id/accesses", usersHandler.UpdateUserAccesses)
 	adminGroup.Get("/rooms", roomHandler.AdminListRooms)
 	adminGroup.Post("/rooms/:roomId/token", roomHandler.AdminGenerateToken)
+	adminGroup.Get("/rooms/:roomId/tokens", roomHandler.AdminListIssuedTokens)
 	adminGroup.Delete("/rooms/:roomId", roomHandler.AdminCloseRoom)
 	adminGroup.Put("/rooms/:roomId", roomHandler.AdminUpdateRoom)
 	adminGroup.Get("/online-count", roomHandler.GetOnlineCount)
 	adminGroup.Get("/livekit/stats", roomHandler.AdminLiveKitStats)
+	statsHandler := handlers.NewStatsHandler(statsRepo)
+	adminGroup.Get("/stats/history", statsHandler.History)
 	adminGroup.Get("/users/:id", usersHandler.GetUserDetail)
+	adminGroup.Delete("/users/:id/sessions", authHandler.AdminRevokeUserSessions)
 	adminGroup.Get("/rooms/:roomId/participants", roomHandler.AdminGetRoomParticipants)
 	adminGroup.Post("/rooms/:roomId/participants/:identity/kick", roomHandler.AdminKickParticipant)
 	adminGroup.Post("/rooms/:roomId/participants/:identity/mute", roomHandler.AdminMuteParticipant)
@@ -266,6 +352,10 @@ func Run(configPath string) error {
 	adminGroup.Get("/invite-tokens", adminHandler.ListInviteTokens)
 	adminGroup.Post("/invite-tokens", adminHandler.CreateInviteToken)
 	adminGroup.Delete("/invite-tokens/:id", adminHandler.DeleteInviteToken)
+	blocklistHandler := handlers.NewBlocklistHandler(blocklistRepo)
+	adminGroup.Get("/blocklist", blocklistHandler.ListEntries)
+	adminGroup.Post("/blocklist", blocklistHandler.AddEntry)
+	adminGroup.Delete("/blocklist/:id", blocklistHandler.DeleteEntry)
 
 	app.Use("/", filesystem.New(filesystem.Config{Root: http.FS(root.UI), PathPrefix: "frontend"}))
 
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"bedrud/config"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// RecordingStore tells LiveKit Egress where to write recordings and resolves
+// finished recordings for download.
+type RecordingStore interface {
+	// Backend returns the backend name ("disk" or "s3").
+	Backend() string
+	// FileOutput is the egress output for a room composite recording.
+	FileOutput(key string) *livekit.EncodedFileOutput
+	// DirectFileOutput is the egress output for a single-track recording.
+	DirectFileOutput(key string) *livekit.DirectFileOutput
+	// Locate resolves the file path egress wrote to into a local file path
+	// (disk) or a time-limited download URL (s3).
+	Locate(location string) (*RecordingLocation, error)
+}
+
+// RecordingLocation is where a finished recording can be fetched from.
+// Exactly one of Path or URL is set.
+type RecordingLocation struct {
+	Path string
+	URL  string
+}
+
+// recordingURLExpiry is how long presigned download URLs stay valid.
+const recordingURLExpiry = 15 * time.Minute
+
+// NewRecordingStore creates the appropriate backend from config.
+func NewRecordingStore(cfg *config.RecordingConfig) RecordingStore {
+	switch strings.ToLower(cfg.Backend) {
+	case "s3":
+		return &s3RecordingStore{s3: &s3Store{cfg: cfg.S3}}
+	default: // "disk" or empty
+		dir := cfg.DiskDir
+		if dir == "" {
+			dir = "./data/recordings"
+		}
+		return &diskRecordingStore{dir: dir}
+	}
+}
+
+// ─── Disk backend ─────────────────────────────────────────────────────────────
+
+// diskRecordingStore writes recordings to a directory shared between the
+// egress service and this server.
+type diskRecordingStore struct{ dir string }
+
+func (s *diskRecordingStore) Backend() string { return "disk" }
+
+func (s *diskRecordingStore) FileOutput(key string) *livekit.EncodedFileOutput {
+	return &livekit.EncodedFileOutput{Filepath: filepath.Join(s.dir, key)}
+}
+
+func (s *diskRecordingStore) DirectFileOutput(key string) *livekit.DirectFileOutput {
+	return &livekit.DirectFileOutput{Filepath: filepath.Join(s.dir, key)}
+}
+
+func (s *diskRecordingStore) Locate(location string) (*RecordingLocation, error) {
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(location)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("recording path outside of recording directory: %s", location)
+	}
+	return &RecordingLocation{Path: path}, nil
+}
+
+// ─── S3-compatible backend ─────────────────────────────────────────────────────
+
+// s3RecordingStore has egress upload straight to the bucket and hands out
+// presigned GET URLs for downloads.
+type s3RecordingStore struct{ s3 *s3Store }
+
+func (s *s3RecordingStore) Backend() string { return "s3" }
+
+func (s *s3RecordingStore) upload() *livekit.S3Upload {
+	cfg := s.s3.cfg
+	return &livekit.S3Upload{
+		AccessKey:      cfg.AccessKey,
+		Secret:         cfg.SecretKey,
+		Region:         s.region(),
+		Endpoint:       cfg.Endpoint,
+		Bucket:         cfg.Bucket,
+		ForcePathStyle: true,
+	}
+}
+
+func (s *s3RecordingStore) FileOutput(key string) *livekit.EncodedFileOutput {
+	return &livekit.EncodedFileOutput{
+		Filepath: key,
+		Output:   &livekit.EncodedFileOutput_S3{S3: s.upload()},
+	}
+}
+
+func (s *s3RecordingStore) DirectFileOutput(key string) *livekit.DirectFileOutput {
+	return &livekit.DirectFileOutput{
+		Filepath: key,
+		Output:   &livekit.DirectFileOutput_S3{S3: s.upload()},
+	}
+}
+
+func (s *s3RecordingStore) Locate(location string) (*RecordingLocation, error) {
+	key := strings.TrimLeft(location, "/")
+	if key == "" || strings.Contains(key, "..") {
+		return nil, fmt.Errorf("invalid recording key: %s", location)
+	}
+	u, err := s.presignGet(key, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &RecordingLocation{URL: u}, nil
+}
+
+func (s *s3RecordingStore) region() string {
+	if s.s3.cfg.Region == "" {
+		return "auto"
+	}
+	return s.s3.cfg.Region
+}
+
+// presignGet builds an AWS SigV4 query-string signed GET URL for key.
+func (s *s3RecordingStore) presignGet(key string, now time.Time) (string, error) {
+	cfg := s.s3.cfg
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" {
+		return "", fmt.Errorf("s3 recording storage is not configured")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil {
+		return "", err
+	}
+	region := s.region()
+	datestamp := now.Format("20060102")
+	amzdate := now.Format("20060102T150405Z")
+	credScope := datestamp + "/" + region + "/s3/aws4_request"
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", cfg.AccessKey+"/"+credScope)
+	query.Set("X-Amz-Date", amzdate)
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int(recordingURLExpiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	// Encode sorts by key, which is what the canonical query string needs.
+	canonicalQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	canonicalURI := endpoint.Path + "/" + cfg.Bucket + "/" + key
+	canonicalRequest := strings.Join([]string{
+		"GET", canonicalURI, canonicalQuery, "host:" + endpoint.Host + "\n", "host", "UNSIGNED-PAYLOAD",
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzdate, credScope,
+		fmt.Sprintf("%x", sha256.Sum256([]byte(canonicalRequest))),
+	}, "\n")
+	signature := fmt.Sprintf("%x", s.s3.hmacSHA256(s.s3.deriveSigningKey(datestamp, region), stringToSign))
+
+	return endpoint.Scheme + "://" + endpoint.Host + canonicalURI + "?" + canonicalQuery + "&X-Amz-Signature=" + signature, nil
+}
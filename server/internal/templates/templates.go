@@ -0,0 +1,10 @@
+// Package templates embeds the server-rendered templates.
+package templates
+
+import "embed"
+
+// Email holds the outbound email templates. Each message has a .txt and a
+// .html template of the same name.
+//
+//go:embed email
+var Email embed.FS
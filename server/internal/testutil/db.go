@@ -25,12 +25,33 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 	err = db.AutoMigrate(
 		&models.User{},
 		&models.BlockedRefreshToken{},
+		&models.RevokedAccessToken{},
+		&models.Session{},
+		&models.MFARecoveryCode{},
+		&models.EmailToken{},
 		&models.Room{},
 		&models.RoomParticipant{},
 		&models.RoomPermissions{},
 		&models.Passkey{},
 		&models.SystemSettings{},
 		&models.InviteToken{},
+		&models.IssuedRoomToken{},
+		&models.Recording{},
+		&models.RoomIngress{},
+		&models.ChatMessage{},
+		&models.RoomInvite{},
+		&models.RoomInviteRedemption{},
+		&models.RoomACLEntry{},
+		&models.RoomAccessRequest{},
+		&models.RoomBan{},
+		&models.BlocklistEntry{},
+		&models.ScheduledMeeting{},
+		&models.CalendarFeed{},
+		&models.RoomSession{},
+		&models.AttendanceSegment{},
+		&models.UsageStat{},
+		&models.UsageSampleClaim{},
+		&models.RoomE2EEKey{},
 	)
 	if err != nil {
 		t.Fatalf("failed to migrate test database: %v", err)
@@ -0,0 +1,64 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key from RFC 6238 appendix B.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists eight digit codes; six digit codes are their last six digits.
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("at %d: expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestValidate_AllowsOneStepOfSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	prev, _ := Code(secret, Step(now)-1)
+	old, _ := Code(secret, Step(now)-2)
+
+	if step, ok := Validate(secret, prev, now); !ok || step != Step(now)-1 {
+		t.Fatalf("expected the previous code to be accepted for its step, got %d, %v", step, ok)
+	}
+	if _, ok := Validate(secret, old, now); ok {
+		t.Fatal("expected a code two periods old to be rejected")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Fatal("expected a short code to be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Bedrud", "ada@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Bedrud:ada@example.com?") {
+		t.Fatalf("unexpected label in %s", uri)
+	}
+	for _, part := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Bedrud", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("expected %s in %s", part, uri)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package usercli
+
+import (
+	"bedrud/config"
+	"bedrud/internal/blocklist"
+	"bedrud/internal/database"
+	"bedrud/internal/models"
+	"bedrud/internal/repository"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BanAdd adds an entry to the site-wide blocklist. An empty typ is guessed
+// from value; a zero duration blocks until the entry is removed. A running
+// server picks the change up within a minute.
+func BanAdd(configPath, typ, value, reason string, duration time.Duration) error {
+	typ, value, err := blocklist.Normalize(typ, value)
+	if err != nil {
+		return err
+	}
+	return withBlocklist(configPath, func(repo *repository.BlocklistRepository) error {
+		entry := &models.BlocklistEntry{Type: typ, Value: value, Reason: reason}
+		if duration > 0 {
+			expiresAt := time.Now().Add(duration)
+			entry.ExpiresAt = &expiresAt
+		}
+		if err := repo.CreateEntry(entry); err != nil {
+			if errors.Is(err, repository.ErrBlocklistEntryExists) {
+				fmt.Printf("%s %q is already blocked.\n", typ, value)
+				return nil
+			}
+			return fmt.Errorf("failed to add blocklist entry: %w", err)
+		}
+		fmt.Printf("✓ Blocked %s %q.\n", typ, value)
+		return nil
+	})
+}
+
+// BanRemove removes an entry from the site-wide blocklist.
+func BanRemove(configPath, typ, value string) error {
+	typ, value, err := blocklist.Normalize(typ, value)
+	if err != nil {
+		return err
+	}
+	return withBlocklist(configPath, func(repo *repository.BlocklistRepository) error {
+		found, err := repo.DeleteEntryByValue(typ, value)
+		if err != nil {
+			return fmt.Errorf("failed to remove blocklist entry: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("%s %q is not blocked", typ, value)
+		}
+		fmt.Printf("✓ Unblocked %s %q.\n", typ, value)
+		return nil
+	})
+}
+
+// BanList prints the blocklist entries in force.
+func BanList(configPath string) error {
+	return withBlocklist(configPath, func(repo *repository.BlocklistRepository) error {
+		entries, err := repo.GetActiveEntries()
+		if err != nil {
+			return fmt.Errorf("failed to list blocklist: %w", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("The blocklist is empty.")
+			return nil
+		}
+		for _, e := range entries {
+			expires := "never"
+			if e.ExpiresAt != nil {
+				expires = e.ExpiresAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-7s %-40s expires %-25s %s\n", e.Type, e.Value, expires, e.Reason)
+		}
+		return nil
+	})
+}
+
+func withBlocklist(configPath string, fn func(*repository.BlocklistRepository) error) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := database.Initialize(&cfg.Database); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer database.Close()
+
+	if err := database.RunMigrations(); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return fn(repository.NewBlocklistRepository(database.GetDB()))
+}